@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/poolski/powertracker/cmd/client"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var importFormat string
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local cache of consumption data",
+}
+
+var cacheImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import historical consumption from a CSV file into the local cache",
+	Long: `
+	Loads historical consumption exported from elsewhere into the local cache, so it is used alongside the data from Home Assistant.
+	Supported formats are Octopus Energy consumption downloads (octopus), n3rgy consumer exports (n3rgy) and "timestamp,kwh" files (generic).`,
+	Args: cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		f, err := os.Open(args[0])
+		if err != nil {
+			log.Fatal().Msgf("opening file: %s", err.Error())
+		}
+		defer f.Close()
+
+		sensorID := client.SensorID()
+		c := client.New(clientConfig())
+		n, err := c.ImportCSV(f, importFormat, sensorID)
+		if err != nil {
+			log.Fatal().Msgf("importing %s: %s", args[0], err.Error())
+		}
+		log.Info().Msgf("imported %d days for %s", n, sensorID)
+	},
+}
+
+func init() {
+	cacheImportCmd.Flags().StringVar(&importFormat, "format", "generic", "format of the CSV file (octopus, n3rgy, generic)")
+
+	cacheCmd.AddCommand(cacheImportCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
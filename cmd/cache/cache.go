@@ -0,0 +1,176 @@
+// Package cache stores fetched consumption data on disk, so it can be reused between runs
+// and combined with historical data imported from elsewhere.
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Entry holds the cached values for a single day of a statistic.
+type Entry struct {
+	Values  []float64 `json:"values"`  // Values holds one entry per hour of the day.
+	Fetched time.Time `json:"fetched"` // Fetched is when the values were stored.
+	Source  string    `json:"source"`  // Source describes where the values came from.
+}
+
+// Store is a cache of daily values, keyed by statistic ID and date. The date is the one in the
+// location of the day given, so days that run from midnight in another time zone than UTC have
+// the dates they have there.
+type Store struct {
+	db *bolt.DB
+}
+
+const dateKey = "2006-01-02"
+
+// Open opens the cache at path, creating it if it doesn't exist.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating cache dir: %w", err)
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening cache: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the cache.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Get returns the entry for the statistic on the given day, and whether one was found.
+func (s *Store) Get(id string, day time.Time) (Entry, bool, error) {
+	var entry Entry
+	var found bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(id))
+		if b == nil {
+			return nil
+		}
+		v := b.Get([]byte(day.Format(dateKey)))
+		if v == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(v, &entry)
+	})
+	if err != nil {
+		return Entry{}, false, fmt.Errorf("reading %s %s: %w", id, day.Format(dateKey), err)
+	}
+	return entry, found, nil
+}
+
+// Put stores the entry for the statistic on the given day, replacing any existing entry.
+func (s *Store) Put(id string, day time.Time, entry Entry) error {
+	v, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encoding entry: %w", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(id))
+		if err != nil {
+			return fmt.Errorf("creating bucket for %s: %w", id, err)
+		}
+		return b.Put([]byte(day.Format(dateKey)), v)
+	})
+}
+
+// checkpointBucket holds the progress of fetches, keyed by statistic ID. Statistic IDs always
+// contain a "." or ":", so it can't clash with the bucket of a statistic.
+const checkpointBucket = "_checkpoints"
+
+// Checkpoint records the progress of a fetch, so it can be resumed if it is interrupted.
+type Checkpoint struct {
+	Started time.Time `json:"started"` // Started is when the fetch began.
+	Through time.Time `json:"through"` // Through is the end of the last day that was completed.
+}
+
+// Checkpoint returns the checkpoint for the statistic, and whether one was found.
+func (s *Store) Checkpoint(id string) (Checkpoint, bool, error) {
+	var cp Checkpoint
+	var found bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(checkpointBucket))
+		if b == nil {
+			return nil
+		}
+		v := b.Get([]byte(id))
+		if v == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(v, &cp)
+	})
+	if err != nil {
+		return Checkpoint{}, false, fmt.Errorf("reading checkpoint for %s: %w", id, err)
+	}
+	return cp, found, nil
+}
+
+// PutCheckpoint stores the checkpoint for the statistic, replacing any existing one.
+func (s *Store) PutCheckpoint(id string, cp Checkpoint) error {
+	v, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("encoding checkpoint: %w", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(checkpointBucket))
+		if err != nil {
+			return fmt.Errorf("creating checkpoint bucket: %w", err)
+		}
+		return b.Put([]byte(id), v)
+	})
+}
+
+// DeleteCheckpoint removes the checkpoint for the statistic, once its fetch has completed.
+func (s *Store) DeleteCheckpoint(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(checkpointBucket))
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+// metaBucket holds facts about the cache as a whole, such as the time zone of Home Assistant when
+// days were last fetched into it. Like the checkpoint bucket, it can't clash with a statistic.
+const metaBucket = "_meta"
+
+// Meta returns the value stored under key, and whether there is one.
+func (s *Store) Meta(key string) (string, bool, error) {
+	var value string
+	var found bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(metaBucket))
+		if b == nil {
+			return nil
+		}
+		if v := b.Get([]byte(key)); v != nil {
+			value, found = string(v), true
+		}
+		return nil
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("reading %s: %w", key, err)
+	}
+	return value, found, nil
+}
+
+// PutMeta stores the value under key, replacing any existing one.
+func (s *Store) PutMeta(key, value string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(metaBucket))
+		if err != nil {
+			return fmt.Errorf("creating meta bucket: %w", err)
+		}
+		return b.Put([]byte(key), []byte(value))
+	})
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestStore_PutGet(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "powertracker", "cache.db"))
+	assert.NilError(t, err)
+	defer s.Close()
+
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	_, found, err := s.Get("sensor.energy", day)
+	assert.NilError(t, err)
+	assert.Assert(t, !found, "expected no entry before put")
+
+	entry := Entry{Values: []float64{0.5, 1.25}, Fetched: day.Add(48 * time.Hour), Source: "import:octopus"}
+	assert.NilError(t, s.Put("sensor.energy", day, entry))
+
+	got, found, err := s.Get("sensor.energy", day)
+	assert.NilError(t, err)
+	assert.Assert(t, found, "expected entry after put")
+	assert.DeepEqual(t, got.Values, entry.Values)
+	assert.Equal(t, got.Source, entry.Source)
+	assert.Assert(t, got.Fetched.Equal(entry.Fetched))
+
+	_, found, err = s.Get("sensor.other", day)
+	assert.NilError(t, err)
+	assert.Assert(t, !found, "expected entries to be keyed by statistic ID")
+
+	// A day starting at midnight in London in summer is keyed by its date there, not in UTC.
+	london, err := time.LoadLocation("Europe/London")
+	assert.NilError(t, err)
+	local := time.Date(2023, 9, 2, 0, 0, 0, 0, london)
+	assert.NilError(t, s.Put("sensor.energy", local, entry))
+	_, found, err = s.Get("sensor.energy", time.Date(2023, 9, 2, 0, 0, 0, 0, time.UTC))
+	assert.NilError(t, err)
+	assert.Assert(t, found, "expected entry to be keyed by its local date")
+}
+
+func TestStore_Checkpoint(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "cache.db"))
+	assert.NilError(t, err)
+	defer s.Close()
+
+	assert.NilError(t, s.DeleteCheckpoint("sensor.energy"))
+	_, found, err := s.Checkpoint("sensor.energy")
+	assert.NilError(t, err)
+	assert.Assert(t, !found, "expected no checkpoint before put")
+
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	cp := Checkpoint{Started: day.Add(72 * time.Hour), Through: day}
+	assert.NilError(t, s.PutCheckpoint("sensor.energy", cp))
+
+	got, found, err := s.Checkpoint("sensor.energy")
+	assert.NilError(t, err)
+	assert.Assert(t, found, "expected checkpoint after put")
+	assert.Assert(t, got.Started.Equal(cp.Started))
+	assert.Assert(t, got.Through.Equal(cp.Through))
+
+	assert.NilError(t, s.DeleteCheckpoint("sensor.energy"))
+	_, found, err = s.Checkpoint("sensor.energy")
+	assert.NilError(t, err)
+	assert.Assert(t, !found, "expected checkpoint to be deleted")
+}
+
+func TestStore_Meta(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "cache.db"))
+	assert.NilError(t, err)
+	defer s.Close()
+
+	_, found, err := s.Meta("time_zone")
+	assert.NilError(t, err)
+	assert.Assert(t, !found, "expected no value before put")
+
+	assert.NilError(t, s.PutMeta("time_zone", "Europe/London"))
+	value, found, err := s.Meta("time_zone")
+	assert.NilError(t, err)
+	assert.Assert(t, found, "expected value after put")
+	assert.Equal(t, value, "Europe/London")
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var amberURL = "https://api.amber.com.au/v1"
+
+// nemTime is the timezone used by the Australian National Electricity Market, which the Amber
+// API uses for its date parameters.
+var nemTime = time.FixedZone("AEST", 10*60*60)
+
+// amber fetches the half-hourly prices charged on an Amber Electric site's general usage
+// channel. Prices are published in c/kWh, including GST, and converted to AUD/kWh.
+type amber struct {
+	token  string
+	siteID string
+}
+
+type amberInterval struct {
+	Type        string    `json:"type"`
+	Duration    int       `json:"duration"`
+	EndTime     time.Time `json:"endTime"`
+	PerKwh      float64   `json:"perKwh"`
+	ChannelType string    `json:"channelType"`
+}
+
+func newAmber() (*amber, error) {
+	a := &amber{
+		token:  viper.GetString("prices.amber.token"),
+		siteID: viper.GetString("prices.amber.site_id"),
+	}
+	if a.token == "" {
+		return nil, fmt.Errorf("prices.amber.token is required")
+	}
+	if a.siteID == "" {
+		return nil, fmt.Errorf("prices.amber.site_id is required")
+	}
+	return a, nil
+}
+
+func (a *amber) Prices(start, end time.Time) ([]Price, error) {
+	params := url.Values{
+		"startDate":  {start.In(nemTime).Format("2006-01-02")},
+		"endDate":    {end.Add(-time.Second).In(nemTime).Format("2006-01-02")},
+		"resolution": {"30"},
+	}
+	req, err := http.NewRequest(http.MethodGet, amberURL+"/sites/"+url.PathEscape(a.siteID)+"/prices?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("amber: creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+a.token)
+
+	var intervals []amberInterval
+	if err := doJSON(req, &intervals); err != nil {
+		return nil, fmt.Errorf("amber: %w", err)
+	}
+
+	var prices []Price
+	for _, interval := range intervals {
+		if interval.ChannelType != "general" {
+			continue
+		}
+		// Start times are offset by a second, so work back from the end of the interval.
+		prices = append(prices, Price{
+			Start: interval.EndTime.Add(-time.Duration(interval.Duration) * time.Minute),
+			End:   interval.EndTime,
+			Rate:  interval.PerKwh / 100,
+		})
+	}
+	if len(prices) == 0 {
+		return nil, fmt.Errorf("amber: no prices returned for site %s", a.siteID)
+	}
+	return prices, nil
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestAmber_Prices(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Path, "/sites/site-1/prices", "unexpected path")
+		assert.Equal(t, r.Header.Get("Authorization"), "Bearer test_token", "unexpected auth header")
+		assert.Equal(t, r.URL.Query().Get("startDate"), "2023-09-01", "unexpected start date")
+		assert.Equal(t, r.URL.Query().Get("endDate"), "2023-09-01", "unexpected end date")
+		_, _ = w.Write([]byte(`[
+			{"type":"ActualInterval","duration":30,"startTime":"2023-08-31T14:00:01Z","endTime":"2023-08-31T14:30:00Z","perKwh":25.5,"channelType":"general"},
+			{"type":"ActualInterval","duration":30,"startTime":"2023-08-31T14:00:01Z","endTime":"2023-08-31T14:30:00Z","perKwh":-3.2,"channelType":"feedIn"},
+			{"type":"ActualInterval","duration":30,"startTime":"2023-08-31T14:30:01Z","endTime":"2023-08-31T15:00:00Z","perKwh":30,"channelType":"general"}
+		]`))
+	}))
+	defer s.Close()
+
+	amberURL = s.URL
+	viper.Set("prices.amber.token", "test_token")
+	viper.Set("prices.amber.site_id", "site-1")
+
+	a, err := newAmber()
+	assert.NilError(t, err)
+
+	start := time.Date(2023, 8, 31, 14, 0, 0, 0, time.UTC)
+	prices, err := a.Prices(start, start.Add(24*time.Hour))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, prices, []Price{
+		{Start: start, End: start.Add(30 * time.Minute), Rate: 0.255},
+		{Start: start.Add(30 * time.Minute), End: start.Add(time.Hour), Rate: 0.3},
+	})
+}
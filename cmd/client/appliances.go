@@ -0,0 +1,193 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/powertracker/cmd/i18n"
+)
+
+const (
+	// fiveMinutes is the width of the recorder's short-term statistics.
+	fiveMinutes = 5 * time.Minute
+	// minEventPower is how far above the baseload, in kW, the draw must rise to count as a load switching on.
+	minEventPower = 0.3
+	// minEventSamples is the shortest run of 5-minute samples that counts as a load.
+	minEventSamples = 2
+	// minOccurrences is how often a load signature must recur to be reported as its own group.
+	minOccurrences = 3
+)
+
+// loadEvent is a period where the draw stayed above the baseload.
+type loadEvent struct {
+	Start    time.Time
+	Duration time.Duration
+	Power    float64 // Power is the average draw above the baseload, in kW.
+	Energy   float64 // Energy is the consumption above the baseload, in kWh.
+}
+
+// applianceGroup is a cluster of load events with a similar draw and duration.
+type applianceGroup struct {
+	Power    float64
+	Duration time.Duration
+	Events   []loadEvent
+}
+
+func (g applianceGroup) energy() float64 {
+	total := 0.0
+	for _, e := range g.Events {
+		total += e.Energy
+	}
+	return total
+}
+
+// findLoadEvents returns the baseload, in kW, and the periods where the draw rose above it.
+// Readings must be 5-minute consumption values, sorted by time.
+func findLoadEvents(readings []Reading) (float64, []loadEvent) {
+	power := make([]float64, len(readings))
+	for i, r := range readings {
+		power[i] = r.Value * float64(time.Hour/fiveMinutes)
+	}
+	baseload := percentile(power, 0.1)
+
+	var events []loadEvent
+	var current *loadEvent
+	var samples int
+	closeEvent := func() {
+		if current != nil && samples >= minEventSamples {
+			current.Power = current.Energy / current.Duration.Hours()
+			events = append(events, *current)
+		}
+		current, samples = nil, 0
+	}
+
+	for i, r := range readings {
+		// A gap in the data ends the event, as we don't know what happened during it.
+		if current != nil && r.Start.Sub(readings[i-1].Start) != fiveMinutes {
+			closeEvent()
+		}
+		excess := power[i] - baseload
+		if excess < minEventPower {
+			closeEvent()
+			continue
+		}
+		if current == nil {
+			current = &loadEvent{Start: r.Start}
+		}
+		current.Duration += fiveMinutes
+		current.Energy += excess * fiveMinutes.Hours()
+		samples++
+	}
+	closeEvent()
+	return baseload, events
+}
+
+// clusterLoadEvents groups events whose average draw is within 25% and duration within 50% of
+// each other, which is enough to separate e.g. a 2kW tumble dryer cycle from a 3kW kettle boil.
+// Groups are returned largest consumer first.
+func clusterLoadEvents(events []loadEvent) []applianceGroup {
+	sorted := append([]loadEvent(nil), events...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Power < sorted[j].Power })
+
+	var groups []applianceGroup
+	for _, e := range sorted {
+		matched := false
+		for i := range groups {
+			g := &groups[i]
+			if math.Abs(e.Power-g.Power) <= 0.25*g.Power &&
+				math.Abs(float64(e.Duration-g.Duration)) <= 0.5*float64(g.Duration) {
+				n := float64(len(g.Events))
+				g.Power = (g.Power*n + e.Power) / (n + 1)
+				g.Duration = time.Duration((float64(g.Duration)*n + float64(e.Duration)) / (n + 1))
+				g.Events = append(g.Events, e)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			groups = append(groups, applianceGroup{Power: e.Power, Duration: e.Duration, Events: []loadEvent{e}})
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].energy() > groups[j].energy() })
+	return groups
+}
+
+// printAppliances is an experimental analysis that looks for recurring load signatures, such as
+// a dishwasher or tumble dryer cycle, in 5-minute data and estimates how much each group of
+// similar loads used. It can't tell which appliance is which, but the typical draw and duration
+// of each group is usually enough to work it out.
+func (c *Client) printAppliances() error {
+	sensorID := SensorID()
+	if sensorID == "" {
+		return fmt.Errorf("sensor_id is required")
+	}
+	end := c.until()
+	start := end.AddDate(0, 0, -c.days())
+
+	readings, err := c.fetch(sensorID, start, end, "5minute", nil)
+	if err != nil {
+		return err
+	}
+	if len(readings) == 0 {
+		return fmt.Errorf("no 5-minute data returned")
+	}
+	sort.Slice(readings, func(i, j int) bool { return readings[i].Start.Before(readings[j].Start) })
+	if covered := end.Sub(readings[0].Start); covered < end.Sub(start) {
+		c.logger().Warn().Msgf("only %.0f days of 5-minute data available - Home Assistant keeps short-term statistics for 10 days by default", covered.Hours()/24)
+	}
+
+	total := 0.0
+	for _, r := range readings {
+		total += r.Value
+	}
+	baseload, events := findLoadEvents(readings)
+	groups := clusterLoadEvents(events)
+	hours := float64(len(readings)) * fiveMinutes.Hours()
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{i18n.T("Group"), i18n.T("Typical draw"), i18n.T("Typical duration"), i18n.T("Occurrences"), "kWh", i18n.T("Share")})
+	row := func(name, power, duration, occurrences string, energy float64) {
+		share := 0.0
+		if total > 0 {
+			share = energy / total * 100
+		}
+		table.Append([]string{name, power, duration, occurrences, fmt.Sprintf("%.2f", energy), fmt.Sprintf("%.1f%%", share)})
+	}
+
+	row("Baseload", fmt.Sprintf("%.0f W", baseload*1000), "always on", "-", baseload*hours)
+	attributed := baseload * hours
+	other := 0.0
+	var n int
+	for _, g := range groups {
+		if len(g.Events) < minOccurrences {
+			other += g.energy()
+			continue
+		}
+		n++
+		row(fmt.Sprintf("Load %d", n), fmt.Sprintf("%.2f kW", g.Power), g.Duration.Round(fiveMinutes).String(), fmt.Sprintf("%d", len(g.Events)), g.energy())
+		attributed += g.energy()
+	}
+	row("Other intermittent loads", "-", "-", "-", other)
+	row("Unattributed", "-", "-", "-", total-attributed-other)
+	table.SetFooter([]string{i18n.T("Total"), "", "", "", fmt.Sprintf("%.2f", total), "100%"})
+	table.Render()
+	return nil
+}
+
+// percentile returns the p-th percentile (0-1) of values, using the nearest-rank method.
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestLoadEvents(t *testing.T) {
+	start := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	// A day of 200W baseload, with a 2kW load running for an hour three times, and a 0.8kW
+	// load running for 30 minutes four times.
+	power := make([]float64, 288)
+	for i := range power {
+		power[i] = 0.2
+	}
+	for _, at := range []int{24, 96, 200} {
+		for i := at; i < at+12; i++ {
+			power[i] += 2
+		}
+	}
+	for _, at := range []int{50, 130, 160, 250} {
+		for i := at; i < at+6; i++ {
+			power[i] += 0.8
+		}
+	}
+	readings := make([]Reading, len(power))
+	for i, p := range power {
+		readings[i] = Reading{Start: start.Add(time.Duration(i) * fiveMinutes), Value: p / 12}
+	}
+
+	baseload, events := findLoadEvents(readings)
+	assert.Equal(t, baseload, 0.2)
+	assert.Equal(t, len(events), 7)
+
+	groups := clusterLoadEvents(events)
+	assert.Equal(t, len(groups), 2)
+	assert.Equal(t, len(groups[0].Events), 3)
+	assert.Equal(t, groups[0].Duration, time.Hour)
+	assert.Assert(t, groups[0].energy() > 5.99 && groups[0].energy() < 6.01, "unexpected energy %f", groups[0].energy())
+	assert.Equal(t, len(groups[1].Events), 4)
+	assert.Equal(t, groups[1].Duration, 30*time.Minute)
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{5, 1, 4, 2, 3}
+	assert.Equal(t, percentile(values, 0.1), 1.0)
+	assert.Equal(t, percentile(values, 0.5), 3.0)
+	assert.Equal(t, percentile(values, 1), 5.0)
+	assert.Equal(t, percentile(nil, 0.5), 0.0)
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/apache/arrow/go/v12/arrow/ipc"
+)
+
+// writeArrow writes the results to an Arrow IPC file (Feather v2) with a row per hour. It isn't
+// compressed, so pandas and Polars can map it straight into memory without parsing it, e.g.
+//
+//	pl.read_ipc("results.arrow", memory_map=True)
+func (c *Client) writeArrow(results []Day) error {
+	rec := hourlyRecord(results)
+	defer rec.Release()
+
+	f, err := os.Create(c.outputFile(".arrow"))
+	if err != nil {
+		return fmt.Errorf("creating file: %w", err)
+	}
+	defer f.Close()
+
+	w, err := ipc.NewFileWriter(f, ipc.WithSchema(hourlySchema))
+	if err != nil {
+		return fmt.Errorf("creating writer: %w", err)
+	}
+	if err := w.Write(rec); err != nil {
+		w.Close()
+		return fmt.Errorf("writing rows: %w", err)
+	}
+	// Closing the writer writes the footer, without which the file can't be read.
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("writing footer: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,40 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v12/arrow"
+	"github.com/apache/arrow/go/v12/arrow/array"
+	"github.com/apache/arrow/go/v12/arrow/ipc"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestClient_WriteArrow(t *testing.T) {
+	viper.Set("sensor_id", "sensor.energy")
+	path := filepath.Join(t.TempDir(), "results.arrow")
+	client := New(Config{FilePath: path})
+
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	err := client.writeArrow([]Day{{Date: day, Values: []float64{0.5, 1.25}}})
+	assert.NilError(t, err)
+
+	f, err := os.Open(path)
+	assert.NilError(t, err)
+	defer f.Close()
+	r, err := ipc.NewFileReader(f)
+	assert.NilError(t, err)
+	defer r.Close()
+
+	assert.Assert(t, r.Schema().Equal(hourlySchema))
+	assert.Equal(t, r.NumRecords(), 1)
+	rec, err := r.Record(0)
+	assert.NilError(t, err)
+	assert.Equal(t, rec.NumRows(), int64(2))
+	assert.Equal(t, rec.Column(0).(*array.String).Value(0), "sensor.energy")
+	assert.Equal(t, rec.Column(1).(*array.Timestamp).Value(1), arrow.Timestamp(day.Add(time.Hour).UnixMilli()))
+	assert.Equal(t, rec.Column(2).(*array.Float64).Value(1), 1.25)
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var awattarURLs = map[string]string{
+	"de": "https://api.awattar.de/v1",
+	"at": "https://api.awattar.at/v1",
+}
+
+// awattar fetches the hourly EPEX spot prices that aWATTar's hourly tariff is indexed against,
+// for Germany or Austria. Prices are published in EUR/MWh and converted to EUR/kWh.
+type awattar struct {
+	baseURL string
+}
+
+func newAwattar() (*awattar, error) {
+	country := viper.GetString("prices.awattar.country")
+	if country == "" {
+		country = "de"
+	}
+	baseURL, ok := awattarURLs[country]
+	if !ok {
+		return nil, fmt.Errorf("prices.awattar.country must be one of de, at")
+	}
+	return &awattar{baseURL: baseURL}, nil
+}
+
+func (a *awattar) Prices(start, end time.Time) ([]Price, error) {
+	params := url.Values{
+		"start": {strconv.FormatInt(start.UnixMilli(), 10)},
+		"end":   {strconv.FormatInt(end.UnixMilli(), 10)},
+	}
+	req, err := http.NewRequest(http.MethodGet, a.baseURL+"/marketdata?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("awattar: creating request: %w", err)
+	}
+
+	var data struct {
+		Data []struct {
+			Start       int64   `json:"start_timestamp"`
+			End         int64   `json:"end_timestamp"`
+			MarketPrice float64 `json:"marketprice"`
+		} `json:"data"`
+	}
+	if err := doJSON(req, &data); err != nil {
+		return nil, fmt.Errorf("awattar: %w", err)
+	}
+	if len(data.Data) == 0 {
+		return nil, fmt.Errorf("awattar: no prices returned")
+	}
+
+	prices := make([]Price, len(data.Data))
+	for i, d := range data.Data {
+		prices[i] = Price{Start: time.UnixMilli(d.Start), End: time.UnixMilli(d.End), Rate: d.MarketPrice / 1000}
+	}
+	return prices, nil
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestAwattar_Prices(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Path, "/marketdata", "unexpected path")
+		assert.Equal(t, r.URL.Query().Get("start"), "1693526400000", "unexpected start")
+		assert.Equal(t, r.URL.Query().Get("end"), "1693533600000", "unexpected end")
+		_, _ = w.Write([]byte(`{"object":"list","data":[
+			{"start_timestamp":1693526400000,"end_timestamp":1693530000000,"marketprice":98.5,"unit":"Eur/MWh"},
+			{"start_timestamp":1693530000000,"end_timestamp":1693533600000,"marketprice":-5,"unit":"Eur/MWh"}
+		]}`))
+	}))
+	defer s.Close()
+
+	awattarURLs["test"] = s.URL
+	viper.Set("prices.awattar.country", "test")
+
+	a, err := newAwattar()
+	assert.NilError(t, err)
+
+	start := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	prices, err := a.Prices(start, start.Add(2*time.Hour))
+	assert.NilError(t, err)
+	assert.Equal(t, len(prices), 2)
+	assert.Assert(t, prices[0].Start.Equal(start))
+	assert.Equal(t, prices[0].Rate, 0.0985)
+	assert.Equal(t, prices[1].Rate, -0.005)
+
+	viper.Set("prices.awattar.country", "fr")
+	_, err = newAwattar()
+	assert.ErrorContains(t, err, "prices.awattar.country must be one of de, at")
+}
@@ -0,0 +1,139 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/powertracker/cmd/i18n"
+	"github.com/spf13/viper"
+)
+
+// balance is the average energy flow in one hour of the day, in kWh.
+type balance struct {
+	Consumption float64
+	Generation  float64
+	SelfUse     float64 // SelfUse is the generation used in the home.
+	Export      float64
+	Import      float64
+}
+
+// generationSensorID returns the solar generation statistic, which can also be set for PVOutput.
+func generationSensorID() string {
+	if id := viper.GetString("generation_sensor_id"); id != "" {
+		return id
+	}
+	return viper.GetString("pvoutput.generation_sensor_id")
+}
+
+// hourlyAverages gets a statistic over the same days as the results and returns its average in
+// each hour of the day.
+func (c *Client) hourlyAverages(id string, results []Day) ([]float64, error) {
+	readings, err := c.statistic(id, results)
+	if err != nil {
+		return nil, err
+	}
+	averages := make([]float64, hoursInADay)
+	for _, day := range results {
+		for h, v := range bucket(readings, day.Date, time.Hour, hoursInADay) {
+			averages[h] += v / float64(len(results))
+		}
+	}
+	return averages, nil
+}
+
+// energyBalance works out where the energy went in each hour. With exports measured, self-use is
+// whatever was generated and not exported. Without them, it is estimated as the lesser of
+// generation and consumption in each hour, with the rest of the generation exported.
+func energyBalance(consumption, generation, exports []float64) []balance {
+	balances := make([]balance, len(consumption))
+	for h := range consumption {
+		b := balance{Consumption: consumption[h], Generation: generation[h]}
+		if exports != nil {
+			b.Export = exports[h]
+			b.SelfUse = math.Max(0, math.Min(b.Generation-b.Export, b.Consumption))
+		} else {
+			b.SelfUse = math.Min(b.Generation, b.Consumption)
+			b.Export = b.Generation - b.SelfUse
+		}
+		b.Import = b.Consumption - b.SelfUse
+		balances[h] = b
+	}
+	return balances
+}
+
+// printBalance prints consumption and solar generation side by side for each hour of the day,
+// along with how much of the generation was used in the home and exported, and how much was
+// imported, so the whole energy balance is visible in one place.
+func (c *Client) printBalance(averages []float64, results []Day) error {
+	id := generationSensorID()
+	if id == "" {
+		return fmt.Errorf("generation_sensor_id is required")
+	}
+	generation, err := c.hourlyAverages(id, results)
+	if err != nil {
+		return fmt.Errorf("getting generation: %w", err)
+	}
+	var exports []float64
+	if exportID := viper.GetString("export_sensor_id"); exportID != "" {
+		if exports, err = c.hourlyAverages(exportID, results); err != nil {
+			return fmt.Errorf("getting exports: %w", err)
+		}
+	}
+	balances := energyBalance(averages, generation, exports)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{i18n.T("Hour"), i18n.T("Consumption"), i18n.T("Generation"), i18n.T("Self-use"), i18n.T("Export"), i18n.T("Import")})
+	var total balance
+	for h, b := range balances {
+		total.Consumption += b.Consumption
+		total.Generation += b.Generation
+		total.SelfUse += b.SelfUse
+		total.Export += b.Export
+		total.Import += b.Import
+		table.Append(balanceRow(fmt.Sprintf("%d", h), b))
+	}
+	table.SetFooter(balanceRow(i18n.T("Day"), total))
+	table.Render()
+
+	if c.Config.Chart {
+		fmt.Print(balanceChart(balances, 60))
+	}
+	return nil
+}
+
+func balanceRow(label string, b balance) []string {
+	return []string{
+		label,
+		fmt.Sprintf("%f", b.Consumption),
+		fmt.Sprintf("%f", b.Generation),
+		fmt.Sprintf("%f", b.SelfUse),
+		fmt.Sprintf("%f", b.Export),
+		fmt.Sprintf("%f", b.Import),
+	}
+}
+
+// balanceChart draws a stacked bar for each hour: self-use and imports, which make up the
+// consumption, followed by exports, which make up the rest of the generation.
+func balanceChart(balances []balance, width int) string {
+	largest := 0.0
+	for _, b := range balances {
+		largest = math.Max(largest, b.SelfUse+b.Import+b.Export)
+	}
+	if largest == 0 {
+		largest = 1
+	}
+	bar := func(v float64, char string) string {
+		return strings.Repeat(char, int(math.Round(v/largest*float64(width))))
+	}
+
+	var sb strings.Builder
+	for h, b := range balances {
+		fmt.Fprintf(&sb, "%02d |%s%s%s\n", h, bar(b.SelfUse, "#"), bar(b.Import, "="), bar(b.Export, "+"))
+	}
+	fmt.Fprintf(&sb, "   # self-use  = import  + export  (full width %.2f kWh)\n", largest)
+	return sb.String()
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestEnergyBalance(t *testing.T) {
+	consumption := []float64{1, 0.5}
+	generation := []float64{0, 2}
+
+	// Without exports, self-use is estimated from generation and consumption.
+	assert.DeepEqual(t, energyBalance(consumption, generation, nil), []balance{
+		{Consumption: 1, Generation: 0, SelfUse: 0, Export: 0, Import: 1},
+		{Consumption: 0.5, Generation: 2, SelfUse: 0.5, Export: 1.5, Import: 0},
+	})
+
+	// Measured exports take precedence.
+	assert.DeepEqual(t, energyBalance(consumption, generation, []float64{0, 1.75}), []balance{
+		{Consumption: 1, Generation: 0, SelfUse: 0, Export: 0, Import: 1},
+		{Consumption: 0.5, Generation: 2, SelfUse: 0.25, Export: 1.75, Import: 0.25},
+	})
+}
+
+func TestBalanceChart(t *testing.T) {
+	chart := balanceChart([]balance{
+		{SelfUse: 0.5, Import: 0.5},
+		{SelfUse: 1, Export: 1},
+	}, 4)
+	lines := strings.Split(chart, "\n")
+	assert.Equal(t, lines[0], "00 |#=")
+	assert.Equal(t, lines[1], "01 |##++")
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/powertracker/cmd/i18n"
+	"github.com/spf13/viper"
+)
+
+// typicalAnnualKWh are Ofgem's Typical Domestic Consumption Values for electricity on a single-rate
+// meter, from April 2023.
+var typicalAnnualKWh = map[string]float64{
+	"low":    1800,
+	"medium": 2700,
+	"high":   4100,
+}
+
+// typicalShape is the share of a day's consumption used in each hour by a typical household,
+// approximating Elexon's profile class 1 (domestic unrestricted) averaged over the year.
+var typicalShape = []float64{
+	0.030, 0.025, 0.023, 0.022, 0.022, 0.024, 0.031, 0.042,
+	0.045, 0.043, 0.042, 0.042, 0.043, 0.041, 0.040, 0.042,
+	0.050, 0.062, 0.066, 0.063, 0.058, 0.053, 0.046, 0.035,
+}
+
+// typicalAnnual returns the annual consumption of the typical household to compare against. This is
+// benchmark.annual_kwh if it is set, otherwise the TDCV for benchmark.size, or for a household with
+// benchmark.occupants people in it.
+func typicalAnnual() (float64, string, error) {
+	if kwh := viper.GetFloat64("benchmark.annual_kwh"); kwh > 0 {
+		return kwh, "custom", nil
+	}
+	size := viper.GetString("benchmark.size")
+	if size == "" {
+		switch occupants := viper.GetInt("benchmark.occupants"); {
+		case occupants == 1:
+			size = "low"
+		case occupants >= 4:
+			size = "high"
+		default:
+			size = "medium"
+		}
+	}
+	kwh, ok := typicalAnnualKWh[size]
+	if !ok {
+		return 0, "", fmt.Errorf("unknown benchmark.size %q", size)
+	}
+	return kwh, size, nil
+}
+
+// typicalProfile spreads the annual consumption over an average day.
+func typicalProfile(annual float64) []float64 {
+	profile := make([]float64, len(typicalShape))
+	for i, share := range typicalShape {
+		profile[i] = annual / 365 * share / sum(typicalShape)
+	}
+	return profile
+}
+
+// printBenchmark compares the average consumption in each hour with that of a typical household.
+func printBenchmark(averages []float64) error {
+	annual, size, err := typicalAnnual()
+	if err != nil {
+		return err
+	}
+	typical := typicalProfile(annual)
+
+	diff := func(yours, typical float64) string {
+		return fmt.Sprintf("%+.0f%%", (yours-typical)/typical*100)
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{i18n.T("Hour"), i18n.T("You (kWh)"), i18n.T("Typical %s (kWh)", i18n.T(size)), i18n.T("Difference")})
+	for h, v := range averages {
+		table.Append([]string{fmt.Sprintf("%d", h), fmt.Sprintf("%f", v), fmt.Sprintf("%f", typical[h]), diff(v, typical[h])})
+	}
+	yours, theirs := sum(averages), sum(typical)
+	table.SetFooter([]string{i18n.T("Day"), fmt.Sprintf("%f", yours), fmt.Sprintf("%f", theirs), diff(yours, theirs)})
+	table.Render()
+
+	fmt.Println(i18n.T("You use around %.0f kWh a year, against %.0f kWh for a typical %s-consumption household.", yours*365, annual, i18n.T(size)))
+	return nil
+}
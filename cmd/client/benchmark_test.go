@@ -0,0 +1,43 @@
+package client
+
+import (
+	"math"
+	"testing"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestTypicalAnnual(t *testing.T) {
+	defer func() {
+		viper.Set("benchmark.size", "")
+		viper.Set("benchmark.occupants", 0)
+		viper.Set("benchmark.annual_kwh", 0)
+	}()
+
+	kwh, size, err := typicalAnnual()
+	assert.NilError(t, err)
+	assert.Equal(t, size, "medium")
+	assert.Equal(t, kwh, 2700.0)
+
+	viper.Set("benchmark.occupants", 5)
+	_, size, err = typicalAnnual()
+	assert.NilError(t, err)
+	assert.Equal(t, size, "high")
+
+	viper.Set("benchmark.size", "huge")
+	_, _, err = typicalAnnual()
+	assert.ErrorContains(t, err, "unknown benchmark.size \"huge\"")
+
+	viper.Set("benchmark.annual_kwh", 3100)
+	kwh, _, err = typicalAnnual()
+	assert.NilError(t, err)
+	assert.Equal(t, kwh, 3100.0)
+}
+
+func TestTypicalProfile(t *testing.T) {
+	profile := typicalProfile(3650)
+	assert.Equal(t, len(profile), hoursInADay)
+	assert.Assert(t, math.Abs(sum(profile)-10) < 1e-9, "expected the profile to add up to a tenth of the annual consumption, got %f", sum(profile))
+	assert.Assert(t, profile[18] > profile[3], "expected the evening peak to be above the overnight trough")
+}
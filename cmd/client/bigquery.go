@@ -0,0 +1,200 @@
+package client
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var bigqueryURL = "https://bigquery.googleapis.com/bigquery/v2"
+
+// bigqueryBatchSize is the number of rows sent in each insertAll request, well under the
+// API's limit of 50,000 rows and 10MB per request.
+const bigqueryBatchSize = 500
+
+// serviceAccount holds the fields used from a Google service account key file.
+type serviceAccount struct {
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// streamBigQuery streams each hourly value into a BigQuery table using the insertAll API.
+// The table must already exist, with the schema:
+//
+//	sensor_id STRING, start TIMESTAMP, kwh FLOAT64
+//
+// Each row is sent with an insert ID derived from the sensor and hour, so BigQuery can drop
+// duplicates when the same period is streamed more than once.
+func (c *Client) streamBigQuery(results []Day) error {
+	credentials := viper.GetString("bigquery.credentials")
+	if credentials == "" {
+		return fmt.Errorf("bigquery.credentials is required")
+	}
+	dataset := viper.GetString("bigquery.dataset")
+	table := viper.GetString("bigquery.table")
+	if dataset == "" || table == "" {
+		return fmt.Errorf("bigquery.dataset and bigquery.table are required")
+	}
+
+	b, err := os.ReadFile(credentials)
+	if err != nil {
+		return fmt.Errorf("reading credentials: %w", err)
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(b, &account); err != nil {
+		return fmt.Errorf("parsing credentials: %w", err)
+	}
+	project := viper.GetString("bigquery.project")
+	if project == "" {
+		project = account.ProjectID
+	}
+
+	token, err := account.accessToken("https://www.googleapis.com/auth/bigquery.insertdata")
+	if err != nil {
+		return fmt.Errorf("getting access token: %w", err)
+	}
+
+	type row struct {
+		InsertID string         `json:"insertId"`
+		JSON     map[string]any `json:"json"`
+	}
+	sensorID := SensorID()
+	var rows []row
+	for _, day := range results {
+		for i, v := range day.Values {
+			start, ok := slotStart(day.Date, i, time.Hour)
+			if !ok {
+				continue
+			}
+			rows = append(rows, row{
+				InsertID: fmt.Sprintf("%s-%d", sensorID, start.Unix()),
+				JSON: map[string]any{
+					"sensor_id": sensorID,
+					"start":     start.UTC().Format(time.RFC3339),
+					"kwh":       v,
+				},
+			})
+		}
+	}
+
+	endpoint := fmt.Sprintf("%s/projects/%s/datasets/%s/tables/%s/insertAll", bigqueryURL,
+		url.PathEscape(project), url.PathEscape(dataset), url.PathEscape(table))
+	for i := 0; i < len(rows); i += bigqueryBatchSize {
+		end := i + bigqueryBatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		batch := rows[i:end]
+		body, err := json.Marshal(map[string]any{"rows": batch})
+		if err != nil {
+			return fmt.Errorf("encoding rows: %w", err)
+		}
+		req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("creating request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		var resp struct {
+			InsertErrors []struct {
+				Index  int `json:"index"`
+				Errors []struct {
+					Reason  string `json:"reason"`
+					Message string `json:"message"`
+				} `json:"errors"`
+			} `json:"insertErrors"`
+		}
+		if err := doJSON(req, &resp); err != nil {
+			return fmt.Errorf("inserting rows: %w", err)
+		}
+		// Any entry means a row wasn't inserted, but not every entry says why, so the first that
+		// does is reported.
+		if len(resp.InsertErrors) > 0 {
+			for _, e := range resp.InsertErrors {
+				for _, detail := range e.Errors {
+					if detail.Message != "" {
+						return fmt.Errorf("inserting %d rows: row %d: %s", len(resp.InsertErrors), i+e.Index, detail.Message)
+					}
+				}
+			}
+			return fmt.Errorf("inserting %d rows: row %d was rejected", len(resp.InsertErrors), i+resp.InsertErrors[0].Index)
+		}
+	}
+	c.logger().Info().Msgf("streamed %d rows to %s.%s.%s", len(rows), project, dataset, table)
+	return nil
+}
+
+// accessToken exchanges a signed JWT for an OAuth2 access token with the given scope, using
+// the service account flow.
+func (a serviceAccount) accessToken(scope string) (string, error) {
+	block, _ := pem.Decode([]byte(a.PrivateKey))
+	if block == nil {
+		return "", fmt.Errorf("no PEM private key found")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("parsing private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", fmt.Errorf("private key is not an RSA key")
+	}
+
+	now := time.Now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": a.PrivateKeyID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iss":   a.ClientEmail,
+		"scope": scope,
+		"aud":   a.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("signing token: %w", err)
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)},
+	}
+	req, err := http.NewRequest(http.MethodPost, a.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var resp struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := doJSON(req, &resp); err != nil {
+		return "", err
+	}
+	return resp.AccessToken, nil
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestClient_StreamBigQuery(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NilError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NilError(t, err)
+
+	var rows []map[string]any
+	response := `{"kind":"bigquery#tableDataInsertAllResponse"}`
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			assert.NilError(t, r.ParseForm(), "parse form failed")
+			parts := strings.Split(r.PostForm.Get("assertion"), ".")
+			assert.Equal(t, len(parts), 3, "malformed JWT")
+			signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+			assert.NilError(t, err, "decode signature failed")
+			hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+			assert.NilError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], signature), "invalid signature")
+			_, _ = w.Write([]byte(`{"access_token":"test_token","token_type":"Bearer"}`))
+		case "/projects/my-project/datasets/home/tables/usage/insertAll":
+			assert.Equal(t, r.Header.Get("Authorization"), "Bearer test_token", "unexpected auth header")
+			var body struct {
+				Rows []map[string]any `json:"rows"`
+			}
+			assert.NilError(t, json.NewDecoder(r.Body).Decode(&body), "decode rows failed")
+			rows = append(rows, body.Rows...)
+			_, _ = w.Write([]byte(response))
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer s.Close()
+
+	credentials, err := json.Marshal(serviceAccount{
+		ProjectID:   "my-project",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		ClientEmail: "powertracker@my-project.iam.gserviceaccount.com",
+		TokenURI:    s.URL + "/token",
+	})
+	assert.NilError(t, err)
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	assert.NilError(t, os.WriteFile(path, credentials, 0600))
+
+	bigqueryURL = s.URL
+	viper.Set("sensor_id", "sensor.energy")
+	viper.Set("bigquery.credentials", path)
+	viper.Set("bigquery.dataset", "home")
+	viper.Set("bigquery.table", "usage")
+
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	client := New(Config{})
+	err = client.streamBigQuery([]Day{{Date: day, Values: []float64{0.5, 1.25}}})
+
+	assert.NilError(t, err)
+	assert.Equal(t, len(rows), 2)
+	assert.Equal(t, rows[1]["insertId"], "sensor.energy-1693530000")
+	assert.DeepEqual(t, rows[1]["json"], map[string]any{
+		"sensor_id": "sensor.energy",
+		"start":     "2023-09-01T01:00:00Z",
+		"kwh":       1.25,
+	})
+
+	// Every entry in insertErrors is a row that wasn't inserted, whether or not it says why.
+	response = `{"insertErrors":[{"index":0,"errors":[]},{"index":1,"errors":[{"reason":"invalid","message":"no such field: kwh"}]}]}`
+	err = client.streamBigQuery([]Day{{Date: day, Values: []float64{0.5, 1.25}}})
+	assert.ErrorContains(t, err, "inserting 2 rows: row 1: no such field: kwh")
+	response = `{"insertErrors":[{"index":1,"errors":[]}]}`
+	err = client.streamBigQuery([]Day{{Date: day, Values: []float64{0.5, 1.25}}})
+	assert.ErrorContains(t, err, "inserting 1 rows: row 1 was rejected")
+}
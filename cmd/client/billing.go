@@ -0,0 +1,50 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// billingDay returns the day of the month billing cycles start on, from billing_day, so monthly
+// figures line up with the supplier's bills. It defaults to 1, for calendar months.
+func billingDay() (int, error) {
+	day := viper.GetInt("billing_day")
+	if day == 0 {
+		return 1, nil
+	}
+	if day < 1 || day > 31 {
+		return 0, fmt.Errorf("billing_day must be between 1 and 31, got %d", day)
+	}
+	return day, nil
+}
+
+// billingMonth returns the start of the billing cycle t falls in, for cycles starting on the given
+// day of the month, in t's location. In months too short for the day, the cycle starts on the last
+// day of the month instead.
+func billingMonth(t time.Time, day int) time.Time {
+	start := cycleStart(t.Year(), t.Month(), day, t.Location())
+	if t.Before(start) {
+		start = cycleStart(t.Year(), t.Month()-1, day, t.Location())
+	}
+	return start
+}
+
+func cycleStart(year int, month time.Month, day int, loc *time.Location) time.Time {
+	// The zeroth day of the next month is the last day of this one.
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+	if day > last {
+		day = last
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// billingLabel returns the label of the billing cycle starting at start: "2006-01" for calendar
+// months, or the date it starts otherwise.
+func billingLabel(start time.Time, day int) string {
+	if day == 1 {
+		return start.Format("2006-01")
+	}
+	return start.Format("2006-01-02")
+}
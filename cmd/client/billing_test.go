@@ -0,0 +1,51 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestBillingMonth(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		t    time.Time
+		day  int
+		want time.Time
+	}{
+		{date(2023, 9, 20), 1, date(2023, 9, 1)},
+		{date(2023, 9, 20), 14, date(2023, 9, 14)},
+		{date(2023, 9, 14), 14, date(2023, 9, 14)},
+		{date(2023, 9, 13), 14, date(2023, 8, 14)},
+		{date(2023, 1, 5), 14, date(2022, 12, 14)},
+		// February is too short for the 31st, so its cycle starts on the 28th...
+		{date(2023, 3, 1), 31, date(2023, 2, 28)},
+		{date(2023, 2, 27), 31, date(2023, 1, 31)},
+		// ...and March's on the 31st.
+		{date(2023, 3, 30), 31, date(2023, 2, 28)},
+		{date(2023, 3, 31), 31, date(2023, 3, 31)},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, billingMonth(tt.t.Add(13*time.Hour), tt.day), tt.want, "%s, day %d", tt.t.Format("2006-01-02"), tt.day)
+	}
+	assert.Equal(t, billingLabel(date(2023, 9, 1), 1), "2023-09")
+	assert.Equal(t, billingLabel(date(2023, 9, 14), 14), "2023-09-14")
+}
+
+func TestBillingDay(t *testing.T) {
+	defer viper.Set("billing_day", nil)
+	day, err := billingDay()
+	assert.NilError(t, err)
+	assert.Equal(t, day, 1)
+
+	viper.Set("billing_day", 14)
+	day, err = billingDay()
+	assert.NilError(t, err)
+	assert.Equal(t, day, 14)
+
+	viper.Set("billing_day", 32)
+	_, err = billingDay()
+	assert.Error(t, err, "billing_day must be between 1 and 31, got 32")
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// checkBlock reports whether slots of the given width can be combined into blocks of the given
+// size, which must be a whole number of slots and fit a whole number of times into a day.
+func checkBlock(block, width time.Duration) error {
+	if block <= width || block%width != 0 || (24*time.Hour)%block != 0 {
+		return fmt.Errorf("a block must be several whole slots that divide a day evenly, e.g. 2h, 3h, 4h or 6h")
+	}
+	return nil
+}
+
+// resample returns the results and averages with the slots of each day combined into blocks, along
+// with a header for each block, such as "00-03".
+func resample(results []Day, averages []float64, width, block time.Duration) ([]Day, []float64, []string) {
+	n := int(block / width)
+	combine := func(values []float64) []float64 {
+		blocks := make([]float64, len(values)/n)
+		for i, v := range values {
+			blocks[i/n] += v
+		}
+		return blocks
+	}
+
+	days := make([]Day, len(results))
+	for i, day := range results {
+		days[i] = Day{Date: day.Date, Values: combine(day.Values), Partial: day.Partial}
+	}
+	headers := make([]string, len(averages)/n)
+	for i := range headers {
+		start := time.Time{}.Add(time.Duration(i) * block)
+		end := start.Add(block)
+		layout := "15"
+		if block%time.Hour != 0 {
+			layout = "15:04"
+		}
+		headers[i] = fmt.Sprintf("%s-%s", start.Format(layout), end.Format(layout))
+	}
+	return days, combine(averages), headers
+}
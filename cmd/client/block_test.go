@@ -0,0 +1,34 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestCheckBlock(t *testing.T) {
+	assert.NilError(t, checkBlock(3*time.Hour, time.Hour))
+	assert.NilError(t, checkBlock(90*time.Minute, 30*time.Minute))
+	assert.ErrorContains(t, checkBlock(5*time.Hour, time.Hour), "divide a day evenly")
+	assert.ErrorContains(t, checkBlock(90*time.Minute, time.Hour), "divide a day evenly")
+	assert.ErrorContains(t, checkBlock(time.Hour, time.Hour), "divide a day evenly")
+}
+
+func TestResample(t *testing.T) {
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	values := make([]float64, hoursInADay)
+	for i := range values {
+		values[i] = float64(i)
+	}
+
+	days, averages, headers := resample([]Day{{Date: day, Values: values}}, values, time.Hour, 6*time.Hour)
+	assert.DeepEqual(t, days, []Day{{Date: day, Values: []float64{15, 51, 87, 123}}})
+	assert.DeepEqual(t, averages, []float64{15, 51, 87, 123})
+	assert.DeepEqual(t, headers, []string{"00-06", "06-12", "12-18", "18-00"})
+
+	// Blocks that aren't whole hours are labelled with minutes.
+	_, averages, headers = resample(nil, make([]float64, halfHoursInADay), 30*time.Minute, 90*time.Minute)
+	assert.Equal(t, len(averages), 16)
+	assert.Equal(t, headers[1], "01:30-03:00")
+}
@@ -1,26 +1,108 @@
 package client
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/powertracker/cmd/cache"
+	"github.com/poolski/powertracker/cmd/i18n"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
+	"golang.org/x/net/http/httpproxy"
 )
 
 type Config struct {
-	Days     int
+	Days int
+	// Start and End, if set, are the first and last days to report on. Only their dates are used.
+	// Start takes the place of Days, and End of yesterday.
+	Start time.Time
+	End   time.Time
+	// TimeZone is the IANA name of the time zone days run from midnight in, such as
+	// Europe/London. If empty, Home Assistant's is used.
+	TimeZone string
 	Output   string
 	FilePath string
 	Insecure bool
+	// CAFile is the path of a PEM file of certificates to trust, besides the system's, for a Home
+	// Assistant with a self-signed certificate or one from a private CA. Insecure overrides it.
+	CAFile string
+	// ClientCert and ClientKey are the paths of a PEM certificate and its key, presented to a
+	// reverse proxy in front of Home Assistant that requires client certificates.
+	ClientCert string
+	ClientKey  string
+	// HalfHourly divides each day into 48 half-hour settlement periods instead of 24 hours.
+	HalfHourly bool
+	// Period is the length of each value: "5minute" divides each day into 288 slots, and "day",
+	// "week" and "month" report a row for each with its total. If empty, it is "hour".
+	Period string
+	// Chart adds a chart to outputs that support one.
+	Chart bool
+	// Split reports a separate profile for each group of hours, e.g. "occupancy".
+	Split string
+	// Clipboard puts the averages on the system clipboard, in the format of the text output.
+	Clipboard bool
+	// Cost adds each day's cost, on the selected tariff, to the table, CSV and Markdown outputs.
+	Cost bool
+	// Explain records how the figures were worked out, for Explain to print at the end of the run.
+	Explain bool
+	// Block combines the hours (or half hours) of the table, CSV, Markdown and Excel outputs into
+	// blocks, e.g. 3h. If zero, every slot is shown.
+	Block time.Duration
+	// IncludeToday adds a row for today's slots so far to the table, CSV and Markdown outputs,
+	// above the days before it and left out of their summaries.
+	IncludeToday bool
+	// Statistics are what to report of each slot over the days: mean, median or a percentile such
+	// as p95. The first takes the place of the averages in the text output, and the table, CSV and
+	// Markdown outputs have a row for each. If empty, the mean is reported.
+	Statistics []string
+	// CacheFile is the path of the local cache. Days found in the cache are used instead of
+	// being fetched from the source, and complete days that are fetched are added to it.
+	// If empty, no cache is used.
+	CacheFile string
+	// SyncDatabase is the path of the SQLite database Sync mirrors consumption into.
+	SyncDatabase string
+	// GlowDir is the directory RecordGlow records the readings of a Glow CAD in, and the glow
+	// source reads them from.
+	GlowDir string
+	// Refresh fetches every day from the source, replacing what is in the cache.
+	Refresh bool
+	// Resume continues a fetch that was interrupted, using the days it had already cached.
+	Resume bool
+	// Offline answers entirely from the cache, without connecting to the source.
+	Offline bool
+	// Demo uses made-up consumption instead of connecting to a source.
+	Demo bool
+	// Record is the path of a file to record the frames exchanged with Home Assistant to.
+	Record string
+	// Replay is the path of a recorded session to play back instead of connecting to Home Assistant.
+	Replay string
+	// Logger receives the client's log messages. If nil, the global zerolog logger is used.
+	Logger *zerolog.Logger
 }
 
+// Client reads consumption from the configured source and reports on it.
+//
+// A connected Client may be shared by several goroutines. Requests to Home Assistant are written
+// one at a time, and each response is handed to the request with its message ID, so several can
+// be in flight at once.
+// Config must not be changed once the Client is in use.
 type Client struct {
 	Config Config
 	Conn   *websocket.Conn
@@ -28,25 +110,76 @@ type Client struct {
 	// These must be incremented with each subsequent request, otherwise the API will
 	// return an error.
 	MessageID int
+
+	// mu guards Conn and MessageID once the Client is connected.
+	mu     sync.Mutex
+	source Source
+	// pending are the requests waiting for a response, by message ID, and reading is the connection
+	// responses are being read from. Like Conn, they are guarded by mu.
+	pending map[int]pendingRequest
+	reading *websocket.Conn
+	// subscriptions are the event subscriptions, by the message ID they were made with. Like Conn,
+	// they are guarded by mu.
+	subscriptions map[int]subscription
+
+	statsMu sync.Mutex
+	stats   Stats
+
+	// stop is closed by Stop.
+	stop     chan struct{}
+	stopInit sync.Once
+	stopOnce sync.Once
+
+	// provenance records how the figures were worked out, with --explain.
+	explainMu  sync.Mutex
+	provenance *provenance
+
+	// zone is the time zone days run from midnight in, once it has been looked up.
+	zoneMu sync.Mutex
+	zone   *time.Location
+
+	// session is the session being recorded, if any. Like Conn, it is guarded by mu.
+	session *session
+	replay  *replayServer
 }
 
 // APIResponse represents the structure of the response received from the Home Assistant API.
 type APIResponse struct {
-	ID      int    `json:"id"`      // ID is the unique identifier of the response.
-	Type    string `json:"type"`    // Type is the type of the response.
-	Success bool   `json:"success"` // Success indicates whether the response was successful or not.
-	Result  map[string][]struct {
-		Change float64 `json:"change"`
-		End    int64   `json:"end"`
-		Start  int64   `json:"start"`
-	} `json:"result"` // Result contains the data returned by the API.
-	Error struct {
+	ID      int                    `json:"id"`      // ID is the unique identifier of the response.
+	Type    string                 `json:"type"`    // Type is the type of the response.
+	Success bool                   `json:"success"` // Success indicates whether the response was successful or not.
+	Result  map[string][]Statistic `json:"result"`  // Result contains the data returned by the API.
+	Error   struct {
 		Code    string `json:"code"`
 		Message string `json:"message"`
 	} `json:"error,omitempty"`
 }
 
-const hoursInADay = 24
+// Statistic is a single row of long-term statistics returned by the recorder.
+type Statistic struct {
+	Change float64 `json:"change"`
+	Mean   float64 `json:"mean"`
+	Sum    float64 `json:"sum"`
+	State  float64 `json:"state"`
+	End    int64   `json:"end"`
+	Start  int64   `json:"start"`
+}
+
+// Day holds the consumption recorded for each hour (or half hour) of a single day. A row of totals,
+// with --period day, week or month, is a Day with just the total of the period starting at Date.
+type Day struct {
+	Date   time.Time // Date is the start of the day.
+	Values []float64 // Values holds one entry per hour, or half hour in half-hourly mode, starting at Date.
+	// Partial marks today's row with --include-today, which only has the slots so far.
+	Partial bool
+	// Label, if set, is shown in place of the date, as for the billing months of --period month.
+	Label string
+}
+
+const (
+	hoursInADay     = 24
+	halfHoursInADay = 48
+)
 
 func New(cfg Config) *Client {
 	return &Client{
@@ -54,52 +187,341 @@ func New(cfg Config) *Client {
 	}
 }
 
+// Connect sets up the configured data source. By default this is the Home Assistant
+// recorder, reached over the websocket API.
 func (c *Client) Connect() error {
-	c.MessageID = 1
+	return c.ConnectContext(context.Background())
+}
 
-	// Set up the websocket dialer
-	dialer := websocket.Dialer{
-		HandshakeTimeout: 10 * time.Second,
+// ConnectContext is like Connect, but gives up connecting to Home Assistant if ctx is done or the
+// client is stopped first, returning ErrInterrupted if it was stopped.
+func (c *Client) ConnectContext(ctx context.Context) error {
+	if _, err := loadLocation(c.Config.TimeZone); err != nil {
+		return err
+	}
+	if c.Config.Demo {
+		// Made-up values mustn't end up in the cache.
+		c.Config.CacheFile = ""
+		c.source = demo{}
+		return nil
+	}
+	if c.Config.Offline {
+		if c.Config.CacheFile == "" {
+			return fmt.Errorf("--offline needs the local cache")
+		}
+		c.source = offline{}
+		return nil
+	}
+
+	source := viper.GetString("source")
+	if c.Config.Replay != "" {
+		// The cache would answer some days without the frames that were recorded for them.
+		r, err := startReplay(c.Config.Replay)
+		if err != nil {
+			return fmt.Errorf("replaying session: %w", err)
+		}
+		c.replay = r
+		c.Config.CacheFile = ""
+		source = "homeassistant"
+	}
+	if c.Config.Record != "" {
+		if source != "" && source != "homeassistant" {
+			return fmt.Errorf("--record needs the Home Assistant source")
+		}
+		c.session = &session{Started: time.Now()}
+	}
+
+	switch source {
+	case "", "homeassistant":
+		if err := c.connectHomeAssistant(ctx); err != nil {
+			return err
+		}
+		c.source = recorder{c}
+	case "glow":
+		g, err := connectGlow(c.Config.GlowDir)
+		if err != nil {
+			return fmt.Errorf("glow: %w", err)
+		}
+		c.source = g
+	default:
+		return fmt.Errorf("unknown source %q", source)
+	}
+	return nil
+}
+
+// logger returns the configured logger, falling back to the global one.
+func (c *Client) logger() *zerolog.Logger {
+	if c.Config.Logger != nil {
+		return c.Config.Logger
+	}
+	return &log.Logger
+}
+
+// Close closes the connection to Home Assistant, if there is one, and saves the recorded session.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.replay != nil {
+		c.replay.Close()
+	}
+	if c.session != nil {
+		if err := c.saveSession(); err != nil {
+			return err
+		}
+	}
+	if c.Conn == nil {
+		return nil
+	}
+	// Closing the websocket properly lets Home Assistant tidy up straight away, rather than when
+	// the connection times out.
+	deadline := time.Now().Add(time.Second)
+	_ = c.Conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), deadline)
+	return c.Conn.Close()
+}
+
+// now returns the current time, or the time a replayed session was recorded.
+func (c *Client) now() time.Time {
+	if c.replay != nil {
+		return c.replay.session.Started
+	}
+	return time.Now()
+}
+
+// tlsConfig returns the TLS settings for Home Assistant: verification skipped with Insecure, or
+// the certificates in CAFile trusted along with the system's, and the client certificate to
+// present, if there is one. It returns nil for the defaults.
+func (c *Client) tlsConfig() (*tls.Config, error) {
+	if !c.Config.Insecure && c.Config.CAFile == "" && c.Config.ClientCert == "" && c.Config.ClientKey == "" {
+		return nil, nil
+	}
+	config := &tls.Config{InsecureSkipVerify: c.Config.Insecure}
+	if c.Config.CAFile != "" && !c.Config.Insecure {
+		pem, err := os.ReadFile(c.Config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA certificate: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", c.Config.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	if c.Config.ClientCert != "" || c.Config.ClientKey != "" {
+		if c.Config.ClientCert == "" || c.Config.ClientKey == "" {
+			return nil, fmt.Errorf("client_cert and client_key are both required for a client certificate")
+		}
+		cert, err := tls.LoadX509KeyPair(c.Config.ClientCert, c.Config.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// proxy returns the proxy to reach Home Assistant through: the one in the proxy setting, or else
+// the one in HTTPS_PROXY, HTTP_PROXY or ALL_PROXY, unless NO_PROXY leaves the host out. HTTP proxies
+// are tunnelled through with CONNECT, and SOCKS5 ones, such as ssh -D, are supported too. It
+// returns nil to connect directly.
+func proxy(req *http.Request) (*url.URL, error) {
+	if setting := viper.GetString("proxy"); setting != "" {
+		u, err := url.Parse(socks5(setting))
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("proxy should be a URL such as http://proxy.local:3128 or socks5://localhost:1080")
+		}
+		return u, nil
 	}
 
+	env := httpproxy.FromEnvironment()
+	all := os.Getenv("ALL_PROXY")
+	if all == "" {
+		all = os.Getenv("all_proxy")
+	}
+	if env.HTTPProxy == "" {
+		env.HTTPProxy = all
+	}
+	if env.HTTPSProxy == "" {
+		env.HTTPSProxy = all
+	}
+	env.HTTPProxy, env.HTTPSProxy = socks5(env.HTTPProxy), socks5(env.HTTPSProxy)
+	return env.ProxyFunc()(req.URL)
+}
+
+// socks5 changes socks5h, as curl names SOCKS5 with the proxy looking up the host, to socks5,
+// which the dialer always does that way.
+func socks5(proxy string) string {
+	if strings.HasPrefix(proxy, "socks5h://") {
+		return "socks5://" + strings.TrimPrefix(proxy, "socks5h://")
+	}
+	return proxy
+}
+
+// nabuCasaHandshakeTimeout is how long to wait for the websocket handshake with a Home Assistant
+// Cloud remote URL.
+const nabuCasaHandshakeTimeout = 30 * time.Second
+
+// isNabuCasa returns whether host is a Home Assistant Cloud remote URL, such as
+// abcdef.ui.nabu.casa.
+func isNabuCasa(host string) bool {
+	return strings.HasSuffix(strings.ToLower(host), ".ui.nabu.casa")
+}
+
+// websocketURL returns the address of Home Assistant's websocket API from its URL, such as the one
+// copied from the browser, whatever page it was on. A remote URL, which is only served over HTTPS,
+// can be given without a scheme.
+func websocketURL(target string) (*url.URL, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" && !strings.Contains(target, "://") {
+		if remote, err := url.Parse("https://" + target); err == nil && isNabuCasa(remote.Hostname()) {
+			u = remote
+		}
+	}
+	switch {
+	case isNabuCasa(u.Hostname()):
+		// http:// is redirected to https://, which the handshake wouldn't follow.
+		u.Scheme = "wss"
+	case u.Scheme == "http":
+		u.Scheme = "ws"
+	case u.Scheme == "https":
+		u.Scheme = "wss"
+	}
+	u.Path, u.RawPath, u.RawQuery, u.Fragment = "/api/websocket", "", "", ""
+	if u.Host == "supervisor" {
+		// Inside an add-on, the Supervisor proxies the websocket API under /core.
+		u.Path = "/core/websocket"
+	}
+	return u, nil
+}
+
+// redirect returns where the response to a websocket handshake redirects to, if it does.
+func redirect(resp *http.Response) *url.URL {
+	if resp == nil {
+		return nil
+	}
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		location, err := resp.Location()
+		if err != nil {
+			return nil
+		}
+		return location
+	}
+	return nil
+}
+
+// connectHomeAssistant dials Home Assistant and authenticates. The whole handshake has to finish
+// within the request timeout, and is abandoned if ctx is done or the client is stopped.
+func (c *Client) connectHomeAssistant(ctx context.Context) error {
+	c.MessageID = 1
+	ctx, cancel := c.withStop(ctx)
+	defer cancel()
+	ctx, cancelTimeout := context.WithTimeout(ctx, c.requestTimeout())
+	defer cancelTimeout()
+
 	// Work out the URL to dial
-	if viper.GetString("url") == "" {
+	target := viper.GetString("url")
+	if c.replay != nil {
+		target = c.replay.url
+	}
+	if target == "" {
 		return fmt.Errorf("url is required")
 	}
-	dialURL, err := url.Parse(viper.GetString("url"))
+	dialURL, err := websocketURL(target)
 	if err != nil {
 		return err
 	}
-	if dialURL.Scheme == "http" {
-		dialURL.Scheme = "ws"
-	} else if dialURL.Scheme == "https" {
-		dialURL.Scheme = "wss"
+
+	// Set up the websocket dialer
+	dialer := websocket.Dialer{
+		HandshakeTimeout: 10 * time.Second,
+		Proxy:            proxy,
+	}
+	var header http.Header
+	if isNabuCasa(dialURL.Hostname()) {
+		// Remote connections go through Nabu Casa's relay to the instance, which can take a
+		// while to set up, and are turned away without the Origin the frontend sends.
+		dialer.HandshakeTimeout = nabuCasaHandshakeTimeout
+		header = http.Header{"Origin": {"https://" + dialURL.Host}}
+	}
+	dialer.TLSClientConfig, err = c.tlsConfig()
+	if err != nil {
+		return err
 	}
-	dialURL.Path = "/api/websocket"
 
-	// Skip TLS verification if insecure flag is set
-	if c.Config.Insecure {
-		dialer.TLSClientConfig = &tls.Config{
-			InsecureSkipVerify: true,
+	// Dial the websocket
+	c.logger().Info().Msgf("connecting to %s", dialURL.String())
+	conn, resp, err := dialer.DialContext(ctx, dialURL.String(), header)
+	if location := redirect(resp); err != nil && location != nil {
+		// A websocket handshake can't follow a redirect itself, such as one from http:// to
+		// https://, so it's dialled again at the new address.
+		dialURL, err = websocketURL(dialURL.ResolveReference(location).String())
+		if err != nil {
+			return err
 		}
+		c.logger().Info().Msgf("redirected to %s", dialURL.String())
+		conn, resp, err = dialer.DialContext(ctx, dialURL.String(), header)
 	}
+	if err != nil {
+		if errors.Is(err, websocket.ErrBadHandshake) && resp != nil {
+			err = fmt.Errorf("%w (%s)", err, resp.Status)
+		}
+		return fmt.Errorf("dial: %w", c.cancelled(ctx, err))
+	}
+	c.logger().Info().Msg("connected")
 
-	// Dial the websocket
-	log.Info().Msgf("connecting to %s", dialURL.String())
-	conn, _, err := dialer.Dial(dialURL.String(), nil)
+	// The reads and writes below can't take a context, so the connection is closed to end them
+	// if ctx is done first.
+	deadline, _ := ctx.Deadline()
+	conn.SetReadDeadline(deadline)
+	conn.SetWriteDeadline(deadline)
+	handshake := make(chan struct{})
+	watching := make(chan struct{})
+	go func() {
+		defer close(watching)
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-handshake:
+		}
+	}()
+	err = c.authenticate(conn)
+	close(handshake)
+	<-watching
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
 	if err != nil {
-		return fmt.Errorf("dial: %w", err)
+		conn.Close()
+		return c.cancelled(ctx, err)
+	}
+	// Requests set their own deadlines.
+	conn.SetReadDeadline(time.Time{})
+	conn.SetWriteDeadline(time.Time{})
+	c.logger().Info().Msg("authenticated")
+	if interval := pingInterval(); interval > 0 {
+		c.keepAlive(conn, interval)
 	}
-	log.Info().Msg("connected")
 
+	c.Conn = conn
+	return nil
+}
+
+// authenticate sends the access token over a new connection and checks that it was accepted.
+func (c *Client) authenticate(conn *websocket.Conn) error {
 	// Read the initial message
 	var initMsg map[string]any
-	if err := conn.ReadJSON(&initMsg); err != nil {
+	if err := c.readFrame(conn, &initMsg); err != nil {
 		return fmt.Errorf("initial message: %w", err)
 	}
 
 	// Send the authentication message
-	if err := conn.WriteJSON(map[string]string{
+	if err := c.writeFrame(conn, map[string]string{
 		"type":         "auth",
 		"access_token": viper.GetString("api_key"),
 	}); err != nil {
@@ -108,57 +530,382 @@ func (c *Client) Connect() error {
 
 	// Read the authentication response
 	var authResp map[string]any
-	if err := conn.ReadJSON(&authResp); err != nil {
+	if err := c.readFrame(conn, &authResp); err != nil {
 		return fmt.Errorf("auth response: %w", err)
 	}
 	if authResp["type"] != "auth_ok" {
 		return fmt.Errorf("authentication failed: %v", authResp["message"])
 	}
-	log.Info().Msg("authenticated")
-
-	c.Conn = conn
 	return nil
 }
 
+// cancelled returns err, or the reason ctx is done if it is: ErrInterrupted if the client was
+// stopped, or the context's error, such as its deadline passing.
+func (c *Client) cancelled(ctx context.Context, err error) error {
+	switch {
+	case ctx.Err() == nil:
+		return err
+	case c.Interrupted():
+		return ErrInterrupted
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("Home Assistant didn't answer in time: %w", ctx.Err())
+	default:
+		return ctx.Err()
+	}
+}
+
+// needsHomeAssistant returns the option that needs data the cache doesn't keep, or "" if everything
+// can be answered from the cache. Hours of consumption and of the other energy statistics are
+// cached, but 5-minute data, mean temperatures, the fossil share and state history are always
+// fetched.
+func (c *Client) needsHomeAssistant() string {
+	switch c.Config.Output {
+	case "appliances", "demand", "fossil", "temperature":
+		return "-o " + c.Config.Output
+	}
+	if c.Config.Split == "occupancy" {
+		return "--split occupancy"
+	}
+	return ""
+}
+
 // computePowerStats computes the power statistics for a given number of days and hours.
 // It prints a table to stdout where the rows are "days" and the columns are "hours".
 // The function writes the results to a CSV file and prints the averages to the console.
 func (c *Client) ComputePowerStats() {
-	results, err := getResults(c)
+	if what := c.needsHomeAssistant(); what != "" && c.Config.Offline {
+		c.logger().Error().Msg(fmt.Sprintf("%s needs Home Assistant, so it can't be used with --offline", what))
+		return
+	}
+	if err := c.checkEnd(); err != nil {
+		c.logger().Error().Msg(err.Error())
+		return
+	}
+
+	// Analyses of 5-minute data, of each phase and of heat pumps fetch their own readings.
+	switch c.Config.Output {
+	case "phases":
+		if err := c.printPhases(); err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("comparing phases: %v", err))
+		}
+		return
+	case "cop":
+		if err := c.printCOP(); err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("computing heat pump COP: %v", err))
+		}
+		return
+	case "appliances":
+		if err := c.printAppliances(); err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("finding appliances: %v", err))
+		}
+		return
+	case "demand":
+		if err := c.printDemand(); err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("computing maximum demand: %v", err))
+		}
+		return
+	}
+
+	if err := checkPeriod(c.Config.Period, c.Config.HalfHourly); err != nil {
+		c.logger().Error().Msg(err.Error())
+		return
+	}
+
+	// Everything other than the plain layouts assumes hourly values. Gaps can be found, and costs
+	// worked out, in any slots of a day, but rows of totals don't have any.
+	if !c.hourly() {
+		switch {
+		case c.Config.Split != "":
+			c.logger().Error().Msg(fmt.Sprintf("--split is not supported %s", c.mode()))
+			return
+		case c.Config.Output != "" && c.Config.Output != "text" && c.Config.Output != "table" && c.Config.Output != "csv" && c.Config.Output != "markdown" && c.Config.Output != "xlsx" && ((c.Config.Output != "gaps" && c.Config.Output != "cost") || c.totalsOnly()):
+			c.logger().Error().Msg(fmt.Sprintf("output %q is not supported %s", c.Config.Output, c.mode()))
+			return
+		}
+	}
+
+	width, slots := c.slots()
+	if c.Config.Block != 0 {
+		if c.totalsOnly() {
+			c.logger().Error().Msg(fmt.Sprintf("--block is not supported %s", c.mode()))
+			return
+		}
+		if err := checkBlock(c.Config.Block, width); err != nil {
+			c.logger().Error().Msg(err.Error())
+			return
+		}
+		if c.Config.Split != "" || (c.Config.Output != "" && c.Config.Output != "table" && c.Config.Output != "csv" && c.Config.Output != "markdown" && c.Config.Output != "xlsx") {
+			c.logger().Error().Msg("--block is only supported by the table, CSV, Markdown and Excel outputs")
+			return
+		}
+	}
+
+	if c.Config.IncludeToday {
+		switch {
+		case !c.Config.End.IsZero():
+			c.logger().Error().Msg("--include-today can't be combined with --end")
+			return
+		case c.Config.Offline:
+			c.logger().Error().Msg("--include-today can't be combined with --offline, as today is never cached")
+			return
+		case c.totalsOnly():
+			c.logger().Error().Msg(fmt.Sprintf("--include-today is not supported %s", c.mode()))
+			return
+		case c.Config.Cost || c.Config.Split != "" || (c.Config.Output != "" && c.Config.Output != "table" && c.Config.Output != "csv" && c.Config.Output != "markdown"):
+			c.logger().Error().Msg("--include-today is only supported by the table, CSV and Markdown outputs, without --cost")
+			return
+		}
+	}
+
+	stats, err := parseStats(c.Config.Statistics)
 	if err != nil {
-		log.Error().Msg(fmt.Sprintf("getting results: %v", err))
+		c.logger().Error().Msg(err.Error())
+		return
+	}
+	if (len(stats) > 1 || !stats[0].isMean()) && (c.Config.Split != "" || (c.Config.Output != "" && c.Config.Output != "text" && c.Config.Output != "table" && c.Config.Output != "csv" && c.Config.Output != "markdown" && c.Config.Output != "xlsx")) {
+		c.logger().Error().Msg("--stat is only supported by the text, table, CSV, Markdown and Excel outputs")
+		return
+	}
+
+	if c.Config.Cost && (c.totalsOnly() || c.Config.Split != "" || (c.Config.Output != "" && c.Config.Output != "table" && c.Config.Output != "csv" && c.Config.Output != "markdown")) {
+		c.logger().Error().Msg(fmt.Sprintf("--cost is only supported by the table, CSV and Markdown outputs, and not %s", c.mode()))
 		return
 	}
 
-	// Compute averages
-	averages := make([]float64, hoursInADay)
-	for i := range averages {
-		sum := 0.0
-		for j := range results {
-			sum += results[j][i]
+	// The plain averages don't need the days themselves, so each day is folded into them as it
+	// is read, and the memory taken is the same however long the range.
+	if c.Config.Output == "text" && len(stats) == 1 && stats[0].isMean() && !c.totalsOnly() && c.Config.Split == "" && !c.Config.Clipboard && SensorID() != "" {
+		averages, n, err := c.foldedMeans(c.days())
+		if c.fetchFailed(err, n) {
+			return
 		}
-		averages[i] = sum / float64(c.Config.Days)
+		writePlainText(averages)
+		return
 	}
 
-	// Generate column headers for table/CSV
-	headers := make([]string, hoursInADay)
-	for i := range headers {
-		headers[i] = fmt.Sprintf("%d", i)
+	results, err := getResults(c)
+	if c.fetchFailed(err, len(results)) {
+		return
+	}
+	c.fixSpikes(results)
+	c.note(func(p *provenance) {
+		for _, day := range results {
+			p.Included = append(p.Included, day.Date)
+		}
+	})
+	if c.totalsOnly() {
+		billingDay, err := billingDay()
+		if err != nil {
+			c.logger().Error().Msg(err.Error())
+			return
+		}
+		results, slots = rollUp(results, c.Config.Period, billingDay), 1
+	}
+
+	// Compute averages, or whichever statistic comes first. Insights talk about averages, so they
+	// always have the means.
+	averages := stats[0].profile(results, slots)
+	means := averages
+	if !stats[0].isMean() {
+		means = stat{q: math.NaN()}.profile(results, slots)
+	}
+
+	if c.Config.Clipboard {
+		if err := copyToClipboard(plainText(averages)); err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("copying to the clipboard: %v", err))
+		} else {
+			c.logger().Info().Msg(fmt.Sprintf("copied %d averages to the clipboard", len(averages)))
+		}
+	}
+
+	headers := c.slotHeaders()
+
+	// Blocks only change what is shown, so insights still work from every slot.
+	shown, shownHeaders := results, headers
+	if c.Config.Block != 0 {
+		shown, _, shownHeaders = resample(results, averages, width, c.Config.Block)
+	}
+	// Percentiles of blocks aren't the sum of the percentiles of their hours, so the summary rows
+	// are worked out from the blocks themselves.
+	rows := summaries(shown, len(shownHeaders), stats)
+
+	// Today is shown above the other days, but is left out of the summaries and insights, as most
+	// of it is still to come.
+	if c.Config.IncludeToday && !c.Interrupted() {
+		today, err := c.today()
+		if err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("getting today so far: %v", err))
+			return
+		}
+		if c.Config.Block != 0 {
+			today, _, _ = resample(today, nil, width, c.Config.Block)
+		}
+		shown = append(today, shown...)
+	}
+
+	// Each day's total, and then its cost, are added after the slots, with the summaries in the
+	// footer. Rows of totals have nothing to add up.
+	var tableColumns, csvColumns []extraColumn
+	if !c.totalsOnly() {
+		total := totalColumn(shown, stats)
+		tableColumns, csvColumns = []extraColumn{total}, []extraColumn{total}
+	}
+	if c.Config.Cost {
+		costs, err := c.dailyBills(results)
+		if err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("computing costs: %v", err))
+			return
+		}
+		cur := currencyConfig()
+		// The CSV has plain numbers, for spreadsheets to add up.
+		tableColumns = append(tableColumns, costColumn(costs, cur.format, stats))
+		csvColumns = append(csvColumns, costColumn(costs, currency{Decimals: cur.Decimals}.format, stats))
+	}
+
+	if c.Config.Split != "" {
+		if err := c.writeSplit(results, headers); err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("splitting results: %v", err))
+		}
+		return
 	}
 
 	switch c.Config.Output {
 	case "text":
 		writePlainText(averages)
 	case "table":
-		printTable(results, averages, headers)
+		printTable(shown, rows, shownHeaders, tableColumns...)
+		if c.hourly() {
+			printInsights(results, means)
+		}
 	case "csv":
-		err = c.writeCSVFile(headers, results, averages)
+		err = c.writeCSVFile(shownHeaders, shown, rows, csvColumns...)
+		if err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("writing CSV file: %v", err))
+			return
+		}
+	case "markdown":
+		writeMarkdown(os.Stdout, shown, rows, shownHeaders, tableColumns...)
+	case "xlsx":
+		err = c.writeXLSX(shown, rows, shownHeaders, stats)
+		if err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("writing Excel file: %v", err))
+			return
+		}
+	case "emoncms":
+		err = c.postEmoncms(results)
+		if err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("posting to emoncms: %v", err))
+			return
+		}
+	case "pvoutput":
+		err = c.uploadPVOutput(results)
+		if err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("uploading to PVOutput: %v", err))
+			return
+		}
+	case "greenbutton":
+		err = c.writeGreenButton(results)
+		if err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("writing Green Button file: %v", err))
+			return
+		}
+	case "parquet":
+		err = c.writeParquet(results)
+		if err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("writing Parquet file: %v", err))
+			return
+		}
+	case "arrow":
+		err = c.writeArrow(results)
+		if err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("writing Arrow file: %v", err))
+			return
+		}
+	case "mqtt":
+		err = c.publishMQTT(results, averages)
+		if err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("publishing to MQTT: %v", err))
+			return
+		}
+	case "graphite":
+		err = c.sendGraphite(results)
+		if err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("sending to Graphite: %v", err))
+			return
+		}
+	case "influxdb":
+		err = c.writeInfluxDB(results)
+		if err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("writing to InfluxDB: %v", err))
+			return
+		}
+	case "bigquery":
+		err = c.streamBigQuery(results)
+		if err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("streaming to BigQuery: %v", err))
+			return
+		}
+	case "temperature":
+		err = c.printTemperature(results)
+		if err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("correlating with temperature: %v", err))
+			return
+		}
+	case "gaps":
+		printOutages(results, width)
+	case "solar":
+		err = c.printSolarForecast(results)
+		if err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("comparing solar forecasts: %v", err))
+			return
+		}
+	case "fossil":
+		err = c.printFossil(results)
+		if err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("computing fossil share: %v", err))
+			return
+		}
+	case "daylight":
+		err = c.printDaylight(results)
+		if err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("splitting daylight and night: %v", err))
+			return
+		}
+	case "balance":
+		err = c.printBalance(averages, results)
+		if err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("computing energy balance: %v", err))
+			return
+		}
+	case "compare":
+		err = c.printComparison(results)
+		if err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("comparing tariffs: %v", err))
+			return
+		}
+	case "benchmark":
+		err = printBenchmark(averages)
+		if err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("benchmarking: %v", err))
+			return
+		}
+	case "recommendations":
+		err = c.printRecommendations(results)
+		if err != nil {
+			c.logger().Error().Msg(fmt.Sprintf("computing recommendations: %v", err))
+			return
+		}
+	case "cost":
+		err = c.printCosts(results)
 		if err != nil {
-			log.Error().Msg(fmt.Sprintf("writing CSV file: %v", err))
+			c.logger().Error().Msg(fmt.Sprintf("computing costs: %v", err))
 			return
 		}
 	default:
-		printTable(results, averages, headers)
+		printTable(shown, rows, shownHeaders, tableColumns...)
+		if c.hourly() {
+			printInsights(results, means)
+		}
 	}
 }
 
@@ -166,12 +913,28 @@ func (c *Client) ComputePowerStats() {
 // This is useful for using with something like https://garydoessolar.com/utilities/dailymodellingutility/
 // You can copy and paste the results into the custom usage pattern section and it will generate more accurate predictions.
 func writePlainText(averages []float64) {
+	fmt.Print(plainText(averages))
+}
+
+// plainText returns the averages in the format printed by the text output.
+func plainText(averages []float64) string {
+	var sb strings.Builder
 	for _, v := range averages {
-		fmt.Printf("%f,\n", v)
+		fmt.Fprintf(&sb, "%f,\n", v)
+	}
+	return sb.String()
+}
+
+// outputFile returns the path to write a file-based output to. The default path is a CSV file,
+// so when it is left as one, its extension is swapped for the one matching the output format.
+func (c *Client) outputFile(ext string) string {
+	if filepath.Ext(c.Config.FilePath) != ".csv" {
+		return c.Config.FilePath
 	}
+	return strings.TrimSuffix(c.Config.FilePath, ".csv") + ext
 }
 
-func (c *Client) writeCSVFile(headers []string, results [][]float64, averages []float64) error {
+func (c *Client) writeCSVFile(headers []string, results []Day, summaries []summary, extra ...extraColumn) error {
 	f, err := os.Create(c.Config.FilePath)
 	if err != nil {
 		return fmt.Errorf("creating file: %w", err)
@@ -179,29 +942,31 @@ func (c *Client) writeCSVFile(headers []string, results [][]float64, averages []
 	defer f.Close()
 
 	writer := csv.NewWriter(f)
-	err = writer.Write(headers)
+	err = writer.Write(append([]string{i18n.T("Date")}, withHeaders(headers, extra)...))
 	if err != nil {
 		return fmt.Errorf("writing headers: %w", err)
 	}
 
-	for _, row := range results {
-		rowString := make([]string, len(row))
-		for j, val := range row {
-			rowString[j] = fmt.Sprintf("%f", val)
+	for i, row := range results {
+		rowString := []string{dateLabel(row)}
+		for _, val := range row.Values {
+			rowString = append(rowString, fmt.Sprintf("%f", val))
 		}
-		err = writer.Write(rowString)
+		err = writer.Write(withValues(rowString, extra, i))
 		if err != nil {
 			return fmt.Errorf("writing row: %w", err)
 		}
 	}
 
-	averageString := make([]string, len(averages))
-	for i, val := range averages {
-		averageString[i] = fmt.Sprintf("%f", val)
-	}
-	err = writer.Write(averageString)
-	if err != nil {
-		return fmt.Errorf("writing averages: %w", err)
+	for i, row := range summaries {
+		rowString := []string{row.Label}
+		for _, val := range row.Values {
+			rowString = append(rowString, fmt.Sprintf("%f", val))
+		}
+		err = writer.Write(withFooters(rowString, extra, i))
+		if err != nil {
+			return fmt.Errorf("writing summaries: %w", err)
+		}
 	}
 
 	writer.Flush()
@@ -209,83 +974,898 @@ func (c *Client) writeCSVFile(headers []string, results [][]float64, averages []
 	return nil
 }
 
-func printTable(results [][]float64, averages []float64, headers []string) {
+func printTable(results []Day, summaries []summary, headers []string, extra ...extraColumn) {
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader(headers)
+	table.SetHeader(append([]string{i18n.T("Date")}, withHeaders(headers, extra)...))
 
-	for _, row := range results {
-		rowString := make([]string, len(row))
-		for j, val := range row {
-			rowString[j] = fmt.Sprintf("%f", val)
+	for i, row := range results {
+		rowString := []string{dateLabel(row)}
+		for _, val := range row.Values {
+			rowString = append(rowString, fmt.Sprintf("%f", val))
 		}
-		table.Append(rowString)
+		table.Append(withValues(rowString, extra, i))
 	}
 
-	averageString := make([]string, len(averages))
-	for i, val := range averages {
-		averageString[i] = fmt.Sprintf("%f", val)
+	// The footer has a line for each summary, as a table has only one footer row.
+	var footer []string
+	for i, row := range summaries {
+		rowString := []string{row.Label}
+		for _, val := range row.Values {
+			rowString = append(rowString, fmt.Sprintf("%f", val))
+		}
+		rowString = withFooters(rowString, extra, i)
+		if footer == nil {
+			footer = rowString
+			continue
+		}
+		for j := range footer {
+			footer[j] += "\n" + rowString[j]
+		}
 	}
-	table.SetFooter(averageString)
+	table.SetFooter(footer)
 	table.Render()
 }
 
-func getResults(c *Client) ([][]float64, error) {
-	// We're going to store the results in a slice of slices, where each slice is a day's worth of data
+// extraColumn is a column added after the slots of the table or CSV file, with a value for each day
+// and one for each summary row.
+type extraColumn struct {
+	Header  string
+	Values  []string
+	Footers []string
+}
+
+// totalColumn is each day's total, with the statistics of the daily totals in the footer. Today's
+// total so far, with --include-today, is left out of the statistics.
+func totalColumn(results []Day, stats []stat) extraColumn {
+	col := extraColumn{Header: i18n.T("Total"), Values: make([]string, len(results))}
+	var totals []float64
+	for i, day := range results {
+		total := sum(day.Values)
+		col.Values[i] = fmt.Sprintf("%f", total)
+		if !day.Partial {
+			totals = append(totals, total)
+		}
+	}
+	for _, s := range stats {
+		col.Footers = append(col.Footers, fmt.Sprintf("%f", statOrZero(s, totals)))
+	}
+	return col
+}
+
+// statOrZero returns the statistic of the values, or 0 if there aren't any, for footers.
+func statOrZero(s stat, values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	return s.of(values)
+}
+
+func withHeaders(headers []string, extra []extraColumn) []string {
+	// The slot headers are shared, so they are copied rather than appended to.
+	all := append([]string(nil), headers...)
+	for _, col := range extra {
+		all = append(all, col.Header)
+	}
+	return all
+}
+
+func withValues(row []string, extra []extraColumn, day int) []string {
+	for _, col := range extra {
+		row = append(row, col.Values[day])
+	}
+	return row
+}
+
+func withFooters(footer []string, extra []extraColumn, summary int) []string {
+	for _, col := range extra {
+		cell := ""
+		if summary < len(col.Footers) {
+			cell = col.Footers[summary]
+		}
+		footer = append(footer, cell)
+	}
+	return footer
+}
+
+func (c *Client) slotHeaders() []string {
+	if c.totalsOnly() {
+		return []string{"kWh"}
+	}
+	width, slots := c.slots()
+	headers := make([]string, slots)
+	for i := range headers {
+		headers[i] = fmt.Sprintf("%d", i)
+		if width < time.Hour {
+			headers[i] = time.Time{}.Add(time.Duration(i) * width).Format("15:04")
+		}
+	}
+	return headers
+}
+
+// SensorID returns the statistic ID of the consumption sensor, from sensor_id. A list of sensors,
+// for homes with more than one meter, is joined with commas, which statistic IDs can't contain,
+// and their consumption is added together.
+func SensorID() string {
+	return strings.Join(viper.GetStringSlice("sensor_id"), ",")
+}
+
+func getResults(c *Client) ([]Day, error) {
+	return c.results(c.days())
+}
+
+// until returns the end of the last day to report on: the day after End, or else the start of
+// today, as today isn't over yet. Days run from midnight in the time zone.
+func (c *Client) until() time.Time {
+	loc := c.timeZone()
+	if !c.Config.End.IsZero() {
+		y, m, d := c.Config.End.Date()
+		return time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	}
+	return midnight(c.now().In(loc))
+}
+
+// fetchFailed logs an error getting n days of results, and reports whether there is nothing to
+// report on. An interrupted or incomplete fetch still reports on the days fetched in full.
+func (c *Client) fetchFailed(err error, n int) bool {
+	if errors.Is(err, ErrInterrupted) && n > 0 {
+		c.logger().Warn().Msg(fmt.Sprintf("interrupted - reporting on the %d days fetched in full", n))
+	} else if errors.Is(err, ErrIncomplete) && n > 0 {
+		c.logger().Error().Msg(fmt.Sprintf("getting results: %v - reporting on the %d days fetched in full; run again with --resume to fetch the rest", err, n))
+	} else if err != nil {
+		c.logger().Error().Msg(fmt.Sprintf("getting results: %v", err))
+		return true
+	}
+	return false
+}
+
+// checkEnd reports an error if End isn't before today, as today's consumption isn't complete.
+// Today starts at midnight in the time zone days run from midnight in, which can be a day either
+// side of the date in UTC.
+func (c *Client) checkEnd() error {
+	if c.Config.End.IsZero() {
+		return nil
+	}
+	if c.until().After(midnight(c.now().In(c.timeZone()))) {
+		return fmt.Errorf("--end must be before today")
+	}
+	return nil
+}
+
+// days returns how many days to report on, back from until: those from Start if it is set, or
+// else Days.
+func (c *Client) days() int {
+	if c.Config.Start.IsZero() {
+		return c.Config.Days
+	}
+	return datesBetween(c.Config.Start, c.until())
+}
+
+// datesBetween returns the number of dates from the date of start up to the date of end, or 0 if
+// end is before start. Days aren't all 24 hours long where the clocks change, so they are counted
+// in dates.
+func datesBetween(start, end time.Time) int {
+	y, m, d := start.Date()
+	from := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	y, m, d = end.Date()
+	n := int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Sub(from) / (24 * time.Hour))
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// results returns the given number of days up to the end of yesterday, most recent first. If the
+// client is stopped part way through, it returns the days it has in full along with ErrInterrupted,
+// and if the fetch fails part way through, along with ErrIncomplete.
+// Without sensor_id, the consumption of the phases in phase_sensor_ids is added together.
+func (c *Client) results(days int) ([]Day, error) {
+	sensorID := SensorID()
+	if sensorID == "" {
+		if phases := viper.GetStringSlice("phase_sensor_ids"); len(phases) > 0 {
+			return c.combinedResults(phases, days)
+		}
+		return nil, fmt.Errorf("sensor_id is required")
+	}
+	return c.sensorResults(sensorID, days)
+}
+
+// sensorResults returns the given number of days of a single sensor's consumption, as results does.
+func (c *Client) sensorResults(sensorID string, days int) ([]Day, error) {
+	return c.sensorDays(sensorID, c.until(), days)
+}
+
+// sensorDays returns the given number of days of a single statistic up to until, most recent
+// first, through the cache, as sensorResults does.
+func (c *Client) sensorDays(sensorID string, until time.Time, days int) ([]Day, error) {
+	// We're going to store the results in a slice of days, where each day holds 24 hourly values.
 	// In other words, we're creating a table where the rows are "days" and the columns are "hours"
-	// This is a bit of a hack, but it works.
+	results := make([]Day, days)
+	err := c.eachDay(sensorID, until, days, func(i int, day Day) error {
+		results[i] = day
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrInterrupted) && !errors.Is(err, ErrIncomplete) {
+		return nil, err
+	}
+	// Whatever stopped the fetch, the days it has already fetched are kept, and days left out
+	// for having no data aren't.
+	var fetched []Day
+	for _, day := range results {
+		if day.Values != nil {
+			fetched = append(fetched, day)
+		}
+	}
+	return fetched, err
+}
 
+// eachDay hands each of the given number of days of a single statistic up to until to fn, through
+// the cache, without keeping them, so the memory it needs doesn't grow with the number of days.
+// fn is given the index of each day back from until, as sensorDays orders them, and the days are
+// handed over in no particular order: cached days first, then the rest as they are fetched. If the
+// client is stopped part way through, it returns ErrInterrupted, and if the fetch fails part way
+// through, an error wrapping ErrIncomplete, with the days it has handed over being those fetched
+// in full.
+func (c *Client) eachDay(sensorID string, until time.Time, days int, fn func(i int, day Day) error) error {
 	// What we're doing is creating an offset from the current *day* based on a multiple of
 	// 24 hours, each time we iterate through the a "row" of the results slice.
-	results := make([][]float64, c.Config.Days)
-	sensorID := viper.GetString("sensor_id")
-	if sensorID == "" {
-		return nil, fmt.Errorf("sensor_id is required")
+	dates := make([]Day, days)
+	handed := make([]bool, days)
+
+	// Half hours are resampled from 5-minute statistics, except for sources that have them
+	// already, and are cached separately from hourly values, as 5-minute values are.
+	width, slots := c.slots()
+	period, cacheID := c.readingPeriod(), c.cacheID(sensorID)
+	if c.Config.HalfHourly {
+		cacheID = c.cacheID(sensorID + "/30minute")
+	} else if c.Config.Period == "5minute" {
+		cacheID = c.cacheID(sensorID + "/5minute")
 	}
 
-	for i := range results {
-		c.MessageID++
+	store, err := c.openCache()
+	if err != nil {
+		return err
+	}
+	if store != nil {
+		defer store.Close()
+	}
+
+	// A checkpoint is kept while days are being fetched. With --resume, days cached by the
+	// interrupted fetch are used as they are, rather than being fetched all over again.
+	checkpoint := cache.Checkpoint{Started: time.Now()}
+	resuming := false
+	if c.Config.Resume {
+		if store == nil {
+			return fmt.Errorf("--resume needs the local cache")
+		}
+		cp, found, err := store.Checkpoint(cacheID)
+		if err != nil {
+			return err
+		}
+		if found {
+			checkpoint, resuming = cp, true
+			c.logger().Info().Msgf("resuming fetch started at %s, completed through %s", cp.Started.Format(time.RFC3339), cp.Through.Format("2006-01-02"))
+		} else {
+			c.logger().Warn().Msgf("no interrupted fetch of %s to resume", cacheID)
+		}
+	}
 
-		offset := time.Duration((i+1)*24) * time.Hour
-		start := time.Now().Add(-offset).Truncate(24 * time.Hour).Format("2006-01-02T15:04:05.000Z")
+	// Days that aren't in the cache are collected into runs of consecutive days, so each run
+	// can be fetched in as few requests as possible.
+	var missing []int
+	for i := range dates {
+		day := until.AddDate(0, 0, -(i + 1))
+		dates[i] = Day{Date: day}
 
-		msg := map[string]interface{}{
-			"id":            c.MessageID,
-			"type":          "recorder/statistics_during_period",
-			"start_time":    start,
-			"end_time":      time.Now().Truncate(24 * time.Hour).Format("2006-01-02T15:04:05.000Z"),
-			"statistic_ids": []string{sensorID},
-			"period":        "hour",
-			"types":         []string{"change"},
-			"units": map[string]string{
-				"energy": "kWh",
-			},
+		if store != nil {
+			entry, found, err := store.Get(cacheID, day)
+			if err != nil {
+				return err
+			}
+			// Offline, whatever is cached has to do.
+			fresh := c.Config.Offline || (!c.Config.Refresh && settled(entry, day.AddDate(0, 0, 1)))
+			if resuming && !entry.Fetched.Before(checkpoint.Started) {
+				fresh = true
+			}
+			if found && fresh {
+				c.count(func(s *Stats) { s.CacheHits++ })
+				c.note(func(p *provenance) { p.Cached[day] = true })
+				if err := fn(i, Day{Date: day, Values: entry.Values}); err != nil {
+					return err
+				}
+				handed[i] = true
+				continue
+			}
+			c.count(func(s *Stats) { s.CacheMisses++ })
 		}
+		missing = append(missing, i)
+	}
 
-		if err := c.write(msg); err != nil {
-			return nil, fmt.Errorf("writing to websocket: %w", err)
+	// Complete days are cached as each chunk arrives, so little is lost if the fetch is interrupted.
+	if store != nil && len(missing) > 0 {
+		if err := store.PutCheckpoint(cacheID, checkpoint); err != nil {
+			return err
+		}
+	}
+	// Home Assistant only keeps 5-minute statistics for 10 days by default, so the days before
+	// that are left out rather than counted as zero.
+	var unread int
+	defer func() {
+		if unread > 0 {
+			c.logger().Warn().Msgf("left out %d days with no 5-minute data - Home Assistant keeps short-term statistics for 10 days by default", unread)
+		}
+	}()
+	for _, run := range c.runs(missing, dates) {
+		start := dates[run[len(run)-1]].Date
+		end := dates[run[0]].Date.AddDate(0, 0, 1)
+		// The days in each chunk are handed over as it arrives, and its readings dropped, so only
+		// the chunks in flight are held however long the range is. Days between the runs that were
+		// merged are already cached, and are left as they are.
+		err := c.stream(sensorID, start, end, period, func(from, to time.Time, readings []Reading) error {
+			for _, i := range run {
+				day := dates[i].Date
+				if day.Before(from) || !day.Before(to) {
+					continue
+				}
+				empty := emptySlots(readings, day, width, slots)
+				if period == "5minute" && empty == slots {
+					c.note(func(p *provenance) { p.Excluded[day] = i18n.T("no 5-minute data") })
+					unread++
+					handed[i] = true
+					continue
+				}
+				values := bucket(readings, day, width, slots)
+				c.note(func(p *provenance) { p.Padded[day] = empty })
+				if store != nil && covers(readings, day.AddDate(0, 0, 1), width) {
+					entry := cache.Entry{Values: values, Fetched: time.Now(), Source: sourceName()}
+					if err := store.Put(cacheID, day, entry); err != nil {
+						return err
+					}
+				}
+				if err := fn(i, Day{Date: day, Values: values}); err != nil {
+					return err
+				}
+				handed[i] = true
+			}
+			if store == nil {
+				return nil
+			}
+			checkpoint.Through = to
+			return store.PutCheckpoint(cacheID, checkpoint)
+		})
+		if err != nil {
+			// The checkpoint is left in place, so the rest can be fetched with --resume.
+			reason := i18n.T("not fetched before the run was stopped")
+			if !errors.Is(err, ErrInterrupted) {
+				reason = i18n.T("not fetched before the fetch failed")
+				err = fmt.Errorf("%w: %w", ErrIncomplete, err)
+			}
+			for i, day := range dates {
+				if !handed[i] {
+					c.note(func(p *provenance) { p.Excluded[day.Date] = reason })
+				}
+			}
+			return err
 		}
+	}
+
+	if store != nil {
+		if err := store.DeleteCheckpoint(cacheID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statistic returns the readings of a statistic other than consumption, such as generation or
+// exports, over the same days as the results. Its days are cached like those of consumption, so
+// once they have been fetched, or mirrored with sync, they are there with --offline.
+func (c *Client) statistic(id string, results []Day) ([]Reading, error) {
+	if len(results) == 0 {
+		return nil, nil
+	}
+	first, last := results[0].Date, results[0].Date
+	for _, day := range results {
+		if day.Date.Before(first) {
+			first = day.Date
+		}
+		if day.Date.After(last) {
+			last = day.Date
+		}
+	}
+	until := midnight(last).AddDate(0, 0, 1)
+	days, err := c.sensorDays(id, until, datesBetween(first, until))
+	if err != nil {
+		return nil, err
+	}
+	width, _ := c.slots()
+	var readings []Reading
+	for _, day := range days {
+		y, m, d := day.Date.Date()
+		for i, v := range day.Values {
+			// Slots go by the clock, so they start at the time they are for on the day.
+			start := time.Date(y, m, d, 0, 0, 0, int(time.Duration(i)*width), day.Date.Location())
+			readings = append(readings, Reading{Start: start, Value: v})
+		}
+	}
+	return readings, nil
+}
 
-		var data APIResponse
-		err := c.Conn.ReadJSON(&data)
+// runs groups the indexes of the missing days, which run backwards from the most recent, into
+// runs of consecutive days, so each run can be fetched in as few requests as possible. Runs close
+// enough together to be fetched in a single chunk are merged, so days scattered between cached
+// ones, such as the unsettled days at the end of the range, don't each cost a request.
+func (c *Client) runs(missing []int, results []Day) [][]int {
+	days := viper.GetInt("chunk_days")
+	if days <= 0 {
+		days = defaultChunkDays
+	}
+	chunk := time.Duration(days) * 24 * time.Hour
+
+	var runs [][]int
+	for len(missing) > 0 {
+		n := 1
+		for n < len(missing) && missing[n] == missing[n-1]+1 {
+			n++
+		}
+		run := missing[:n]
+		missing = missing[n:]
+
+		if len(runs) > 0 {
+			last := runs[len(runs)-1]
+			span := results[last[0]].Date.AddDate(0, 0, 1).Sub(results[run[len(run)-1]].Date)
+			if span <= chunk {
+				runs[len(runs)-1] = append(last[:len(last):len(last)], run...)
+				continue
+			}
+		}
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// defaultCacheTTL is how long after the end of a day its cached values are re-fetched, as Home
+// Assistant may still be finalizing the statistics for the most recent hours.
+const defaultCacheTTL = 48 * time.Hour
+
+// settled reports whether a cached entry for a period ending at end was fetched long enough
+// afterwards, according to cache_ttl, to be trusted. Imported entries are always trusted.
+func settled(entry cache.Entry, end time.Time) bool {
+	if strings.HasPrefix(entry.Source, "import:") {
+		return true
+	}
+	return !entry.Fetched.Before(end.Add(cacheTTL()))
+}
+
+// cacheTTL returns how long after the end of a day its values settle, from cache_ttl.
+func cacheTTL() time.Duration {
+	if ttl := viper.GetDuration("cache_ttl"); ttl != 0 {
+		return ttl
+	}
+	return defaultCacheTTL
+}
+
+// sourceName returns the name of the configured source, as recorded in the cache.
+func sourceName() string {
+	if name := viper.GetString("source"); name != "" {
+		return name
+	}
+	return "homeassistant"
+}
+
+// slots returns the width and number of the periods each day is divided into. Days, weeks and
+// months are added up from hours.
+func (c *Client) slots() (time.Duration, int) {
+	if c.Config.HalfHourly {
+		return 30 * time.Minute, halfHoursInADay
+	}
+	if c.Config.Period == "5minute" {
+		return 5 * time.Minute, fiveMinutesInADay
+	}
+	return time.Hour, hoursInADay
+}
+
+// readingPeriod returns the period of the readings the slots are added up from.
+func (c *Client) readingPeriod() string {
+	if c.Config.HalfHourly {
+		if _, ok := c.source.(*glow); ok {
+			return "30minute"
+		}
+		return "5minute"
+	}
+	if c.Config.Period == "5minute" {
+		return "5minute"
+	}
+	return "hour"
+}
+
+// openCache opens the configured cache, returning nil if there isn't one.
+func (c *Client) openCache() (*cache.Store, error) {
+	if c.Config.CacheFile == "" {
+		return nil, nil
+	}
+	return cache.Open(c.Config.CacheFile)
+}
+
+// recorder reads hourly statistics from the Home Assistant recorder over the websocket API.
+type recorder struct {
+	*Client
+}
+
+// recorderPeriods are the widths of the periods the recorder can return statistics for, other
+// than months, which vary.
+var recorderPeriods = map[string]time.Duration{
+	"5minute": 5 * time.Minute,
+	"hour":    time.Hour,
+	"day":     24 * time.Hour,
+	"week":    7 * 24 * time.Hour,
+}
+
+// Readings returns the consumption in each period. By default this is the "change" statistic,
+// but sensors that only have cumulative totals can set statistic_type to "sum" or "state" to
+// work it out from the difference between consecutive totals instead. The id can list several
+// statistic IDs separated by commas, which are fetched in one request and added together.
+func (r recorder) Readings(id string, start, end time.Time, period string) ([]Reading, error) {
+	ids := strings.Split(id, ",")
+	switch statType := viper.GetString("statistic_type"); statType {
+	case "", "change":
+		stats, err := r.statisticsFor(ids, start, end, period, "change")
+		if err != nil {
+			return nil, err
+		}
+		var readings [][]Reading
+		for _, id := range ids {
+			sensor := make([]Reading, len(stats[id]))
+			for i, stat := range stats[id] {
+				sensor[i] = Reading{Start: time.UnixMilli(stat.Start), Value: stat.Change}
+			}
+			readings = append(readings, sensor)
+		}
+		return addReadings(readings), nil
+	case "sum", "state":
+		width, ok := recorderPeriods[period]
+		if !ok {
+			return nil, permanent(fmt.Errorf("unsupported period %q for statistic_type %s", period, statType))
+		}
+		// Totals are recorded at the end of each period, so the one before the start is needed
+		// to work out the consumption in the first period.
+		stats, err := r.statisticsFor(ids, start.Add(-width), end, period, statType)
 		if err != nil {
-			return nil, fmt.Errorf("reading from websocket: %w", err)
+			return nil, err
+		}
+		total := func(s Statistic) float64 { return s.Sum }
+		if statType == "state" {
+			total = func(s Statistic) float64 { return s.State }
 		}
+		var readings [][]Reading
+		for _, id := range ids {
+			readings = append(readings, differences(stats[id], total))
+		}
+		return addReadings(readings), nil
+	default:
+		return nil, permanent(fmt.Errorf("unknown statistic_type %q", statType))
+	}
+}
 
-		if !data.Success {
-			return nil, fmt.Errorf("api response error: %v", data.Error)
+// addReadings adds up the readings of several sensors that start at the same time, in order.
+func addReadings(sensors [][]Reading) []Reading {
+	if len(sensors) == 1 {
+		return sensors[0]
+	}
+	totals := make(map[int64]float64)
+	for _, readings := range sensors {
+		for _, r := range readings {
+			totals[r.Start.UnixMilli()] += r.Value
+		}
+	}
+	readings := make([]Reading, 0, len(totals))
+	for start, v := range totals {
+		readings = append(readings, Reading{Start: time.UnixMilli(start), Value: v})
+	}
+	sort.Slice(readings, func(i, j int) bool { return readings[i].Start.Before(readings[j].Start) })
+	return readings
+}
+
+// differences turns consecutive cumulative totals into the consumption in each period. A total
+// that goes down means the meter was reset, so the new total is all consumption since the reset.
+func differences(stats []Statistic, total func(Statistic) float64) []Reading {
+	var readings []Reading
+	for i := 1; i < len(stats); i++ {
+		delta := total(stats[i]) - total(stats[i-1])
+		if delta < 0 {
+			delta = total(stats[i])
+		}
+		readings = append(readings, Reading{Start: time.UnixMilli(stats[i].Start), Value: delta})
+	}
+	return readings
+}
+
+// reconnect replaces the connection if reading from it has failed. It is left alone if responses
+// are still being read from it, as the request failed in Home Assistant, or another request has
+// already reconnected.
+func (r recorder) reconnect() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Conn != nil && r.reading == r.Conn {
+		return nil
+	}
+	if r.Conn != nil {
+		r.Conn.Close()
+	}
+	return r.connectHomeAssistant(context.Background())
+}
+
+// statistics fetches long-term statistics of the given type, such as "change" or "mean", for a
+// single statistic ID. Energy is converted to kWh and temperatures to °C.
+func (c *Client) statistics(id string, start, end time.Time, period, statType string) ([]Statistic, error) {
+	stats, err := c.statisticsFor([]string{id}, start, end, period, statType)
+	if err != nil {
+		return nil, err
+	}
+	return stats[id], nil
+}
+
+// statisticsFor fetches long-term statistics for several statistic IDs in one request, keyed by
+// ID. Each ID has to return some statistics, as a typo in one would otherwise go unnoticed.
+func (c *Client) statisticsFor(ids []string, start, end time.Time, period, statType string) (map[string][]Statistic, error) {
+	if !c.connected() {
+		return nil, fmt.Errorf("statistics require the Home Assistant source")
+	}
+
+	msg := map[string]interface{}{
+		"type":          "recorder/statistics_during_period",
+		"start_time":    start.UTC().Format("2006-01-02T15:04:05.000Z"),
+		"end_time":      end.UTC().Format("2006-01-02T15:04:05.000Z"),
+		"statistic_ids": ids,
+		"period":        period,
+		"types":         []string{statType},
+		"units": map[string]string{
+			"energy":      "kWh",
+			"temperature": "°C",
+		},
+	}
+
+	var data APIResponse
+	if err := c.request(msg, &data); err != nil {
+		return nil, err
+	}
+
+	if !data.Success {
+		return nil, fmt.Errorf("api response error: %v", data.Error)
+	}
+	for _, id := range ids {
+		if len(data.Result[id]) == 0 {
+			return nil, permanent(fmt.Errorf("no results returned - is your sensorID '%s' correct?", id))
+		}
+	}
+	return data.Result, nil
+}
+
+// ping checks that Home Assistant still answers on the websocket connection, giving up after the
+// timeout. A connection that has silently gone away can otherwise block a read for a long time.
+func (c *Client) ping(timeout time.Duration) error {
+	var resp struct {
+		Type string `json:"type"`
+	}
+	if err := c.requestWithin(map[string]interface{}{"type": "ping"}, &resp, timeout); err != nil {
+		return err
+	}
+	if resp.Type != "pong" {
+		return fmt.Errorf("unexpected response to ping: %q", resp.Type)
+	}
+	return nil
+}
+
+// connected reports whether there is a websocket connection to Home Assistant.
+func (c *Client) connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Conn != nil
+}
+
+// response is a frame read from the websocket for a pending request, or the error that ended the
+// connection before it arrived.
+type response struct {
+	data []byte
+	err  error
+}
+
+// pendingRequest is a request waiting for its response on the connection it was sent on.
+type pendingRequest struct {
+	conn *websocket.Conn
+	ch   chan response
+}
+
+// defaultRequestTimeout is how long to wait for Home Assistant to answer a request, unless
+// request_timeout is set.
+const defaultRequestTimeout = time.Minute
+
+// requestTimeout returns how long to wait for Home Assistant to answer a request, or to accept a
+// new connection.
+func (c *Client) requestTimeout() time.Duration {
+	if d := viper.GetDuration("request_timeout"); d > 0 {
+		return d
+	}
+	return defaultRequestTimeout
+}
+
+// subscription receives the events Home Assistant sends for a subscription made on the connection.
+type subscription struct {
+	conn   *websocket.Conn
+	events chan []byte
+}
+
+// request sends a message with the next message ID and decodes the response into resp. Several
+// requests can be in flight at once, as Home Assistant answers each with the ID it was sent with,
+// and not necessarily in the order they were sent.
+func (c *Client) request(msg map[string]interface{}, resp interface{}) error {
+	return c.requestWithin(msg, resp, c.requestTimeout())
+}
+
+// requestWithin is like request, but gives up if there is no response within the timeout. As
+// Home Assistant has stopped answering on the connection, it is closed, which fails the other
+// requests waiting on it and lets the next retry reconnect.
+func (c *Client) requestWithin(msg map[string]interface{}, resp interface{}, timeout time.Duration) error {
+	return c.send(msg, resp, timeout, nil)
+}
+
+// subscribe sends a message that subscribes to events, such as subscribe_events, and returns the
+// events as they arrive. The channel is closed when the connection ends, after which the
+// subscription has to be made again on a new connection. Events that arrive while the channel is
+// full are dropped, rather than holding up responses to other requests.
+func (c *Client) subscribe(msg map[string]interface{}) (<-chan []byte, error) {
+	events := make(chan []byte, 64)
+	var resp struct {
+		Success bool `json:"success"`
+		Error   struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := c.send(msg, &resp, c.requestTimeout(), events); err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		c.unsubscribe(msg["id"].(int))
+		return nil, fmt.Errorf("api response error: %s", resp.Error.Message)
+	}
+	return events, nil
+}
+
+// unsubscribe stops passing on the events of a subscription, and closes its channel.
+func (c *Client) unsubscribe(id int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if sub, ok := c.subscriptions[id]; ok {
+		close(sub.events)
+		delete(c.subscriptions, id)
+	}
+}
+
+// send sends a request and waits for its response, as requestWithin does. If events isn't nil,
+// the events sent with the request's message ID are passed on to it once the request is made.
+func (c *Client) send(msg map[string]interface{}, resp interface{}, timeout time.Duration, events chan []byte) error {
+	c.mu.Lock()
+	if c.Conn == nil {
+		c.mu.Unlock()
+		return fmt.Errorf("not connected to Home Assistant")
+	}
+	c.MessageID++
+	id := c.MessageID
+	msg["id"] = id
+	c.noteQuery(msg)
+
+	ch := make(chan response, 1)
+	if c.pending == nil {
+		c.pending = make(map[int]pendingRequest)
+	}
+	c.pending[id] = pendingRequest{conn: c.Conn, ch: ch}
+	if events != nil {
+		// Events can follow the result straight away, so the subscription is in place first.
+		if c.subscriptions == nil {
+			c.subscriptions = make(map[int]subscription)
+		}
+		c.subscriptions[id] = subscription{conn: c.Conn, events: events}
+	}
+	if c.reading != c.Conn {
+		c.reading = c.Conn
+		go c.readResponses(c.Conn)
+	}
+	conn := c.Conn
+	conn.SetWriteDeadline(time.Now().Add(timeout))
+	if err := c.write(msg); err != nil {
+		// The connection is no use for any other request either, so it is closed, which ends the
+		// requests waiting on it and lets the next retry reconnect.
+		delete(c.pending, id)
+		c.reading = nil
+		c.Conn.Close()
+		c.mu.Unlock()
+		if events != nil {
+			c.unsubscribe(id)
+		}
+		return fmt.Errorf("writing to websocket: %w", err)
+	}
+	c.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var r response
+	select {
+	case r = <-ch:
+	case <-timer.C:
+		c.mu.Lock()
+		delete(c.pending, id)
+		if c.reading == conn {
+			c.reading = nil
+		}
+		c.mu.Unlock()
+		conn.Close()
+		if events != nil {
+			c.unsubscribe(id)
+		}
+		return fmt.Errorf("no response after %s", timeout)
+	}
+	if r.err != nil {
+		// The subscription was ended along with the connection.
+		return fmt.Errorf("reading from websocket: %w", r.err)
+	}
+	return json.Unmarshal(r.data, resp)
+}
+
+// readResponses reads frames from the connection until it fails, handing each to the pending
+// request with its message ID, and each event to the subscription with its message ID. Other
+// frames are dropped. When the connection fails, every request still waiting on it gets the
+// error, and its subscriptions are ended.
+func (c *Client) readResponses(conn *websocket.Conn) {
+	for {
+		data, err := c.readMessage(conn)
+		if err != nil {
+			c.mu.Lock()
+			if c.reading == conn {
+				c.reading = nil
+			}
+			for id, p := range c.pending {
+				if p.conn == conn {
+					p.ch <- response{err: err}
+					delete(c.pending, id)
+				}
+			}
+			for id, sub := range c.subscriptions {
+				if sub.conn == conn {
+					close(sub.events)
+					delete(c.subscriptions, id)
+				}
+			}
+			c.mu.Unlock()
+			return
+		}
+
+		var header struct {
+			ID   int    `json:"id"`
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(data, &header); err != nil {
+			c.logger().Warn().Msgf("ignoring a frame that isn't JSON: %v", err)
+			continue
 		}
-		if len(data.Result[sensorID]) == 0 {
-			return nil, fmt.Errorf("no results returned - is your sensorID '%s' correct?", sensorID)
+		c.mu.Lock()
+		if header.Type == "event" {
+			if sub, ok := c.subscriptions[header.ID]; ok && sub.conn == conn {
+				select {
+				case sub.events <- data:
+				default:
+				}
+			}
+			c.mu.Unlock()
+			continue
 		}
-		changeSlice := make([]float64, hoursInADay)
-		for j := range changeSlice {
-			changeSlice[j] = data.Result[sensorID][j].Change
+		p, ok := c.pending[header.ID]
+		if ok && p.conn == conn {
+			p.ch <- response{data: data}
+			delete(c.pending, header.ID)
 		}
-		results[i] = changeSlice
+		c.mu.Unlock()
 	}
-	return results, nil
 }
 
 func (c *Client) write(data map[string]interface{}) error {
-	return c.Conn.WriteJSON(data)
+	return c.writeFrame(c.Conn, data)
 }
@@ -1,11 +1,29 @@
 package client
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/poolski/powertracker/cmd/hatest"
 	"github.com/spf13/viper"
 	"gotest.tools/v3/assert"
 )
@@ -108,3 +126,341 @@ func TestClient_Connect_ErrorStates(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_Connect_CAFile(t *testing.T) {
+	s := hatest.NewTLSServer("test_token")
+	defer s.Close()
+	viper.Set("url", s.URL)
+	viper.Set("api_key", "test_token")
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	assert.NilError(t, os.WriteFile(caFile, s.Certificate(), 0o600))
+	notPEM := filepath.Join(dir, "ca.txt")
+	assert.NilError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0o600))
+
+	// The self-signed certificate can't be verified against the system's CAs alone.
+	c := New(Config{})
+	assert.ErrorContains(t, c.Connect(), "certificate")
+
+	c = New(Config{CAFile: caFile})
+	assert.NilError(t, c.Connect())
+	c.Close()
+
+	assert.ErrorContains(t, New(Config{CAFile: notPEM}).Connect(), "no PEM certificates found in "+notPEM)
+	assert.ErrorContains(t, New(Config{CAFile: filepath.Join(dir, "missing.pem")}).Connect(), "reading CA certificate")
+}
+
+func TestClient_Connect_ClientCert(t *testing.T) {
+	// A self-signed client certificate, which the server trusts.
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "powertracker"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NilError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NilError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NilError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key"), filepath.Join(dir, "ca.pem")
+	assert.NilError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	assert.NilError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	s := hatest.NewMutualTLSServer("test_token", pool)
+	defer s.Close()
+	assert.NilError(t, os.WriteFile(caFile, s.Certificate(), 0o600))
+	viper.Set("url", s.URL)
+	viper.Set("api_key", "test_token")
+
+	// Without the certificate, the server turns the connection away.
+	assert.Assert(t, New(Config{CAFile: caFile}).Connect() != nil)
+
+	c := New(Config{CAFile: caFile, ClientCert: certFile, ClientKey: keyFile})
+	assert.NilError(t, c.Connect())
+	c.Close()
+
+	// It is still presented when the server's certificate isn't verified.
+	c = New(Config{Insecure: true, ClientCert: certFile, ClientKey: keyFile})
+	assert.NilError(t, c.Connect())
+	c.Close()
+
+	assert.ErrorContains(t, New(Config{ClientCert: certFile}).Connect(), "client_cert and client_key are both required")
+	assert.ErrorContains(t, New(Config{ClientCert: certFile, ClientKey: caFile}).Connect(), "loading client certificate")
+}
+
+func TestProxy(t *testing.T) {
+	for _, key := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy", "ALL_PROXY", "all_proxy", "NO_PROXY", "no_proxy"} {
+		t.Setenv(key, "")
+	}
+	defer viper.Set("proxy", "")
+	req := &http.Request{URL: &url.URL{Scheme: "https", Host: "ha.example.com"}}
+	proxied := func() string {
+		t.Helper()
+		u, err := proxy(req)
+		assert.NilError(t, err)
+		if u == nil {
+			return ""
+		}
+		return u.String()
+	}
+
+	assert.Equal(t, proxied(), "")
+	t.Setenv("ALL_PROXY", "socks5h://localhost:1080")
+	assert.Equal(t, proxied(), "socks5://localhost:1080")
+	t.Setenv("HTTPS_PROXY", "http://proxy.example.com:3128")
+	assert.Equal(t, proxied(), "http://proxy.example.com:3128")
+	t.Setenv("NO_PROXY", "example.com")
+	assert.Equal(t, proxied(), "")
+
+	// The setting is used whatever the environment says.
+	viper.Set("proxy", "socks5://localhost:1081")
+	assert.Equal(t, proxied(), "socks5://localhost:1081")
+	viper.Set("proxy", "localhost")
+	_, err := proxy(req)
+	assert.ErrorContains(t, err, "proxy should be a URL")
+}
+
+func TestClient_Connect_Proxy(t *testing.T) {
+	s := hatest.NewServer("test_token")
+	defer s.Close()
+	viper.Set("url", s.URL)
+	viper.Set("api_key", "test_token")
+
+	// An HTTP proxy that tunnels connections with CONNECT.
+	var tunnelled []string
+	p := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		tunnelled = append(tunnelled, r.Host)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		assert.NilError(t, err)
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go func() { _, _ = io.Copy(upstream, conn) }()
+		go func() { _, _ = io.Copy(conn, upstream) }()
+	}))
+	defer p.Close()
+	viper.Set("proxy", p.URL)
+	defer viper.Set("proxy", "")
+
+	c := New(Config{})
+	assert.NilError(t, c.Connect())
+	defer c.Close()
+	assert.DeepEqual(t, tunnelled, []string{strings.TrimPrefix(s.URL, "http://")})
+}
+
+func TestWebsocketURL(t *testing.T) {
+	for target, want := range map[string]string{
+		"http://homeassistant.local:8123":          "ws://homeassistant.local:8123/api/websocket",
+		"https://ha.example.com/lovelace/0?edit=1": "wss://ha.example.com/api/websocket",
+		"https://abcdef.ui.nabu.casa/":             "wss://abcdef.ui.nabu.casa/api/websocket",
+		"http://abcdef.ui.nabu.casa/lovelace":      "wss://abcdef.ui.nabu.casa/api/websocket",
+		"abcdef.ui.nabu.casa":                      "wss://abcdef.ui.nabu.casa/api/websocket",
+		"http://supervisor/core":                   "ws://supervisor/core/websocket",
+	} {
+		u, err := websocketURL(target)
+		assert.NilError(t, err, target)
+		assert.Equal(t, u.String(), want, target)
+	}
+}
+
+func TestClient_Connect_Redirect(t *testing.T) {
+	s := hatest.NewServer("test_token")
+	defer s.Close()
+	r := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, s.URL+r.URL.Path, http.StatusMovedPermanently)
+	}))
+	defer r.Close()
+	viper.Set("url", r.URL)
+	viper.Set("api_key", "test_token")
+
+	c := New(Config{})
+	assert.NilError(t, c.Connect())
+	c.Close()
+
+	// Anything else that isn't the websocket says what came back instead.
+	viper.Set("url", r.URL)
+	r.Config.Handler = http.NotFoundHandler()
+	assert.ErrorContains(t, New(Config{}).Connect(), "bad handshake (404 Not Found)")
+}
+
+func TestClient_ConnectContext_Hung(t *testing.T) {
+	// The server accepts the websocket but never starts the authentication flow.
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, _ := upgrader.Upgrade(w, r, nil)
+		defer conn.Close()
+		_, _, _ = conn.ReadMessage()
+	}))
+	defer s.Close()
+	viper.Set("url", s.URL)
+	viper.Set("api_key", "test_token")
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		err := New(Config{}).ConnectContext(ctx)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+	t.Run("stopped", func(t *testing.T) {
+		c := New(Config{})
+		time.AfterFunc(50*time.Millisecond, c.Stop)
+		assert.ErrorIs(t, c.Connect(), ErrInterrupted)
+	})
+	t.Run("timeout", func(t *testing.T) {
+		viper.Set("request_timeout", 50*time.Millisecond)
+		defer viper.Set("request_timeout", 0)
+		err := New(Config{}).Connect()
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestClient_ConcurrentRequests(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, _ := upgrader.Upgrade(w, r, nil)
+		defer conn.Close()
+
+		// Answer each request with the ID it asked for, so a response read by the wrong
+		// caller shows up as a missing result.
+		for {
+			var msg map[string]interface{}
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			id := msg["statistic_ids"].([]interface{})[0].(string)
+			assert.NilError(t, conn.WriteJSON(map[string]interface{}{
+				"id":      msg["id"],
+				"type":    "result",
+				"success": true,
+				"result": map[string]interface{}{
+					id: []map[string]interface{}{{"start": 0, "change": msg["id"]}},
+				},
+			}), "write statistics response failed")
+		}
+	}))
+	defer s.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+	assert.NilError(t, err)
+	client := &Client{Conn: conn}
+	defer client.Close()
+
+	const requests = 20
+	changes := make([]float64, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			stats, err := client.statistics(fmt.Sprintf("sensor.%d", i), time.Now(), time.Now(), "hour", "change")
+			assert.Check(t, err)
+			if err == nil {
+				changes[i] = stats[0].Change
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Every request got its own response, and each used a different message ID.
+	seen := map[float64]bool{}
+	for _, change := range changes {
+		assert.Assert(t, !seen[change], "message ID %v used twice", change)
+		seen[change] = true
+	}
+	assert.Equal(t, client.MessageID, requests)
+	assert.Assert(t, client.Stats().BytesReceived > 0, "expected the responses to be counted")
+}
+
+func TestClient_OutOfOrderResponses(t *testing.T) {
+	const requests = 5
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, _ := upgrader.Upgrade(w, r, nil)
+		defer conn.Close()
+
+		// Once every request is in, answer them last first, each after an event carrying its
+		// message ID that nothing subscribed to.
+		var msgs []map[string]interface{}
+		for len(msgs) < requests {
+			var msg map[string]interface{}
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			msgs = append(msgs, msg)
+		}
+		for i := len(msgs) - 1; i >= 0; i-- {
+			id := msgs[i]["statistic_ids"].([]interface{})[0].(string)
+			assert.NilError(t, conn.WriteJSON(map[string]interface{}{
+				"id":    msgs[i]["id"],
+				"type":  "event",
+				"event": map[string]interface{}{"a": map[string]interface{}{}},
+			}))
+			assert.NilError(t, conn.WriteJSON(map[string]interface{}{
+				"id":      msgs[i]["id"],
+				"type":    "result",
+				"success": true,
+				"result": map[string]interface{}{
+					id: []map[string]interface{}{{"start": 0, "change": 1}},
+				},
+			}))
+		}
+		_, _, _ = conn.ReadMessage()
+	}))
+	defer s.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+	assert.NilError(t, err)
+	client := &Client{Conn: conn}
+	defer client.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// The statistics for another sensor, or an event, would fail the request.
+			stats, err := client.statistics(fmt.Sprintf("sensor.%d", i), time.Now(), time.Now(), "hour", "change")
+			assert.Check(t, err)
+			assert.Check(t, len(stats) == 1)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestTotalColumn(t *testing.T) {
+	stats, err := parseStats([]string{"mean", "p100"})
+	assert.NilError(t, err)
+	col := totalColumn([]Day{{Values: []float64{0.5, 1.5}}, {Values: []float64{1, 2}}}, stats)
+	assert.Equal(t, col.Header, "Total")
+	assert.DeepEqual(t, col.Values, []string{"2.000000", "3.000000"})
+	assert.DeepEqual(t, col.Footers, []string{"2.500000", "3.000000"})
+
+	assert.DeepEqual(t, totalColumn(nil, stats).Footers, []string{"0.000000", "0.000000"})
+}
+
+func TestClient_NeedsHomeAssistant(t *testing.T) {
+	assert.Equal(t, New(Config{Output: "balance"}).needsHomeAssistant(), "")
+	assert.Equal(t, New(Config{Output: "demand"}).needsHomeAssistant(), "-o demand")
+	assert.Equal(t, New(Config{Split: "occupancy"}).needsHomeAssistant(), "--split occupancy")
+}
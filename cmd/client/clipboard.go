@@ -0,0 +1,47 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// clipboardCommand returns a command that puts what it reads from stdin on the system clipboard.
+var clipboardCommand = func() (*exec.Cmd, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("pbcopy"), nil
+	case "windows":
+		return exec.Command("clip"), nil
+	}
+
+	// Other systems have one of these, depending on the display server.
+	tools := [][]string{
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+	}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		tools = append([][]string{{"wl-copy"}}, tools...)
+	}
+	for _, tool := range tools {
+		if path, err := exec.LookPath(tool[0]); err == nil {
+			return exec.Command(path, tool[1:]...), nil
+		}
+	}
+	return nil, fmt.Errorf("no clipboard tool found - install wl-clipboard, xclip or xsel")
+}
+
+// copyToClipboard puts the text on the system clipboard.
+func copyToClipboard(text string) error {
+	cmd, err := clipboardCommand()
+	if err != nil {
+		return err
+	}
+	cmd.Stdin = strings.NewReader(text)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("running %s: %w: %s", cmd.Path, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestCopyToClipboard(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clipboard")
+	defer func(f func() (*exec.Cmd, error)) { clipboardCommand = f }(clipboardCommand)
+	clipboardCommand = func() (*exec.Cmd, error) {
+		return exec.Command("sh", "-c", `cat > "$0"`, path), nil
+	}
+
+	assert.NilError(t, copyToClipboard(plainText([]float64{0.5, 1.25})))
+	data, err := os.ReadFile(path)
+	assert.NilError(t, err)
+	assert.Equal(t, string(data), "0.500000,\n1.250000,\n")
+
+	clipboardCommand = func() (*exec.Cmd, error) {
+		return exec.Command("sh", "-c", "echo no display >&2; exit 1"), nil
+	}
+	assert.ErrorContains(t, copyToClipboard("x"), "exit status 1: no display")
+}
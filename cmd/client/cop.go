@@ -0,0 +1,166 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/powertracker/cmd/i18n"
+	"github.com/spf13/viper"
+)
+
+// cop returns the coefficient of performance of a heat pump: the heat it put out for each kWh of
+// electricity it used. It is NaN when no electricity was used, as the ratio means nothing then.
+func cop(heat, electricity float64) float64 {
+	if electricity <= 0 {
+		return math.NaN()
+	}
+	return heat / electricity
+}
+
+// formatCOP formats a COP for a table, leaving it blank when there isn't one.
+func formatCOP(v float64) string {
+	if math.IsNaN(v) {
+		return ""
+	}
+	return fmt.Sprintf("%.2f", v)
+}
+
+// heatPumpResults returns the days of the heat pump's electricity use and heat output, paired by
+// date. Days missing from either, which only happens when the fetch is interrupted, are left out.
+func (c *Client) heatPumpResults() (electricity, heat []Day, err error) {
+	heatID := viper.GetString("heat_pump.heat_sensor_id")
+	if heatID == "" {
+		return nil, nil, fmt.Errorf("heat_pump.heat_sensor_id is required")
+	}
+	// Without its own meter, the heat pump is assumed to be the only load on sensor_id.
+	electricityID := viper.GetString("heat_pump.electricity_sensor_id")
+	if electricityID == "" {
+		electricityID = SensorID()
+	}
+	if electricityID == "" {
+		return nil, nil, fmt.Errorf("heat_pump.electricity_sensor_id or sensor_id is required")
+	}
+
+	electricity, err = c.sensorResults(electricityID, c.days())
+	if err != nil && !errors.Is(err, ErrInterrupted) {
+		return nil, nil, fmt.Errorf("electricity: %w", err)
+	}
+	var interrupted error
+	if err != nil {
+		interrupted = err
+	} else {
+		heat, err = c.sensorResults(heatID, c.days())
+		if err != nil && !errors.Is(err, ErrInterrupted) {
+			return nil, nil, fmt.Errorf("heat: %w", err)
+		}
+		interrupted = err
+	}
+
+	byDate := make(map[int64]Day, len(heat))
+	for _, day := range heat {
+		byDate[day.Date.Unix()] = day
+	}
+	var pairedElectricity, pairedHeat []Day
+	for _, day := range electricity {
+		if h, ok := byDate[day.Date.Unix()]; ok {
+			pairedElectricity = append(pairedElectricity, day)
+			pairedHeat = append(pairedHeat, h)
+		}
+	}
+	return pairedElectricity, pairedHeat, interrupted
+}
+
+// printCOP prints the heat pump's electricity use, heat output and COP for each day and for each
+// hour of the average day, followed by the COP over the whole period. With temperature_sensor_id,
+// each day's average outdoor temperature is shown too, with a line fitted through COP against
+// temperature, as heat pumps are less efficient the colder it is outside.
+func (c *Client) printCOP() error {
+	electricity, heat, err := c.heatPumpResults()
+	if errors.Is(err, ErrInterrupted) && len(electricity) > 0 {
+		c.logger().Warn().Msg(fmt.Sprintf("interrupted - reporting on the %d days fetched in full", len(electricity)))
+	} else if err != nil {
+		return err
+	}
+	if len(electricity) == 0 {
+		return fmt.Errorf("no days to report on")
+	}
+	c.fixSpikes(electricity)
+	c.fixSpikes(heat)
+
+	temps := make([]float64, len(electricity))
+	for i := range temps {
+		temps[i] = math.NaN()
+	}
+	tempID := viper.GetString("temperature_sensor_id")
+	if tempID != "" {
+		if temps, err = c.dailyTemperatures(tempID, electricity); err != nil {
+			return fmt.Errorf("getting temperatures: %w", err)
+		}
+	}
+
+	days := tablewriter.NewWriter(os.Stdout)
+	header := []string{i18n.T("Date"), i18n.T("Electricity kWh"), i18n.T("Heat kWh"), "COP"}
+	if tempID != "" {
+		header = append(header, i18n.T("Avg temp (°C)"))
+	}
+	days.SetHeader(header)
+	var totalElectricity, totalHeat float64
+	var x, y []float64
+	for i, day := range electricity {
+		used, output := sum(day.Values), sum(heat[i].Values)
+		totalElectricity += used
+		totalHeat += output
+		dayCOP := cop(output, used)
+		row := []string{day.Date.Format("2006-01-02"), fmt.Sprintf("%f", used), fmt.Sprintf("%f", output), formatCOP(dayCOP)}
+		if tempID != "" {
+			temp := ""
+			if !math.IsNaN(temps[i]) {
+				temp = fmt.Sprintf("%.1f", temps[i])
+				if !math.IsNaN(dayCOP) {
+					x = append(x, temps[i])
+					y = append(y, dayCOP)
+				}
+			}
+			row = append(row, temp)
+		}
+		days.Append(row)
+	}
+	footer := []string{i18n.T("Total"), fmt.Sprintf("%f", totalElectricity), fmt.Sprintf("%f", totalHeat), formatCOP(cop(totalHeat, totalElectricity))}
+	if tempID != "" {
+		footer = append(footer, "")
+	}
+	days.SetFooter(footer)
+	days.Render()
+
+	// The COP of each hour of the average day is worked out from the totals, so hours the heat
+	// pump barely ran in don't count as much as the rest.
+	_, slots := c.slots()
+	hourlyElectricity, hourlyHeat := make([]float64, slots), make([]float64, slots)
+	for i, day := range electricity {
+		for h := range day.Values {
+			hourlyElectricity[h] += day.Values[h]
+			hourlyHeat[h] += heat[i].Values[h]
+		}
+	}
+	hours := tablewriter.NewWriter(os.Stdout)
+	hours.SetHeader([]string{i18n.T("Hour"), i18n.T("Electricity kWh"), i18n.T("Heat kWh"), "COP"})
+	n := float64(len(electricity))
+	for h, label := range c.slotHeaders() {
+		hours.Append([]string{label, fmt.Sprintf("%f", hourlyElectricity[h]/n), fmt.Sprintf("%f", hourlyHeat[h]/n), formatCOP(cop(hourlyHeat[h], hourlyElectricity[h]))})
+	}
+	hours.Render()
+
+	fmt.Println(i18n.T("COP over the period: %s", formatCOP(cop(totalHeat, totalElectricity))))
+	if tempID == "" {
+		return nil
+	}
+	f, err := linearFit(x, y)
+	if err != nil {
+		return fmt.Errorf("fitting line: %w", err)
+	}
+	fmt.Printf("COP = %.3f × temp + %.3f (r² = %.2f)\n", f.Slope, f.Intercept, f.R2)
+	return nil
+}
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestCOP(t *testing.T) {
+	assert.Equal(t, cop(7, 2), 3.5)
+	assert.Assert(t, math.IsNaN(cop(1, 0)))
+	assert.Equal(t, formatCOP(3.5), "3.50")
+	assert.Equal(t, formatCOP(math.NaN()), "")
+}
+
+func TestClient_HeatPumpResults(t *testing.T) {
+	viper.Set("sensor_id", "sensor.energy")
+	defer viper.Set("heat_pump", nil)
+
+	c := New(Config{Days: 2})
+	_, _, err := c.heatPumpResults()
+	assert.Error(t, err, "heat_pump.heat_sensor_id is required")
+
+	yesterday := time.Now().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	source := fakeSource{}
+	for h := 0; h < 2*hoursInADay; h++ {
+		start := yesterday.Add(-24 * time.Hour).Add(time.Duration(h) * time.Hour)
+		source["sensor.heat_pump"] = append(source["sensor.heat_pump"], Reading{Start: start, Value: 1})
+		source["sensor.heat"] = append(source["sensor.heat"], Reading{Start: start, Value: 3})
+	}
+	c.source = source
+
+	// The heat pump's own meter is used over sensor_id.
+	viper.Set("heat_pump", map[string]interface{}{"heat_sensor_id": "sensor.heat", "electricity_sensor_id": "sensor.heat_pump"})
+	electricity, heat, err := c.heatPumpResults()
+	assert.NilError(t, err)
+	assert.Equal(t, len(electricity), 2)
+	assert.Equal(t, len(heat), 2)
+	assert.Equal(t, heat[0].Date, electricity[0].Date)
+	assert.Equal(t, cop(sum(heat[0].Values), sum(electricity[0].Values)), 3.0)
+}
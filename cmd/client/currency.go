@@ -0,0 +1,55 @@
+package client
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/spf13/viper"
+)
+
+// currency describes how amounts of money are shown, from the currency config.
+type currency struct {
+	Symbol   string `mapstructure:"symbol"`
+	Decimals int    `mapstructure:"decimals"`
+	// Position is "before" or "after" the amount.
+	Position string `mapstructure:"position"`
+}
+
+// currencyConfig returns the configured currency. Without one, amounts are shown with two decimal
+// places and no symbol, as prices can be in any currency.
+func currencyConfig() currency {
+	cur := currency{Decimals: 2, Position: "before"}
+	_ = viper.UnmarshalKey("currency", &cur)
+	if cur.Decimals < 0 {
+		cur.Decimals = 0
+	}
+	return cur
+}
+
+// format returns an amount of money, such as "£1.23", "-£0.40" or "12.50 kr" with the symbol after.
+func (cur currency) format(amount float64) string {
+	return cur.show(amount, cur.Decimals)
+}
+
+// rate returns a price per kWh, which needs two more decimal places than a bill to be useful.
+func (cur currency) rate(amount float64) string {
+	return cur.show(amount, cur.Decimals+2)
+}
+
+func (cur currency) show(amount float64, decimals int) string {
+	// Rounding first stops tiny negative amounts from being shown as "-0.00".
+	scale := math.Pow10(decimals)
+	amount = math.Round(amount*scale) / scale
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+	}
+	s := strconv.FormatFloat(math.Abs(amount), 'f', decimals, 64)
+	if cur.Symbol == "" {
+		return sign + s
+	}
+	if cur.Position == "after" {
+		return sign + s + " " + cur.Symbol
+	}
+	return sign + cur.Symbol + s
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestCurrency(t *testing.T) {
+	defer viper.Set("currency", nil)
+
+	viper.Set("currency", nil)
+	cur := currencyConfig()
+	assert.Equal(t, cur.format(1.234), "1.23")
+	assert.Equal(t, cur.rate(0.24512), "0.2451")
+
+	viper.Set("currency", map[string]any{"symbol": "£"})
+	cur = currencyConfig()
+	assert.Equal(t, cur.format(1.235), "£1.24")
+	assert.Equal(t, cur.format(-0.4), "-£0.40")
+	assert.Equal(t, cur.format(-0.001), "£0.00")
+
+	viper.Set("currency", map[string]any{"symbol": "kr", "decimals": 0, "position": "after"})
+	cur = currencyConfig()
+	assert.Equal(t, cur.format(12.5), "13 kr")
+	assert.Equal(t, cur.rate(1.2345), "1.23 kr")
+}
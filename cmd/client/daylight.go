@@ -0,0 +1,168 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/powertracker/cmd/i18n"
+	"github.com/spf13/viper"
+)
+
+// daylight is the time between sunrise and sunset on a day.
+type daylight struct {
+	Rise time.Time
+	Set  time.Time
+}
+
+// julianDay converts between times and Julian dates, which the sun's position is worked out in.
+func julianDay(t time.Time) float64 {
+	return float64(t.Unix())/86400 + 2440587.5
+}
+
+func fromJulianDay(j float64) time.Time {
+	return time.Unix(int64(math.Round((j-2440587.5)*86400)), 0).UTC()
+}
+
+// sunTimes returns when the sun rises and sets on the UTC date of day, at the given latitude and
+// longitude in degrees, east and north being positive. It uses the sunrise equation, which is good
+// to a minute or two. When the sun doesn't set, daylight runs for the whole UTC day, and when it
+// doesn't rise, Rise and Set are both solar noon.
+func sunTimes(day time.Time, lat, lon float64) daylight {
+	rad := math.Pi / 180
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+
+	// Days since the J2000 epoch, at the mean solar noon of the longitude.
+	n := math.Ceil(julianDay(midnight) - 2451545.0 + 0.0008)
+	mean := n - lon/360
+	anomaly := math.Mod(357.5291+0.98560028*mean, 360)
+	centre := 1.9148*math.Sin(anomaly*rad) + 0.02*math.Sin(2*anomaly*rad) + 0.0003*math.Sin(3*anomaly*rad)
+	ecliptic := math.Mod(anomaly+centre+180+102.9372, 360)
+	transit := 2451545.0 + mean + 0.0053*math.Sin(anomaly*rad) - 0.0069*math.Sin(2*ecliptic*rad)
+
+	declination := math.Asin(math.Sin(ecliptic*rad) * math.Sin(23.4397*rad))
+	// The sun has risen when its upper edge clears the horizon, allowing for refraction.
+	cosHour := (math.Sin(-0.833*rad) - math.Sin(lat*rad)*math.Sin(declination)) / (math.Cos(lat*rad) * math.Cos(declination))
+	var hour float64
+	switch {
+	case cosHour < -1:
+		return daylight{Rise: midnight, Set: midnight.Add(24 * time.Hour)}
+	case cosHour > 1:
+		hour = 0
+	default:
+		hour = math.Acos(cosHour) / rad
+	}
+	return daylight{Rise: fromJulianDay(transit - hour/360), Set: fromJulianDay(transit + hour/360)}
+}
+
+// overlap returns how long the two periods overlap for.
+func overlap(start, end, from, to time.Time) time.Duration {
+	if from.Before(start) {
+		from = start
+	}
+	if to.After(end) {
+		to = end
+	}
+	if !to.After(from) {
+		return 0
+	}
+	return to.Sub(from)
+}
+
+// splitDaylight divides a day's consumption into the parts used in daylight and at night. Slots
+// the sun rises or sets in are divided in proportion to the time the sun was up. Days run from
+// midnight in the time zone rather than by the sun, so daylight from the dates either side is
+// counted too, for longitudes where it crosses midnight.
+func splitDaylight(day Day, width time.Duration, lat, lon float64) (light, night float64) {
+	var periods []daylight
+	for _, offset := range []int{-1, 0, 1} {
+		periods = append(periods, sunTimes(day.Date.AddDate(0, 0, offset), lat, lon))
+	}
+	for i, v := range day.Values {
+		start, ok := slotStart(day.Date, i, width)
+		if !ok {
+			continue
+		}
+		end := slotEnd(day.Date, i, width)
+		var up time.Duration
+		for _, p := range periods {
+			up += overlap(start, end, p.Rise, p.Set)
+		}
+		fraction := math.Min(float64(up)/float64(end.Sub(start)), 1)
+		light += v * fraction
+		night += v * (1 - fraction)
+	}
+	return light, night
+}
+
+// location returns the latitude and longitude to work out daylight for, from location.latitude
+// and location.longitude, or else the home location configured in Home Assistant, which its sun
+// entity uses.
+func (c *Client) location() (lat, lon float64, err error) {
+	if viper.IsSet("location.latitude") && viper.IsSet("location.longitude") {
+		return viper.GetFloat64("location.latitude"), viper.GetFloat64("location.longitude"), nil
+	}
+	if !c.connected() {
+		return 0, 0, fmt.Errorf("location.latitude and location.longitude are required without the Home Assistant source")
+	}
+
+	var data struct {
+		Success bool `json:"success"`
+		Result  struct {
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+		} `json:"result"`
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := c.request(map[string]interface{}{"type": "get_config"}, &data); err != nil {
+		return 0, 0, err
+	}
+	if !data.Success {
+		return 0, 0, fmt.Errorf("api response error: %v", data.Error)
+	}
+	return data.Result.Latitude, data.Result.Longitude, nil
+}
+
+// printDaylight prints how much of each day's consumption was used in daylight and how much at
+// night, with the average day at the bottom. Daytime usage is what solar panels could cover
+// directly, without a battery.
+func (c *Client) printDaylight(results []Day) error {
+	lat, lon, err := c.location()
+	if err != nil {
+		return fmt.Errorf("getting the location: %w", err)
+	}
+	width, _ := c.slots()
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{i18n.T("Date"), i18n.T("Daylight kWh"), i18n.T("Night kWh"), i18n.T("Daylight %"), i18n.T("Sunrise"), i18n.T("Sunset")})
+	var totalLight, totalNight float64
+	for _, day := range results {
+		light, night := splitDaylight(day, width, lat, lon)
+		totalLight += light
+		totalNight += night
+		sun := sunTimes(day.Date, lat, lon)
+		table.Append([]string{
+			day.Date.Format("2006-01-02"),
+			fmt.Sprintf("%f", light),
+			fmt.Sprintf("%f", night),
+			fmt.Sprintf("%.1f", share(light, light+night)),
+			sun.Rise.Local().Format("15:04"),
+			sun.Set.Local().Format("15:04"),
+		})
+	}
+	n := math.Max(float64(len(results)), 1)
+	table.SetFooter([]string{
+		i18n.T("Average"),
+		fmt.Sprintf("%f", totalLight/n),
+		fmt.Sprintf("%f", totalNight/n),
+		fmt.Sprintf("%.1f", share(totalLight, totalLight+totalNight)),
+		"", "",
+	})
+	table.Render()
+	return nil
+}
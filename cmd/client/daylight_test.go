@@ -0,0 +1,91 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/poolski/powertracker/cmd/hatest"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestSunTimes(t *testing.T) {
+	within := func(t *testing.T, got time.Time, want string) {
+		t.Helper()
+		w, err := time.Parse(time.RFC3339, want)
+		assert.NilError(t, err)
+		assert.Assert(t, got.Sub(w).Abs() <= 3*time.Minute, "got %s, want %s", got.Format(time.RFC3339), want)
+	}
+
+	// London at midsummer and midwinter.
+	summer := sunTimes(time.Date(2023, 6, 21, 0, 0, 0, 0, time.UTC), 51.5074, -0.1278)
+	within(t, summer.Rise, "2023-06-21T03:43:00Z")
+	within(t, summer.Set, "2023-06-21T20:21:00Z")
+	winter := sunTimes(time.Date(2023, 12, 21, 0, 0, 0, 0, time.UTC), 51.5074, -0.1278)
+	within(t, winter.Rise, "2023-12-21T08:04:00Z")
+	within(t, winter.Set, "2023-12-21T15:53:00Z")
+
+	// Sydney, where the sun rises the UTC day before.
+	sydney := sunTimes(time.Date(2023, 6, 21, 0, 0, 0, 0, time.UTC), -33.8688, 151.2093)
+	within(t, sydney.Rise, "2023-06-20T20:59:00Z")
+	within(t, sydney.Set, "2023-06-21T06:54:00Z")
+
+	// Tromsø, where the sun neither sets in summer nor rises in winter.
+	midnightSun := sunTimes(time.Date(2023, 6, 21, 0, 0, 0, 0, time.UTC), 69.6492, 18.9553)
+	assert.Equal(t, midnightSun.Set.Sub(midnightSun.Rise), 24*time.Hour)
+	polarNight := sunTimes(time.Date(2023, 12, 21, 0, 0, 0, 0, time.UTC), 69.6492, 18.9553)
+	assert.Equal(t, polarNight.Set, polarNight.Rise)
+}
+
+func TestSplitDaylight(t *testing.T) {
+	values := make([]float64, hoursInADay)
+	for i := range values {
+		values[i] = 1
+	}
+
+	// On the equinox at 0° longitude, the sun is up for about 12 hours.
+	equinox := Day{Date: time.Date(2023, 3, 20, 0, 0, 0, 0, time.UTC), Values: values}
+	light, night := splitDaylight(equinox, time.Hour, 0, 0)
+	assert.Equal(t, light+night, 24.0)
+	assert.Assert(t, light > 12 && light < 12.2, "daylight %f", light)
+
+	// Sydney's daylight crosses midnight UTC, so it comes from the days either side too.
+	sydney := Day{Date: time.Date(2023, 6, 21, 0, 0, 0, 0, time.UTC), Values: values}
+	light, _ = splitDaylight(sydney, time.Hour, -33.8688, 151.2093)
+	assert.Assert(t, light > 9.8 && light < 10.1, "daylight %f", light)
+
+	polar := Day{Date: time.Date(2023, 12, 21, 0, 0, 0, 0, time.UTC), Values: values}
+	light, night = splitDaylight(polar, time.Hour, 69.6492, 18.9553)
+	assert.Equal(t, light, 0.0)
+	assert.Equal(t, night, 24.0)
+	light, _ = splitDaylight(Day{Date: time.Date(2023, 6, 21, 0, 0, 0, 0, time.UTC), Values: values}, time.Hour, 69.6492, 18.9553)
+	assert.Equal(t, light, 24.0)
+}
+
+func TestClient_Location(t *testing.T) {
+	defer viper.Set("location", nil)
+	c := New(Config{})
+	_, _, err := c.location()
+	assert.ErrorContains(t, err, "location.latitude and location.longitude are required")
+
+	// Without a location in the config, Home Assistant's is used.
+	s := hatest.NewServer("test_token")
+	defer s.Close()
+	s.Handle("get_config", func(msg map[string]interface{}) (interface{}, *hatest.Error) {
+		return map[string]interface{}{"latitude": 51.5, "longitude": -0.12}, nil
+	})
+	viper.Set("url", s.URL)
+	viper.Set("api_key", "test_token")
+	assert.NilError(t, c.Connect())
+	defer c.Close()
+	lat, lon, err := c.location()
+	assert.NilError(t, err)
+	assert.Equal(t, lat, 51.5)
+	assert.Equal(t, lon, -0.12)
+
+	viper.Set("location", map[string]interface{}{"latitude": 40.4, "longitude": -3.7})
+	lat, lon, err = c.location()
+	assert.NilError(t, err)
+	assert.Equal(t, lat, 40.4)
+	assert.Equal(t, lon, -3.7)
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/powertracker/cmd/i18n"
+	"github.com/spf13/viper"
+)
+
+// peak is the highest average power drawn over a demand window.
+type peak struct {
+	Start time.Time // Start is the beginning of the window the peak was drawn in.
+	Power float64   // Power is the average draw over the window, in kW.
+}
+
+// windowDemand returns the average power, in kW, drawn over each window of the given width.
+// Windows are aligned to the clock in the time zone, so 30-minute windows start on the hour and
+// half hour there, and start in it. Readings must be 5-minute consumption values, sorted by time.
+func windowDemand(readings []Reading, width time.Duration, loc *time.Location) []peak {
+	var windows []peak
+	for _, r := range readings {
+		day := midnight(r.Start.In(loc))
+		i, _ := slot(r.Start, day, width, int(25*time.Hour/width))
+		start, _ := slotStart(day, i, width)
+		if len(windows) == 0 || !windows[len(windows)-1].Start.Equal(start) {
+			windows = append(windows, peak{Start: start})
+		}
+		windows[len(windows)-1].Power += r.Value / width.Hours()
+	}
+	return windows
+}
+
+// maxDemand returns the highest window in each period, keyed by the period's label, e.g.
+// "2006-01-02" for days or "2006-01" for billing months.
+func maxDemand(windows []peak, label func(t time.Time) string) map[string]peak {
+	peaks := make(map[string]peak)
+	for _, w := range windows {
+		key := label(w.Start)
+		if p, ok := peaks[key]; !ok || w.Power > p.Power {
+			peaks[key] = w
+		}
+	}
+	return peaks
+}
+
+// printDemand prints the maximum demand for each day and each billing month, for tariffs that
+// charge for capacity as well as energy. Demand is the highest average power drawn over a window,
+// 30 minutes by default, worked out from 5-minute data.
+func (c *Client) printDemand() error {
+	sensorID := SensorID()
+	if sensorID == "" {
+		return fmt.Errorf("sensor_id is required")
+	}
+	minutes := viper.GetInt("demand.window")
+	if minutes == 0 {
+		minutes = 30
+	}
+	if minutes%5 != 0 || 60%minutes != 0 {
+		return fmt.Errorf("demand.window must be 5, 10, 15, 20, 30 or 60 minutes, got %d", minutes)
+	}
+	width := time.Duration(minutes) * time.Minute
+	billing, err := billingDay()
+	if err != nil {
+		return err
+	}
+	month := "Month"
+	if billing != 1 {
+		month = "Billing month"
+	}
+
+	end := c.until()
+	start := end.AddDate(0, 0, -c.days())
+	readings, err := c.fetch(sensorID, start, end, "5minute", nil)
+	if err != nil {
+		return err
+	}
+	if len(readings) == 0 {
+		return fmt.Errorf("no 5-minute data returned")
+	}
+	sort.Slice(readings, func(i, j int) bool { return readings[i].Start.Before(readings[j].Start) })
+	if covered := end.Sub(readings[0].Start); covered < end.Sub(start) {
+		c.logger().Warn().Msgf("only %.0f days of 5-minute data available - Home Assistant keeps short-term statistics for 10 days by default", covered.Hours()/24)
+	}
+
+	windows := windowDemand(readings, width, c.timeZone())
+	for _, period := range []struct {
+		name  string
+		label func(t time.Time) string
+	}{
+		{"Date", func(t time.Time) string { return t.Format("2006-01-02") }},
+		{month, func(t time.Time) string { return billingLabel(billingMonth(t, billing), billing) }},
+	} {
+		peaks := maxDemand(windows, period.label)
+		keys := make([]string, 0, len(peaks))
+		for k := range peaks {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{i18n.T(period.name), i18n.T("Max demand (%d min, kW)", minutes), i18n.T("At")})
+		for _, k := range keys {
+			table.Append([]string{k, fmt.Sprintf("%.2f", peaks[k].Power), peaks[k].Start.Format("2006-01-02 15:04")})
+		}
+		table.Render()
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestWindowDemand(t *testing.T) {
+	day := time.Date(2023, 9, 30, 23, 0, 0, 0, time.UTC)
+	var readings []Reading
+	for i := 0; i < 24; i++ {
+		readings = append(readings, Reading{Start: day.Add(time.Duration(i) * fiveMinutes), Value: 0.125})
+	}
+	// A 3kW load for 15 minutes, half an hour into the first day.
+	for i := 6; i < 9; i++ {
+		readings[i].Value = 0.25
+	}
+	// A smaller 2.25kW load for 15 minutes on the second day, in the next month.
+	for i := 12; i < 15; i++ {
+		readings[i].Value = 0.1875
+	}
+
+	windows := windowDemand(readings, 30*time.Minute, time.UTC)
+	assert.Equal(t, len(windows), 4)
+	assert.Equal(t, windows[0].Power, 1.5)
+	assert.Equal(t, windows[1].Power, 2.25)
+
+	days := maxDemand(windows, func(t time.Time) string { return t.Format("2006-01-02") })
+	assert.Assert(t, days["2023-09-30"].Start.Equal(day.Add(30*time.Minute)))
+	assert.Equal(t, days["2023-10-01"].Power, 1.875)
+
+	months := maxDemand(windows, func(t time.Time) string { return billingLabel(billingMonth(t, 1), 1) })
+	assert.Equal(t, len(months), 2)
+	assert.Equal(t, months["2023-09"].Power, windows[1].Power)
+
+	// A billing cycle starting on the 14th holds both days.
+	cycles := maxDemand(windows, func(t time.Time) string { return billingLabel(billingMonth(t, 14), 14) })
+	assert.Equal(t, len(cycles), 1)
+	assert.Equal(t, cycles["2023-09-14"].Power, windows[1].Power)
+}
+
+func TestWindowDemand_TimeZone(t *testing.T) {
+	// Hours in India start half way through hours in UTC.
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	assert.NilError(t, err)
+	day := time.Date(2023, 10, 1, 0, 0, 0, 0, kolkata)
+	var readings []Reading
+	for i := 0; i < 24; i++ {
+		readings = append(readings, Reading{Start: day.Add(time.Duration(i) * fiveMinutes).UTC(), Value: 0.125})
+	}
+
+	windows := windowDemand(readings, time.Hour, kolkata)
+	assert.Equal(t, len(windows), 2)
+	assert.Assert(t, windows[0].Start.Equal(day))
+	// The peaks are labelled with the date there, not in UTC, where it is still the day before.
+	days := maxDemand(windows, func(t time.Time) string { return t.Format("2006-01-02") })
+	assert.Equal(t, len(days), 1)
+	_, ok := days["2023-10-01"]
+	assert.Assert(t, ok)
+}
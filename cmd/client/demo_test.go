@@ -0,0 +1,44 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestDemo_Readings(t *testing.T) {
+	viper.Set("generation_sensor_id", "sensor.solar")
+	defer viper.Set("generation_sensor_id", "")
+
+	day := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	hourly, err := demo{}.Readings("sensor.energy", day, day.Add(24*time.Hour), "hour")
+	assert.NilError(t, err)
+	assert.Equal(t, len(hourly), hoursInADay)
+
+	// Hours are the sum of their 5-minute readings, and the same every time.
+	fine, err := demo{}.Readings("sensor.energy", day, day.Add(24*time.Hour), "5minute")
+	assert.NilError(t, err)
+	assert.Equal(t, len(fine), 12*hoursInADay)
+	var first float64
+	for _, r := range fine[:12] {
+		first += r.Value
+	}
+	assert.Assert(t, first-hourly[0].Value < 1e-9 && hourly[0].Value-first < 1e-9, "hour %f, 5-minute sum %f", hourly[0].Value, first)
+	again, err := demo{}.Readings("sensor.energy", day, day.Add(24*time.Hour), "hour")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, again, hourly)
+
+	// The evening is busier than the small hours.
+	assert.Assert(t, hourly[18].Value > hourly[3].Value)
+
+	// The solar array only generates in daylight.
+	solar, err := demo{}.Readings("sensor.solar", day, day.Add(24*time.Hour), "hour")
+	assert.NilError(t, err)
+	assert.Equal(t, solar[1].Value, 0.0)
+	assert.Assert(t, solar[12].Value > 0)
+
+	_, err = demo{}.Readings("sensor.energy", day, day.Add(24*time.Hour), "month")
+	assert.ErrorContains(t, err, "unsupported period \"month\"")
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// postEmoncms sends each hourly value to an emoncms instance using the input/bulk API.
+// Values are posted with their absolute timestamps, so emoncms feeds can be backfilled
+// with historical data. The node and input names default to "powertracker" and "kwh".
+func (c *Client) postEmoncms(results []Day) error {
+	baseURL := viper.GetString("emoncms.url")
+	if baseURL == "" {
+		return fmt.Errorf("emoncms.url is required")
+	}
+	apiKey := viper.GetString("emoncms.api_key")
+	if apiKey == "" {
+		return fmt.Errorf("emoncms.api_key is required")
+	}
+	node := viper.GetString("emoncms.node")
+	if node == "" {
+		node = "powertracker"
+	}
+	input := viper.GetString("emoncms.input")
+	if input == "" {
+		input = "kwh"
+	}
+
+	// Each entry is [timestamp, node, {input: value}]. Passing time=0 tells emoncms
+	// the timestamps are absolute rather than offsets from the time of the request.
+	var data []any
+	for _, day := range results {
+		for i, v := range day.Values {
+			start, ok := slotStart(day.Date, i, time.Hour)
+			if !ok {
+				continue
+			}
+			data = append(data, []any{start.Unix(), node, map[string]float64{input: v}})
+		}
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("encoding data: %w", err)
+	}
+
+	endpoint, err := url.JoinPath(baseURL, "input/bulk.json")
+	if err != nil {
+		return fmt.Errorf("parsing URL: %w", err)
+	}
+	form := url.Values{
+		"data":   {string(payload)},
+		"time":   {"0"},
+		"apikey": {apiKey},
+	}
+
+	resp, err := httpClient.PostForm(endpoint, form)
+	if err != nil {
+		return fmt.Errorf("posting data: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || strings.TrimSpace(string(body)) != "ok" {
+		return fmt.Errorf("unexpected response (%d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	c.logger().Info().Msgf("posted %d values to emoncms node %s", len(data), node)
+	return nil
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestClient_PostEmoncms(t *testing.T) {
+	var form map[string][]string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Path, "/emoncms/input/bulk.json", "unexpected path")
+		assert.NilError(t, r.ParseForm(), "parse form failed")
+		form = r.PostForm
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer s.Close()
+
+	viper.Set("emoncms.url", s.URL+"/emoncms")
+	viper.Set("emoncms.api_key", "test_key")
+	viper.Set("emoncms.node", "house")
+
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	client := New(Config{})
+	err := client.postEmoncms([]Day{{Date: day, Values: []float64{0.5, 1.25}}})
+
+	assert.NilError(t, err)
+	assert.Equal(t, form["apikey"][0], "test_key")
+	assert.Equal(t, form["time"][0], "0")
+	assert.Equal(t, form["data"][0], `[[1693526400,"house",{"kwh":0.5}],[1693530000,"house",{"kwh":1.25}]]`)
+}
+
+func TestClient_PostEmoncms_ErrorStates(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"success":false,"message":"Invalid API key"}`))
+	}))
+	defer s.Close()
+
+	tests := []struct {
+		name     string
+		url      string
+		apiKey   string
+		expected string
+	}{
+		{
+			name:     "Empty URL",
+			url:      "",
+			apiKey:   "test_key",
+			expected: "emoncms.url is required",
+		},
+		{
+			name:     "Empty API key",
+			url:      s.URL,
+			apiKey:   "",
+			expected: "emoncms.api_key is required",
+		},
+		{
+			name:     "Rejected",
+			url:      s.URL,
+			apiKey:   "test_key",
+			expected: "unexpected response (200): {\"success\":false,\"message\":\"Invalid API key\"}",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			viper.Set("emoncms.url", test.url)
+			viper.Set("emoncms.api_key", test.apiKey)
+
+			client := New(Config{})
+			err := client.postEmoncms([]Day{{Values: []float64{1}}})
+
+			assert.ErrorContains(t, err, test.expected)
+		})
+	}
+}
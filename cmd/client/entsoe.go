@@ -0,0 +1,124 @@
+package client
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var entsoeURL = "https://web-api.tp.entsoe.eu/api"
+
+// entsoe fetches day-ahead wholesale prices for a bidding zone from the ENTSO-E transparency
+// platform. Prices are published in EUR/MWh and converted to EUR/kWh.
+type entsoe struct {
+	token string
+	zone  string
+}
+
+type entsoeDocument struct {
+	TimeSeries []struct {
+		Period []struct {
+			TimeInterval struct {
+				Start string `xml:"start"`
+				End   string `xml:"end"`
+			} `xml:"timeInterval"`
+			Resolution string `xml:"resolution"`
+			Points     []struct {
+				Position int     `xml:"position"`
+				Price    float64 `xml:"price.amount"`
+			} `xml:"Point"`
+		} `xml:"Period"`
+	} `xml:"TimeSeries"`
+	// Reason is only set on acknowledgement documents, which are returned for errors.
+	Reason []struct {
+		Code string `xml:"code"`
+		Text string `xml:"text"`
+	} `xml:"Reason"`
+}
+
+var entsoeResolutions = map[string]time.Duration{
+	"PT15M": 15 * time.Minute,
+	"PT30M": 30 * time.Minute,
+	"PT60M": time.Hour,
+}
+
+func newEntsoe() (*entsoe, error) {
+	e := &entsoe{
+		token: viper.GetString("prices.entsoe.token"),
+		zone:  viper.GetString("prices.entsoe.zone"),
+	}
+	if e.token == "" {
+		return nil, fmt.Errorf("prices.entsoe.token is required")
+	}
+	if e.zone == "" {
+		return nil, fmt.Errorf("prices.entsoe.zone is required")
+	}
+	return e, nil
+}
+
+func (e *entsoe) Prices(start, end time.Time) ([]Price, error) {
+	params := url.Values{
+		"securityToken": {e.token},
+		"documentType":  {"A44"}, // price document
+		"in_Domain":     {e.zone},
+		"out_Domain":    {e.zone},
+		"periodStart":   {start.UTC().Format("200601021504")},
+		"periodEnd":     {end.UTC().Format("200601021504")},
+	}
+	resp, err := httpClient.Get(entsoeURL + "?" + params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("entsoe: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var doc entsoeDocument
+	if err := xml.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("entsoe: decoding response: %w", err)
+	}
+	if len(doc.Reason) > 0 && len(doc.TimeSeries) == 0 {
+		return nil, fmt.Errorf("entsoe: %s", doc.Reason[0].Text)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("entsoe: unexpected status: %s", resp.Status)
+	}
+
+	var prices []Price
+	for _, ts := range doc.TimeSeries {
+		for _, period := range ts.Period {
+			resolution, ok := entsoeResolutions[period.Resolution]
+			if !ok {
+				return nil, fmt.Errorf("entsoe: unsupported resolution %q", period.Resolution)
+			}
+			periodStart, err := time.Parse("2006-01-02T15:04Z", period.TimeInterval.Start)
+			if err != nil {
+				return nil, fmt.Errorf("entsoe: parsing period start: %w", err)
+			}
+			periodEnd, err := time.Parse("2006-01-02T15:04Z", period.TimeInterval.End)
+			if err != nil {
+				return nil, fmt.Errorf("entsoe: parsing period end: %w", err)
+			}
+
+			// Points may be omitted when the price doesn't change (curve type A03), so each
+			// point applies until the next one, or the end of the period.
+			for i, point := range period.Points {
+				p := Price{
+					Start: periodStart.Add(time.Duration(point.Position-1) * resolution),
+					End:   periodEnd,
+					Rate:  point.Price / 1000,
+				}
+				if i+1 < len(period.Points) {
+					p.End = periodStart.Add(time.Duration(period.Points[i+1].Position-1) * resolution)
+				}
+				prices = append(prices, p)
+			}
+		}
+	}
+	if len(prices) == 0 {
+		return nil, fmt.Errorf("entsoe: no prices returned for zone %s", e.zone)
+	}
+	return prices, nil
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+const entsoeResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<Publication_MarketDocument xmlns="urn:iec62325.351:tc57wg16:451-3:publicationdocument:7:0">
+  <TimeSeries>
+    <currency_Unit.name>EUR</currency_Unit.name>
+    <price_Measure_Unit.name>MWH</price_Measure_Unit.name>
+    <curveType>A03</curveType>
+    <Period>
+      <timeInterval>
+        <start>2023-08-31T22:00Z</start>
+        <end>2023-09-01T02:00Z</end>
+      </timeInterval>
+      <resolution>PT60M</resolution>
+      <Point><position>1</position><price.amount>100</price.amount></Point>
+      <Point><position>2</position><price.amount>80.5</price.amount></Point>
+      <Point><position>4</position><price.amount>120</price.amount></Point>
+    </Period>
+  </TimeSeries>
+</Publication_MarketDocument>`
+
+func TestEntsoe_Prices(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		assert.Equal(t, q.Get("securityToken"), "test_token", "unexpected token")
+		assert.Equal(t, q.Get("documentType"), "A44", "unexpected document type")
+		assert.Equal(t, q.Get("in_Domain"), "10YNL----------L", "unexpected zone")
+		assert.Equal(t, q.Get("periodStart"), "202308312200", "unexpected start")
+		_, _ = w.Write([]byte(entsoeResponse))
+	}))
+	defer s.Close()
+
+	entsoeURL = s.URL
+	viper.Set("prices.entsoe.token", "test_token")
+	viper.Set("prices.entsoe.zone", "10YNL----------L")
+
+	e, err := newEntsoe()
+	assert.NilError(t, err)
+
+	start := time.Date(2023, 8, 31, 22, 0, 0, 0, time.UTC)
+	prices, err := e.Prices(start, start.Add(4*time.Hour))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, prices, []Price{
+		{Start: start, End: start.Add(time.Hour), Rate: 0.1},
+		{Start: start.Add(time.Hour), End: start.Add(3 * time.Hour), Rate: 0.0805},
+		{Start: start.Add(3 * time.Hour), End: start.Add(4 * time.Hour), Rate: 0.12},
+	})
+}
+
+func TestEntsoe_Error(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`<Acknowledgement_MarketDocument><Reason><code>999</code><text>No matching data found</text></Reason></Acknowledgement_MarketDocument>`))
+	}))
+	defer s.Close()
+
+	entsoeURL = s.URL
+	viper.Set("prices.entsoe.token", "test_token")
+	viper.Set("prices.entsoe.zone", "10YNL----------L")
+
+	e, err := newEntsoe()
+	assert.NilError(t, err)
+
+	_, err = e.Prices(time.Now(), time.Now())
+	assert.ErrorContains(t, err, "entsoe: No matching data found")
+}
+
+func TestEntsoe_Timeout(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		_, _ = w.Write([]byte(entsoeResponse))
+	}))
+	defer s.Close()
+	defer func(timeout time.Duration) { httpClient.Timeout = timeout }(httpClient.Timeout)
+	httpClient.Timeout = 50 * time.Millisecond
+
+	entsoeURL = s.URL
+	viper.Set("prices.entsoe.token", "test_token")
+	viper.Set("prices.entsoe.zone", "10YNL----------L")
+
+	e, err := newEntsoe()
+	assert.NilError(t, err)
+
+	_, err = e.Prices(time.Now(), time.Now())
+	assert.ErrorContains(t, err, "Client.Timeout exceeded")
+}
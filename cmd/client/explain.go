@@ -0,0 +1,219 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/poolski/powertracker/cmd/i18n"
+	"github.com/spf13/viper"
+)
+
+// provenance records how the figures of a run were arrived at, for --explain.
+type provenance struct {
+	mu sync.Mutex
+	// Included are the days the figures were worked out from.
+	Included []time.Time
+	// Cached are the days answered from the cache rather than the source.
+	Cached map[time.Time]bool
+	// Padded counts the slots of each day without any readings, which count as zero.
+	Padded map[time.Time]int
+	// Excluded gives the reason each day in the range was left out.
+	Excluded map[time.Time]string
+	// Corrected are the values replaced because they couldn't be real consumption.
+	Corrected []adjustment
+	// Queries are the messages sent to Home Assistant, in order.
+	Queries []string
+}
+
+// note updates the provenance of the run, if --explain is on.
+func (c *Client) note(update func(p *provenance)) {
+	if !c.Config.Explain {
+		return
+	}
+	c.explainMu.Lock()
+	if c.provenance == nil {
+		c.provenance = &provenance{Cached: map[time.Time]bool{}, Padded: map[time.Time]int{}, Excluded: map[time.Time]string{}}
+	}
+	p := c.provenance
+	c.explainMu.Unlock()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	update(p)
+}
+
+// noteQuery records a message sent to Home Assistant, leaving out the ID, which only matters to
+// the connection.
+func (c *Client) noteQuery(msg map[string]interface{}) {
+	c.note(func(p *provenance) {
+		query := make(map[string]interface{}, len(msg))
+		for k, v := range msg {
+			if k != "id" {
+				query[k] = v
+			}
+		}
+		// Maps are marshalled with sorted keys, so the same query always reads the same.
+		data, _ := json.Marshal(query)
+		p.Queries = append(p.Queries, string(data))
+	})
+}
+
+// emptySlots returns how many of the n slots of the given width starting at start have no
+// readings at all.
+func emptySlots(readings []Reading, start time.Time, width time.Duration, n int) int {
+	seen := make([]bool, n)
+	for _, r := range readings {
+		if i, ok := slot(r.Start, start, width, n); ok {
+			seen[i] = true
+		}
+	}
+	var empty int
+	for _, s := range seen {
+		if !s {
+			empty++
+		}
+	}
+	return empty
+}
+
+// Explain writes how the figures of the run were worked out: the days included and left out, the
+// slots padded with zero or corrected, the time zone, and the queries sent to Home Assistant.
+func (c *Client) Explain(w io.Writer) {
+	c.explainMu.Lock()
+	p := c.provenance
+	c.explainMu.Unlock()
+	if p == nil {
+		fmt.Fprintln(w, i18n.T("Nothing to explain - no consumption was read."))
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	unit, period := i18n.T("hours"), i18n.T("hourly")
+	if c.Config.HalfHourly {
+		unit, period = i18n.T("half hours"), i18n.T("half-hourly")
+	} else if c.Config.Period == "5minute" {
+		unit, period = i18n.T("5-minute periods"), i18n.T("5-minute")
+	}
+	statType := viper.GetString("statistic_type")
+	if statType == "" {
+		statType = "change"
+	}
+	days := sortedDays(p.Included)
+
+	// The labels are translated, so the column after them is as wide as the longest needs.
+	labels := []string{"days:", "time zone:", "source:", "cache:", "padded:", "corrected:", "excluded:", "averages:", "queries:"}
+	width := 0
+	for _, label := range labels {
+		if n := utf8.RuneCountInString(i18n.T(label)); n > width {
+			width = n
+		}
+	}
+	line := func(label, text string) {
+		fmt.Fprintf(w, "  %-*s %s\n", width, i18n.T(label), text)
+	}
+
+	fmt.Fprintln(w, i18n.T("How these figures were worked out:"))
+	if len(days) > 0 {
+		line("days:", i18n.T("%d, from %s to %s", len(days), days[0].Format("2006-01-02"), days[len(days)-1].Format("2006-01-02")))
+	} else {
+		line("days:", i18n.T("none"))
+	}
+	line("time zone:", i18n.T("days run from midnight in %s; local times are shown in %s (%s)", c.timeZone(), time.Local, time.Now().Format("MST -07:00")))
+	line("source:", i18n.T("%s, %s, the %q statistic, %s", sourceName(), SensorID(), statType, period))
+	cached := 0
+	for _, day := range days {
+		if p.Cached[day] {
+			cached++
+		}
+	}
+	line("cache:", i18n.T("%d days from the cache, %d fetched", cached, len(days)-cached))
+
+	var padded []string
+	total := 0
+	for _, day := range days {
+		if n := p.Padded[day]; n > 0 {
+			padded = append(padded, fmt.Sprintf("%s (%d)", day.Format("2006-01-02"), n))
+			total += n
+		}
+	}
+	if total == 0 {
+		line("padded:", i18n.T("none - every one of the %s had readings", unit))
+	} else {
+		line("padded:", i18n.T("%d %s without readings count as zero: %s", total, unit, strings.Join(padded, ", ")))
+	}
+
+	if len(p.Corrected) == 0 {
+		line("corrected:", i18n.T("none"))
+	} else {
+		line("corrected:", i18n.T("%d impossible values replaced with the median of the same period on other days:", len(p.Corrected)))
+		for _, a := range p.Corrected {
+			fmt.Fprintf(w, "    %s: %f kWh -> %f kWh\n", a.Start.Format("2006-01-02 15:04"), a.Original, a.Value)
+		}
+	}
+
+	if len(p.Excluded) == 0 {
+		line("excluded:", i18n.T("none"))
+	} else {
+		fmt.Fprintf(w, "  %s\n", i18n.T("excluded:"))
+		excluded := make([]time.Time, 0, len(p.Excluded))
+		for day := range p.Excluded {
+			excluded = append(excluded, day)
+		}
+		for _, day := range sortedDays(excluded) {
+			fmt.Fprintf(w, "    %s: %s\n", day.Format("2006-01-02"), p.Excluded[day])
+		}
+	}
+	line("averages:", c.describeStats(unit))
+
+	if len(p.Queries) == 0 {
+		line("queries:", i18n.T("none sent to Home Assistant"))
+		return
+	}
+	fmt.Fprintf(w, "  %s\n", i18n.T("queries sent to Home Assistant:"))
+	for _, q := range p.Queries {
+		fmt.Fprintf(w, "    %s\n", q)
+	}
+}
+
+// describeStats says what the averages reported are: the statistics of each slot, or nothing when
+// only the total of each day, week or month is shown.
+func (c *Client) describeStats(unit string) string {
+	if c.totalsOnly() {
+		return i18n.T("none - --period %s only shows the total of each", c.Config.Period)
+	}
+	stats, err := parseStats(c.Config.Statistics)
+	if err != nil {
+		return err.Error()
+	}
+	// The text output only shows the first.
+	if c.Config.Output == "" || c.Config.Output == "text" {
+		stats = stats[:1]
+	}
+	described := make([]string, len(stats))
+	for i, s := range stats {
+		switch {
+		case s.isMean():
+			described[i] = i18n.T("the mean of each of the %s over the days included", unit)
+		case strings.HasPrefix(s.label, "P"):
+			described[i] = i18n.T("percentile %s of each of the %s over the days included", strconv.FormatFloat(s.q*100, 'f', -1, 64), unit)
+		default:
+			described[i] = i18n.T("the median of each of the %s over the days included", unit)
+		}
+	}
+	return strings.Join(described, "; ")
+}
+
+// sortedDays returns the days in order, oldest first.
+func sortedDays(days []time.Time) []time.Time {
+	sorted := append([]time.Time(nil), days...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+	return sorted
+}
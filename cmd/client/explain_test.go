@@ -0,0 +1,104 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/poolski/powertracker/cmd/i18n"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestEmptySlots(t *testing.T) {
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	readings := []Reading{
+		{Start: day.Add(-time.Hour), Value: 1},
+		{Start: day, Value: 1},
+		{Start: day.Add(90 * time.Minute), Value: 1},
+		{Start: day.Add(24 * time.Hour), Value: 1},
+	}
+	assert.Equal(t, emptySlots(readings, day, time.Hour, hoursInADay), hoursInADay-2)
+	assert.Equal(t, emptySlots(nil, day, time.Hour, hoursInADay), hoursInADay)
+}
+
+func TestClient_Explain(t *testing.T) {
+	viper.Set("sensor_id", "sensor.energy")
+
+	yesterday := time.Now().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	oldest := yesterday.Add(-24 * time.Hour)
+	var readings []Reading
+	for i := 0; i < 2*hoursInADay; i++ {
+		// Three hours of the oldest day are missing, and one of yesterday's is a spike.
+		if i >= 3 && i < 6 {
+			continue
+		}
+		value := 1.0
+		if i == hoursInADay+12 {
+			value = 500
+		}
+		readings = append(readings, Reading{Start: oldest.Add(time.Duration(i) * time.Hour), Value: value})
+	}
+
+	c := New(Config{Days: 2, Explain: true})
+	c.source = fakeSource{"sensor.energy": readings}
+	results, err := getResults(c)
+	assert.NilError(t, err)
+	c.fixSpikes(results)
+	c.note(func(p *provenance) {
+		for _, day := range results {
+			p.Included = append(p.Included, day.Date)
+		}
+	})
+	c.noteQuery(map[string]interface{}{"id": 7, "type": "recorder/statistics_during_period", "period": "hour"})
+
+	var buf bytes.Buffer
+	c.Explain(&buf)
+	out := buf.String()
+	assert.Assert(t, bytes.Contains(buf.Bytes(), []byte("days:      2, from "+oldest.Format("2006-01-02")+" to "+yesterday.Format("2006-01-02"))), out)
+	assert.Assert(t, bytes.Contains(buf.Bytes(), []byte("padded:    3 hours without readings count as zero: "+oldest.Format("2006-01-02")+" (3)")), out)
+	assert.Assert(t, bytes.Contains(buf.Bytes(), []byte("corrected: 1 impossible values")), out)
+	assert.Assert(t, bytes.Contains(buf.Bytes(), []byte(yesterday.Format("2006-01-02")+" 12:00: 500.000000 kWh")), out)
+	assert.Assert(t, bytes.Contains(buf.Bytes(), []byte(`{"period":"hour","type":"recorder/statistics_during_period"}`)), out)
+	assert.Assert(t, bytes.Contains(buf.Bytes(), []byte("excluded:  none")), out)
+}
+
+func TestClient_Explain_Off(t *testing.T) {
+	c := New(Config{})
+	c.noteQuery(map[string]interface{}{"type": "ping"})
+	var buf bytes.Buffer
+	c.Explain(&buf)
+	assert.Equal(t, buf.String(), "Nothing to explain - no consumption was read.\n")
+}
+
+func TestClient_Explain_Translated(t *testing.T) {
+	defer func() { _ = i18n.SetLanguage("en") }()
+	assert.NilError(t, i18n.SetLanguage("de"))
+
+	var buf bytes.Buffer
+	New(Config{}).Explain(&buf)
+	assert.Equal(t, buf.String(), "Nichts zu erklären - es wurde kein Verbrauch gelesen.\n")
+
+	viper.Set("sensor_id", "sensor.energy")
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	c := New(Config{Days: 1, Explain: true, TimeZone: "UTC"})
+	c.note(func(p *provenance) { p.Included = append(p.Included, day) })
+	buf.Reset()
+	c.Explain(&buf)
+	out := buf.String()
+	// The values line up after the longest of the translated labels.
+	assert.Assert(t, bytes.Contains(buf.Bytes(), []byte("\n  Tage:        1, vom 2023-09-01 bis 2023-09-01\n")), out)
+	assert.Assert(t, bytes.Contains(buf.Bytes(), []byte("\n  ausgelassen: keine\n")), out)
+	assert.Assert(t, bytes.Contains(buf.Bytes(), []byte("keine - alle Stunden hatten Messwerte")), out)
+}
+
+func TestClient_DescribeStats(t *testing.T) {
+	c := New(Config{Output: "table", Statistics: []string{"mean", "median", "p95"}})
+	assert.Equal(t, c.describeStats("hours"), "the mean of each of the hours over the days included; "+
+		"the median of each of the hours over the days included; percentile 95 of each of the hours over the days included")
+	// The text output only shows the first.
+	c = New(Config{Output: "text", Statistics: []string{"p90", "mean"}})
+	assert.Equal(t, c.describeStats("hours"), "percentile 90 of each of the hours over the days included")
+	c = New(Config{Period: "week"})
+	assert.Equal(t, c.describeStats("hours"), "none - --period week only shows the total of each")
+}
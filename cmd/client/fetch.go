@@ -0,0 +1,256 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// defaultChunkDays is the longest range fetched in a single request. Longer requests can
+	// time out in the recorder or produce websocket frames too large to handle.
+	defaultChunkDays = 30
+	// defaultRetryAttempts is how many times each chunk is tried before giving up. With the
+	// delays doubling from retryDelay, the last try is half a minute after the first, long enough
+	// for Home Assistant to restart.
+	defaultRetryAttempts = 5
+	// maxRetryDelay is the longest wait between tries, however many there are.
+	maxRetryDelay = time.Minute
+	// defaultConcurrentRequests is how many chunks are fetched from Home Assistant at once.
+	defaultConcurrentRequests = 4
+)
+
+// retryDelay is how long to wait before the first retry of a chunk, unless retry_delay is set.
+// It doubles for each retry.
+var retryDelay = 2 * time.Second
+
+// permanentError is an error that retrying won't fix, such as a sensor that doesn't exist.
+type permanentError struct{ error }
+
+func (e permanentError) Unwrap() error { return e.error }
+
+// permanent marks err as not worth retrying.
+func permanent(err error) error {
+	return permanentError{err}
+}
+
+// ErrInterrupted is returned when a fetch is cut short by Stop.
+var ErrInterrupted = errors.New("interrupted")
+
+// ErrIncomplete is returned, wrapping the reason, when a fetch fails part way through even after
+// retrying. The days fetched in full before it failed are returned with it, and are cached, so
+// the rest can be fetched with --resume once the source is back.
+var ErrIncomplete = errors.New("fetch incomplete")
+
+// partial reports whether err cut a fetch short, leaving the days fetched before it to report on.
+func partial(err error) bool {
+	return errors.Is(err, ErrInterrupted) || errors.Is(err, ErrIncomplete)
+}
+
+// Stop makes the client stop issuing requests once those in progress complete, so a long fetch
+// can be cut short without losing what it has already fetched. It may be called from another
+// goroutine, such as a signal handler, and more than once.
+func (c *Client) Stop() {
+	c.stopped()
+	c.stopOnce.Do(func() { close(c.stop) })
+}
+
+// Interrupted reports whether Stop has been called.
+func (c *Client) Interrupted() bool {
+	select {
+	case <-c.stopped():
+		return true
+	default:
+		return false
+	}
+}
+
+// StopWhen stops the client, as Stop does, once ctx is done, so a run can be given a deadline or
+// cancelled along with the work it is part of.
+func (c *Client) StopWhen(ctx context.Context) {
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.Stop()
+		case <-c.stopped():
+		}
+	}()
+}
+
+// withStop returns a copy of ctx that is also done once the client is stopped.
+func (c *Client) withStop(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-c.stopped():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// stopped returns a channel that is closed when Stop is called.
+func (c *Client) stopped() <-chan struct{} {
+	c.stopInit.Do(func() { c.stop = make(chan struct{}) })
+	return c.stop
+}
+
+// reconnecter is implemented by sources whose connection may need to be re-established after
+// a failed request, such as the websocket to Home Assistant.
+type reconnecter interface {
+	reconnect() error
+}
+
+// fetch reads the range from the source in chunks of up to chunk_days days, retrying each chunk
+// up to retry_attempts times before giving up, so a dropped connection or Home Assistant restarting
+// doesn't abort a long backfill. If done isn't nil, it is called with the readings of each chunk as
+// it completes. Once the client is stopped, it returns the readings of the chunks completed so far
+// along with ErrInterrupted.
+func (c *Client) fetch(id string, start, end time.Time, period string, done func(from, to time.Time, readings []Reading) error) ([]Reading, error) {
+	var readings []Reading
+	err := c.stream(id, start, end, period, func(from, to time.Time, r []Reading) error {
+		if done != nil {
+			if err := done(from, to, r); err != nil {
+				return err
+			}
+		}
+		readings = append(readings, r...)
+		return nil
+	})
+	if errors.Is(err, ErrInterrupted) {
+		return readings, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return readings, nil
+}
+
+// stream reads the range in chunks as fetch does, but only hands the readings of each chunk to
+// fn, without keeping them, so the memory it needs depends on chunk_days rather than on the length
+// of the range. Chunks start at the start of the range and are a whole number of days long, by the
+// calendar in its time zone, so they end at midnight there whether or not the clocks change.
+//
+// Up to concurrent_requests chunks are fetched at once, but fn is always called with them in order,
+// so a failed chunk leaves everything before it handed over and nothing after it.
+func (c *Client) stream(id string, start, end time.Time, period string, fn func(from, to time.Time, readings []Reading) error) error {
+	days := viper.GetInt("chunk_days")
+	if days <= 0 {
+		days = defaultChunkDays
+	}
+	type result struct {
+		from, to time.Time
+		readings []Reading
+		err      error
+	}
+	var inFlight []chan result
+	// Chunks still being fetched when the stream ends are waited for, so no requests outlive it.
+	defer func() {
+		for _, ch := range inFlight {
+			<-ch
+		}
+	}()
+
+	from := start
+	workers := c.workers()
+	for {
+		for len(inFlight) < workers && from.Before(end) && !c.Interrupted() {
+			to := from.AddDate(0, 0, days)
+			if to.After(end) {
+				to = end
+			}
+			ch := make(chan result, 1)
+			go func(from, to time.Time) {
+				r, err := c.fetchChunk(id, from, to, period)
+				ch <- result{from: from, to: to, readings: r, err: err}
+			}(from, to)
+			inFlight = append(inFlight, ch)
+			from = to
+		}
+		if len(inFlight) == 0 {
+			if from.Before(end) {
+				return ErrInterrupted
+			}
+			return nil
+		}
+
+		r := <-inFlight[0]
+		inFlight = inFlight[1:]
+		if r.err != nil {
+			return r.err
+		}
+		if err := fn(r.from, r.to, r.readings); err != nil {
+			return err
+		}
+	}
+}
+
+// workers returns how many chunks are fetched at once. Only Home Assistant answers several
+// requests at once over its one connection; other sources are asked for a chunk at a time. So are
+// recorded and replayed sessions, as the frames have to be replayed in the order they were sent.
+func (c *Client) workers() int {
+	if _, ok := c.source.(recorder); !ok || c.session != nil || c.replay != nil {
+		return 1
+	}
+	if n := viper.GetInt("concurrent_requests"); n > 0 {
+		return n
+	}
+	return defaultConcurrentRequests
+}
+
+// retryPolicy returns how many times to try each chunk, from retry_attempts, and how long to wait
+// before the first retry, from retry_delay.
+func retryPolicy() (attempts int, delay time.Duration) {
+	attempts = viper.GetInt("retry_attempts")
+	if attempts <= 0 {
+		attempts = defaultRetryAttempts
+	}
+	delay = viper.GetDuration("retry_delay")
+	if delay <= 0 {
+		delay = retryDelay
+	}
+	return attempts, delay
+}
+
+func (c *Client) fetchChunk(id string, start, end time.Time, period string) ([]Reading, error) {
+	attempts, delay := retryPolicy()
+	for attempt := 1; ; attempt++ {
+		began := time.Now()
+		readings, err := c.source.Readings(id, start, end, period)
+		c.count(func(s *Stats) {
+			s.Requests++
+			s.RequestTime += time.Since(began)
+			if attempt > 1 {
+				s.Retries++
+			}
+			if err == nil {
+				s.LastFetch = time.Now()
+			}
+		})
+		if err == nil || c.Config.Offline {
+			return readings, err
+		}
+		var p permanentError
+		if attempt == attempts || errors.As(err, &p) {
+			return nil, fmt.Errorf("fetching %s to %s: %w", start.Format("2006-01-02"), end.Format("2006-01-02"), err)
+		}
+		c.logger().Warn().Msgf("fetching %s to %s failed, retrying in %s: %v", start.Format("2006-01-02"), end.Format("2006-01-02"), delay, err)
+		select {
+		case <-time.After(delay):
+		case <-c.stopped():
+			return nil, ErrInterrupted
+		}
+		delay = time.Duration(math.Min(float64(2*delay), float64(maxRetryDelay)))
+
+		if r, ok := c.source.(reconnecter); ok {
+			if err := r.reconnect(); err != nil {
+				c.logger().Warn().Msgf("reconnecting: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,555 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/poolski/powertracker/cmd/cache"
+	"github.com/poolski/powertracker/cmd/hatest"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+// flakySource fails the first request for each range, and records the ranges requested.
+type flakySource struct {
+	fakeSource
+	failed   map[time.Time]bool
+	requests [][2]time.Time
+}
+
+func (f *flakySource) Readings(id string, start, end time.Time, period string) ([]Reading, error) {
+	f.requests = append(f.requests, [2]time.Time{start, end})
+	if !f.failed[start] {
+		f.failed[start] = true
+		return nil, fmt.Errorf("timeout")
+	}
+	return f.fakeSource.Readings(id, start, end, period)
+}
+
+func TestClient_Fetch(t *testing.T) {
+	retryDelay = 0
+	viper.Set("chunk_days", 2)
+	defer viper.Set("chunk_days", 0)
+
+	start := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	var readings []Reading
+	for i := 0; i < 5*hoursInADay; i++ {
+		readings = append(readings, Reading{Start: start.Add(time.Duration(i) * time.Hour), Value: 1})
+	}
+	source := &flakySource{fakeSource: fakeSource{"sensor.energy": readings}, failed: map[time.Time]bool{}}
+	c := New(Config{})
+	c.source = source
+
+	got, err := c.fetch("sensor.energy", start, start.Add(5*24*time.Hour), "hour", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, len(got), len(readings))
+	// Three chunks of up to two days, each failing once before succeeding.
+	assert.Equal(t, len(source.requests), 6)
+	assert.DeepEqual(t, source.requests[5], [2]time.Time{start.Add(4 * 24 * time.Hour), start.Add(5 * 24 * time.Hour)})
+	stats := c.Stats()
+	assert.Equal(t, stats.Requests, 6)
+	assert.Equal(t, stats.Retries, 3)
+}
+
+// failingSource fails every request.
+type failingSource struct{}
+
+func (failingSource) Readings(id string, start, end time.Time, period string) ([]Reading, error) {
+	return nil, fmt.Errorf("timeout")
+}
+
+func TestClient_Fetch_GivesUp(t *testing.T) {
+	retryDelay = 0
+	c := New(Config{})
+	c.source = failingSource{}
+
+	start := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	_, err := c.fetch("sensor.energy", start, start.Add(24*time.Hour), "hour", nil)
+	assert.ErrorContains(t, err, "fetching 2023-09-01 to 2023-09-02: timeout")
+}
+
+// badSensorSource fails every request with an error that retrying won't fix.
+type badSensorSource struct{ requests int }
+
+func (s *badSensorSource) Readings(id string, start, end time.Time, period string) ([]Reading, error) {
+	s.requests++
+	return nil, permanent(fmt.Errorf("no results returned"))
+}
+
+func TestClient_Fetch_RetryAttempts(t *testing.T) {
+	retryDelay = 0
+	viper.Set("retry_attempts", 7)
+	defer viper.Set("retry_attempts", 0)
+	c := New(Config{})
+	c.source = failingSource{}
+
+	start := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	_, err := c.fetch("sensor.energy", start, start.Add(24*time.Hour), "hour", nil)
+	assert.ErrorContains(t, err, "timeout")
+	assert.Equal(t, c.Stats().Requests, 7)
+	assert.Equal(t, c.Stats().Retries, 6)
+
+	// Errors that retrying won't fix are given up on straight away.
+	bad := &badSensorSource{}
+	c = New(Config{})
+	c.source = bad
+	_, err = c.fetch("sensor.energy", start, start.Add(24*time.Hour), "hour", nil)
+	assert.ErrorContains(t, err, "fetching 2023-09-01 to 2023-09-02: no results returned")
+	assert.Equal(t, bad.requests, 1)
+}
+
+func TestRetryPolicy(t *testing.T) {
+	defer func(d time.Duration) { retryDelay = d }(retryDelay)
+	retryDelay = 2 * time.Second
+	attempts, delay := retryPolicy()
+	assert.Equal(t, attempts, defaultRetryAttempts)
+	assert.Equal(t, delay, 2*time.Second)
+
+	viper.Set("retry_attempts", 10)
+	viper.Set("retry_delay", "5s")
+	defer viper.Set("retry_attempts", 0)
+	defer viper.Set("retry_delay", 0)
+	attempts, delay = retryPolicy()
+	assert.Equal(t, attempts, 10)
+	assert.Equal(t, delay, 5*time.Second)
+}
+
+// countingSource records the start of each range requested, and fails those starting at failAt.
+type countingSource struct {
+	fakeSource
+	failAt   time.Time
+	requests []time.Time
+}
+
+func (s *countingSource) Readings(id string, start, end time.Time, period string) ([]Reading, error) {
+	s.requests = append(s.requests, start)
+	if start.Equal(s.failAt) {
+		return nil, fmt.Errorf("connection lost")
+	}
+	return s.fakeSource.Readings(id, start, end, period)
+}
+
+func TestGetResults_Resume(t *testing.T) {
+	retryDelay = 0
+	viper.Set("chunk_days", 1)
+	defer viper.Set("chunk_days", 0)
+	viper.Set("sensor_id", "sensor.energy")
+
+	yesterday := time.Now().UTC().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	oldest := yesterday.Add(-48 * time.Hour)
+	var readings []Reading
+	for i := 0; i < 3*hoursInADay; i++ {
+		readings = append(readings, Reading{Start: oldest.Add(time.Duration(i) * time.Hour), Value: 1})
+	}
+	cfg := Config{Days: 3, Refresh: true, CacheFile: filepath.Join(t.TempDir(), "cache.db")}
+
+	// The fetch is interrupted after the oldest day...
+	c := New(cfg)
+	c.source = &countingSource{fakeSource: fakeSource{"sensor.energy": readings}, failAt: oldest.Add(24 * time.Hour)}
+	fetched, err := getResults(c)
+	assert.ErrorIs(t, err, ErrIncomplete)
+	assert.ErrorContains(t, err, "connection lost")
+	// The day fetched before the failure isn't thrown away.
+	assert.Equal(t, len(fetched), 1)
+	assert.Equal(t, fetched[0].Date, oldest)
+
+	// ...so resuming only fetches the days after it.
+	cfg.Resume = true
+	c = New(cfg)
+	source := &countingSource{fakeSource: fakeSource{"sensor.energy": readings}}
+	c.source = source
+	results, err := getResults(c)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, source.requests, []time.Time{oldest.Add(24 * time.Hour), yesterday})
+	assert.Equal(t, results[2].Values[0], 1.0)
+	assert.Equal(t, c.Stats().CacheHits, 1)
+	assert.Equal(t, c.Stats().CacheMisses, 2)
+
+	// Once it has completed, there is nothing left to resume.
+	c = New(cfg)
+	source = &countingSource{fakeSource: fakeSource{"sensor.energy": readings}}
+	c.source = source
+	_, err = getResults(c)
+	assert.NilError(t, err)
+	assert.Equal(t, len(source.requests), 3)
+}
+
+func TestGetResults_MergesRuns(t *testing.T) {
+	viper.Set("sensor_id", "sensor.energy")
+	yesterday := time.Now().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	oldest := yesterday.Add(-4 * 24 * time.Hour)
+	var readings []Reading
+	for i := 0; i < 5*hoursInADay; i++ {
+		readings = append(readings, Reading{Start: oldest.Add(time.Duration(i) * time.Hour), Value: 1})
+	}
+
+	// Every other day is cached, as imported data that is never fetched again...
+	path := filepath.Join(t.TempDir(), "cache.db")
+	store, err := cache.Open(path)
+	assert.NilError(t, err)
+	imported := make([]float64, hoursInADay)
+	for i := range imported {
+		imported[i] = 2
+	}
+	for _, day := range []time.Time{oldest.Add(24 * time.Hour), oldest.Add(3 * 24 * time.Hour)} {
+		assert.NilError(t, store.Put("sensor.energy", day, cache.Entry{Values: imported, Fetched: time.Now(), Source: "import:generic"}))
+	}
+	assert.NilError(t, store.Close())
+
+	// ...so the other three are fetched in a single request, leaving the cached days as they were.
+	c := New(Config{Days: 5, CacheFile: path})
+	source := &countingSource{fakeSource: fakeSource{"sensor.energy": readings}}
+	c.source = source
+	results, err := getResults(c)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, source.requests, []time.Time{oldest})
+	for i, want := range []float64{1, 2, 1, 2, 1} {
+		assert.Equal(t, results[i].Values[0], want, results[i].Date)
+	}
+	store, err = cache.Open(path)
+	assert.NilError(t, err)
+	defer store.Close()
+	entry, _, err := store.Get("sensor.energy", oldest.Add(24*time.Hour))
+	assert.NilError(t, err)
+	assert.Equal(t, entry.Source, "import:generic")
+}
+
+func TestClient_Runs(t *testing.T) {
+	viper.Set("chunk_days", 10)
+	defer viper.Set("chunk_days", 0)
+	yesterday := time.Date(2023, 9, 30, 0, 0, 0, 0, time.UTC)
+	results := make([]Day, 40)
+	for i := range results {
+		results[i] = Day{Date: yesterday.Add(-time.Duration(i) * 24 * time.Hour)}
+	}
+	c := New(Config{})
+
+	// Runs within a chunk of each other are merged, but a run longer than a chunk is left on its
+	// own, so its chunks are still fetched concurrently.
+	missing := []int{0, 1, 4, 8, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 30}
+	assert.DeepEqual(t, c.runs(missing, results), [][]int{
+		{0, 1, 4, 8},
+		{11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22},
+		{30},
+	})
+}
+
+// stoppingSource stops the client once it has answered a number of requests, as a signal would.
+type stoppingSource struct {
+	fakeSource
+	client *Client
+	after  int
+}
+
+func (s *stoppingSource) Readings(id string, start, end time.Time, period string) ([]Reading, error) {
+	if s.after--; s.after == 0 {
+		s.client.Stop()
+	}
+	return s.fakeSource.Readings(id, start, end, period)
+}
+
+func TestGetResults_Interrupted(t *testing.T) {
+	viper.Set("chunk_days", 1)
+	defer viper.Set("chunk_days", 0)
+	viper.Set("sensor_id", "sensor.energy")
+
+	yesterday := time.Now().UTC().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	oldest := yesterday.Add(-48 * time.Hour)
+	var readings []Reading
+	for i := 0; i < 3*hoursInADay; i++ {
+		readings = append(readings, Reading{Start: oldest.Add(time.Duration(i) * time.Hour), Value: 1})
+	}
+	cfg := Config{Days: 3, CacheFile: filepath.Join(t.TempDir(), "cache.db")}
+
+	// Stopping after the first chunk keeps the oldest day, both in the results and the cache...
+	c := New(cfg)
+	c.source = &stoppingSource{fakeSource: fakeSource{"sensor.energy": readings}, client: c, after: 1}
+	results, err := getResults(c)
+	assert.ErrorIs(t, err, ErrInterrupted)
+	assert.Equal(t, len(results), 1)
+	assert.Equal(t, results[0].Date, oldest)
+	assert.Equal(t, c.Stats().Requests, 1)
+
+	// ...so resuming only fetches the rest.
+	cfg.Resume = true
+	c = New(cfg)
+	source := &countingSource{fakeSource: fakeSource{"sensor.energy": readings}}
+	c.source = source
+	_, err = getResults(c)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, source.requests, []time.Time{oldest.Add(24 * time.Hour), yesterday})
+}
+
+func TestClient_ComputePowerStats_Incomplete(t *testing.T) {
+	retryDelay = 0
+	viper.Set("chunk_days", 1)
+	defer viper.Set("chunk_days", 0)
+	viper.Set("sensor_id", "sensor.energy")
+
+	yesterday := time.Now().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	oldest := yesterday.Add(-48 * time.Hour)
+	var readings []Reading
+	for i := 0; i < 3*hoursInADay; i++ {
+		readings = append(readings, Reading{Start: oldest.Add(time.Duration(i) * time.Hour), Value: 1})
+	}
+	path := filepath.Join(t.TempDir(), "results.csv")
+	c := New(Config{Days: 3, Output: "csv", FilePath: path})
+	c.source = &countingSource{fakeSource: fakeSource{"sensor.energy": readings}, failAt: yesterday}
+	c.ComputePowerStats()
+
+	// The two days fetched before the failure are still reported on.
+	b, err := os.ReadFile(path)
+	assert.NilError(t, err)
+	rows := strings.Split(strings.TrimSpace(string(b)), "\n")
+	assert.Equal(t, len(rows), 4)
+	assert.Assert(t, strings.HasPrefix(rows[1], oldest.Add(24*time.Hour).Format("2006-01-02")))
+	assert.Assert(t, strings.HasPrefix(rows[2], oldest.Format("2006-01-02")))
+}
+
+func TestClient_Fetch_StopsRetrying(t *testing.T) {
+	defer func(d time.Duration) { retryDelay = d }(retryDelay)
+	retryDelay = time.Hour
+	c := New(Config{})
+	c.source = failingSource{}
+	go c.Stop()
+
+	start := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	_, err := c.fetch("sensor.energy", start, start.Add(24*time.Hour), "hour", nil)
+	assert.ErrorIs(t, err, ErrInterrupted)
+}
+
+func TestClient_Stream(t *testing.T) {
+	viper.Set("chunk_days", 2)
+	defer viper.Set("chunk_days", 0)
+
+	start := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	var readings []Reading
+	for i := 0; i < 5*hoursInADay; i++ {
+		readings = append(readings, Reading{Start: start.Add(time.Duration(i) * time.Hour), Value: 1})
+	}
+	c := New(Config{})
+	c.source = fakeSource{"sensor.energy": readings}
+
+	// Each chunk is handed over on its own, covering whole days.
+	var sizes []int
+	err := c.stream("sensor.energy", start, start.Add(5*24*time.Hour), "hour", func(from, to time.Time, r []Reading) error {
+		assert.Equal(t, from.Sub(start)%(24*time.Hour), time.Duration(0))
+		sizes = append(sizes, len(r))
+		return nil
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, sizes, []int{48, 48, 24})
+}
+
+func TestGetResults_HomeAssistant(t *testing.T) {
+	retryDelay = 0
+	s := hatest.NewServer("test_token")
+	defer s.Close()
+	viper.Set("url", s.URL)
+	viper.Set("api_key", "test_token")
+	viper.Set("sensor_id", "sensor.energy")
+
+	yesterday := time.Now().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	values := make([]float64, 2*hoursInADay)
+	for i := range values {
+		values[i] = 0.5
+	}
+	s.SetStatistics("sensor.energy", hatest.Hourly(yesterday.Add(-24*time.Hour), values...))
+	// The first request fails, and is retried. With the time zone given, that is the statistics
+	// request rather than get_config.
+	s.FailNext(1, "home_assistant_error", "Database is locked")
+
+	c := New(Config{Days: 2, TimeZone: "UTC"})
+	assert.NilError(t, c.Connect())
+	defer c.Close()
+	results, err := getResults(c)
+	assert.NilError(t, err)
+	assert.Equal(t, len(results), 2)
+	for _, day := range results {
+		assert.Equal(t, len(day.Values), hoursInADay)
+		assert.Equal(t, day.Values[0], 0.5)
+	}
+	assert.Equal(t, c.Stats().Retries, 1)
+	assert.Equal(t, s.Received()[0]["type"], "recorder/statistics_during_period")
+}
+
+func TestGetResults_Concurrent(t *testing.T) {
+	s := hatest.NewServer("test_token")
+	defer s.Close()
+	viper.Set("url", s.URL)
+	viper.Set("api_key", "test_token")
+	viper.Set("sensor_id", "sensor.energy")
+	viper.Set("chunk_days", 1)
+	defer viper.Set("chunk_days", 0)
+	viper.Set("concurrent_requests", 4)
+	defer viper.Set("concurrent_requests", 0)
+
+	// Requests are held until four are in flight at once, and then answered in whatever order
+	// they finish, with each hour's change being its day of the month.
+	var mu sync.Mutex
+	inFlight, most := 0, 0
+	release := make(chan struct{})
+	var once sync.Once
+	s.Handle("recorder/statistics_during_period", func(msg map[string]interface{}) (interface{}, *hatest.Error) {
+		mu.Lock()
+		inFlight++
+		if inFlight > most {
+			most = inFlight
+		}
+		if inFlight == 4 {
+			once.Do(func() { close(release) })
+		}
+		mu.Unlock()
+		select {
+		case <-release:
+		case <-time.After(5 * time.Second):
+		}
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		start, _ := time.Parse(time.RFC3339, msg["start_time"].(string))
+		end, _ := time.Parse(time.RFC3339, msg["end_time"].(string))
+		var stats []map[string]interface{}
+		for hour := start; hour.Before(end); hour = hour.Add(time.Hour) {
+			stats = append(stats, map[string]interface{}{"start": hour.UnixMilli(), "change": float64(hour.Day())})
+		}
+		return map[string]interface{}{"sensor.energy": stats}, nil
+	})
+
+	c := New(Config{Days: 8, TimeZone: "UTC"})
+	assert.NilError(t, c.Connect())
+	defer c.Close()
+	results, err := getResults(c)
+	assert.NilError(t, err)
+	assert.Equal(t, most, 4)
+	assert.Equal(t, len(results), 8)
+	for _, day := range results {
+		assert.Equal(t, day.Values[0], float64(day.Date.Day()), day.Date)
+	}
+	assert.Equal(t, len(s.Received()), 8)
+}
+
+func TestGetResults_Concurrent_Reconnects(t *testing.T) {
+	retryDelay = 0
+	s := hatest.NewServer("test_token")
+	defer s.Close()
+	viper.Set("url", s.URL)
+	viper.Set("api_key", "test_token")
+	viper.Set("sensor_id", "sensor.energy")
+	viper.Set("chunk_days", 1)
+	defer viper.Set("chunk_days", 0)
+
+	yesterday := time.Now().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	values := make([]float64, 8*hoursInADay)
+	for i := range values {
+		values[i] = 0.5
+	}
+	s.SetStatistics("sensor.energy", hatest.Hourly(yesterday.Add(-7*24*time.Hour), values...))
+	// The connection drops with several requests in flight, which are all retried on one new
+	// connection.
+	s.DropNext(1)
+
+	c := New(Config{Days: 8})
+	assert.NilError(t, c.Connect())
+	defer c.Close()
+	results, err := getResults(c)
+	assert.NilError(t, err)
+	assert.Equal(t, len(results), 8)
+	for _, day := range results {
+		assert.Equal(t, day.Values[0], 0.5)
+	}
+}
+
+func TestGetResults_RequestTimeout(t *testing.T) {
+	retryDelay = 0
+	s := hatest.NewServer("test_token")
+	defer s.Close()
+	viper.Set("url", s.URL)
+	viper.Set("api_key", "test_token")
+	viper.Set("sensor_id", "sensor.energy")
+	viper.Set("request_timeout", 100*time.Millisecond)
+	defer viper.Set("request_timeout", 0)
+
+	yesterday := time.Now().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	values := make([]float64, hoursInADay)
+	for i := range values {
+		values[i] = 0.5
+	}
+	s.SetStatistics("sensor.energy", hatest.Hourly(yesterday, values...))
+	// The first request is never answered, as happens when Home Assistant hangs.
+	s.HangNext(1)
+
+	c := New(Config{Days: 1})
+	assert.NilError(t, c.Connect())
+	defer c.Close()
+	results, err := getResults(c)
+	assert.NilError(t, err)
+	assert.Equal(t, len(results), 1)
+	assert.Equal(t, c.Stats().Retries, 1)
+}
+
+func TestGetResults_SeveralSensors(t *testing.T) {
+	s := hatest.NewServer("test_token")
+	defer s.Close()
+	viper.Set("url", s.URL)
+	viper.Set("api_key", "test_token")
+	viper.Set("sensor_id", []string{"sensor.house", "sensor.garage"})
+	defer viper.Set("sensor_id", "sensor.energy")
+
+	yesterday := time.Now().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	house, garage := make([]float64, hoursInADay), make([]float64, hoursInADay)
+	for i := range house {
+		house[i], garage[i] = 0.5, 0.25
+	}
+	s.SetStatistics("sensor.house", hatest.Hourly(yesterday, house...))
+	s.SetStatistics("sensor.garage", hatest.Hourly(yesterday, garage...))
+
+	c := New(Config{Days: 1, TimeZone: "UTC"})
+	assert.NilError(t, c.Connect())
+	defer c.Close()
+	results, err := getResults(c)
+	assert.NilError(t, err)
+	assert.Equal(t, len(results), 1)
+	for _, v := range results[0].Values {
+		assert.Equal(t, v, 0.75)
+	}
+
+	// Both sensors are fetched in the one request.
+	received := s.Received()
+	assert.Equal(t, len(received), 1)
+	assert.DeepEqual(t, received[0]["statistic_ids"], []interface{}{"sensor.house", "sensor.garage"})
+}
+
+func TestGetResults_DateRange(t *testing.T) {
+	viper.Set("sensor_id", "sensor.energy")
+	start := time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	var readings []Reading
+	for i := 0; i < 10*hoursInADay; i++ {
+		readings = append(readings, Reading{Start: start.Add(time.Duration(i) * time.Hour), Value: float64(i / hoursInADay)})
+	}
+
+	// Start and End pick out the days between them, most recent first as usual...
+	c := New(Config{Days: 30, Start: start.Add(2 * 24 * time.Hour), End: start.Add(4 * 24 * time.Hour)})
+	c.source = fakeSource{"sensor.energy": readings}
+	assert.Equal(t, c.days(), 3)
+	results, err := getResults(c)
+	assert.NilError(t, err)
+	assert.Equal(t, len(results), 3)
+	assert.Equal(t, results[0].Date, start.Add(4*24*time.Hour))
+	assert.Equal(t, results[0].Values[0], 4.0)
+	assert.Equal(t, results[2].Values[0], 2.0)
+
+	// ...and End alone moves the days back from yesterday.
+	c = New(Config{Days: 2, End: start.Add(24 * time.Hour)})
+	c.source = fakeSource{"sensor.energy": readings}
+	results, err = getResults(c)
+	assert.NilError(t, err)
+	assert.Equal(t, len(results), 2)
+	assert.Equal(t, results[1].Date, start)
+}
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/powertracker/cmd/cache"
+	"github.com/poolski/powertracker/cmd/i18n"
+)
+
+// solarForecastID is the cache ID solar forecasts are kept under.
+const solarForecastID = "forecast:solar"
+
+// solarForecast returns the hourly solar generation forecast, in kWh, summed over every forecast
+// provider configured in the Energy dashboard, such as Forecast.Solar or Solcast, using the
+// energy/solar_forecast websocket API.
+func (c *Client) solarForecast() ([]Reading, error) {
+	if !c.connected() {
+		return nil, fmt.Errorf("the solar forecast requires the Home Assistant source")
+	}
+
+	var data struct {
+		Success bool `json:"success"`
+		Result  map[string]struct {
+			WhHours map[string]float64 `json:"wh_hours"`
+		} `json:"result"`
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := c.request(map[string]interface{}{"type": "energy/solar_forecast"}, &data); err != nil {
+		return nil, err
+	}
+	if !data.Success {
+		return nil, fmt.Errorf("api response error: %v", data.Error)
+	}
+	if len(data.Result) == 0 {
+		return nil, fmt.Errorf("no solar forecast is configured in the Energy dashboard")
+	}
+
+	var readings []Reading
+	for _, entry := range data.Result {
+		for key, wh := range entry.WhHours {
+			t, err := parsePeriodStart(key)
+			if err != nil {
+				return nil, err
+			}
+			readings = append(readings, Reading{Start: t, Value: wh / 1000})
+		}
+	}
+	return readings, nil
+}
+
+// keepForecasts stores the forecast for each day after today in the cache, replacing any earlier
+// forecast for it. Forecasts only cover the next day or two, so they have to be kept until the day
+// is over to be compared with what was actually generated. The last one made before the day began
+// is the one that's kept.
+func keepForecasts(store *cache.Store, readings []Reading, now time.Time) error {
+	days := map[time.Time]bool{}
+	for _, r := range readings {
+		if day := midnight(r.Start.In(now.Location())); day.After(now) {
+			days[day] = true
+		}
+	}
+	for day := range days {
+		entry := cache.Entry{Values: bucket(readings, day, time.Hour, hoursInADay), Fetched: now, Source: "energy/solar_forecast"}
+		if err := store.Put(solarForecastID, day, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printSolarForecast compares the kept solar forecasts with the generation actually recorded on
+// each day, to show how accurate the forecast configuration is. It also keeps the latest forecast
+// for future days, so running it daily builds up the history it reports on.
+func (c *Client) printSolarForecast(results []Day) error {
+	id := generationSensorID()
+	if id == "" {
+		return fmt.Errorf("generation_sensor_id is required")
+	}
+	// The generation is cached too, so it is got before the cache is opened here.
+	actual, err := c.statistic(id, results)
+	if err != nil {
+		return fmt.Errorf("getting generation: %w", err)
+	}
+
+	// Forecasts are kept for the days that the results are divided into.
+	now := c.now().In(c.timeZone())
+	store, err := c.openCache()
+	if err != nil {
+		return err
+	}
+	if store == nil {
+		return fmt.Errorf("the local cache is needed to keep forecasts")
+	}
+	defer store.Close()
+
+	forecast, err := c.solarForecast()
+	if err != nil {
+		c.logger().Warn().Msgf("getting the solar forecast: %v", err)
+	} else if err := keepForecasts(store, forecast, now); err != nil {
+		return fmt.Errorf("keeping forecasts: %w", err)
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{i18n.T("Date"), i18n.T("Forecast kWh"), i18n.T("Actual kWh"), i18n.T("Error %")})
+	var compared int
+	var totalForecast, totalActual, absError float64
+	for _, day := range results {
+		generated := sum(bucket(actual, day.Date, time.Hour, hoursInADay))
+		entry, found, err := store.Get(solarForecastID, day.Date)
+		if err != nil {
+			return err
+		}
+		if !found {
+			table.Append([]string{day.Date.Format("2006-01-02"), "", fmt.Sprintf("%f", generated), ""})
+			continue
+		}
+		predicted := sum(entry.Values)
+		errorPct := ""
+		if generated > 0 {
+			errorPct = fmt.Sprintf("%+.0f%%", (predicted-generated)/generated*100)
+			compared++
+			totalForecast += predicted
+			totalActual += generated
+			absError += math.Abs(predicted - generated)
+		}
+		table.Append([]string{day.Date.Format("2006-01-02"), fmt.Sprintf("%f", predicted), fmt.Sprintf("%f", generated), errorPct})
+	}
+	table.Render()
+
+	if compared == 0 {
+		fmt.Println(i18n.T("No forecasts have been kept for these days yet. Run -o solar daily to build them up."))
+		return nil
+	}
+	summary := "Over %d days, the forecast was out by %.0f%% a day on average, and %.0f%% too high overall."
+	if totalForecast < totalActual {
+		summary = "Over %d days, the forecast was out by %.0f%% a day on average, and %.0f%% too low overall."
+	}
+	fmt.Println(i18n.T(summary, compared, absError/totalActual*100, math.Abs(totalForecast-totalActual)/totalActual*100))
+	return nil
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/poolski/powertracker/cmd/cache"
+	"gotest.tools/v3/assert"
+)
+
+func TestClient_SolarForecast(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, _ := upgrader.Upgrade(w, r, nil)
+		defer conn.Close()
+
+		var msg map[string]interface{}
+		assert.NilError(t, conn.ReadJSON(&msg), "read forecast request failed")
+		assert.Equal(t, msg["type"], "energy/solar_forecast")
+
+		assert.NilError(t, conn.WriteJSON(map[string]interface{}{
+			"id":      msg["id"],
+			"type":    "result",
+			"success": true,
+			"result": map[string]interface{}{
+				"entry_1": map[string]interface{}{"wh_hours": map[string]float64{"2023-09-02T12:00:00+00:00": 1500}},
+				"entry_2": map[string]interface{}{"wh_hours": map[string]float64{"2023-09-02T12:00:00+00:00": 500}},
+			},
+		}), "write forecast response failed")
+	}))
+	defer s.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+	assert.NilError(t, err)
+	client := &Client{Conn: conn}
+
+	readings, err := client.solarForecast()
+	assert.NilError(t, err)
+	day := time.Date(2023, 9, 2, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, bucket(readings, day, time.Hour, hoursInADay)[12], 2.0)
+}
+
+func TestKeepForecasts(t *testing.T) {
+	store, err := cache.Open(filepath.Join(t.TempDir(), "cache.db"))
+	assert.NilError(t, err)
+	defer store.Close()
+
+	today := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	tomorrow := today.Add(24 * time.Hour)
+	now := today.Add(18 * time.Hour)
+	readings := []Reading{
+		{Start: today.Add(12 * time.Hour), Value: 2},
+		{Start: tomorrow.Add(12 * time.Hour), Value: 3},
+	}
+	assert.NilError(t, keepForecasts(store, readings, now))
+
+	// Only tomorrow's forecast is kept, as today has already begun.
+	_, found, err := store.Get(solarForecastID, today)
+	assert.NilError(t, err)
+	assert.Assert(t, !found, "today's forecast was kept")
+	entry, found, err := store.Get(solarForecastID, tomorrow)
+	assert.NilError(t, err)
+	assert.Assert(t, found, "tomorrow's forecast wasn't kept")
+	assert.Equal(t, entry.Values[12], 3.0)
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/powertracker/cmd/i18n"
+	"github.com/spf13/viper"
+)
+
+// fossilConsumption returns the part of a statistic's consumption in each period that came from
+// fossil fuels, using the energy/fossil_energy_consumption websocket API. The fossil share comes
+// from a grid fossil fuel percentage statistic, such as the one provided by the CO2 Signal or
+// Electricity Maps integration, just as on the Energy dashboard.
+func (c *Client) fossilConsumption(id, co2ID string, start, end time.Time, period string) ([]Reading, error) {
+	if !c.connected() {
+		return nil, fmt.Errorf("the fossil share requires the Home Assistant source")
+	}
+
+	msg := map[string]interface{}{
+		"type":                 "energy/fossil_energy_consumption",
+		"start_time":           start.UTC().Format("2006-01-02T15:04:05.000Z"),
+		"end_time":             end.UTC().Format("2006-01-02T15:04:05.000Z"),
+		"energy_statistic_ids": strings.Split(id, ","),
+		"co2_statistic_id":     co2ID,
+		"period":               period,
+	}
+
+	// The result maps the start of each period to the fossil energy consumed in it.
+	var data struct {
+		Success bool               `json:"success"`
+		Result  map[string]float64 `json:"result"`
+		Error   struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := c.request(msg, &data); err != nil {
+		return nil, err
+	}
+	if !data.Success {
+		return nil, fmt.Errorf("api response error: %v", data.Error)
+	}
+
+	readings := make([]Reading, 0, len(data.Result))
+	for key, value := range data.Result {
+		t, err := parsePeriodStart(key)
+		if err != nil {
+			return nil, err
+		}
+		readings = append(readings, Reading{Start: t, Value: value})
+	}
+	sort.Slice(readings, func(i, j int) bool { return readings[i].Start.Before(readings[j].Start) })
+	return readings, nil
+}
+
+// parsePeriodStart parses the start of a period used as a key in the results of the energy APIs,
+// which is a timestamp string or, in some versions of Home Assistant, milliseconds since the epoch.
+func parsePeriodStart(key string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, key); err == nil {
+		return t, nil
+	}
+	ms, err := strconv.ParseFloat(key, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected period start %q", key)
+	}
+	return time.UnixMilli(int64(ms)), nil
+}
+
+// share returns part as a percentage of total, or 0 if total is 0.
+func share(part, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return part / total * 100
+}
+
+// printFossil prints how much of each day's consumption came from fossil fuels, followed by the
+// average fossil share in each hour of the day, which shows when the grid is cleanest.
+func (c *Client) printFossil(results []Day) error {
+	co2ID := viper.GetString("fossil_sensor_id")
+	if co2ID == "" {
+		return fmt.Errorf("fossil_sensor_id is required")
+	}
+	start, end := span(results)
+	readings, err := c.fossilConsumption(SensorID(), co2ID, start, end, "hour")
+	if err != nil {
+		return fmt.Errorf("getting fossil energy consumption: %w", err)
+	}
+
+	days := tablewriter.NewWriter(os.Stdout)
+	days.SetHeader([]string{i18n.T("Date"), "kWh", i18n.T("Fossil kWh"), i18n.T("Fossil %")})
+	var total, totalFossil float64
+	var hourly, hourlyFossil [hoursInADay]float64
+	for _, day := range results {
+		fossil := bucket(readings, day.Date, time.Hour, hoursInADay)
+		usage, fossilUsage := sum(day.Values), sum(fossil)
+		for h := range fossil {
+			hourly[h] += day.Values[h]
+			hourlyFossil[h] += fossil[h]
+		}
+		total += usage
+		totalFossil += fossilUsage
+		days.Append([]string{
+			day.Date.Format("2006-01-02"),
+			fmt.Sprintf("%f", usage),
+			fmt.Sprintf("%f", fossilUsage),
+			fmt.Sprintf("%.1f", share(fossilUsage, usage)),
+		})
+	}
+	days.SetFooter([]string{i18n.T("Total"), fmt.Sprintf("%f", total), fmt.Sprintf("%f", totalFossil), fmt.Sprintf("%.1f", share(totalFossil, total))})
+	days.Render()
+
+	hours := tablewriter.NewWriter(os.Stdout)
+	hours.SetHeader([]string{i18n.T("Hour"), i18n.T("Fossil %")})
+	for h := range hourly {
+		hours.Append([]string{strconv.Itoa(h), fmt.Sprintf("%.1f", share(hourlyFossil[h], hourly[h]))})
+	}
+	hours.Render()
+	return nil
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"gotest.tools/v3/assert"
+)
+
+func TestClient_FossilConsumption(t *testing.T) {
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, _ := upgrader.Upgrade(w, r, nil)
+		defer conn.Close()
+
+		var msg map[string]interface{}
+		assert.NilError(t, conn.ReadJSON(&msg), "read fossil request failed")
+		assert.Equal(t, msg["type"], "energy/fossil_energy_consumption")
+		assert.DeepEqual(t, msg["energy_statistic_ids"], []interface{}{"sensor.energy"})
+		assert.Equal(t, msg["co2_statistic_id"], "sensor.fossil_percentage")
+		assert.Equal(t, msg["period"], "hour")
+
+		assert.NilError(t, conn.WriteJSON(map[string]interface{}{
+			"id":      msg["id"],
+			"type":    "result",
+			"success": true,
+			"result": map[string]interface{}{
+				"2023-09-01T01:00:00+00:00": 0.25,
+				"2023-09-01T00:00:00+00:00": 0.5,
+			},
+		}), "write fossil response failed")
+	}))
+	defer s.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+	assert.NilError(t, err)
+	client := &Client{Conn: conn}
+
+	readings, err := client.fossilConsumption("sensor.energy", "sensor.fossil_percentage", day, day.Add(24*time.Hour), "hour")
+	assert.NilError(t, err)
+	assert.Equal(t, len(readings), 2)
+	assert.Assert(t, readings[0].Start.Equal(day))
+	assert.Equal(t, readings[0].Value, 0.5)
+	assert.Assert(t, readings[1].Start.Equal(day.Add(time.Hour)))
+}
+
+func TestParsePeriodStart(t *testing.T) {
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	got, err := parsePeriodStart("2023-09-01T00:00:00+00:00")
+	assert.NilError(t, err)
+	assert.Assert(t, got.Equal(day))
+
+	got, err = parsePeriodStart("1693526400000.0")
+	assert.NilError(t, err)
+	assert.Assert(t, got.Equal(day))
+
+	_, err = parsePeriodStart("yesterday")
+	assert.ErrorContains(t, err, "unexpected period start \"yesterday\"")
+
+	assert.Equal(t, share(1, 4), 25.0)
+	assert.Equal(t, share(1, 0), 0.0)
+}
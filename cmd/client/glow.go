@@ -0,0 +1,229 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/spf13/viper"
+)
+
+// glowPeriod is the length of the readings recorded from a Glow CAD: the half-hour settlement
+// periods smart meters are read in.
+const glowPeriod = 30 * time.Minute
+
+// glow reads consumption recorded from the MQTT messages of a Glow CAD or display, without going
+// through the Glowmarkt cloud. The CAD only publishes the meter's cumulative import as it goes, so
+// RecordGlow has to be left running to collect the half-hourly readings this answers from. The
+// statistic ID is the CAD's device ID.
+type glow struct {
+	dir string
+}
+
+func connectGlow(dir string) (*glow, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("there is nowhere readings from the CAD are recorded")
+	}
+	return &glow{dir: dir}, nil
+}
+
+func (g *glow) Readings(id string, start, end time.Time, period string) ([]Reading, error) {
+	// Half-hourly readings are added up into hours and longer periods, but can't be split.
+	if period == "5minute" {
+		return nil, fmt.Errorf("glow: unsupported period %q", period)
+	}
+	path := glowFile(g.dir, id)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no readings from %s have been recorded in %s - run powertracker glow to record them", id, g.dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("glow: %w", err)
+	}
+	defer f.Close()
+
+	recorded, err := readCSV(f, parseGenericRow)
+	if err != nil {
+		return nil, fmt.Errorf("glow: reading %s: %w", path, err)
+	}
+	var readings []Reading
+	for _, r := range recorded {
+		if !r.Start.Before(start) && r.Start.Before(end) {
+			readings = append(readings, r)
+		}
+	}
+	if len(readings) == 0 {
+		return nil, fmt.Errorf("no readings from %s were recorded between %s and %s", id, start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+	return readings, nil
+}
+
+// glowFile returns the path of the file the readings of the CAD with the device ID are recorded in.
+func glowFile(dir, id string) string {
+	return filepath.Join(dir, "glow-"+strings.ToLower(id)+".csv")
+}
+
+// glowMessage is the part of the electricity meter message published by a Glow CAD that is used.
+type glowMessage struct {
+	ElectricityMeter struct {
+		Timestamp string `json:"timestamp"`
+		Energy    struct {
+			Import struct {
+				Cumulative *float64 `json:"cumulative"`
+			} `json:"import"`
+		} `json:"energy"`
+	} `json:"electricitymeter"`
+}
+
+// parseGlowMessage returns the time of a meter reading published by a Glow CAD and the meter's
+// cumulative import then, in kWh.
+func parseGlowMessage(payload []byte) (time.Time, float64, error) {
+	var m glowMessage
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return time.Time{}, 0, err
+	}
+	cumulative := m.ElectricityMeter.Energy.Import.Cumulative
+	if cumulative == nil {
+		return time.Time{}, 0, fmt.Errorf("there is no cumulative import in the message")
+	}
+	at, err := time.Parse(time.RFC3339, m.ElectricityMeter.Timestamp)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("reading the timestamp: %w", err)
+	}
+	return at, *cumulative, nil
+}
+
+// meterHalfHours turns a meter's cumulative import, read every few seconds, into the consumption
+// of each half hour: the difference between the first readings in it and in the next.
+type meterHalfHours struct {
+	start      time.Time
+	cumulative float64
+	// whole is false until the first half hour that began after recording started.
+	whole bool
+}
+
+// add takes a reading of the cumulative import, returning the consumption of the half hour before
+// if it is the first reading in a new one. The half hour recording started part way through isn't
+// returned, nor is one followed by a gap in the readings, as when it was used can't be told.
+func (m *meterHalfHours) add(at time.Time, cumulative float64) (Reading, bool) {
+	start := at.Truncate(glowPeriod)
+	if m.start.IsZero() {
+		m.start, m.cumulative = start, cumulative
+		return Reading{}, false
+	}
+	if !start.After(m.start) {
+		return Reading{}, false
+	}
+	r := Reading{Start: m.start, Value: cumulative - m.cumulative}
+	complete := m.whole && start.Equal(m.start.Add(glowPeriod)) && r.Value >= 0
+	m.start, m.cumulative, m.whole = start, cumulative, true
+	return r, complete
+}
+
+// RecordGlow subscribes to the electricity meter messages a Glow CAD publishes over MQTT, recording
+// the consumption of each half hour for the glow source until the client is stopped. The CAD is
+// set up to publish to a broker on the local network, so no cloud service is involved.
+func (c *Client) RecordGlow() error {
+	id := SensorID()
+	if id == "" {
+		return fmt.Errorf("sensor_id is required: set it to the CAD's device ID")
+	}
+	if c.Config.GlowDir == "" {
+		return fmt.Errorf("there is nowhere to record the readings")
+	}
+	broker := viper.GetString("glow.broker")
+	if broker == "" {
+		return fmt.Errorf("glow.broker is required")
+	}
+	topic := viper.GetString("glow.topic")
+	if topic == "" {
+		topic = "glow/" + id + "/SENSOR/electricitymeter"
+	}
+
+	// Messages that arrive while the channel is full are dropped rather than holding up the
+	// MQTT client, and counted so the loss is logged.
+	messages := make(chan []byte, 16)
+	var dropped atomic.Int64
+	handler := func(_ mqtt.Client, m mqtt.Message) {
+		select {
+		case messages <- m.Payload():
+		default:
+			dropped.Add(1)
+		}
+	}
+	// The subscription is made again whenever the connection is, so it survives the broker
+	// going away for a while.
+	opts := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(glowClientID()).
+		SetUsername(viper.GetString("glow.username")).
+		SetPassword(viper.GetString("glow.password")).
+		SetConnectTimeout(10 * time.Second).
+		SetOnConnectHandler(func(client mqtt.Client) {
+			if token := client.Subscribe(topic, 0, handler); token.Wait() && token.Error() != nil {
+				c.logger().Error().Msgf("subscribing to %s: %s", topic, token.Error())
+			}
+		})
+	client := mqtt.NewClient(opts)
+	if token := client.Connect(); token.Wait() && token.Error() != nil {
+		return fmt.Errorf("connecting to %s: %w", broker, token.Error())
+	}
+	defer client.Disconnect(250)
+
+	path := glowFile(c.Config.GlowDir, id)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil && info.Size() == 0 {
+		if _, err := f.WriteString("timestamp,kwh\n"); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+	}
+	c.logger().Info().Msgf("recording %s to %s", topic, path)
+
+	var halfHours meterHalfHours
+	for {
+		select {
+		case <-c.stopped():
+			return nil
+		case payload := <-messages:
+			if n := dropped.Swap(0); n > 0 {
+				c.logger().Warn().Msgf("dropped %d messages from %s that arrived faster than they could be recorded", n, topic)
+			}
+			at, cumulative, err := parseGlowMessage(payload)
+			if err != nil {
+				c.logger().Warn().Msgf("skipping a message from %s: %s", topic, err.Error())
+				continue
+			}
+			r, ok := halfHours.add(at, cumulative)
+			if !ok {
+				continue
+			}
+			line := r.Start.UTC().Format(time.RFC3339) + "," + strconv.FormatFloat(r.Value, 'f', 3, 64) + "\n"
+			if _, err := f.WriteString(line); err != nil {
+				return fmt.Errorf("writing %s: %w", path, err)
+			}
+			c.logger().Debug().Msgf("%s: %.3f kWh", r.Start.Format("15:04"), r.Value)
+		}
+	}
+}
+
+// glowClientID returns the ID the recorder connects to the broker with. Brokers disconnect a client
+// when another connects with the same ID, so it is made unique to the host and process, letting
+// recorders on different machines, or one restarted before the broker has noticed the last has
+// gone, run side by side.
+func glowClientID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return fmt.Sprintf("powertracker-glow-%s-%d", host, os.Getpid())
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParseGlowMessage(t *testing.T) {
+	at, cumulative, err := parseGlowMessage([]byte(`{"electricitymeter":{"timestamp":"2023-09-01T00:30:05Z",` +
+		`"energy":{"export":{"cumulative":0.000,"units":"kWh"},"import":{"cumulative":6613.405,"day":13.252,"units":"kWh"}},` +
+		`"power":{"value":0.951,"units":"kW"}}}`))
+	assert.NilError(t, err)
+	assert.Equal(t, at, time.Date(2023, 9, 1, 0, 30, 5, 0, time.UTC))
+	assert.Equal(t, cumulative, 6613.405)
+
+	_, _, err = parseGlowMessage([]byte(`{"gasmeter":{"timestamp":"2023-09-01T00:30:05Z"}}`))
+	assert.ErrorContains(t, err, "there is no cumulative import")
+	_, _, err = parseGlowMessage([]byte(`{"electricitymeter":{"energy":{"import":{"cumulative":1}}}}`))
+	assert.ErrorContains(t, err, "reading the timestamp")
+}
+
+func TestMeterHalfHours(t *testing.T) {
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	var m meterHalfHours
+	var got []Reading
+	for _, r := range []struct {
+		after      time.Duration
+		cumulative float64
+	}{
+		// Recording starts part way through the first half hour, which is left out.
+		{10 * time.Minute, 100},
+		{31 * time.Minute, 100.25},
+		{45 * time.Minute, 100.375},
+		{60 * time.Minute, 100.5},
+		{89 * time.Minute, 100.9},
+		// The half hour before a gap in the readings is left out too.
+		{150 * time.Minute, 101.5},
+		{180 * time.Minute, 101.75},
+		// As is one over which the meter went back.
+		{210 * time.Minute, 1},
+		{240 * time.Minute, 1.5},
+	} {
+		if reading, ok := m.add(day.Add(r.after), r.cumulative); ok {
+			got = append(got, reading)
+		}
+	}
+	assert.DeepEqual(t, got, []Reading{
+		{Start: day.Add(30 * time.Minute), Value: 0.25},
+		{Start: day.Add(150 * time.Minute), Value: 0.25},
+		{Start: day.Add(210 * time.Minute), Value: 0.5},
+	})
+}
+
+func TestGlow_Readings(t *testing.T) {
+	dir := t.TempDir()
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "glow-abc123.csv"), []byte("timestamp,kwh\n"+
+		"2023-08-31T23:30:00Z,0.125\n"+
+		"2023-09-01T00:00:00Z,0.25\n"+
+		"2023-09-01T00:30:00Z,0.25\n"+
+		"2023-09-01T01:30:00Z,0.5\n"), 0600))
+
+	g, err := connectGlow(dir)
+	assert.NilError(t, err)
+
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	readings, err := g.Readings("ABC123", day, day.Add(24*time.Hour), "hour")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, bucket(readings, day, time.Hour, 3), []float64{0.5, 0.5, 0})
+	readings, err = g.Readings("ABC123", day, day.Add(24*time.Hour), "30minute")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, bucket(readings, day, 30*time.Minute, 4), []float64{0.25, 0.25, 0, 0.5})
+}
+
+func TestGlow_ErrorStates(t *testing.T) {
+	_, err := connectGlow("")
+	assert.ErrorContains(t, err, "there is nowhere readings from the CAD are recorded")
+
+	g, err := connectGlow(t.TempDir())
+	assert.NilError(t, err)
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	_, err = g.Readings("abc123", day, day.Add(24*time.Hour), "hour")
+	assert.ErrorContains(t, err, "run powertracker glow to record them")
+
+	assert.NilError(t, os.WriteFile(glowFile(g.dir, "abc123"), []byte("timestamp,kwh\n2023-08-01T00:00:00Z,0.25\n"), 0600))
+	_, err = g.Readings("abc123", day, day.Add(24*time.Hour), "hour")
+	assert.ErrorContains(t, err, "no readings from abc123 were recorded between")
+
+	_, err = g.Readings("abc123", day, day.Add(24*time.Hour), "5minute")
+	assert.ErrorContains(t, err, "unsupported period \"5minute\"")
+}
+
+func TestGlowClientID(t *testing.T) {
+	id := glowClientID()
+	assert.Assert(t, strings.HasPrefix(id, "powertracker-glow-"), id)
+	assert.Assert(t, strings.HasSuffix(id, "-"+strconv.Itoa(os.Getpid())), id)
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// sendGraphite writes each hourly value, with its historical timestamp, to a Graphite/Carbon
+// endpoint using the plaintext protocol. Values are sent to <prefix>.<sensor>, where dots in
+// the sensor ID are replaced with underscores, e.g. powertracker.sensor_energy.
+func (c *Client) sendGraphite(results []Day) error {
+	address := viper.GetString("graphite.address")
+	if address == "" {
+		return fmt.Errorf("graphite.address is required")
+	}
+	prefix := viper.GetString("graphite.prefix")
+	if prefix == "" {
+		prefix = "powertracker"
+	}
+	path := prefix + "." + strings.NewReplacer(".", "_", ",", "-").Replace(SensorID())
+
+	conn, err := net.DialTimeout("tcp", address, 10*time.Second)
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", address, err)
+	}
+	defer conn.Close()
+
+	w := bufio.NewWriter(conn)
+	var sent int
+	for _, day := range results {
+		for i, v := range day.Values {
+			start, ok := slotStart(day.Date, i, time.Hour)
+			if !ok {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "%s %f %d\n", path, v, start.Unix()); err != nil {
+				return fmt.Errorf("writing metric: %w", err)
+			}
+			sent++
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("writing metrics: %w", err)
+	}
+	c.logger().Info().Msgf("sent %d values to %s", sent, path)
+	return nil
+}
@@ -0,0 +1,40 @@
+package client
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestClient_SendGraphite(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	defer l.Close()
+
+	received := make(chan string)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		b, _ := io.ReadAll(conn)
+		received <- string(b)
+	}()
+
+	viper.Set("graphite.address", l.Addr().String())
+	viper.Set("graphite.prefix", "home.power")
+	viper.Set("sensor_id", "sensor.energy")
+
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	client := New(Config{})
+	err = client.sendGraphite([]Day{{Date: day, Values: []float64{0.5, 1.25}}})
+	assert.NilError(t, err)
+
+	assert.Equal(t, <-received, "home.power.sensor_energy 0.500000 1693526400\n"+
+		"home.power.sensor_energy 1.250000 1693530000\n")
+}
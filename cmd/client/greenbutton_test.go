@@ -0,0 +1,43 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestClient_WriteGreenButton(t *testing.T) {
+	viper.Set("sensor_id", "sensor.energy")
+	path := filepath.Join(t.TempDir(), "usage.csv")
+	client := New(Config{FilePath: path})
+
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	err := client.writeGreenButton([]Day{{Date: day, Values: []float64{0.5, 1.2345}}})
+	assert.NilError(t, err)
+
+	b, err := os.ReadFile(strings.TrimSuffix(path, ".csv") + ".xml")
+	assert.NilError(t, err)
+	out := string(b)
+
+	for _, expected := range []string{
+		`<feed xmlns="http://www.w3.org/2005/Atom">`,
+		`<UsagePoint xmlns="http://naesb.org/espi">`,
+		`<uom>72</uom>`,
+		`<interval><duration>7200</duration><start>1693526400</start></interval>`,
+		`<timePeriod><duration>3600</duration><start>1693526400</start></timePeriod><value>500</value>`,
+		`<timePeriod><duration>3600</duration><start>1693530000</start></timePeriod><value>1235</value>`,
+	} {
+		assert.Assert(t, strings.Contains(strings.Join(strings.Fields(out), ""), strings.Join(strings.Fields(expected), "")), "missing %s", expected)
+	}
+}
+
+func TestEspiID(t *testing.T) {
+	assert.Equal(t, espiID("sensor.energy", "usagepoint"), espiID("sensor.energy", "usagepoint"))
+	assert.Assert(t, espiID("ab", "c") != espiID("a", "bc"))
+	assert.Assert(t, strings.HasPrefix(espiID("a"), "urn:uuid:"))
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// stateChange is a state an entity entered at a point in time.
+type stateChange struct {
+	State string
+	Time  time.Time
+}
+
+// history returns the states an entity went through between start and end, using the
+// history/history_during_period websocket API. The first entry is the state the entity was
+// already in at the start.
+func (c *Client) history(entityID string, start, end time.Time) ([]stateChange, error) {
+	if !c.connected() {
+		return nil, fmt.Errorf("state history requires the Home Assistant source")
+	}
+
+	msg := map[string]interface{}{
+		"type":                     "history/history_during_period",
+		"start_time":               start.UTC().Format("2006-01-02T15:04:05.000Z"),
+		"end_time":                 end.UTC().Format("2006-01-02T15:04:05.000Z"),
+		"entity_ids":               []string{entityID},
+		"include_start_time_state": true,
+		"significant_changes_only": false,
+		"minimal_response":         true,
+		"no_attributes":            true,
+	}
+	var data struct {
+		Success bool `json:"success"`
+		Result  map[string][]struct {
+			State       string  `json:"s"`
+			LastUpdated float64 `json:"lu"`
+		} `json:"result"`
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := c.request(msg, &data); err != nil {
+		return nil, err
+	}
+	if !data.Success {
+		return nil, fmt.Errorf("api response error: %v", data.Error)
+	}
+	if len(data.Result[entityID]) == 0 {
+		return nil, fmt.Errorf("no history returned - is the entity ID '%s' correct?", entityID)
+	}
+
+	changes := make([]stateChange, len(data.Result[entityID]))
+	for i, s := range data.Result[entityID] {
+		sec, frac := math.Modf(s.LastUpdated)
+		changes[i] = stateChange{State: s.State, Time: time.Unix(int64(sec), int64(frac*1e9))}
+	}
+	return changes, nil
+}
+
+// timeIn returns how long the entity spent in states matching the predicate between start and end.
+// The changes must be sorted by time.
+func timeIn(changes []stateChange, start, end time.Time, match func(string) bool) time.Duration {
+	var total time.Duration
+	for i, change := range changes {
+		from := change.Time
+		to := end
+		if i+1 < len(changes) {
+			to = changes[i+1].Time
+		}
+		if from.Before(start) {
+			from = start
+		}
+		if to.After(end) {
+			to = end
+		}
+		if to.After(from) && match(change.State) {
+			total += to.Sub(from)
+		}
+	}
+	return total
+}
@@ -0,0 +1,210 @@
+package client
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/poolski/powertracker/cmd/cache"
+)
+
+// ImportCSV loads historical consumption from a CSV export into the cache, under the given
+// statistic ID, so it is used alongside data from Home Assistant. Supported formats are:
+//
+//   - octopus: the Octopus Energy consumption download ("Consumption (kWh), Start, End")
+//   - n3rgy: the n3rgy consumer export ("timestamp (UTC),energyConsumption (kWh)"), where
+//     each timestamp marks the end of a half-hour
+//   - generic: "timestamp,kwh" rows, where each RFC 3339 timestamp marks the start of a period
+//
+// Only days with readings for every hour are stored. It returns the number of days imported.
+func (c *Client) ImportCSV(r io.Reader, format, id string) (int, error) {
+	// Readings stored under no ID would never be read back.
+	if id == "" {
+		return 0, fmt.Errorf("sensor_id is required")
+	}
+	var parse func([]string, map[string]int) (Reading, error)
+	switch format {
+	case "octopus":
+		parse = parseOctopusRow
+	case "n3rgy":
+		parse = parseN3rgyRow
+	case "generic":
+		parse = parseGenericRow
+	default:
+		return 0, fmt.Errorf("unknown format %q", format)
+	}
+
+	readings, err := readCSV(r, parse)
+	if err != nil {
+		return 0, err
+	}
+
+	// Days run from midnight in the time zone, as they do when they are fetched.
+	loc, id := c.timeZone(), c.cacheID(id)
+	store, err := c.openCache()
+	if err != nil {
+		return 0, err
+	}
+	if store == nil {
+		return 0, fmt.Errorf("no cache file configured")
+	}
+	defer store.Close()
+
+	// Group the readings by day, keeping track of which hours have been seen.
+	days := map[time.Time][]Reading{}
+	for _, r := range readings {
+		day := midnight(r.Start.In(loc))
+		days[day] = append(days[day], r)
+	}
+
+	var imported, incomplete int
+	for day, dayReadings := range days {
+		covered := make([]bool, hoursInADay)
+		for _, r := range dayReadings {
+			if i, ok := slot(r.Start, day, time.Hour, hoursInADay); ok {
+				covered[i] = true
+			}
+		}
+		// The hour skipped when the clocks go forward can't have any readings.
+		need := int(day.AddDate(0, 0, 1).Sub(day) / time.Hour)
+		if need > hoursInADay {
+			need = hoursInADay
+		}
+		if count(covered) < need {
+			incomplete++
+			continue
+		}
+
+		if err := store.Put(id, day, cache.Entry{
+			Values:  bucket(dayReadings, day, time.Hour, hoursInADay),
+			Fetched: time.Now(),
+			Source:  "import:" + format,
+		}); err != nil {
+			return imported, err
+		}
+		imported++
+	}
+	if incomplete > 0 {
+		c.logger().Warn().Msgf("skipped %d days without readings for every hour", incomplete)
+	}
+	return imported, nil
+}
+
+func count(values []bool) int {
+	n := 0
+	for _, v := range values {
+		if v {
+			n++
+		}
+	}
+	return n
+}
+
+// readCSV reads every row of a CSV file with a header row, using parse to turn each row into a
+// reading. Columns are looked up by their lower-cased, trimmed header names.
+func readCSV(r io.Reader, parse func([]string, map[string]int) (Reading, error)) ([]Reading, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	var readings []Reading
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading line %d: %w", line, err)
+		}
+		reading, err := parse(row, columns)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		readings = append(readings, reading)
+	}
+	sort.Slice(readings, func(i, j int) bool { return readings[i].Start.Before(readings[j].Start) })
+	return readings, nil
+}
+
+// column returns the value of the first matching column in the row.
+func column(row []string, columns map[string]int, names ...string) (string, error) {
+	for _, name := range names {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i]), nil
+		}
+	}
+	return "", fmt.Errorf("missing column %q", names[0])
+}
+
+func parseKWh(row []string, columns map[string]int, names ...string) (float64, error) {
+	s, err := column(row, columns, names...)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing consumption: %w", err)
+	}
+	return v, nil
+}
+
+func parseOctopusRow(row []string, columns map[string]int) (Reading, error) {
+	value, err := parseKWh(row, columns, "consumption (kwh)", "consumption")
+	if err != nil {
+		return Reading{}, err
+	}
+	s, err := column(row, columns, "start")
+	if err != nil {
+		return Reading{}, err
+	}
+	start, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return Reading{}, fmt.Errorf("parsing start: %w", err)
+	}
+	return Reading{Start: start, Value: value}, nil
+}
+
+func parseN3rgyRow(row []string, columns map[string]int) (Reading, error) {
+	value, err := parseKWh(row, columns, "energyconsumption (kwh)")
+	if err != nil {
+		return Reading{}, err
+	}
+	s, err := column(row, columns, "timestamp (utc)")
+	if err != nil {
+		return Reading{}, err
+	}
+	end, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		return Reading{}, fmt.Errorf("parsing timestamp: %w", err)
+	}
+	return Reading{Start: end.Add(-30 * time.Minute), Value: value}, nil
+}
+
+func parseGenericRow(row []string, columns map[string]int) (Reading, error) {
+	value, err := parseKWh(row, columns, "kwh")
+	if err != nil {
+		return Reading{}, err
+	}
+	s, err := column(row, columns, "timestamp")
+	if err != nil {
+		return Reading{}, err
+	}
+	start, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return Reading{}, fmt.Errorf("parsing timestamp: %w", err)
+	}
+	return Reading{Start: start, Value: value}, nil
+}
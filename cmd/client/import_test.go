@@ -0,0 +1,93 @@
+package client
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestReadCSV_Formats(t *testing.T) {
+	start := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		parse func([]string, map[string]int) (Reading, error)
+		data  string
+	}{
+		{
+			name:  "Octopus",
+			parse: parseOctopusRow,
+			data: "Consumption (kWh), Start, End\n" +
+				"0.25, 2023-09-01T00:30:00+00:00, 2023-09-01T01:00:00+00:00\n" +
+				"0.5, 2023-09-01T01:00:00+01:00, 2023-09-01T01:30:00+01:00\n",
+		},
+		{
+			name:  "n3rgy",
+			parse: parseN3rgyRow,
+			data: "timestamp (UTC),energyConsumption (kWh)\n" +
+				"2023-09-01 00:30,0.5\n" +
+				"2023-09-01 01:00,0.25\n",
+		},
+		{
+			name:  "Generic",
+			parse: parseGenericRow,
+			data: "timestamp,kwh\n" +
+				"2023-09-01T00:30:00Z,0.25\n" +
+				"2023-09-01T00:00:00Z,0.5\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			readings, err := readCSV(strings.NewReader(test.data), test.parse)
+			assert.NilError(t, err)
+			assert.Equal(t, len(readings), 2)
+			assert.Assert(t, readings[0].Start.Equal(start), "unexpected start %s", readings[0].Start)
+			assert.Assert(t, readings[1].Start.Equal(start.Add(30*time.Minute)), "unexpected start %s", readings[1].Start)
+			assert.Equal(t, readings[0].Value+readings[1].Value, 0.75)
+		})
+	}
+}
+
+func TestReadCSV_BadRow(t *testing.T) {
+	_, err := readCSV(strings.NewReader("timestamp,kwh\n2023-09-01T00:00:00Z,lots\n"), parseGenericRow)
+	assert.ErrorContains(t, err, "line 2: parsing consumption")
+
+	_, err = readCSV(strings.NewReader("time,kwh\n2023-09-01T00:00:00Z,1\n"), parseGenericRow)
+	assert.ErrorContains(t, err, "line 2: missing column \"timestamp\"")
+}
+
+func TestClient_ImportCSV(t *testing.T) {
+	yesterday := time.Now().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+
+	// One complete day, and a single reading for the day before, which should be skipped.
+	var b strings.Builder
+	b.WriteString("timestamp,kwh\n")
+	for i := 0; i < hoursInADay; i++ {
+		fmt.Fprintf(&b, "%s,%d\n", yesterday.Add(time.Duration(i)*time.Hour).Format(time.RFC3339), i)
+	}
+	fmt.Fprintf(&b, "%s,1\n", yesterday.Add(-time.Hour).Format(time.RFC3339))
+
+	client := New(Config{Days: 1, CacheFile: filepath.Join(t.TempDir(), "cache.db")})
+	n, err := client.ImportCSV(strings.NewReader(b.String()), "generic", "sensor.imported")
+	assert.NilError(t, err)
+	assert.Equal(t, n, 1)
+
+	// The imported day is served from the cache without touching the source.
+	viper.Set("sensor_id", "sensor.imported")
+	results, err := getResults(client)
+	assert.NilError(t, err)
+	assert.Equal(t, len(results), 1)
+	assert.Assert(t, results[0].Date.Equal(yesterday))
+	assert.Equal(t, results[0].Values[23], 23.0)
+}
+
+func TestClient_ImportCSV_NoSensor(t *testing.T) {
+	client := New(Config{Days: 1, CacheFile: filepath.Join(t.TempDir(), "cache.db"), TimeZone: "Europe/London"})
+	_, err := client.ImportCSV(strings.NewReader("timestamp,kwh\n"), "generic", "")
+	assert.Error(t, err, "sensor_id is required")
+}
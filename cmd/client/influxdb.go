@@ -0,0 +1,105 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// influxBatchSize is the number of points sent in each write, the batch size InfluxDB recommends.
+const influxBatchSize = 5000
+
+// influxTagEscaper escapes the characters with a meaning in line protocol tag values.
+var influxTagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// writeInfluxDB writes each hourly value, with its historical timestamp, to an InfluxDB v2 bucket
+// using the write API. Points are written to the measurement, "energy" by default, with the
+// sensor as a sensor_id tag and the value in a kwh field, e.g.
+//
+//	energy,sensor_id=sensor.energy kwh=0.5 1693526400
+//
+// InfluxDB replaces a point with the same measurement, tags and timestamp, so the same period can
+// be written more than once.
+func (c *Client) writeInfluxDB(results []Day) error {
+	baseURL := viper.GetString("influxdb.url")
+	if baseURL == "" {
+		return fmt.Errorf("influxdb.url is required")
+	}
+	token := viper.GetString("influxdb.token")
+	if token == "" {
+		return fmt.Errorf("influxdb.token is required")
+	}
+	org := viper.GetString("influxdb.org")
+	bucket := viper.GetString("influxdb.bucket")
+	if org == "" || bucket == "" {
+		return fmt.Errorf("influxdb.org and influxdb.bucket are required")
+	}
+	measurement := viper.GetString("influxdb.measurement")
+	if measurement == "" {
+		measurement = "energy"
+	}
+
+	prefix := strings.NewReplacer(",", `\,`, " ", `\ `).Replace(measurement) + ",sensor_id=" + influxTagEscaper.Replace(SensorID())
+	var lines []string
+	for _, day := range results {
+		for i, v := range day.Values {
+			start, ok := slotStart(day.Date, i, time.Hour)
+			if !ok {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("%s kwh=%s %d", prefix, strconv.FormatFloat(v, 'f', -1, 64), start.Unix()))
+		}
+	}
+
+	endpoint, err := url.JoinPath(baseURL, "api/v2/write")
+	if err != nil {
+		return fmt.Errorf("parsing URL: %w", err)
+	}
+	endpoint += "?" + url.Values{"org": {org}, "bucket": {bucket}, "precision": {"s"}}.Encode()
+	for i := 0; i < len(lines); i += influxBatchSize {
+		end := i + influxBatchSize
+		if end > len(lines) {
+			end = len(lines)
+		}
+		if err := writeInfluxBatch(endpoint, token, lines[i:end]); err != nil {
+			return fmt.Errorf("writing points: %w", err)
+		}
+	}
+	c.logger().Info().Msgf("wrote %d values to InfluxDB bucket %s", len(lines), bucket)
+	return nil
+}
+
+func writeInfluxBatch(endpoint, token string, lines []string) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(strings.Join(lines, "\n")))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("Authorization", "Token "+token)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	// Errors come with a JSON body explaining them, such as an unknown bucket or a bad token.
+	body, _ := io.ReadAll(resp.Body)
+	var e struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &e) == nil && e.Message != "" {
+		return fmt.Errorf("unexpected response (%d): %s", resp.StatusCode, e.Message)
+	}
+	return fmt.Errorf("unexpected response (%d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
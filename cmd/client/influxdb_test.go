@@ -0,0 +1,104 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestClient_WriteInfluxDB(t *testing.T) {
+	var batches []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Path, "/influx/api/v2/write", "unexpected path")
+		assert.Equal(t, r.URL.Query().Get("org"), "home")
+		assert.Equal(t, r.URL.Query().Get("bucket"), "energy")
+		assert.Equal(t, r.URL.Query().Get("precision"), "s")
+		assert.Equal(t, r.Header.Get("Authorization"), "Token test_token")
+		b, err := io.ReadAll(r.Body)
+		assert.NilError(t, err)
+		batches = append(batches, string(b))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer s.Close()
+
+	viper.Set("sensor_id", "sensor.energy")
+	viper.Set("influxdb.url", s.URL+"/influx")
+	viper.Set("influxdb.token", "test_token")
+	viper.Set("influxdb.org", "home")
+	viper.Set("influxdb.bucket", "energy")
+	viper.Set("influxdb.measurement", "")
+	defer viper.Set("influxdb", nil)
+
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	client := New(Config{})
+	err := client.writeInfluxDB([]Day{{Date: day, Values: []float64{0.5, 1.25}}})
+
+	assert.NilError(t, err)
+	assert.DeepEqual(t, batches, []string{"energy,sensor_id=sensor.energy kwh=0.5 1693526400\nenergy,sensor_id=sensor.energy kwh=1.25 1693530000"})
+
+	// Large backfills are split into batches.
+	batches = nil
+	values := make([]float64, influxBatchSize+1)
+	err = client.writeInfluxDB([]Day{{Date: day, Values: values}})
+	assert.NilError(t, err)
+	assert.Equal(t, len(batches), 2)
+	assert.Equal(t, strings.Count(batches[1], "\n"), 0)
+
+	// On the day the clocks go forward, the hour that is skipped isn't written and those after it
+	// are stamped by the clock.
+	london, err := time.LoadLocation("Europe/London")
+	assert.NilError(t, err)
+	batches = nil
+	day = time.Date(2023, 3, 26, 0, 0, 0, 0, london)
+	err = client.writeInfluxDB([]Day{{Date: day, Values: []float64{0.5, 0, 1.25}}})
+	assert.NilError(t, err)
+	// 00:00 GMT, then 02:00 BST, an hour later.
+	assert.DeepEqual(t, batches, []string{"energy,sensor_id=sensor.energy kwh=0.5 1679788800\nenergy,sensor_id=sensor.energy kwh=1.25 1679792400"})
+}
+
+func TestClient_WriteInfluxDB_ErrorStates(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"code":"not found","message":"bucket \"energy\" not found"}`))
+	}))
+	defer s.Close()
+	defer viper.Set("influxdb", nil)
+
+	tests := []struct {
+		name     string
+		url      string
+		token    string
+		bucket   string
+		expected string
+	}{
+		{name: "Empty URL", token: "test_token", bucket: "energy", expected: "influxdb.url is required"},
+		{name: "Empty token", url: s.URL, bucket: "energy", expected: "influxdb.token is required"},
+		{name: "Empty bucket", url: s.URL, token: "test_token", expected: "influxdb.org and influxdb.bucket are required"},
+		{name: "Rejected", url: s.URL, token: "test_token", bucket: "energy", expected: `unexpected response (404): bucket "energy" not found`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			viper.Set("influxdb.url", test.url)
+			viper.Set("influxdb.token", test.token)
+			viper.Set("influxdb.org", "home")
+			viper.Set("influxdb.bucket", test.bucket)
+
+			client := New(Config{})
+			err := client.writeInfluxDB([]Day{{Values: []float64{1}}})
+
+			assert.ErrorContains(t, err, test.expected)
+		})
+	}
+}
+
+func TestInfluxTagEscaper(t *testing.T) {
+	assert.Equal(t, influxTagEscaper.Replace("sensor.a,sensor.b"), `sensor.a\,sensor.b`)
+	assert.Equal(t, influxTagEscaper.Replace("a b=c"), `a\ b\=c`)
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/poolski/powertracker/cmd/i18n"
+)
+
+// minInsightChange is the smallest difference, as a fraction, worth mentioning in an insight.
+const minInsightChange = 0.1
+
+// insights returns short plain-English findings about the results. Results must be in the order
+// they are fetched, most recent day first.
+func insights(results []Day, averages []float64) []string {
+	var found []string
+	mean := sum(averages) / float64(len(averages))
+	if mean <= 0 {
+		return nil
+	}
+
+	// The busiest three hours of the day.
+	const window = 3
+	peak, peakStart := 0.0, 0
+	for h := 0; h+window <= len(averages); h++ {
+		if v := sum(averages[h:h+window]) / window; v > peak {
+			peak, peakStart = v, h
+		}
+	}
+	if change := peak/mean - 1; change >= minInsightChange {
+		found = append(found, i18n.T("Your %02d:00-%02d:00 usage is %.0f%% above your hourly average.", peakStart, peakStart+window, change*100))
+	}
+
+	// Weekdays against weekends.
+	var weekday, weekend []float64
+	for _, day := range results {
+		if wd := day.Date.Weekday(); wd == time.Saturday || wd == time.Sunday {
+			weekend = append(weekend, sum(day.Values))
+		} else {
+			weekday = append(weekday, sum(day.Values))
+		}
+	}
+	if len(weekday) > 0 && len(weekend) > 0 {
+		change := average(weekend)/average(weekday) - 1
+		if math.Abs(change) >= minInsightChange {
+			msg := "You use %.0f%% more on weekends than on weekdays."
+			if change < 0 {
+				msg = "You use %.0f%% less on weekends than on weekdays."
+			}
+			found = append(found, i18n.T(msg, math.Abs(change)*100))
+		}
+	}
+
+	// The baseload, the lowest hourly draw of each day, in the recent half of the period against the earlier half.
+	if n := len(results) / 2; n >= 7 {
+		base := func(days []Day) float64 {
+			var lows []float64
+			for _, day := range days {
+				lows = append(lows, percentile(day.Values, 0.1))
+			}
+			return average(lows)
+		}
+		recent, earlier := base(results[:n]), base(results[n:2*n])
+		if earlier > 0 {
+			if change := recent/earlier - 1; math.Abs(change) >= minInsightChange {
+				msg := "Your baseload rose %.0f%% over the last %d days compared with the %d days before."
+				if change < 0 {
+					msg = "Your baseload fell %.0f%% over the last %d days compared with the %d days before."
+				}
+				found = append(found, i18n.T(msg, math.Abs(change)*100, n, n))
+			}
+		}
+	}
+
+	// The highest day.
+	var highest Day
+	for _, day := range results {
+		if sum(day.Values) > sum(highest.Values) {
+			highest = day
+		}
+	}
+	daily := mean * float64(len(averages))
+	if ratio := sum(highest.Values) / daily; ratio >= 1.5 {
+		found = append(found, i18n.T("Your highest day was %s at %.1f kWh, %.1fx your daily average.", highest.Date.Format(i18n.T("Monday 2 January")), sum(highest.Values), ratio))
+	}
+	// Windows without any usage at all.
+	var cuts []outage
+	var downtime int
+	for _, o := range findOutages(results, time.Hour) {
+		switch o.Cause {
+		case causeOutage:
+			cuts = append(cuts, o)
+		case causeDowntime:
+			downtime++
+		}
+	}
+	switch {
+	case len(cuts) == 1:
+		found = append(found, i18n.T("Nothing was used %s, which looks like a power cut.", during(cuts[0].Start, cuts[0].End)))
+	case len(cuts) > 1:
+		found = append(found, i18n.T("Nothing was used in %d separate windows, which look like power cuts - see -o gaps.", len(cuts)))
+	}
+	if downtime > 0 {
+		found = append(found, i18n.T("%d gaps in the data look like Home Assistant was down - see -o gaps.", downtime))
+	}
+	return found
+}
+
+func average(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	return sum(values) / float64(len(values))
+}
+
+// during describes the time between start and end in words.
+func during(start, end time.Time) string {
+	if start.Truncate(24 * time.Hour).Equal(end.Add(-time.Nanosecond).Truncate(24 * time.Hour)) {
+		return i18n.T("between %s and %s on %s", start.Format("15:04"), end.Format("15:04"), start.Format(i18n.T("Monday 2 January")))
+	}
+	layout := i18n.T("15:04 on Monday 2 January")
+	return i18n.T("from %s until %s", start.Format(layout), end.Format(layout))
+}
+
+// printInsights prints the insights as a bulleted list.
+func printInsights(results []Day, averages []float64) {
+	found := insights(results, averages)
+	if len(found) == 0 {
+		return
+	}
+	fmt.Println("\n" + i18n.T("Insights:"))
+	for _, insight := range found {
+		fmt.Printf("  - %s\n", insight)
+	}
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestInsights(t *testing.T) {
+	// Two weeks, most recent first, ending on a Sunday. Every day has a busy evening, weekends
+	// use twice as much, and the baseload doubled in the last week.
+	sunday := time.Date(2023, 9, 17, 0, 0, 0, 0, time.UTC)
+	var results []Day
+	for i := 0; i < 14; i++ {
+		day := sunday.Add(-time.Duration(i) * 24 * time.Hour)
+		base := 0.25
+		if i < 7 {
+			base = 0.5
+		}
+		values := make([]float64, hoursInADay)
+		for h := range values {
+			values[h] = base
+		}
+		values[18], values[19], values[20] = 2, 2, 2
+		if wd := day.Weekday(); wd == time.Saturday || wd == time.Sunday {
+			for h := range values {
+				values[h] *= 2
+			}
+		}
+		results = append(results, Day{Date: day, Values: values})
+	}
+	averages := make([]float64, hoursInADay)
+	for _, day := range results {
+		for h, v := range day.Values {
+			averages[h] += v / float64(len(results))
+		}
+	}
+
+	found := insights(results, averages)
+	assert.DeepEqual(t, found, []string{
+		"Your 18:00-21:00 usage is 246% above your hourly average.",
+		"You use 100% more on weekends than on weekdays.",
+		"Your baseload rose 100% over the last 7 days compared with the 7 days before.",
+		"Your highest day was Sunday 17 September at 33.0 kWh, 1.8x your daily average.",
+	})
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/spf13/viper"
+)
+
+// defaultPingInterval is how often Home Assistant is pinged, unless ping_interval is set.
+const defaultPingInterval = 30 * time.Second
+
+// pingInterval returns how often to ping Home Assistant, or 0 if ping_interval is 0 to turn pings
+// off.
+func pingInterval() time.Duration {
+	if !viper.IsSet("ping_interval") {
+		return defaultPingInterval
+	}
+	if d := viper.GetDuration("ping_interval"); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// keepAlive pings Home Assistant every interval until the connection is closed. A connection that
+// has dropped without closing, as over flaky Wi-Fi, would otherwise only be noticed when a request
+// timed out. If a ping hasn't been answered by the time the next is due, the connection is closed,
+// which fails the requests waiting on it so they are retried on a new one.
+func (c *Client) keepAlive(conn *websocket.Conn, interval time.Duration) {
+	var answered atomic.Bool
+	answered.Store(true)
+	conn.SetPongHandler(func(string) error {
+		answered.Store(true)
+		return nil
+	})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			// Pongs are only seen while responses are being read from the connection.
+			c.mu.Lock()
+			reading := c.reading == conn
+			c.mu.Unlock()
+			if reading && !answered.Load() {
+				c.logger().Warn().Msgf("Home Assistant didn't answer a ping within %s, dropping the connection", interval)
+				conn.Close()
+				return
+			}
+			answered.Store(false)
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(interval)); err != nil {
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestPingInterval(t *testing.T) {
+	defer viper.Reset()
+	assert.Equal(t, pingInterval(), defaultPingInterval)
+	viper.Set("ping_interval", "10s")
+	assert.Equal(t, pingInterval(), 10*time.Second)
+	viper.Set("ping_interval", "0s")
+	assert.Equal(t, pingInterval(), time.Duration(0))
+}
+
+func TestClient_KeepAlive(t *testing.T) {
+	// The server answers requests only after a while, and pings only if answerPings is set.
+	serve := func(answerPings bool) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			upgrader := websocket.Upgrader{}
+			conn, _ := upgrader.Upgrade(w, r, nil)
+			defer conn.Close()
+			if !answerPings {
+				conn.SetPingHandler(func(string) error { return nil })
+			}
+			_ = conn.WriteJSON(map[string]string{"type": "auth_required"})
+			var auth map[string]interface{}
+			_ = conn.ReadJSON(&auth)
+			_ = conn.WriteJSON(map[string]string{"type": "auth_ok"})
+			for {
+				var msg map[string]interface{}
+				if err := conn.ReadJSON(&msg); err != nil {
+					return
+				}
+				time.AfterFunc(200*time.Millisecond, func() {
+					_ = conn.WriteJSON(map[string]interface{}{"id": msg["id"], "type": "result", "success": true})
+				})
+			}
+		}))
+	}
+	viper.Set("api_key", "test_token")
+	viper.Set("ping_interval", 20*time.Millisecond)
+	defer viper.Set("ping_interval", defaultPingInterval)
+
+	t.Run("answered", func(t *testing.T) {
+		s := serve(true)
+		defer s.Close()
+		viper.Set("url", s.URL)
+		c := New(Config{})
+		assert.NilError(t, c.Connect())
+		defer c.Close()
+
+		var resp map[string]interface{}
+		assert.NilError(t, c.request(map[string]interface{}{"type": "ping"}, &resp))
+		assert.Equal(t, resp["success"], true)
+	})
+
+	t.Run("missed", func(t *testing.T) {
+		s := serve(false)
+		defer s.Close()
+		viper.Set("url", s.URL)
+		c := New(Config{})
+		assert.NilError(t, c.Connect())
+		defer c.Close()
+
+		// The connection is dropped well before the response is due.
+		var resp map[string]interface{}
+		started := time.Now()
+		err := c.request(map[string]interface{}{"type": "ping"}, &resp)
+		assert.ErrorContains(t, err, "reading from websocket")
+		assert.Assert(t, time.Since(started) < 200*time.Millisecond)
+	})
+}
@@ -0,0 +1,197 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/poolski/powertracker/cmd/i18n"
+)
+
+// powerSample is the power drawn, in watts, from when it was reported.
+type powerSample struct {
+	At    time.Time
+	Watts float64
+}
+
+// rollingPower keeps the power readings of the last window, for the least, average and most
+// drawn over it.
+type rollingPower struct {
+	window  time.Duration
+	samples []powerSample
+}
+
+func (r *rollingPower) add(s powerSample) {
+	r.samples = append(r.samples, s)
+	r.prune(s.At)
+}
+
+// prune drops the readings from before the window, except the last of them, as that was the
+// draw when the window started.
+func (r *rollingPower) prune(now time.Time) {
+	cut := now.Add(-r.window)
+	i := 0
+	for i+1 < len(r.samples) && !r.samples[i+1].At.After(cut) {
+		i++
+	}
+	r.samples = r.samples[i:]
+}
+
+// stats returns the least, average and most drawn over the window up to now. Power sensors report
+// when the draw changes rather than at regular intervals, so the average is weighted by how long
+// each reading held.
+func (r *rollingPower) stats(now time.Time) (min, avg, max float64) {
+	r.prune(now)
+	if len(r.samples) == 0 {
+		return math.NaN(), math.NaN(), math.NaN()
+	}
+	cut := now.Add(-r.window)
+	min, max = math.Inf(1), math.Inf(-1)
+	var energy, seconds float64
+	for i, s := range r.samples {
+		min = math.Min(min, s.Watts)
+		max = math.Max(max, s.Watts)
+		from, to := s.At, now
+		if from.Before(cut) {
+			from = cut
+		}
+		if i+1 < len(r.samples) {
+			to = r.samples[i+1].At
+		}
+		if d := to.Sub(from).Seconds(); d > 0 {
+			energy += s.Watts * d
+			seconds += d
+		}
+	}
+	if seconds == 0 {
+		return min, r.samples[len(r.samples)-1].Watts, max
+	}
+	return min, energy / seconds, max
+}
+
+// powerUnits converts the units power sensors report in to watts.
+var powerUnits = map[string]float64{"": 1, "W": 1, "kW": 1000, "MW": 1e6}
+
+// powerState returns the power, in watts, in a state_changed event for the entity. It reports
+// false for other entities, and for states that aren't a power, such as "unavailable".
+func powerState(data []byte, entityID string) (float64, bool) {
+	var frame struct {
+		Event struct {
+			Data struct {
+				EntityID string `json:"entity_id"`
+				NewState *struct {
+					State      string `json:"state"`
+					Attributes struct {
+						Unit string `json:"unit_of_measurement"`
+					} `json:"attributes"`
+				} `json:"new_state"`
+			} `json:"data"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return 0, false
+	}
+	d := frame.Event.Data
+	if d.EntityID != entityID || d.NewState == nil {
+		return 0, false
+	}
+	scale, ok := powerUnits[d.NewState.Attributes.Unit]
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(d.NewState.State, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v * scale, true
+}
+
+// Live shows the power drawn through a power sensor as it changes, with the least, average and
+// most drawn over the window, until the client is stopped. With inPlace, the readout is redrawn
+// on one line, for a terminal; otherwise a line is written each time the power changes.
+func (c *Client) Live(w io.Writer, entityID string, window time.Duration, inPlace bool) error {
+	if !c.connected() {
+		return fmt.Errorf("live power requires the Home Assistant source")
+	}
+	events, err := c.subscribeStates()
+	if err != nil {
+		return fmt.Errorf("subscribing to state changes: %w", err)
+	}
+	c.logger().Info().Msgf("waiting for %s to change", entityID)
+
+	r := rollingPower{window: window}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopped():
+			if inPlace && len(r.samples) > 0 {
+				fmt.Fprintln(w)
+			}
+			return nil
+		case data, ok := <-events:
+			if !ok {
+				c.logger().Warn().Msg("lost the connection to Home Assistant, reconnecting")
+				if err := (recorder{c}).reconnect(); err != nil {
+					return fmt.Errorf("reconnecting: %w", err)
+				}
+				if events, err = c.subscribeStates(); err != nil {
+					return fmt.Errorf("subscribing to state changes: %w", err)
+				}
+				continue
+			}
+			watts, ok := powerState(data, entityID)
+			if !ok {
+				continue
+			}
+			now := time.Now()
+			r.add(powerSample{At: now, Watts: watts})
+			writeLive(w, &r, now, inPlace)
+		case now := <-ticker.C:
+			// The average moves on even when the power doesn't, but only a readout that is redrawn
+			// in place can show that without repeating itself.
+			if inPlace && len(r.samples) > 0 {
+				writeLive(w, &r, now, inPlace)
+			}
+		}
+	}
+}
+
+// subscribeStates subscribes to the state_changed events of every entity, as Home Assistant
+// doesn't filter them by entity.
+func (c *Client) subscribeStates() (<-chan []byte, error) {
+	return c.subscribe(map[string]interface{}{"type": "subscribe_events", "event_type": "state_changed"})
+}
+
+// writeLive writes the current draw and the least, average and most drawn over the window.
+func writeLive(w io.Writer, r *rollingPower, now time.Time, inPlace bool) {
+	min, avg, max := r.stats(now)
+	current := r.samples[len(r.samples)-1].Watts
+	line := i18n.T("Now %s   min %s   avg %s   max %s   (last %s)", formatWatts(current), formatWatts(min), formatWatts(avg), formatWatts(max), shortDuration(r.window))
+	if inPlace {
+		// Clearing to the end of the line removes what's left of a longer readout.
+		fmt.Fprintf(w, "\r%s\033[K", line)
+		return
+	}
+	fmt.Fprintf(w, "%s %s\n", now.Format("15:04:05"), line)
+}
+
+func formatWatts(v float64) string {
+	return fmt.Sprintf("%.0f W", v)
+}
+
+// shortDuration formats a duration without trailing zero units, e.g. 5m rather than 5m0s.
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/poolski/powertracker/cmd/hatest"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestRollingPower(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	r := rollingPower{window: 10 * time.Minute}
+	min, avg, max := r.stats(start)
+	assert.Assert(t, math.IsNaN(min) && math.IsNaN(avg) && math.IsNaN(max))
+
+	r.add(powerSample{At: start, Watts: 100})
+	min, avg, max = r.stats(start)
+	assert.Equal(t, [3]float64{min, avg, max}, [3]float64{100, 100, 100})
+
+	// 100 W for 2 minutes, then 1000 W for 8 minutes.
+	r.add(powerSample{At: start.Add(2 * time.Minute), Watts: 1000})
+	min, avg, max = r.stats(start.Add(10 * time.Minute))
+	assert.Equal(t, [3]float64{min, avg, max}, [3]float64{100, 820, 1000})
+
+	// Once the window has moved past it, the 100 W reading no longer counts.
+	min, avg, max = r.stats(start.Add(15 * time.Minute))
+	assert.Equal(t, [3]float64{min, avg, max}, [3]float64{1000, 1000, 1000})
+	assert.Equal(t, len(r.samples), 1)
+}
+
+func stateChanged(entityID, state, unit string) map[string]interface{} {
+	return map[string]interface{}{
+		"entity_id": entityID,
+		"new_state": map[string]interface{}{
+			"entity_id":  entityID,
+			"state":      state,
+			"attributes": map[string]interface{}{"unit_of_measurement": unit},
+		},
+	}
+}
+
+func TestPowerState(t *testing.T) {
+	event := func(data map[string]interface{}) []byte {
+		b, err := json.Marshal(map[string]interface{}{"id": 2, "type": "event", "event": map[string]interface{}{"event_type": "state_changed", "data": data}})
+		assert.NilError(t, err)
+		return b
+	}
+	watts, ok := powerState(event(stateChanged("sensor.power", "1.5", "kW")), "sensor.power")
+	assert.Assert(t, ok)
+	assert.Equal(t, watts, 1500.0)
+	_, ok = powerState(event(stateChanged("sensor.other", "150", "W")), "sensor.power")
+	assert.Assert(t, !ok)
+	_, ok = powerState(event(stateChanged("sensor.power", "unavailable", "W")), "sensor.power")
+	assert.Assert(t, !ok)
+	_, ok = powerState(event(stateChanged("sensor.power", "3", "kWh")), "sensor.power")
+	assert.Assert(t, !ok)
+}
+
+func TestShortDuration(t *testing.T) {
+	assert.Equal(t, shortDuration(5*time.Minute), "5m")
+	assert.Equal(t, shortDuration(90*time.Second), "1m30s")
+	assert.Equal(t, shortDuration(time.Hour), "1h")
+	assert.Equal(t, shortDuration(30*time.Second), "30s")
+}
+
+// syncBuffer is a buffer that can be written and read from different goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestClient_Live(t *testing.T) {
+	s := hatest.NewServer("test_token")
+	defer s.Close()
+	viper.Set("url", s.URL)
+	viper.Set("api_key", "test_token")
+
+	c := New(Config{})
+	assert.NilError(t, c.Connect())
+	defer c.Close()
+
+	var out syncBuffer
+	done := make(chan error)
+	go func() { done <- c.Live(&out, "sensor.power", 5*time.Minute, false) }()
+
+	// Events are only sent once Live has subscribed.
+	for s.FireEvent("state_changed", stateChanged("sensor.power", "250", "W")) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.FireEvent("state_changed", stateChanged("sensor.fridge", "90", "W"))
+	s.FireEvent("state_changed", stateChanged("sensor.power", "2", "kW"))
+	for strings.Count(out.String(), "\n") < 2 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Stop()
+	assert.NilError(t, <-done)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, len(lines), 2)
+	assert.Assert(t, strings.Contains(lines[0], "Now 250 W   min 250 W   avg 250 W   max 250 W   (last 5m)"), lines[0])
+	assert.Assert(t, strings.Contains(lines[1], "Now 2000 W   min 250 W"), lines[1])
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/poolski/powertracker/cmd/i18n"
+)
+
+// writeMarkdown writes the days and their summaries as a GitHub-flavoured Markdown table, for
+// pasting into issues, wikis and notes. The summaries are the last rows, in bold, as Markdown
+// tables have no footer.
+func writeMarkdown(w io.Writer, results []Day, summaries []summary, headers []string, extra ...extraColumn) {
+	row := func(cells []string) {
+		for i, cell := range cells {
+			cells[i] = markdownCell(cell)
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+	}
+
+	all := withHeaders(headers, extra)
+	row(append([]string{i18n.T("Date")}, all...))
+	// Numbers are right-aligned, so their decimal points line up.
+	align := []string{"---"}
+	for range all {
+		align = append(align, "---:")
+	}
+	fmt.Fprintf(w, "| %s |\n", strings.Join(align, " | "))
+
+	for i, day := range results {
+		cells := []string{dateLabel(day)}
+		for _, v := range day.Values {
+			cells = append(cells, fmt.Sprintf("%f", v))
+		}
+		row(withValues(cells, extra, i))
+	}
+
+	for i, summary := range summaries {
+		cells := []string{"**" + summary.Label + "**"}
+		for _, v := range summary.Values {
+			cells = append(cells, fmt.Sprintf("**%f**", v))
+		}
+		for _, footer := range withFooters(nil, extra, i) {
+			if footer != "" {
+				footer = "**" + footer + "**"
+			}
+			cells = append(cells, footer)
+		}
+		row(cells)
+	}
+}
+
+// markdownCell escapes the pipes in a cell, which would otherwise end it.
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
@@ -0,0 +1,27 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestWriteMarkdown(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	results := []Day{
+		{Date: day, Values: []float64{0.5, 1}},
+		{Date: day.AddDate(0, 0, 1), Values: []float64{0.25, 2}},
+	}
+	cost := extraColumn{Header: "Cost", Values: []string{"£1|2", "£3"}, Footers: []string{"£2"}}
+
+	var buf bytes.Buffer
+	writeMarkdown(&buf, results, []summary{{Label: "Average", Values: []float64{0.375, 1.5}}}, []string{"00-12", "12-24"}, cost)
+	assert.Equal(t, buf.String(), `| Date | 00-12 | 12-24 | Cost |
+| --- | ---: | ---: | ---: |
+| 2024-03-01 | 0.500000 | 1.000000 | £1\|2 |
+| 2024-03-02 | 0.250000 | 2.000000 | £3 |
+| **Average** | **0.375000** | **1.500000** | **£2** |
+`)
+}
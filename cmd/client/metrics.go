@@ -0,0 +1,125 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// snapshot is the profile served on /metrics, as of its last refresh.
+type snapshot struct {
+	Sensor  string
+	Slots   []string
+	Means   []float64
+	Days    int
+	Updated time.Time
+}
+
+// metrics keeps the latest snapshot for /metrics, so scrapes don't wait for Home Assistant.
+type metrics struct {
+	mu       sync.Mutex
+	snapshot *snapshot
+	failures int
+}
+
+// refreshMetrics recomputes the profile straight away and then at every interval, until the
+// client is stopped.
+func (s *server) refreshMetrics(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.updateMetrics(); err != nil && !errors.Is(err, ErrInterrupted) {
+			s.client.logger().Error().Msg(fmt.Sprintf("refreshing metrics: %v", err))
+		}
+		select {
+		case <-s.client.stopped():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// updateMetrics replaces the snapshot with the average of each slot of the day over the
+// configured days. A failed refresh keeps the previous snapshot, and is counted.
+func (s *server) updateMetrics() error {
+	s.mu.Lock()
+	means, days, err := s.client.averages(s.client.days())
+	s.mu.Unlock()
+	if err != nil {
+		s.metrics.mu.Lock()
+		s.metrics.failures++
+		s.metrics.mu.Unlock()
+		return err
+	}
+
+	snap := &snapshot{Sensor: SensorID(), Slots: s.client.slotHeaders(), Means: means, Days: days, Updated: time.Now()}
+	s.metrics.mu.Lock()
+	s.metrics.snapshot = snap
+	s.metrics.mu.Unlock()
+	return nil
+}
+
+// prometheus serves the profile and the client's stats in the Prometheus text format. Until the
+// first refresh finishes, only the stats are served.
+func (s *server) prometheus(w http.ResponseWriter, r *http.Request) {
+	s.metrics.mu.Lock()
+	snap, failures := s.metrics.snapshot, s.metrics.failures
+	s.metrics.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetrics(w, snap, failures, s.client.Stats())
+}
+
+// writeMetrics writes the snapshot, if there is one, and the stats.
+func writeMetrics(w io.Writer, snap *snapshot, failures int, stats Stats) {
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	if snap != nil {
+		sensor := fmt.Sprintf(`sensor="%s"`, labelValue(snap.Sensor))
+		metric("powertracker_slot_average_kwh", "gauge", "Average consumption in each hour (or half hour) of the day, in kWh.")
+		var day float64
+		for i, v := range snap.Means {
+			if math.IsNaN(v) {
+				continue
+			}
+			day += v
+			fmt.Fprintf(w, "powertracker_slot_average_kwh{%s,slot=\"%s\"} %g\n", sensor, snap.Slots[i], v)
+		}
+		metric("powertracker_day_average_kwh", "gauge", "Average consumption in a day, in kWh.")
+		fmt.Fprintf(w, "powertracker_day_average_kwh{%s} %g\n", sensor, day)
+		metric("powertracker_profile_days", "gauge", "Number of days the averages are over.")
+		fmt.Fprintf(w, "powertracker_profile_days{%s} %d\n", sensor, snap.Days)
+		metric("powertracker_profile_updated_timestamp_seconds", "gauge", "When the averages were last worked out.")
+		fmt.Fprintf(w, "powertracker_profile_updated_timestamp_seconds{%s} %d\n", sensor, snap.Updated.Unix())
+	}
+
+	metric("powertracker_profile_refresh_failures_total", "counter", "Refreshes of the averages that failed.")
+	fmt.Fprintf(w, "powertracker_profile_refresh_failures_total %d\n", failures)
+	metric("powertracker_requests_total", "counter", "Requests for readings made to the source, including retries.")
+	fmt.Fprintf(w, "powertracker_requests_total %d\n", stats.Requests)
+	metric("powertracker_retries_total", "counter", "Requests that were retries of a failed one.")
+	fmt.Fprintf(w, "powertracker_retries_total %d\n", stats.Retries)
+	metric("powertracker_request_seconds_total", "counter", "Time spent waiting for requests to complete.")
+	fmt.Fprintf(w, "powertracker_request_seconds_total %g\n", stats.RequestTime.Seconds())
+	metric("powertracker_received_bytes_total", "counter", "Size of the responses read from Home Assistant.")
+	fmt.Fprintf(w, "powertracker_received_bytes_total %d\n", stats.BytesReceived)
+	metric("powertracker_cache_hits_total", "counter", "Days answered from the local cache.")
+	fmt.Fprintf(w, "powertracker_cache_hits_total %d\n", stats.CacheHits)
+	metric("powertracker_cache_misses_total", "counter", "Days that had to be fetched.")
+	fmt.Fprintf(w, "powertracker_cache_misses_total %d\n", stats.CacheMisses)
+	if !stats.LastFetch.IsZero() {
+		metric("powertracker_last_fetch_timestamp_seconds", "gauge", "When readings were last fetched without an error.")
+		fmt.Fprintf(w, "powertracker_last_fetch_timestamp_seconds %d\n", stats.LastFetch.Unix())
+	}
+}
+
+// labelValue escapes a label value for the Prometheus text format, which only escapes
+// backslashes, quotes and newlines.
+var labelValue = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace
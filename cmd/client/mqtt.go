@@ -0,0 +1,180 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/spf13/viper"
+)
+
+// metric is a computed value published over MQTT as a Home Assistant sensor.
+type metric struct {
+	ID          string
+	Name        string
+	Unit        string
+	DeviceClass string
+	Value       float64
+	// Attributes, if set, are published as JSON alongside the value, and show up as the sensor's
+	// attributes in Home Assistant.
+	Attributes map[string]any
+}
+
+// summaryMetrics computes the metrics published over MQTT: the average daily consumption, the
+// baseload (the lowest average hourly draw) and, given what each day cost, the bill projected for
+// 30 days at the average daily cost.
+func summaryMetrics(results []Day, averages []float64, costs []float64) []metric {
+	total := 0.0
+	for _, day := range results {
+		total += sum(day.Values)
+	}
+	baseload := averages[0]
+	for _, v := range averages {
+		if v < baseload {
+			baseload = v
+		}
+	}
+
+	metrics := []metric{
+		{ID: "daily_average", Name: "Daily average", Unit: "kWh", Value: total / float64(len(results))},
+		{ID: "baseload", Name: "Baseload", Unit: "W", DeviceClass: "power", Value: baseload * 1000},
+	}
+
+	if len(costs) > 0 {
+		metrics = append(metrics, metric{ID: "projected_bill", Name: "Projected bill", Value: sum(costs) / float64(len(costs)) * 30})
+	}
+	return metrics
+}
+
+// dailyMetrics returns the metrics for the days themselves: the total of the latest day, with
+// every day's total as its attributes, and unless withProfile is false, the average of each hour
+// of the day, for drawing the daily profile on a dashboard.
+func dailyMetrics(results []Day, averages []float64, withProfile bool) []metric {
+	var metrics []metric
+	if len(results) > 0 {
+		latest := results[0]
+		totals := make(map[string]any, len(results))
+		for _, day := range results {
+			if day.Date.After(latest.Date) {
+				latest = day
+			}
+			totals[day.Date.Format("2006-01-02")] = math.Round(sum(day.Values)*1000) / 1000
+		}
+		metrics = append(metrics, metric{ID: "daily_total", Name: "Daily total", Unit: "kWh", Value: sum(latest.Values), Attributes: map[string]any{
+			"date":   latest.Date.Format("2006-01-02"),
+			"totals": totals,
+		}})
+	}
+	if withProfile {
+		for h, v := range averages {
+			metrics = append(metrics, metric{ID: fmt.Sprintf("hour_%02d", h), Name: fmt.Sprintf("Average %02d:00", h), Unit: "kWh", Value: v})
+		}
+	}
+	return metrics
+}
+
+// discoveryConfig returns the Home Assistant MQTT discovery payload for a metric, so it appears
+// as a powertracker_* sensor without any manual configuration.
+func discoveryConfig(m metric, stateTopic string) ([]byte, error) {
+	config := map[string]any{
+		"name":        m.Name,
+		"unique_id":   "powertracker_" + m.ID,
+		"object_id":   "powertracker_" + m.ID,
+		"state_topic": stateTopic,
+		"state_class": "measurement",
+		"device": map[string]any{
+			"identifiers": []string{"powertracker"},
+			"name":        "powertracker",
+		},
+	}
+	if m.Unit != "" {
+		config["unit_of_measurement"] = m.Unit
+	}
+	if m.DeviceClass != "" {
+		config["device_class"] = m.DeviceClass
+	}
+	if m.Attributes != nil {
+		config["json_attributes_topic"] = stateTopic + "/attributes"
+	}
+	return json.Marshal(config)
+}
+
+// publishMQTT publishes the summary metrics, the daily totals and the average profile to an MQTT
+// broker as retained messages under the configured topic prefix. Unless disabled, Home Assistant
+// discovery configs are published first, so the metrics show up as sensors.
+func (c *Client) publishMQTT(results []Day, averages []float64) error {
+	broker := viper.GetString("mqtt.broker")
+	if broker == "" {
+		return fmt.Errorf("mqtt.broker is required")
+	}
+	viper.SetDefault("mqtt.topic_prefix", "powertracker")
+	viper.SetDefault("mqtt.discovery_prefix", "homeassistant")
+	viper.SetDefault("mqtt.discovery", true)
+	viper.SetDefault("mqtt.profile", true)
+
+	// The projected bill is what the days cost on the selected tariff, standing charges, tax and
+	// export credit included. It is left out if there are several tariffs and none is selected.
+	var costs []float64
+	if pricesConfigured() {
+		if _, err := selectedTariff(); err != nil {
+			c.logger().Warn().Msgf("not publishing the projected bill: %s", err)
+		} else if costs, err = c.dailyBills(results); err != nil {
+			return fmt.Errorf("computing the projected bill: %w", err)
+		}
+	}
+	metrics := summaryMetrics(results, averages, costs)
+	metrics = append(metrics, dailyMetrics(results, averages, viper.GetBool("mqtt.profile"))...)
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID("powertracker").
+		SetUsername(viper.GetString("mqtt.username")).
+		SetPassword(viper.GetString("mqtt.password")).
+		SetConnectTimeout(10 * time.Second)
+	client := mqtt.NewClient(opts)
+	if token := client.Connect(); token.Wait() && token.Error() != nil {
+		return fmt.Errorf("connecting to %s: %w", broker, token.Error())
+	}
+	defer client.Disconnect(250)
+
+	publish := func(topic string, payload []byte) error {
+		token := client.Publish(topic, 1, true, payload)
+		token.Wait()
+		if err := token.Error(); err != nil {
+			return fmt.Errorf("publishing to %s: %w", topic, err)
+		}
+		return nil
+	}
+
+	prefix := viper.GetString("mqtt.topic_prefix")
+	for _, m := range metrics {
+		stateTopic := prefix + "/" + m.ID
+		if viper.GetBool("mqtt.discovery") {
+			config, err := discoveryConfig(m, stateTopic)
+			if err != nil {
+				return fmt.Errorf("encoding discovery config: %w", err)
+			}
+			topic := fmt.Sprintf("%s/sensor/powertracker_%s/config", viper.GetString("mqtt.discovery_prefix"), m.ID)
+			if err := publish(topic, config); err != nil {
+				return err
+			}
+		}
+		if m.Attributes != nil {
+			attributes, err := json.Marshal(m.Attributes)
+			if err != nil {
+				return fmt.Errorf("encoding attributes: %w", err)
+			}
+			if err := publish(stateTopic+"/attributes", attributes); err != nil {
+				return err
+			}
+		}
+		if err := publish(stateTopic, []byte(strconv.FormatFloat(m.Value, 'f', 3, 64))); err != nil {
+			return err
+		}
+	}
+	c.logger().Info().Msgf("published %d metrics to %s", len(metrics), broker)
+	return nil
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestSummaryMetrics(t *testing.T) {
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	results := []Day{
+		{Date: day, Values: []float64{0.25, 1}},
+		{Date: day.Add(24 * time.Hour), Values: []float64{0.75, 2}},
+	}
+	averages := []float64{0.5, 1.5}
+	metrics := summaryMetrics(results, averages, []float64{0.25, 0.55})
+	assert.DeepEqual(t, metrics, []metric{
+		{ID: "daily_average", Name: "Daily average", Unit: "kWh", Value: 2},
+		{ID: "baseload", Name: "Baseload", Unit: "W", DeviceClass: "power", Value: 500},
+		{ID: "projected_bill", Name: "Projected bill", Value: 12},
+	})
+
+	metrics = summaryMetrics(results, averages, nil)
+	assert.Equal(t, len(metrics), 2, "expected no projected bill without prices")
+}
+
+func TestDailyMetrics(t *testing.T) {
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	results := []Day{
+		{Date: day.Add(24 * time.Hour), Values: []float64{0.75, 2}},
+		{Date: day, Values: []float64{0.25, 1}},
+	}
+	averages := []float64{0.5, 1.375}
+
+	metrics := dailyMetrics(results, averages, true)
+	assert.DeepEqual(t, metrics, []metric{
+		{ID: "daily_total", Name: "Daily total", Unit: "kWh", Value: 2.75, Attributes: map[string]any{
+			"date":   "2023-09-02",
+			"totals": map[string]any{"2023-09-01": 1.25, "2023-09-02": 2.75},
+		}},
+		{ID: "hour_00", Name: "Average 00:00", Unit: "kWh", Value: 0.5},
+		{ID: "hour_01", Name: "Average 01:00", Unit: "kWh", Value: 1.375},
+	})
+
+	metrics = dailyMetrics(results, averages, false)
+	assert.Equal(t, len(metrics), 1, "expected no profile")
+	assert.Equal(t, len(dailyMetrics(nil, nil, true)), 0)
+}
+
+func TestDiscoveryConfig(t *testing.T) {
+	b, err := discoveryConfig(metric{ID: "baseload", Name: "Baseload", Unit: "W", DeviceClass: "power"}, "powertracker/baseload")
+	assert.NilError(t, err)
+
+	var config map[string]any
+	assert.NilError(t, json.Unmarshal(b, &config))
+	assert.Equal(t, config["unique_id"], "powertracker_baseload")
+	assert.Equal(t, config["state_topic"], "powertracker/baseload")
+	assert.Equal(t, config["unit_of_measurement"], "W")
+	assert.Equal(t, config["device_class"], "power")
+	_, ok := config["json_attributes_topic"]
+	assert.Assert(t, !ok, "expected no attributes topic")
+
+	b, err = discoveryConfig(metric{ID: "daily_total", Attributes: map[string]any{}}, "powertracker/daily_total")
+	assert.NilError(t, err)
+	assert.NilError(t, json.Unmarshal(b, &config))
+	assert.Equal(t, config["json_attributes_topic"], "powertracker/daily_total/attributes")
+}
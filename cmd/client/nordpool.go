@@ -0,0 +1,99 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var nordpoolURL = "https://dataportal-api.nordpoolgroup.com/api"
+
+// nordpool fetches day-ahead spot prices for a delivery area from the Nord Pool data portal.
+// Prices are published per MWh in the configured currency, and converted to per kWh.
+type nordpool struct {
+	area     string
+	currency string
+}
+
+func newNordpool() (*nordpool, error) {
+	n := &nordpool{
+		area:     viper.GetString("prices.nordpool.area"),
+		currency: viper.GetString("prices.nordpool.currency"),
+	}
+	if n.area == "" {
+		return nil, fmt.Errorf("prices.nordpool.area is required")
+	}
+	if n.currency == "" {
+		n.currency = "EUR"
+	}
+	return n, nil
+}
+
+func (n *nordpool) Prices(start, end time.Time) ([]Price, error) {
+	// Prices are published per CET delivery day, which starts an hour or two before midnight
+	// UTC, so the last delivery day needed may be the one after the UTC date of the end.
+	var prices []Price
+	last := end.Add(2*time.Hour - time.Nanosecond).UTC().Truncate(24 * time.Hour)
+	for date := start.UTC().Truncate(24 * time.Hour); !date.After(last); date = date.Add(24 * time.Hour) {
+		entries, err := n.day(date)
+		if err != nil {
+			return nil, fmt.Errorf("nordpool: %s: %w", date.Format("2006-01-02"), err)
+		}
+		for _, p := range entries {
+			if p.End.After(start) && p.Start.Before(end) {
+				prices = append(prices, p)
+			}
+		}
+	}
+	if len(prices) == 0 {
+		return nil, fmt.Errorf("nordpool: no prices returned for area %s", n.area)
+	}
+	return prices, nil
+}
+
+func (n *nordpool) day(date time.Time) ([]Price, error) {
+	params := url.Values{
+		"date":         {date.Format("2006-01-02")},
+		"market":       {"DayAhead"},
+		"deliveryArea": {n.area},
+		"currency":     {n.currency},
+	}
+	resp, err := httpClient.Get(nordpoolURL + "/DayAheadPrices?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// No content is returned for days that haven't been published yet.
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	var data struct {
+		MultiAreaEntries []struct {
+			DeliveryStart time.Time          `json:"deliveryStart"`
+			DeliveryEnd   time.Time          `json:"deliveryEnd"`
+			EntryPerArea  map[string]float64 `json:"entryPerArea"`
+		} `json:"multiAreaEntries"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	prices := make([]Price, 0, len(data.MultiAreaEntries))
+	for _, entry := range data.MultiAreaEntries {
+		rate, ok := entry.EntryPerArea[n.area]
+		if !ok {
+			continue
+		}
+		prices = append(prices, Price{Start: entry.DeliveryStart, End: entry.DeliveryEnd, Rate: rate / 1000})
+	}
+	return prices, nil
+}
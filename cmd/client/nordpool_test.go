@@ -0,0 +1,47 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestNordpool_Prices(t *testing.T) {
+	var dates []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		assert.Equal(t, q.Get("deliveryArea"), "NO1", "unexpected area")
+		assert.Equal(t, q.Get("currency"), "NOK", "unexpected currency")
+		dates = append(dates, q.Get("date"))
+		if q.Get("date") != "2023-09-01" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		_, _ = w.Write([]byte(`{"multiAreaEntries":[
+			{"deliveryStart":"2023-08-31T22:00:00Z","deliveryEnd":"2023-08-31T23:00:00Z","entryPerArea":{"NO1":350.5}},
+			{"deliveryStart":"2023-08-31T23:00:00Z","deliveryEnd":"2023-09-01T00:00:00Z","entryPerArea":{"NO1":420}},
+			{"deliveryStart":"2023-09-01T00:00:00Z","deliveryEnd":"2023-09-01T01:00:00Z","entryPerArea":{"NO1":500}}
+		]}`))
+	}))
+	defer s.Close()
+
+	nordpoolURL = s.URL
+	viper.Set("prices.nordpool.area", "NO1")
+	viper.Set("prices.nordpool.currency", "NOK")
+
+	n, err := newNordpool()
+	assert.NilError(t, err)
+
+	start := time.Date(2023, 8, 31, 23, 0, 0, 0, time.UTC)
+	prices, err := n.Prices(start, start.Add(2*time.Hour))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, dates, []string{"2023-08-31", "2023-09-01"})
+	assert.DeepEqual(t, prices, []Price{
+		{Start: start, End: start.Add(time.Hour), Rate: 0.42},
+		{Start: start.Add(time.Hour), End: start.Add(2 * time.Hour), Rate: 0.5},
+	})
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/powertracker/cmd/i18n"
+)
+
+// zeroUsage is the most a slot can use and still count as no usage at all. Even a home that is
+// asleep has a fridge and standby loads, so genuinely low usage stays above it.
+const zeroUsage = 0.001
+
+// Causes of a gap in the usage.
+const (
+	causeOutage   = "power outage"
+	causeDowntime = "Home Assistant downtime"
+	causeUnknown  = "unknown"
+)
+
+// outage is a window in which the whole home used nothing.
+type outage struct {
+	Start time.Time
+	End   time.Time
+	Cause string
+}
+
+// findOutages returns the windows of at least an hour in which no usage was recorded. Results are
+// in any order, and each holds slots of the given width.
+//
+// When Home Assistant is down, the meter keeps counting, so the first slot after it comes back
+// holds everything used in the meantime. A gap followed by such a catch-up is put down to
+// downtime, while one without is more likely a power cut. A gap at the end of the results can't
+// be told apart.
+func findOutages(results []Day, width time.Duration) []outage {
+	days := make([]Day, len(results))
+	copy(days, results)
+	sort.Slice(days, func(i, j int) bool { return days[i].Date.Before(days[j].Date) })
+
+	type slot struct {
+		start, end time.Time
+		value      float64
+	}
+	var slots []slot
+	var used []float64
+	for _, day := range days {
+		for i, v := range day.Values {
+			start, ok := slotStart(day.Date, i, width)
+			if !ok {
+				continue
+			}
+			slots = append(slots, slot{start, slotEnd(day.Date, i, width), v})
+			if v > zeroUsage {
+				used = append(used, v)
+			}
+		}
+	}
+	typical := average(used)
+
+	var outages []outage
+	minSlots := int(time.Hour / width)
+	for i := 0; i < len(slots); {
+		if slots[i].value > zeroUsage {
+			i++
+			continue
+		}
+		j := i
+		for j < len(slots) && slots[j].value <= zeroUsage {
+			j++
+		}
+		if j-i >= minSlots {
+			o := outage{Start: slots[i].start, End: slots[j-1].end, Cause: causeUnknown}
+			if j < len(slots) {
+				// Half of what would normally have been used is enough to count as catching up.
+				o.Cause = causeOutage
+				if slots[j].value >= typical*float64(j-i+1)/2 {
+					o.Cause = causeDowntime
+				}
+			}
+			outages = append(outages, o)
+		}
+		i = j
+	}
+	return outages
+}
+
+// printOutages prints the windows in which no usage was recorded, and what probably caused them.
+func printOutages(results []Day, width time.Duration) {
+	outages := findOutages(results, width)
+	if len(outages) == 0 {
+		fmt.Println(i18n.T("No gaps in usage found."))
+		return
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{i18n.T("Start"), i18n.T("End"), i18n.T("Hours"), i18n.T("Likely cause")})
+	for _, o := range outages {
+		table.Append([]string{
+			o.Start.Format("2006-01-02 15:04"),
+			o.End.Format("2006-01-02 15:04"),
+			fmt.Sprintf("%.1f", o.End.Sub(o.Start).Hours()),
+			i18n.T(o.Cause),
+		})
+	}
+	table.Render()
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestFindOutages(t *testing.T) {
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	first := make([]float64, hoursInADay)
+	second := make([]float64, hoursInADay)
+	for h := range first {
+		first[h], second[h] = 0.5, 0.5
+	}
+	// A power cut from 02:00 to 05:00, with no catch-up afterwards.
+	first[2], first[3], first[4] = 0, 0, 0
+	// Home Assistant down from 10:00 to 12:00, then catching up on what was used meanwhile.
+	first[10], first[11], first[12] = 0, 0, 1.5
+	// Genuinely low usage is not a gap.
+	first[20] = 0.05
+	// Nothing recorded at the end, which can't be explained yet.
+	second[22], second[23] = 0, 0
+
+	// Results run most recent day first.
+	outages := findOutages([]Day{{Date: day.Add(24 * time.Hour), Values: second}, {Date: day, Values: first}}, time.Hour)
+	assert.DeepEqual(t, outages, []outage{
+		{Start: day.Add(2 * time.Hour), End: day.Add(5 * time.Hour), Cause: causeOutage},
+		{Start: day.Add(10 * time.Hour), End: day.Add(12 * time.Hour), Cause: causeDowntime},
+		{Start: day.Add(46 * time.Hour), End: day.Add(48 * time.Hour), Cause: causeUnknown},
+	})
+
+	// In half hours, a single empty half hour is too short to count.
+	halves := make([]float64, halfHoursInADay)
+	for i := range halves {
+		halves[i] = 0.25
+	}
+	halves[5] = 0
+	assert.Equal(t, len(findOutages([]Day{{Date: day, Values: halves}}, 30*time.Minute)), 0)
+}
+
+func TestDuring(t *testing.T) {
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, during(day.Add(2*time.Hour), day.Add(5*time.Hour)), "between 02:00 and 05:00 on Friday 1 September")
+	assert.Equal(t, during(day.Add(22*time.Hour), day.Add(24*time.Hour)), "between 22:00 and 00:00 on Friday 1 September")
+	assert.Equal(t, during(day.Add(22*time.Hour), day.Add(26*time.Hour)), "from 22:00 on Friday 1 September until 02:00 on Saturday 2 September")
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/apache/arrow/go/v12/arrow"
+	"github.com/apache/arrow/go/v12/arrow/array"
+	"github.com/apache/arrow/go/v12/arrow/memory"
+	"github.com/apache/arrow/go/v12/parquet"
+	"github.com/apache/arrow/go/v12/parquet/compress"
+	"github.com/apache/arrow/go/v12/parquet/pqarrow"
+)
+
+// hourlySchema is the layout of the columnar exports: one row per hour.
+var hourlySchema = arrow.NewSchema([]arrow.Field{
+	{Name: "sensor_id", Type: arrow.BinaryTypes.String},
+	{Name: "start", Type: &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"}},
+	{Name: "kwh", Type: arrow.PrimitiveTypes.Float64},
+}, nil)
+
+// hourlyRecord builds an Arrow record holding a row for each hourly value in the results.
+// The caller must release the record.
+func hourlyRecord(results []Day) arrow.Record {
+	b := array.NewRecordBuilder(memory.DefaultAllocator, hourlySchema)
+	defer b.Release()
+
+	sensorID := SensorID()
+	ids := b.Field(0).(*array.StringBuilder)
+	starts := b.Field(1).(*array.TimestampBuilder)
+	values := b.Field(2).(*array.Float64Builder)
+	for _, day := range results {
+		for i, v := range day.Values {
+			start, ok := slotStart(day.Date, i, time.Hour)
+			if !ok {
+				continue
+			}
+			ids.Append(sensorID)
+			starts.Append(arrow.Timestamp(start.UnixMilli()))
+			values.Append(v)
+		}
+	}
+	return b.NewRecord()
+}
+
+// writeParquet writes the results to a Parquet file with a row per hour, which DuckDB, pandas
+// and most other analysis tools can query directly, e.g.
+//
+//	SELECT date_trunc('month', start) AS month, sum(kwh) FROM 'results.parquet' GROUP BY 1;
+func (c *Client) writeParquet(results []Day) error {
+	rec := hourlyRecord(results)
+	defer rec.Release()
+
+	f, err := os.Create(c.outputFile(".parquet"))
+	if err != nil {
+		return fmt.Errorf("creating file: %w", err)
+	}
+
+	props := parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy))
+	w, err := pqarrow.NewFileWriter(hourlySchema, f, props, pqarrow.DefaultWriterProps())
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("creating writer: %w", err)
+	}
+	if err := w.Write(rec); err != nil {
+		w.Close()
+		return fmt.Errorf("writing rows: %w", err)
+	}
+	// Closing the writer writes the footer and closes the file.
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("closing file: %w", err)
+	}
+	return nil
+}
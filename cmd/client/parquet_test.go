@@ -0,0 +1,44 @@
+package client
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v12/arrow"
+	"github.com/apache/arrow/go/v12/arrow/array"
+	"github.com/apache/arrow/go/v12/arrow/memory"
+	"github.com/apache/arrow/go/v12/parquet/file"
+	"github.com/apache/arrow/go/v12/parquet/pqarrow"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestClient_WriteParquet(t *testing.T) {
+	viper.Set("sensor_id", "sensor.energy")
+	path := filepath.Join(t.TempDir(), "results.parquet")
+	client := New(Config{FilePath: path})
+
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	err := client.writeParquet([]Day{{Date: day, Values: []float64{0.5, 1.25}}})
+	assert.NilError(t, err)
+
+	f, err := os.Open(path)
+	assert.NilError(t, err)
+	defer f.Close()
+	pf, err := file.NewParquetReader(f)
+	assert.NilError(t, err)
+	r, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	assert.NilError(t, err)
+	table, err := r.ReadTable(context.Background())
+	assert.NilError(t, err)
+	defer table.Release()
+
+	assert.Equal(t, table.NumRows(), int64(2))
+	starts := table.Column(1).Data().Chunk(0).(*array.Timestamp)
+	assert.Equal(t, starts.Value(1), arrow.Timestamp(day.Add(time.Hour).UnixMilli()))
+	values := table.Column(2).Data().Chunk(0).(*array.Float64)
+	assert.Equal(t, values.Value(1), 1.25)
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/poolski/powertracker/cmd/i18n"
+)
+
+// stat summarises the values of a slot over the days reported on: their mean, or a percentile,
+// which the odd unusual day doesn't pull about.
+type stat struct {
+	// q is the quantile of a percentile, from 0 to 1, or NaN for the mean.
+	q     float64
+	label string
+}
+
+// parseStats parses the names of the statistics to report: mean, median, or a percentile such as
+// p95. With none, the mean is reported.
+func parseStats(names []string) ([]stat, error) {
+	if len(names) == 0 {
+		names = []string{"mean"}
+	}
+	stats := make([]stat, len(names))
+	for i, name := range names {
+		s, err := parseStat(strings.ToLower(strings.TrimSpace(name)))
+		if err != nil {
+			return nil, err
+		}
+		stats[i] = s
+	}
+	return stats, nil
+}
+
+func parseStat(name string) (stat, error) {
+	switch name {
+	case "mean", "average":
+		return stat{q: math.NaN(), label: i18n.T("Average")}, nil
+	case "median":
+		return stat{q: 0.5, label: i18n.T("Median")}, nil
+	}
+	if p, err := strconv.ParseFloat(strings.TrimPrefix(name, "p"), 64); err == nil && strings.HasPrefix(name, "p") && p >= 0 && p <= 100 {
+		return stat{q: p / 100, label: strings.ToUpper(name)}, nil
+	}
+	return stat{}, fmt.Errorf("unknown statistic %q - use mean, median or a percentile such as p95", name)
+}
+
+func (s stat) isMean() bool {
+	return math.IsNaN(s.q)
+}
+
+// of returns the statistic of the values, or NaN if there aren't any. Percentiles are interpolated
+// between the two nearest values, as spreadsheets do.
+func (s stat) of(values []float64) float64 {
+	if s.isMean() {
+		if len(values) == 0 {
+			return math.NaN()
+		}
+		return sum(values) / float64(len(values))
+	}
+	sorted := make([]float64, 0, len(values))
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			sorted = append(sorted, v)
+		}
+	}
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	sort.Float64s(sorted)
+	pos := s.q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	if lo == len(sorted)-1 {
+		return sorted[lo]
+	}
+	return sorted[lo] + (pos-float64(lo))*(sorted[lo+1]-sorted[lo])
+}
+
+// profile returns the statistic of each slot over the days.
+func (s stat) profile(results []Day, slots int) []float64 {
+	if s.isMean() {
+		p := newProfile(slots)
+		for _, day := range results {
+			p.add(day)
+		}
+		return p.means()
+	}
+	profile := make([]float64, slots)
+	for i := range profile {
+		var values []float64
+		for _, day := range results {
+			if i < len(day.Values) {
+				values = append(values, day.Values[i])
+			}
+		}
+		profile[i] = s.of(values)
+	}
+	return profile
+}
+
+// summary is a row after the days of the table, CSV and Markdown outputs, with a statistic of
+// each slot.
+type summary struct {
+	Label  string
+	Values []float64
+}
+
+// summaries returns a summary row for each statistic.
+func summaries(results []Day, slots int, stats []stat) []summary {
+	rows := make([]summary, len(stats))
+	for i, s := range stats {
+		rows[i] = summary{Label: s.label, Values: s.profile(results, slots)}
+	}
+	return rows
+}
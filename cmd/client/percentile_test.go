@@ -0,0 +1,93 @@
+package client
+
+import (
+	"encoding/csv"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestParseStats(t *testing.T) {
+	stats, err := parseStats(nil)
+	assert.NilError(t, err)
+	assert.Equal(t, len(stats), 1)
+	assert.Assert(t, stats[0].isMean())
+	assert.Equal(t, stats[0].label, "Average")
+
+	stats, err = parseStats([]string{"median", " P95 ", "p99.9"})
+	assert.NilError(t, err)
+	assert.Equal(t, stats[0].q, 0.5)
+	assert.Equal(t, stats[0].label, "Median")
+	assert.Equal(t, stats[1].q, 0.95)
+	assert.Equal(t, stats[1].label, "P95")
+	assert.Equal(t, stats[2].label, "P99.9")
+
+	for _, name := range []string{"mode", "p101", "p-1", "95"} {
+		_, err := parseStats([]string{name})
+		assert.ErrorContains(t, err, "unknown statistic", name)
+	}
+}
+
+func TestStat_Of(t *testing.T) {
+	values := []float64{4, 1, 3, 2, math.NaN()}
+	for name, want := range map[string]float64{"median": 2.5, "p0": 1, "p25": 1.75, "p75": 3.25, "p100": 4} {
+		s, err := parseStat(name)
+		assert.NilError(t, err)
+		assert.Equal(t, s.of(values), want, name)
+	}
+
+	mean, _ := parseStat("mean")
+	assert.Equal(t, mean.of([]float64{1, 2, 6}), 3.0)
+	assert.Assert(t, math.IsNaN(mean.of(nil)))
+	median, _ := parseStat("median")
+	assert.Assert(t, math.IsNaN(median.of([]float64{math.NaN()})))
+}
+
+func TestStat_Profile(t *testing.T) {
+	// The odd day with the car on charge pulls the mean up, but not the median.
+	results := []Day{
+		{Values: []float64{1, 2}},
+		{Values: []float64{1, 2}},
+		{Values: []float64{10, 2}},
+	}
+	rows := summaries(results, 2, []stat{{q: math.NaN(), label: "Average"}, {q: 0.5, label: "Median"}})
+	assert.DeepEqual(t, rows, []summary{
+		{Label: "Average", Values: []float64{4, 2}},
+		{Label: "Median", Values: []float64{1, 2}},
+	})
+}
+
+func TestClient_ComputePowerStats_Statistics(t *testing.T) {
+	viper.Set("sensor_id", "sensor.energy")
+
+	yesterday := time.Now().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	var readings []Reading
+	for d := 0; d < 3; d++ {
+		for h := 0; h < hoursInADay; h++ {
+			readings = append(readings, Reading{Start: yesterday.AddDate(0, 0, d-2).Add(time.Duration(h) * time.Hour), Value: float64(d + 1)})
+		}
+	}
+	path := filepath.Join(t.TempDir(), "results.csv")
+	c := New(Config{Days: 3, Output: "csv", FilePath: path, Statistics: []string{"median", "p100"}})
+	c.source = fakeSource{"sensor.energy": readings}
+	c.ComputePowerStats()
+
+	f, err := os.Open(path)
+	assert.NilError(t, err)
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	assert.NilError(t, err)
+	assert.Equal(t, len(rows), 6)
+	last := func(row []string) string { return row[len(row)-1] }
+	assert.Equal(t, rows[4][0], "Median")
+	assert.Equal(t, rows[4][1], "2.000000")
+	assert.Equal(t, last(rows[4]), "48.000000")
+	assert.Equal(t, rows[5][0], "P100")
+	assert.Equal(t, rows[5][1], "3.000000")
+	assert.Equal(t, last(rows[5]), "72.000000")
+}
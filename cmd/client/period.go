@@ -0,0 +1,71 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// fiveMinutesInADay is the number of slots in a day with --period 5minute.
+const fiveMinutesInADay = 288
+
+// checkPeriod checks the period given with --period: 5minute, hour, day, week or month.
+func checkPeriod(period string, halfHourly bool) error {
+	switch period {
+	case "", "hour":
+		return nil
+	case "5minute", "day", "week", "month":
+		if halfHourly {
+			return fmt.Errorf("--period can't be combined with --half-hourly")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown period %q - use 5minute, hour, day, week or month", period)
+}
+
+// totalsOnly reports whether each row is a day, week or month with just its total, rather than a
+// day divided into slots.
+func (c *Client) totalsOnly() bool {
+	switch c.Config.Period {
+	case "day", "week", "month":
+		return true
+	}
+	return false
+}
+
+// hourly reports whether each day is divided into hours, which everything other than the plain
+// layouts assumes.
+func (c *Client) hourly() bool {
+	width, _ := c.slots()
+	return width == time.Hour && !c.totalsOnly()
+}
+
+// mode describes the slots other than hours, for saying what isn't supported with them.
+func (c *Client) mode() string {
+	if c.Config.HalfHourly {
+		return "in half-hourly mode"
+	}
+	return "with --period " + c.Config.Period
+}
+
+// rollUp returns a row for each day, week or month of the results with its total, most recent
+// first like the results. Weeks start on Monday, months are billing cycles starting on billingDay,
+// labelled as billingLabel does, and days run from midnight in the time zone of the results. The
+// first and last rows may only cover part of a week or month.
+func rollUp(results []Day, period string, billingDay int) []Day {
+	var rows []Day
+	for _, day := range results {
+		start, label := day.Date, ""
+		switch period {
+		case "week":
+			start = start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+		case "month":
+			start = billingMonth(start, billingDay)
+			label = billingLabel(start, billingDay)
+		}
+		if len(rows) == 0 || !rows[len(rows)-1].Date.Equal(start) {
+			rows = append(rows, Day{Date: start, Values: []float64{0}, Label: label})
+		}
+		rows[len(rows)-1].Values[0] += sum(day.Values)
+	}
+	return rows
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestCheckPeriod(t *testing.T) {
+	for _, period := range []string{"", "5minute", "hour", "day", "week", "month"} {
+		assert.NilError(t, checkPeriod(period, false), period)
+	}
+	assert.ErrorContains(t, checkPeriod("year", false), `unknown period "year"`)
+	assert.ErrorContains(t, checkPeriod("5minute", true), "--period can't be combined with --half-hourly")
+	assert.NilError(t, checkPeriod("hour", true))
+}
+
+func TestRollUp(t *testing.T) {
+	// Wednesday 1 November back to Sunday 29 October, most recent first.
+	var results []Day
+	for i := 0; i < 4; i++ {
+		date := time.Date(2023, 11, 1-i, 0, 0, 0, 0, time.UTC)
+		results = append(results, Day{Date: date, Values: []float64{float64(i + 1), 0.5}})
+	}
+
+	assert.DeepEqual(t, rollUp(results, "day", 1), []Day{
+		{Date: time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC), Values: []float64{1.5}},
+		{Date: time.Date(2023, 10, 31, 0, 0, 0, 0, time.UTC), Values: []float64{2.5}},
+		{Date: time.Date(2023, 10, 30, 0, 0, 0, 0, time.UTC), Values: []float64{3.5}},
+		{Date: time.Date(2023, 10, 29, 0, 0, 0, 0, time.UTC), Values: []float64{4.5}},
+	})
+	assert.DeepEqual(t, rollUp(results, "week", 1), []Day{
+		{Date: time.Date(2023, 10, 30, 0, 0, 0, 0, time.UTC), Values: []float64{7.5}},
+		{Date: time.Date(2023, 10, 23, 0, 0, 0, 0, time.UTC), Values: []float64{4.5}},
+	})
+	assert.DeepEqual(t, rollUp(results, "month", 1), []Day{
+		{Date: time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC), Values: []float64{1.5}, Label: "2023-11"},
+		{Date: time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC), Values: []float64{10.5}, Label: "2023-10"},
+	})
+	// Months follow the billing cycle.
+	assert.DeepEqual(t, rollUp(results, "month", 31), []Day{
+		{Date: time.Date(2023, 10, 31, 0, 0, 0, 0, time.UTC), Values: []float64{4}, Label: "2023-10-31"},
+		{Date: time.Date(2023, 9, 30, 0, 0, 0, 0, time.UTC), Values: []float64{8}, Label: "2023-09-30"},
+	})
+}
+
+func TestGetResults_FiveMinutes(t *testing.T) {
+	day := time.Now().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	var readings []Reading
+	for i := 0; i < fiveMinutesInADay; i++ {
+		readings = append(readings, Reading{Start: day.Add(time.Duration(i) * fiveMinutes), Value: float64(i)})
+	}
+	viper.Set("sensor_id", "sensor.energy")
+
+	c := New(Config{Days: 1, Period: "5minute"})
+	c.source = fakeSource{"sensor.energy": readings}
+	results, err := getResults(c)
+	assert.NilError(t, err)
+	assert.Equal(t, len(results[0].Values), fiveMinutesInADay)
+	assert.Equal(t, results[0].Values[1], 1.0)
+	assert.Equal(t, results[0].Values[287], 287.0)
+
+	headers := c.slotHeaders()
+	assert.Equal(t, headers[1], "00:05")
+	assert.Equal(t, headers[287], "23:55")
+}
@@ -0,0 +1,162 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/powertracker/cmd/i18n"
+	"github.com/spf13/viper"
+)
+
+// phaseResults returns the days of each phase in phase_sensor_ids, in order. If the client is
+// stopped or the fetch fails part way through, each phase has the days fetched for every phase,
+// along with ErrInterrupted or ErrIncomplete.
+func (c *Client) phaseResults(phases []string, days int) ([][]Day, error) {
+	if len(phases) < 2 {
+		return nil, fmt.Errorf("phase_sensor_ids needs a sensor for each phase")
+	}
+	var interrupted error
+	results := make([][]Day, len(phases))
+	for i, id := range phases {
+		r, err := c.sensorResults(id, days)
+		if partial(err) {
+			interrupted = err
+		} else if err != nil {
+			return nil, fmt.Errorf("phase %s: %w", id, err)
+		}
+		results[i] = r
+		if interrupted != nil {
+			break
+		}
+	}
+	// Only the days every phase has can be compared or added up: one may have been left out of a
+	// phase, or the fetch stopped before the rest were read.
+	counts := make(map[int64]int)
+	for _, phase := range results {
+		for _, day := range phase {
+			counts[day.Date.Unix()]++
+		}
+	}
+	for i, phase := range results {
+		var kept []Day
+		for _, day := range phase {
+			if counts[day.Date.Unix()] == len(phases) {
+				kept = append(kept, day)
+			}
+		}
+		results[i] = kept
+	}
+	return results, interrupted
+}
+
+// combinedResults returns the consumption of the phases added together, for homes with a sensor
+// for each phase of a three-phase supply but none for the whole.
+func (c *Client) combinedResults(phases []string, days int) ([]Day, error) {
+	results, err := c.phaseResults(phases, days)
+	if results == nil {
+		return nil, err
+	}
+	return addPhases(results), err
+}
+
+// addPhases adds up the phases, which have the same days in the same order.
+func addPhases(phases [][]Day) []Day {
+	total := make([]Day, len(phases[0]))
+	for i, day := range phases[0] {
+		total[i] = Day{Date: day.Date, Values: make([]float64, len(day.Values))}
+		for _, phase := range phases {
+			for j, v := range phase[i].Values {
+				total[i].Values[j] += v
+			}
+		}
+	}
+	return total
+}
+
+// imbalance returns how unevenly the load is spread across the phases: the largest difference
+// between a phase and the mean of the phases, as a percentage of the mean. It is 0 when the
+// phases are perfectly balanced.
+func imbalance(loads []float64) float64 {
+	var mean float64
+	for _, l := range loads {
+		mean += l
+	}
+	mean /= float64(len(loads))
+	if mean == 0 {
+		return 0
+	}
+	var worst float64
+	for _, l := range loads {
+		worst = math.Max(worst, math.Abs(l-mean))
+	}
+	return worst / mean * 100
+}
+
+// phaseName names the i'th phase in tables, L1, L2 and so on.
+func phaseName(i int) string {
+	return fmt.Sprintf("L%d", i+1)
+}
+
+// printPhases prints the hourly consumption of each phase, followed by the average of each hour of
+// the day on every phase, their total, and how imbalanced the phases are.
+func (c *Client) printPhases() error {
+	phases := viper.GetStringSlice("phase_sensor_ids")
+	if len(phases) == 0 {
+		return fmt.Errorf("phase_sensor_ids is required")
+	}
+	results, err := c.phaseResults(phases, c.days())
+	if errors.Is(err, ErrInterrupted) && len(results[0]) > 0 {
+		c.logger().Warn().Msg(fmt.Sprintf("interrupted - reporting on the %d days fetched in full", len(results[0])))
+	} else if errors.Is(err, ErrIncomplete) && len(results[0]) > 0 {
+		c.logger().Error().Msg(fmt.Sprintf("comparing phases: %v - reporting on the %d days fetched in full; run again with --resume to fetch the rest", err, len(results[0])))
+	} else if err != nil {
+		return err
+	}
+
+	_, slots := c.slots()
+	headers := c.slotHeaders()
+	averages := make([][]float64, len(phases))
+	for i, phase := range results {
+		c.fixSpikes(phase)
+		p := newProfile(slots)
+		for _, day := range phase {
+			p.add(day)
+		}
+		averages[i] = p.means()
+
+		fmt.Printf("%s (%s)\n", phaseName(i), phases[i])
+		printTable(phase, []summary{{Label: i18n.T("Average"), Values: averages[i]}}, headers)
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	header := []string{i18n.T("Hour")}
+	for i := range phases {
+		header = append(header, phaseName(i))
+	}
+	table.SetHeader(append(header, i18n.T("Total"), i18n.T("Imbalance %")))
+	totals := make([]float64, len(phases))
+	for h := 0; h < slots; h++ {
+		row := []string{headers[h]}
+		loads := make([]float64, len(phases))
+		var total float64
+		for i := range phases {
+			loads[i] = averages[i][h]
+			totals[i] += loads[i]
+			total += loads[i]
+			row = append(row, fmt.Sprintf("%f", loads[i]))
+		}
+		table.Append(append(row, fmt.Sprintf("%f", total), fmt.Sprintf("%.1f", imbalance(loads))))
+	}
+	footer := []string{i18n.T("Day")}
+	var total float64
+	for _, t := range totals {
+		total += t
+		footer = append(footer, fmt.Sprintf("%f", t))
+	}
+	table.SetFooter(append(footer, fmt.Sprintf("%f", total), fmt.Sprintf("%.1f", imbalance(totals))))
+	table.Render()
+	return nil
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestImbalance(t *testing.T) {
+	assert.Equal(t, imbalance([]float64{1, 1, 1}), 0.0)
+	// The mean is 2, and L3 is furthest from it, by 1.
+	assert.Equal(t, imbalance([]float64{2, 1, 3}), 50.0)
+	assert.Equal(t, imbalance([]float64{0, 0, 0}), 0.0)
+}
+
+func TestGetResults_Phases(t *testing.T) {
+	viper.Set("sensor_id", "")
+	viper.Set("phase_sensor_ids", []string{"sensor.l1", "sensor.l2", "sensor.l3"})
+	defer viper.Set("phase_sensor_ids", nil)
+
+	yesterday := time.Now().UTC().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	source := fakeSource{}
+	for i, id := range []string{"sensor.l1", "sensor.l2", "sensor.l3"} {
+		for h := 0; h < 2*hoursInADay; h++ {
+			start := yesterday.Add(-24 * time.Hour).Add(time.Duration(h) * time.Hour)
+			source[id] = append(source[id], Reading{Start: start, Value: float64(i + 1)})
+		}
+	}
+	c := New(Config{Days: 2})
+	c.source = source
+
+	// Without sensor_id, the phases are added together.
+	results, err := getResults(c)
+	assert.NilError(t, err)
+	assert.Equal(t, len(results), 2)
+	assert.Equal(t, results[0].Date, yesterday)
+	for _, day := range results {
+		for _, v := range day.Values {
+			assert.Equal(t, v, 6.0)
+		}
+	}
+
+	phases, err := c.phaseResults(viper.GetStringSlice("phase_sensor_ids"), 2)
+	assert.NilError(t, err)
+	assert.Equal(t, len(phases), 3)
+	assert.Equal(t, phases[2][1].Values[0], 3.0)
+
+	_, err = c.phaseResults([]string{"sensor.l1"}, 2)
+	assert.ErrorContains(t, err, "needs a sensor for each phase")
+}
@@ -0,0 +1,185 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/powertracker/cmd/i18n"
+)
+
+// Price is the unit rate, per kWh, that applies from Start until End.
+type Price struct {
+	Start time.Time
+	End   time.Time
+	Rate  float64
+}
+
+// PriceSource provides unit rates over a time range.
+type PriceSource interface {
+	Prices(start, end time.Time) ([]Price, error)
+}
+
+// providerSource returns the dynamic price provider with the given name.
+func providerSource(provider string) (PriceSource, error) {
+	switch provider {
+	case "entsoe":
+		return newEntsoe()
+	case "amber":
+		return newAmber()
+	case "nordpool":
+		return newNordpool()
+	case "awattar":
+		return newAwattar()
+	case "":
+		return nil, fmt.Errorf("prices.provider is required")
+	default:
+		return nil, fmt.Errorf("unknown price provider %q", provider)
+	}
+}
+
+// averageRate returns the time-weighted average rate between start and end. The prices must be
+// sorted by start time and cover the whole range.
+func averageRate(prices []Price, start, end time.Time) (float64, error) {
+	i := sort.Search(len(prices), func(i int) bool { return prices[i].End.After(start) })
+
+	var total float64
+	covered := time.Duration(0)
+	for ; i < len(prices) && prices[i].Start.Before(end); i++ {
+		from, to := prices[i].Start, prices[i].End
+		if from.Before(start) {
+			from = start
+		}
+		if to.After(end) {
+			to = end
+		}
+		total += prices[i].Rate * to.Sub(from).Hours()
+		covered += to.Sub(from)
+	}
+	if covered < end.Sub(start) {
+		return 0, fmt.Errorf("no price available for %s", start.Format(time.RFC3339))
+	}
+	return total / end.Sub(start).Hours(), nil
+}
+
+// dailyCosts returns the cost of each day's consumption, valuing every slot of the given width at
+// the average rate that applied during it, so half-hourly rates are charged half hour by half hour.
+func dailyCosts(results []Day, prices []Price, width time.Duration) ([]float64, error) {
+	sort.Slice(prices, func(i, j int) bool { return prices[i].Start.Before(prices[j].Start) })
+
+	costs := make([]float64, len(results))
+	for i, day := range results {
+		for j, v := range day.Values {
+			start, ok := slotStart(day.Date, j, width)
+			if !ok {
+				continue
+			}
+			rate, err := averageRate(prices, start, slotEnd(day.Date, j, width))
+			if err != nil {
+				return nil, err
+			}
+			costs[i] += v * rate
+		}
+	}
+	return costs, nil
+}
+
+// printCosts prints a table of each day's consumption and cost on the selected tariff, including
+// its standing charge and any export credit, with the daily averages in the footer.
+func (c *Client) printCosts(results []Day) error {
+	costs, err := c.dailyBills(results)
+	if err != nil {
+		return err
+	}
+
+	cur := currencyConfig()
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{i18n.T("Date"), "kWh", i18n.T("Cost")})
+	var totalUsage, totalCost float64
+	for i, day := range results {
+		usage := sum(day.Values)
+		totalUsage += usage
+		totalCost += costs[i]
+		table.Append([]string{day.Date.Format("2006-01-02"), fmt.Sprintf("%f", usage), cur.format(costs[i])})
+	}
+	n := float64(len(results))
+	table.SetFooter([]string{i18n.T("Average"), fmt.Sprintf("%f", totalUsage/n), cur.format(totalCost / n)})
+	table.Render()
+	return nil
+}
+
+// dailyBills returns what each day cost on the selected tariff, including standing charges, tax
+// and any credit for exports.
+func (c *Client) dailyBills(results []Day) ([]float64, error) {
+	t, err := c.tariff()
+	if err != nil {
+		return nil, err
+	}
+	exports, err := c.dailyExports(results)
+	if err != nil {
+		return nil, err
+	}
+	width, _ := c.slots()
+	bills, err := t.bills(results, exports, width)
+	if err != nil {
+		return nil, err
+	}
+	costs := make([]float64, len(bills))
+	for i, b := range bills {
+		costs[i] = b.total()
+	}
+	return costs, nil
+}
+
+// costColumn returns the cost of each day as a column, with the statistics of the daily costs in
+// the footer.
+func costColumn(costs []float64, format func(float64) string, stats []stat) extraColumn {
+	col := extraColumn{Header: i18n.T("Cost"), Values: make([]string, len(costs))}
+	for i, cost := range costs {
+		col.Values[i] = format(cost)
+	}
+	for _, s := range stats {
+		col.Footers = append(col.Footers, format(statOrZero(s, costs)))
+	}
+	return col
+}
+
+// span returns the start of the earliest day and the end of the latest day in the results.
+func span(results []Day) (time.Time, time.Time) {
+	var start, end time.Time
+	for _, day := range results {
+		dayEnd := slotEnd(day.Date, len(day.Values)-1, time.Hour)
+		if start.IsZero() || day.Date.Before(start) {
+			start = day.Date
+		}
+		if dayEnd.After(end) {
+			end = dayEnd
+		}
+	}
+	return start, end
+}
+
+// httpClient makes the requests to the price providers and other web APIs. Unlike the default
+// client, it gives up on a server that stops responding rather than hanging the run.
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// doJSON performs an HTTP request and decodes the JSON response body into v.
+func doJSON(req *http.Request, v any) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"encoding/csv"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestDailyCosts(t *testing.T) {
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	prices := []Price{
+		{Start: day.Add(30 * time.Minute), End: day.Add(2 * time.Hour), Rate: 0.3},
+		{Start: day, End: day.Add(30 * time.Minute), Rate: 0.1},
+	}
+
+	costs, err := dailyCosts([]Day{{Date: day, Values: []float64{2, 1}}}, prices, time.Hour)
+	assert.NilError(t, err)
+	// The first hour is priced half at 0.1 and half at 0.3.
+	assert.Equal(t, costs[0], 2*0.2+1*0.3)
+
+	_, err = dailyCosts([]Day{{Date: day, Values: []float64{2, 1, 1}}}, prices, time.Hour)
+	assert.ErrorContains(t, err, "no price available for 2023-09-01T02:00:00Z")
+
+	// Half hours are each priced at their own rate, rather than the average of the hour.
+	costs, err = dailyCosts([]Day{{Date: day, Values: []float64{2, 1}}}, prices, 30*time.Minute)
+	assert.NilError(t, err)
+	assert.Equal(t, costs[0], 2*0.1+1*0.3)
+}
+
+func TestDailyCosts_ClocksChange(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	assert.NilError(t, err)
+	// The clocks went back at 02:00 BST on 29 October 2023, so 01:00 came twice.
+	day := time.Date(2023, 10, 29, 0, 0, 0, 0, london)
+	first := time.Date(2023, 10, 29, 0, 0, 0, 0, time.UTC)  // 01:00 BST
+	second := time.Date(2023, 10, 29, 1, 0, 0, 0, time.UTC) // 01:00 GMT
+	prices := []Price{
+		{Start: day, End: first, Rate: 0.2},
+		{Start: first, End: second, Rate: 0.1},
+		{Start: second, End: second.Add(time.Hour), Rate: 0.3},
+		{Start: second.Add(time.Hour), End: day.AddDate(0, 0, 1), Rate: 0.2},
+	}
+	values := make([]float64, hoursInADay)
+	values[1] = 2
+
+	costs, err := dailyCosts([]Day{{Date: day, Values: values}}, prices, time.Hour)
+	assert.NilError(t, err)
+	// The slot for 01:00 holds both hours, so is priced at the average of both rates.
+	assert.Equal(t, math.Round(costs[0]*1e9)/1e9, 2*0.2)
+}
+
+func TestClient_ComputePowerStats_Cost(t *testing.T) {
+	viper.Set("sensor_id", "sensor.energy")
+	viper.Set("tariffs", map[string]any{"flat": map[string]any{"type": "flat", "rate": 0.5, "standing_charge": 1}})
+	defer viper.Set("tariffs", nil)
+
+	yesterday := time.Now().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	var readings []Reading
+	for i := 0; i < 2*hoursInADay; i++ {
+		readings = append(readings, Reading{Start: yesterday.Add(-24 * time.Hour).Add(time.Duration(i) * time.Hour), Value: float64(1 + i/hoursInADay)})
+	}
+	path := filepath.Join(t.TempDir(), "results.csv")
+	c := New(Config{Days: 2, Output: "csv", FilePath: path, Cost: true})
+	c.source = fakeSource{"sensor.energy": readings}
+	c.ComputePowerStats()
+
+	f, err := os.Open(path)
+	assert.NilError(t, err)
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	assert.NilError(t, err)
+	assert.Equal(t, len(rows), 4)
+	last := func(row []string) string { return row[len(row)-1] }
+	total := func(row []string) string { return row[len(row)-2] }
+	assert.Equal(t, total(rows[0]), "Total")
+	assert.Equal(t, total(rows[1]), "48.000000")
+	assert.Equal(t, total(rows[3]), "36.000000")
+	assert.Equal(t, rows[0][0], "Date")
+	assert.Equal(t, rows[1][0], yesterday.Format("2006-01-02"))
+	assert.Equal(t, rows[3][0], "Average")
+	assert.Equal(t, last(rows[0]), "Cost")
+	// Yesterday used 2 kWh an hour and the day before 1, each with the standing charge on top.
+	assert.Equal(t, last(rows[1]), "25.00")
+	assert.Equal(t, last(rows[2]), "13.00")
+	assert.Equal(t, last(rows[3]), "19.00")
+}
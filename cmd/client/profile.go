@@ -0,0 +1,142 @@
+package client
+
+import (
+	"fmt"
+	"math"
+)
+
+// slotStats accumulates the values seen in one slot of the day.
+type slotStats struct {
+	Count int
+	Sum   float64
+	Min   float64
+	Max   float64
+}
+
+func (s *slotStats) add(v float64) {
+	if s.Count == 0 || v < s.Min {
+		s.Min = v
+	}
+	if s.Count == 0 || v > s.Max {
+		s.Max = v
+	}
+	s.Count++
+	s.Sum += v
+}
+
+// mean returns the average of the values, or NaN if there weren't any.
+func (s slotStats) mean() float64 {
+	if s.Count == 0 {
+		return math.NaN()
+	}
+	return s.Sum / float64(s.Count)
+}
+
+// profile folds days into running statistics for each slot of the day, so summarising any number
+// of days takes the same memory.
+type profile []slotStats
+
+func newProfile(slots int) profile {
+	return make(profile, slots)
+}
+
+// add folds a day's values into the profile.
+func (p profile) add(day Day) {
+	for i, v := range day.Values {
+		p[i].add(v)
+	}
+}
+
+// means returns the average of each slot.
+func (p profile) means() []float64 {
+	means := make([]float64, len(p))
+	for i, s := range p {
+		means[i] = s.mean()
+	}
+	return means
+}
+
+// foldedMeans returns the average of each slot over the given number of days of consumption, and
+// how many days that was, folding each day into a profile as it is read rather than keeping the
+// days, so the memory it needs doesn't grow with the range. Impossible values are corrected as
+// fixSpikes does, with the median of their slot on the other days, which takes a second pass over
+// the days for just the slots they were in. Like results, it returns ErrInterrupted or
+// ErrIncomplete with the days it has so far, and then leaves impossible values out instead.
+func (c *Client) foldedMeans(days int) ([]float64, int, error) {
+	sensorID := SensorID()
+	if sensorID == "" {
+		return nil, 0, fmt.Errorf("sensor_id is required")
+	}
+	width, slots := c.slots()
+	limit := maxHourlyKWh() * width.Hours()
+	bad := func(v float64) bool { return v < 0 || v > limit }
+	p := newProfile(slots)
+	var n int
+	// Each spike, and the slot it was in.
+	var spikes []adjustment
+	var in []int
+	flagged := make(map[int][]float64)
+	err := c.eachDay(sensorID, c.until(), days, func(_ int, day Day) error {
+		for i, v := range day.Values {
+			if i >= slots {
+				break
+			}
+			if bad(v) {
+				start, _ := slotStart(day.Date, i, width)
+				spikes, in = append(spikes, adjustment{Start: start, Original: v}), append(in, i)
+				flagged[i] = nil
+				continue
+			}
+			p[i].add(v)
+		}
+		c.note(func(pr *provenance) { pr.Included = append(pr.Included, day.Date) })
+		n++
+		return nil
+	})
+	if len(spikes) == 0 {
+		return p.means(), n, err
+	}
+	if err != nil {
+		c.logger().Warn().Msgf("left out %d impossible values; set max_hourly_kwh if your usage is genuinely higher than %.0f kWh an hour", len(spikes), maxHourlyKWh())
+		return p.means(), n, err
+	}
+
+	// The values of the slots with spikes in are read again, as the median needs all of them.
+	err = c.eachDay(sensorID, c.until(), days, func(_ int, day Day) error {
+		for i := range flagged {
+			if i < len(day.Values) && !bad(day.Values[i]) {
+				flagged[i] = append(flagged[i], day.Values[i])
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	for k, i := range in {
+		spikes[k].Value = median(flagged[i])
+		p[i].add(spikes[k].Value)
+	}
+	c.reportSpikes(spikes)
+	return p.means(), n, nil
+}
+
+// averages returns the average of each slot over the given number of days, and how many days
+// that was, as foldedMeans does. The phases in phase_sensor_ids are added together day by day, so
+// without sensor_id the days are read in full first.
+func (c *Client) averages(days int) ([]float64, int, error) {
+	if SensorID() != "" {
+		return c.foldedMeans(days)
+	}
+	results, err := c.results(days)
+	if err != nil {
+		return nil, 0, err
+	}
+	c.fixSpikes(results)
+	_, slots := c.slots()
+	p := newProfile(slots)
+	for _, day := range results {
+		p.add(day)
+	}
+	return p.means(), len(results), nil
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestProfile(t *testing.T) {
+	p := newProfile(2)
+	p.add(Day{Values: []float64{1, 4}})
+	p.add(Day{Values: []float64{3, 0.5}})
+	p.add(Day{Values: []float64{2, 1.5}})
+
+	assert.DeepEqual(t, p[1], slotStats{Count: 3, Sum: 6, Min: 0.5, Max: 4})
+	assert.DeepEqual(t, p.means(), []float64{2, 2})
+	assert.Assert(t, math.IsNaN(newProfile(1).means()[0]))
+}
+
+func TestClient_FoldedMeans(t *testing.T) {
+	viper.Set("sensor_id", "sensor.energy")
+	viper.Set("chunk_days", 1)
+	defer viper.Set("chunk_days", 0)
+
+	yesterday := time.Now().UTC().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	var readings []Reading
+	for d := 0; d < 4; d++ {
+		for h := 0; h < hoursInADay; h++ {
+			readings = append(readings, Reading{Start: yesterday.AddDate(0, 0, -d).Add(time.Duration(h) * time.Hour), Value: float64(d + 1)})
+		}
+	}
+	// A meter reset in the first hour of yesterday is replaced with the median of that hour on
+	// the other days, as fixSpikes does.
+	readings[0].Value = 900
+	cfg := Config{Days: 4, TimeZone: "UTC", CacheFile: filepath.Join(t.TempDir(), "cache.db")}
+
+	c := New(cfg)
+	c.source = fakeSource{"sensor.energy": readings}
+	c.Config.Explain = true
+	means, n, err := c.foldedMeans(4)
+	assert.NilError(t, err)
+	assert.Equal(t, n, 4)
+	assert.Equal(t, means[0], (3.0+2+3+4)/4)
+	assert.Equal(t, means[1], 2.5)
+	assert.DeepEqual(t, c.provenance.Corrected, []adjustment{{Start: yesterday, Original: 900, Value: 3}})
+
+	// Days are handed over a chunk at a time, rather than once the whole range is in.
+	c = New(Config{Days: 4, TimeZone: "UTC"})
+	c.source = fakeSource{"sensor.energy": readings}
+	var order []int
+	err = c.eachDay("sensor.energy", c.until(), 4, func(i int, day Day) error {
+		order = append(order, i)
+		assert.Equal(t, len(day.Values), hoursInADay)
+		return nil
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, order, []int{3, 2, 1, 0})
+
+	// The days fetched were cached as they were folded in.
+	c = New(cfg)
+	c.source = offline{}
+	c.Config.Offline = true
+	means, n, err = c.foldedMeans(4)
+	assert.NilError(t, err)
+	assert.Equal(t, n, 4)
+	assert.Equal(t, means[1], 2.5)
+	// The spike's hour is read again to correct it, and both passes are answered from the cache.
+	assert.Equal(t, c.Stats().CacheHits, 8)
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+var pvoutputURL = "https://pvoutput.org/service/r2"
+
+// uploadPVOutput sends the daily consumption totals to PVOutput using its add output service.
+// If a generation statistic is configured, the daily generation is fetched and sent alongside
+// the consumption.
+func (c *Client) uploadPVOutput(results []Day) error {
+	apiKey := viper.GetString("pvoutput.api_key")
+	if apiKey == "" {
+		return fmt.Errorf("pvoutput.api_key is required")
+	}
+	systemID := viper.GetString("pvoutput.system_id")
+	if systemID == "" {
+		return fmt.Errorf("pvoutput.system_id is required")
+	}
+	var generation []Reading
+	generationID := generationSensorID()
+	if generationID != "" {
+		var err error
+		if generation, err = c.statistic(generationID, results); err != nil {
+			return fmt.Errorf("getting generation: %w", err)
+		}
+	}
+
+	for _, day := range results {
+		form := url.Values{
+			"d": {day.Date.Format("20060102")},
+			"c": {fmt.Sprintf("%.0f", sum(day.Values)*1000)},
+		}
+		if generationID != "" {
+			generated := sum(bucket(generation, day.Date, 24*time.Hour, 1))
+			form.Set("g", fmt.Sprintf("%.0f", generated*1000))
+		}
+
+		req, err := http.NewRequest(http.MethodPost, pvoutputURL+"/addoutput.jsp", strings.NewReader(form.Encode()))
+		if err != nil {
+			return fmt.Errorf("creating request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Pvoutput-Apikey", apiKey)
+		req.Header.Set("X-Pvoutput-SystemId", systemID)
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("posting output: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("reading response: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("uploading %s: %s", day.Date.Format("2006-01-02"), strings.TrimSpace(string(body)))
+		}
+	}
+	c.logger().Info().Msgf("uploaded %d days to PVOutput system %s", len(results), systemID)
+	return nil
+}
+
+func sum(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestClient_UploadPVOutput(t *testing.T) {
+	var forms []url.Values
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Path, "/addoutput.jsp", "unexpected path")
+		assert.Equal(t, r.Header.Get("X-Pvoutput-Apikey"), "test_key", "unexpected API key")
+		assert.Equal(t, r.Header.Get("X-Pvoutput-SystemId"), "1234", "unexpected system ID")
+		assert.NilError(t, r.ParseForm(), "parse form failed")
+		forms = append(forms, r.PostForm)
+		_, _ = w.Write([]byte("OK 200: Added Output"))
+	}))
+	defer s.Close()
+
+	pvoutputURL = s.URL
+	viper.Set("pvoutput.api_key", "test_key")
+	viper.Set("pvoutput.system_id", "1234")
+	viper.Set("pvoutput.generation_sensor_id", "sensor.solar")
+
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	client := New(Config{})
+	client.source = fakeSource{
+		"sensor.solar": {
+			{Start: day.Add(12 * time.Hour), Value: 1.5},
+			{Start: day.Add(13 * time.Hour), Value: 2},
+		},
+	}
+
+	err := client.uploadPVOutput([]Day{{Date: day, Values: []float64{0.5, 1.25}}})
+
+	assert.NilError(t, err)
+	assert.Equal(t, len(forms), 1)
+	assert.Equal(t, forms[0].Get("d"), "20230901")
+	assert.Equal(t, forms[0].Get("c"), "1750")
+	assert.Equal(t, forms[0].Get("g"), "3500")
+}
+
+func TestClient_UploadPVOutput_Rejected(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("Bad request 400: Date is in the future [20990101]"))
+	}))
+	defer s.Close()
+
+	pvoutputURL = s.URL
+	viper.Set("pvoutput.api_key", "test_key")
+	viper.Set("pvoutput.system_id", "1234")
+	viper.Set("pvoutput.generation_sensor_id", "")
+
+	day := time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := New(Config{})
+	err := client.uploadPVOutput([]Day{{Date: day, Values: []float64{1}}})
+
+	assert.ErrorContains(t, err, "uploading 2099-01-01: Bad request 400: Date is in the future [20990101]")
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/powertracker/cmd/i18n"
+	"github.com/spf13/viper"
+)
+
+// shift is the cheapest block of contiguous hours in a day, and what moving flexible loads into it
+// would have saved.
+type shift struct {
+	Date   time.Time
+	Start  time.Time // Start is the beginning of the cheapest block of hours.
+	Rate   float64   // Rate is the average rate during the block.
+	Paid   float64   // Paid is the average rate actually paid for the day's consumption.
+	Saving float64
+}
+
+// cheapestHours returns the start and average rate of the cheapest n contiguous hours in the day.
+// The prices must be sorted by start time.
+func cheapestHours(prices []Price, day time.Time, n int) (time.Time, float64, error) {
+	if n < 1 || n > hoursInADay {
+		return time.Time{}, 0, fmt.Errorf("the number of hours must be between 1 and %d, got %d", hoursInADay, n)
+	}
+	var best time.Time
+	bestRate := 0.0
+	for h := 0; h+n <= hoursInADay; h++ {
+		start, ok := slotStart(day, h, time.Hour)
+		if !ok {
+			continue
+		}
+		rate, err := averageRate(prices, start, slotEnd(day, h+n-1, time.Hour))
+		if err != nil {
+			return time.Time{}, 0, err
+		}
+		if best.IsZero() || rate < bestRate {
+			best, bestRate = start, rate
+		}
+	}
+	return best, bestRate, nil
+}
+
+// shifts works out the cheapest n contiguous hours of each day, and how much would have been saved
+// by moving the given amount of flexible consumption into them from the day's average rate. Costs
+// are what each day's consumption cost, as energyCosts works them out.
+func shifts(results []Day, prices []Price, costs []float64, n int, flexible float64) ([]shift, error) {
+	sort.Slice(prices, func(i, j int) bool { return prices[i].Start.Before(prices[j].Start) })
+
+	out := make([]shift, len(results))
+	for i, day := range results {
+		start, rate, err := cheapestHours(prices, day.Date, n)
+		if err != nil {
+			return nil, err
+		}
+
+		// Days without any consumption are valued at the day's average rate instead.
+		paid := 0.0
+		if usage := sum(day.Values); usage > 0 {
+			paid = costs[i] / usage
+		} else if paid, err = averageRate(prices, day.Date, slotEnd(day.Date, hoursInADay-1, time.Hour)); err != nil {
+			return nil, err
+		}
+
+		saving := flexible * (paid - rate)
+		if saving < 0 {
+			saving = 0
+		}
+		out[i] = shift{Date: day.Date, Start: start, Rate: rate, Paid: paid, Saving: saving}
+	}
+	return out, nil
+}
+
+// printRecommendations prints the cheapest block of hours for each day, and how much would have been
+// saved by running flexible loads, such as a dishwasher or an EV charger, during it.
+func (c *Client) printRecommendations(results []Day) error {
+	n := viper.GetInt("recommendations.hours")
+	if n == 0 {
+		n = 3
+	}
+	flexible := viper.GetFloat64("recommendations.flexible_kwh")
+	if flexible == 0 {
+		flexible = 2
+	}
+
+	// What was paid is worked out as for every other cost, but the cheapest hours need rates that
+	// vary with the time of day, which tiered tariffs don't have.
+	t, err := c.tariff()
+	if err != nil {
+		return err
+	}
+	costs, err := t.energyCosts(results, time.Hour)
+	if err != nil {
+		return err
+	}
+	source, err := t.source()
+	if err != nil {
+		return err
+	}
+	start, end := span(results)
+	prices, err := source.Prices(start, end)
+	if err != nil {
+		return fmt.Errorf("getting prices: %w", err)
+	}
+	days, err := shifts(results, prices, costs, n, flexible)
+	if err != nil {
+		return err
+	}
+
+	cur := currencyConfig()
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{i18n.T("Date"), i18n.T("Cheapest hours"), i18n.T("Rate"), i18n.T("Average paid"), i18n.T("Saving")})
+	var total float64
+	counts := make(map[int]int)
+	for _, d := range days {
+		total += d.Saving
+		counts[d.Start.Hour()]++
+		table.Append([]string{
+			d.Date.Format("2006-01-02"),
+			fmt.Sprintf("%s-%s", d.Start.Format("15:04"), d.Start.Add(time.Duration(n)*time.Hour).Format("15:04")),
+			cur.rate(d.Rate),
+			cur.rate(d.Paid),
+			cur.format(d.Saving),
+		})
+	}
+	table.SetFooter([]string{i18n.T("Total"), "", "", "", cur.format(total)})
+	table.Render()
+
+	// The block that was cheapest most often is the one to schedule loads in.
+	best := 0
+	for h, count := range counts {
+		if count > counts[best] || (count == counts[best] && h < best) {
+			best = h
+		}
+	}
+	fmt.Println(i18n.T("Moving %.1f kWh a day into the cheapest %d hours would have saved %s over %d days.", flexible, n, cur.format(total), len(days)))
+	fmt.Println(i18n.T("The cheapest hours were most often %02d:00-%02d:00 (%d of %d days).", best, (best+n)%hoursInADay, counts[best], len(days)))
+	return nil
+}
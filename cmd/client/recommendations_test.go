@@ -0,0 +1,41 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestShifts(t *testing.T) {
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	// 02:00-04:00 is the cheapest block on the first day and 05:00-07:00 on the second.
+	prices := []Price{
+		{Start: day, End: day.Add(2 * time.Hour), Rate: 0.5},
+		{Start: day.Add(2 * time.Hour), End: day.Add(4 * time.Hour), Rate: 0.25},
+		{Start: day.Add(4 * time.Hour), End: day.Add(29 * time.Hour), Rate: 0.5},
+		{Start: day.Add(29 * time.Hour), End: day.Add(31 * time.Hour), Rate: 0.125},
+		{Start: day.Add(31 * time.Hour), End: day.Add(48 * time.Hour), Rate: 0.5},
+	}
+
+	usage := make([]float64, hoursInADay)
+	usage[12] = 4
+	results := []Day{
+		{Date: day, Values: usage},
+		{Date: day.Add(24 * time.Hour), Values: make([]float64, hoursInADay)},
+	}
+
+	days, err := shifts(results, prices, []float64{2, 0}, 2, 2)
+	assert.NilError(t, err)
+	assert.Assert(t, days[0].Start.Equal(day.Add(2*time.Hour)))
+	assert.Equal(t, days[0].Rate, 0.25)
+	assert.Equal(t, days[0].Paid, 0.5)
+	assert.Equal(t, days[0].Saving, 0.5)
+
+	// Without any consumption, the second day is valued at its average rate.
+	assert.Assert(t, days[1].Start.Equal(day.Add(29*time.Hour)))
+	assert.Equal(t, days[1].Paid, (22*0.5+2*0.125)/24)
+
+	_, _, err = cheapestHours(prices, day, 25)
+	assert.ErrorContains(t, err, "the number of hours must be between 1 and 24")
+}
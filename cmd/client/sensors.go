@@ -0,0 +1,144 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/powertracker/cmd/i18n"
+)
+
+// Sensor is an energy statistic kept by the Home Assistant recorder, which can be used as
+// sensor_id.
+type Sensor struct {
+	ID   string
+	Name string
+	Unit string
+	// LastReset is when the meter behind the statistic was last reset, if it ever has been, as
+	// with sensors that count from zero each day.
+	LastReset time.Time
+}
+
+// energyUnits are the units of energy statistics, for versions of Home Assistant that don't
+// report the unit class.
+var energyUnits = map[string]bool{"Wh": true, "kWh": true, "MWh": true}
+
+// Sensors returns the energy statistics kept by the recorder, sorted by statistic ID.
+func (c *Client) Sensors() ([]Sensor, error) {
+	if !c.connected() {
+		return nil, fmt.Errorf("listing sensors requires the Home Assistant source")
+	}
+
+	var list struct {
+		Success bool `json:"success"`
+		Result  []struct {
+			ID        string `json:"statistic_id"`
+			Name      string `json:"name"`
+			Unit      string `json:"statistics_unit_of_measurement"`
+			UnitClass string `json:"unit_class"`
+		} `json:"result"`
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := c.request(map[string]interface{}{
+		"type":           "recorder/list_statistic_ids",
+		"statistic_type": "sum",
+	}, &list); err != nil {
+		return nil, err
+	}
+	if !list.Success {
+		return nil, fmt.Errorf("api response error: %s", list.Error.Message)
+	}
+
+	var sensors []Sensor
+	for _, s := range list.Result {
+		if s.UnitClass == "energy" || (s.UnitClass == "" && energyUnits[s.Unit]) {
+			sensors = append(sensors, Sensor{ID: s.ID, Name: s.Name, Unit: s.Unit})
+		}
+	}
+	sort.Slice(sensors, func(i, j int) bool { return sensors[i].ID < sensors[j].ID })
+	if len(sensors) == 0 {
+		return nil, nil
+	}
+
+	resets, err := c.lastResets(sensors)
+	if err != nil {
+		return nil, fmt.Errorf("getting last resets: %w", err)
+	}
+	for i := range sensors {
+		sensors[i].LastReset = resets[sensors[i].ID]
+	}
+	return sensors, nil
+}
+
+// lastResets returns when each sensor was last reset, going by the daily statistics of the last
+// week. Sensors that have never been reset, such as those that only ever increase, are left out.
+func (c *Client) lastResets(sensors []Sensor) (map[string]time.Time, error) {
+	ids := make([]string, len(sensors))
+	for i, s := range sensors {
+		ids[i] = s.ID
+	}
+	now := c.now()
+	msg := map[string]interface{}{
+		"type":          "recorder/statistics_during_period",
+		"start_time":    now.AddDate(0, 0, -7).UTC().Format("2006-01-02T15:04:05.000Z"),
+		"end_time":      now.UTC().Format("2006-01-02T15:04:05.000Z"),
+		"statistic_ids": ids,
+		"period":        "day",
+		"types":         []string{"last_reset"},
+	}
+
+	var data struct {
+		Success bool `json:"success"`
+		Result  map[string][]struct {
+			// LastReset is in milliseconds since the epoch, or null.
+			LastReset *float64 `json:"last_reset"`
+		} `json:"result"`
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := c.request(msg, &data); err != nil {
+		return nil, err
+	}
+	if !data.Success {
+		return nil, fmt.Errorf("api response error: %s", data.Error.Message)
+	}
+
+	resets := make(map[string]time.Time)
+	for id, rows := range data.Result {
+		for _, row := range rows {
+			if row.LastReset != nil {
+				resets[id] = time.UnixMilli(int64(*row.LastReset))
+			}
+		}
+	}
+	return resets, nil
+}
+
+// PrintSensors prints the energy statistics kept by the recorder, so the right one can be chosen
+// for sensor_id.
+func (c *Client) PrintSensors(w io.Writer) error {
+	sensors, err := c.Sensors()
+	if err != nil {
+		return err
+	}
+	if len(sensors) == 0 {
+		return fmt.Errorf("the recorder has no energy statistics - is there an energy sensor with a state_class?")
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{i18n.T("Statistic ID"), i18n.T("Name"), i18n.T("Unit"), i18n.T("Last reset")})
+	for _, s := range sensors {
+		reset := ""
+		if !s.LastReset.IsZero() {
+			reset = s.LastReset.Local().Format("2006-01-02 15:04")
+		}
+		table.Append([]string{s.ID, s.Name, s.Unit, reset})
+	}
+	table.Render()
+	return nil
+}
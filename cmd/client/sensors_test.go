@@ -0,0 +1,59 @@
+package client
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/poolski/powertracker/cmd/hatest"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestClient_Sensors(t *testing.T) {
+	s := hatest.NewServer("test_token")
+	defer s.Close()
+	viper.Set("url", s.URL)
+	viper.Set("api_key", "test_token")
+
+	reset := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	s.Handle("recorder/list_statistic_ids", func(msg map[string]interface{}) (interface{}, *hatest.Error) {
+		return []map[string]interface{}{
+			{"statistic_id": "sensor.solar", "name": "Solar", "statistics_unit_of_measurement": "kWh", "unit_class": "energy"},
+			{"statistic_id": "sensor.gas", "name": "Gas", "statistics_unit_of_measurement": "m³", "unit_class": "volume"},
+			{"statistic_id": "sensor.energy", "name": "Energy", "statistics_unit_of_measurement": "Wh"},
+		}, nil
+	})
+	s.Handle("recorder/statistics_during_period", func(msg map[string]interface{}) (interface{}, *hatest.Error) {
+		assert.DeepEqual(t, msg["types"], []interface{}{"last_reset"})
+		return map[string]interface{}{
+			"sensor.energy": []map[string]interface{}{{"start": 0, "last_reset": nil}},
+			"sensor.solar":  []map[string]interface{}{{"start": 0, "last_reset": reset.UnixMilli()}},
+		}, nil
+	})
+
+	c := New(Config{})
+	assert.NilError(t, c.Connect())
+	defer c.Close()
+	sensors, err := c.Sensors()
+	assert.NilError(t, err)
+	assert.Equal(t, len(sensors), 2)
+	assert.Equal(t, sensors[0].ID, "sensor.energy")
+	assert.Assert(t, sensors[0].LastReset.IsZero())
+	assert.Equal(t, sensors[1].ID, "sensor.solar")
+	assert.Equal(t, sensors[1].Unit, "kWh")
+	assert.Assert(t, sensors[1].LastReset.Equal(reset))
+
+	var buf bytes.Buffer
+	assert.NilError(t, c.PrintSensors(&buf))
+	assert.Assert(t, strings.Contains(buf.String(), "sensor.solar"))
+	assert.Assert(t, !strings.Contains(buf.String(), "sensor.gas"))
+}
+
+func TestClient_Sensors_NoSource(t *testing.T) {
+	c := New(Config{Demo: true})
+	assert.NilError(t, c.Connect())
+	_, err := c.Sensors()
+	assert.ErrorContains(t, err, "requires the Home Assistant source")
+}
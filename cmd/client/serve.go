@@ -0,0 +1,229 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// maxServeDays is the most days a single request to the server can ask for.
+const maxServeDays = 3650
+
+// pingTimeout is how long readiness checks wait for Home Assistant to answer.
+const pingTimeout = 5 * time.Second
+
+// health is the body of the health and readiness responses.
+type health struct {
+	Status string `json:"status"`
+	// Connected reports whether there is a websocket connection to Home Assistant. Other sources
+	// don't keep a connection open.
+	Connected bool       `json:"connected"`
+	LastFetch *time.Time `json:"last_fetch,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// point is a single value in a chart series. X is a timestamp in milliseconds, or the hour for
+// hour_of_day series.
+type point struct {
+	X int64   `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// series is a named set of points, in the shape ApexCharts and most Lovelace chart cards expect.
+type series struct {
+	Name string  `json:"name"`
+	Data []point `json:"data"`
+}
+
+// server answers HTTP requests for a connected client.
+type server struct {
+	client *Client
+	// mu makes requests wait their turn, as they share the connection and the local cache.
+	mu      sync.Mutex
+	metrics metrics
+}
+
+// Handler returns an HTTP handler that serves the client's analyses, for dashboards such as
+// Home Assistant's own. The client must already be connected, and is shared by every request.
+//
+//	GET /api/series?group=day&days=30
+//
+// returns the consumption as a chart series. group is one of hour, day, week, month or
+// hour_of_day, and days defaults to the configured number of days.
+//
+// GET /healthz answers as long as the server is running, and GET /readyz only while Home Assistant
+// answers on the websocket connection and, if serve.ready_max_age is set, the last successful fetch
+// is no older than it. Both report the connection and the time of the last fetch.
+//
+// GET /metrics serves the average of each hour of the day, and the client's stats, for Prometheus.
+// If serve.metrics_interval is set, the averages are worked out when the handler is created and
+// again at every interval, until the client is stopped.
+func (c *Client) Handler() http.Handler {
+	s := &server{client: c}
+	if interval := viper.GetDuration("serve.metrics_interval"); interval > 0 {
+		go s.refreshMetrics(interval)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.prometheus)
+	mux.HandleFunc("/api/series", s.series)
+	mux.HandleFunc("/healthz", s.healthz)
+	mux.HandleFunc("/readyz", s.readyz)
+	return mux
+}
+
+func (s *server) healthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, s.health(), http.StatusOK)
+}
+
+func (s *server) readyz(w http.ResponseWriter, r *http.Request) {
+	h := s.health()
+	// Before the first request, there is no fetch to be stale.
+	if maxAge := viper.GetDuration("serve.ready_max_age"); maxAge > 0 && h.LastFetch != nil && time.Since(*h.LastFetch) > maxAge {
+		h.Error = fmt.Sprintf("the last successful fetch was more than %s ago", maxAge)
+	}
+	if h.Connected {
+		if err := s.client.ping(pingTimeout); err != nil {
+			h.Connected = false
+			h.Error = fmt.Sprintf("pinging Home Assistant: %v", err)
+		}
+	}
+	if h.Error != "" {
+		h.Status = "unavailable"
+		writeHealth(w, h, http.StatusServiceUnavailable)
+		return
+	}
+	writeHealth(w, h, http.StatusOK)
+}
+
+// health reports the state of the connection and the last fetch, as far as it is known without
+// contacting Home Assistant.
+func (s *server) health() health {
+	h := health{Status: "ok", Connected: s.client.connected()}
+	if last := s.client.Stats().LastFetch; !last.IsZero() {
+		h.LastFetch = &last
+	}
+	return h
+}
+
+func writeHealth(w http.ResponseWriter, h health, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(h)
+}
+
+func (s *server) series(w http.ResponseWriter, r *http.Request) {
+	if origin := viper.GetString("serve.allow_origin"); origin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	group := r.URL.Query().Get("group")
+	if group == "" {
+		group = "day"
+	}
+	days := s.client.days()
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxServeDays {
+			http.Error(w, fmt.Sprintf("days must be between 1 and %d", maxServeDays), http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+
+	s.mu.Lock()
+	results, err := s.client.results(days)
+	if err == nil {
+		s.client.fixSpikes(results)
+	}
+	s.mu.Unlock()
+	if err != nil {
+		s.client.logger().Error().Msgf("getting results: %v", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	width, _ := s.client.slots()
+	points, err := groupPoints(results, width, group)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(series{Name: SensorID(), Data: points})
+}
+
+// groupPoints turns the results into a series of points, one for each group, in order. Time groups
+// are totals, with X the start of the group. hour_of_day gives the average of each hour of the day.
+func groupPoints(results []Day, width time.Duration, group string) ([]point, error) {
+	days := make([]Day, len(results))
+	copy(days, results)
+	sort.Slice(days, func(i, j int) bool { return days[i].Date.Before(days[j].Date) })
+
+	if group == "hour_of_day" {
+		var totals [hoursInADay]float64
+		for _, day := range days {
+			for i, v := range day.Values {
+				totals[int(time.Duration(i)*width/time.Hour)] += v
+			}
+		}
+		points := make([]point, hoursInADay)
+		for h := range points {
+			points[h] = point{X: int64(h), Y: totals[h] / float64(len(days))}
+		}
+		return points, nil
+	}
+
+	// Groups follow the clock and calendar of the time zone the days are in.
+	var start func(t time.Time) time.Time
+	switch group {
+	case "hour":
+		start = func(t time.Time) time.Time {
+			return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+		}
+	case "day":
+		start = midnight
+	case "week":
+		// Weeks start on Monday.
+		start = func(t time.Time) time.Time {
+			day := midnight(t)
+			return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		}
+	case "month":
+		// Months follow the billing cycle.
+		billing, err := billingDay()
+		if err != nil {
+			return nil, err
+		}
+		start = func(t time.Time) time.Time { return billingMonth(t, billing) }
+	default:
+		return nil, fmt.Errorf("unknown group %q - use hour, day, week, month or hour_of_day", group)
+	}
+
+	var points []point
+	for _, day := range days {
+		for i, v := range day.Values {
+			t, ok := slotStart(day.Date, i, width)
+			if !ok {
+				continue
+			}
+			x := start(t).UnixMilli()
+			if n := len(points); n > 0 && points[n-1].X == x {
+				points[n-1].Y += v
+				continue
+			}
+			points = append(points, point{X: x, Y: v})
+		}
+	}
+	return points, nil
+}
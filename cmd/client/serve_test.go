@@ -0,0 +1,185 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestClient_Handler_Series(t *testing.T) {
+	viper.Set("sensor_id", "sensor.energy")
+	viper.Set("serve.allow_origin", "http://homeassistant.local:8123")
+	defer viper.Set("serve.allow_origin", "")
+
+	yesterday := time.Now().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	var readings []Reading
+	for i := 0; i < 2*hoursInADay; i++ {
+		readings = append(readings, Reading{Start: yesterday.Add(-24 * time.Hour).Add(time.Duration(i) * time.Hour), Value: 0.5})
+	}
+	c := New(Config{Days: 7})
+	c.source = fakeSource{"sensor.energy": readings}
+	s := httptest.NewServer(c.Handler())
+	defer s.Close()
+
+	resp, err := http.Get(s.URL + "/api/series?group=day&days=2")
+	assert.NilError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Equal(t, resp.Header.Get("Access-Control-Allow-Origin"), "http://homeassistant.local:8123")
+
+	var got series
+	assert.NilError(t, json.NewDecoder(resp.Body).Decode(&got))
+	assert.DeepEqual(t, got, series{Name: "sensor.energy", Data: []point{
+		{X: yesterday.Add(-24 * time.Hour).UnixMilli(), Y: 12},
+		{X: yesterday.UnixMilli(), Y: 12},
+	}})
+
+	for _, query := range []string{"group=fortnight", "days=0", "days=x"} {
+		resp, err := http.Get(s.URL + "/api/series?" + query)
+		assert.NilError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, resp.StatusCode, http.StatusBadRequest, query)
+	}
+}
+
+func TestClient_Handler_Health(t *testing.T) {
+	var gone atomic.Bool
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, _ := upgrader.Upgrade(w, r, nil)
+		defer conn.Close()
+		for {
+			var msg map[string]interface{}
+			if err := conn.ReadJSON(&msg); err != nil || gone.Load() {
+				return
+			}
+			assert.NilError(t, conn.WriteJSON(map[string]interface{}{"id": msg["id"], "type": "pong"}))
+		}
+	}))
+	defer ws.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ws.URL, "http"), nil)
+	assert.NilError(t, err)
+	c := &Client{Conn: conn}
+	defer c.Close()
+	s := httptest.NewServer(c.Handler())
+	defer s.Close()
+
+	get := func(path string) (int, health) {
+		resp, err := http.Get(s.URL + path)
+		assert.NilError(t, err)
+		defer resp.Body.Close()
+		var h health
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&h))
+		return resp.StatusCode, h
+	}
+
+	status, h := get("/readyz")
+	assert.Equal(t, status, http.StatusOK)
+	assert.DeepEqual(t, h, health{Status: "ok", Connected: true})
+
+	// A stale fetch makes the server unready, but it is still alive.
+	viper.Set("serve.ready_max_age", "1h")
+	defer viper.Set("serve.ready_max_age", nil)
+	c.count(func(s *Stats) { s.LastFetch = time.Now().Add(-2 * time.Hour) })
+	status, h = get("/readyz")
+	assert.Equal(t, status, http.StatusServiceUnavailable)
+	assert.Equal(t, h.Error, "the last successful fetch was more than 1h0m0s ago")
+	status, h = get("/healthz")
+	assert.Equal(t, status, http.StatusOK)
+	assert.Equal(t, h.Status, "ok")
+	assert.Assert(t, h.LastFetch != nil)
+
+	// Once the connection is gone, Home Assistant can't answer.
+	gone.Store(true)
+	viper.Set("serve.ready_max_age", nil)
+	status, h = get("/readyz")
+	assert.Equal(t, status, http.StatusServiceUnavailable)
+	assert.Assert(t, strings.HasPrefix(h.Error, "pinging Home Assistant: reading from websocket"), h.Error)
+	assert.Equal(t, h.Connected, false)
+}
+
+func TestGroupPoints(t *testing.T) {
+	// Sunday 3 and Monday 4 September, most recent first.
+	sunday := time.Date(2023, 9, 3, 0, 0, 0, 0, time.UTC)
+	values := make([]float64, hoursInADay)
+	values[0], values[12] = 1, 0.5
+	results := []Day{{Date: sunday.Add(24 * time.Hour), Values: values}, {Date: sunday, Values: values}}
+
+	points, err := groupPoints(results, time.Hour, "week")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, points, []point{
+		{X: sunday.Add(-6 * 24 * time.Hour).UnixMilli(), Y: 1.5},
+		{X: sunday.Add(24 * time.Hour).UnixMilli(), Y: 1.5},
+	})
+
+	points, err = groupPoints(results, time.Hour, "month")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, points, []point{{X: time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC).UnixMilli(), Y: 3}})
+
+	points, err = groupPoints(results, time.Hour, "hour")
+	assert.NilError(t, err)
+	assert.Equal(t, len(points), 2*hoursInADay)
+	assert.DeepEqual(t, points[12], point{X: sunday.Add(12 * time.Hour).UnixMilli(), Y: 0.5})
+
+	points, err = groupPoints(results, time.Hour, "hour_of_day")
+	assert.NilError(t, err)
+	assert.Equal(t, len(points), hoursInADay)
+	assert.DeepEqual(t, points[0], point{X: 0, Y: 1})
+}
+
+func TestClient_Handler_Metrics(t *testing.T) {
+	viper.Set("sensor_id", "sensor.energy")
+	viper.Set("serve.metrics_interval", time.Hour)
+	defer viper.Set("serve.metrics_interval", 0)
+
+	yesterday := time.Now().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	var readings []Reading
+	for i := 0; i < 2*hoursInADay; i++ {
+		readings = append(readings, Reading{Start: yesterday.Add(-24 * time.Hour).Add(time.Duration(i) * time.Hour), Value: float64(i % 2)})
+	}
+	c := New(Config{Days: 2})
+	c.source = fakeSource{"sensor.energy": readings}
+	s := httptest.NewServer(c.Handler())
+	defer s.Close()
+	defer c.Stop()
+
+	get := func() string {
+		resp, err := http.Get(s.URL + "/metrics")
+		assert.NilError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		var body strings.Builder
+		_, err = io.Copy(&body, resp.Body)
+		assert.NilError(t, err)
+		return body.String()
+	}
+	// The averages are worked out in the background, straight after the handler is created.
+	body := get()
+	for deadline := time.Now().Add(5 * time.Second); !strings.Contains(body, "powertracker_profile_days") && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		body = get()
+	}
+	for _, line := range []string{
+		`powertracker_slot_average_kwh{sensor="sensor.energy",slot="0"} 0`,
+		`powertracker_slot_average_kwh{sensor="sensor.energy",slot="1"} 1`,
+		`powertracker_day_average_kwh{sensor="sensor.energy"} 12`,
+		`powertracker_profile_days{sensor="sensor.energy"} 2`,
+		"# TYPE powertracker_requests_total counter",
+		"powertracker_profile_refresh_failures_total 0",
+	} {
+		assert.Assert(t, strings.Contains(body, line+"\n"), "missing %q in\n%s", line, body)
+	}
+}
+
+func TestLabelValue(t *testing.T) {
+	assert.Equal(t, labelValue(`sensor.a"b\c`+"\n"), `sensor.a\"b\\c\n`)
+}
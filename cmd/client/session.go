@@ -0,0 +1,171 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// session is a record of the frames exchanged with Home Assistant during a run. Replaying it
+// in place of a real connection reproduces the run exactly, which makes bug reports and
+// regression tests of the aggregation pipeline possible without access to the instance.
+type session struct {
+	// Started is when the run began. Replays use it as the current time, so the same days are
+	// requested and reported.
+	Started time.Time `json:"started"`
+	Frames  []frame   `json:"frames"`
+
+	// mu guards Frames, as responses are read on a goroutine of their own.
+	mu sync.Mutex
+}
+
+// add appends a frame to the session.
+func (s *session) add(f frame) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Frames = append(s.Frames, f)
+}
+
+// frame is a single websocket message, sent by powertracker or received from Home Assistant.
+type frame struct {
+	Direction string          `json:"direction"` // Direction is "sent" or "received".
+	Data      json.RawMessage `json:"data"`
+}
+
+// readFrame reads a message from the connection into v, adding it to the session if one is
+// being recorded.
+func (c *Client) readFrame(conn *websocket.Conn, v interface{}) error {
+	data, err := c.readMessage(conn)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// readMessage reads a message from the connection, adding it to the session if one is being
+// recorded.
+func (c *Client) readMessage(conn *websocket.Conn) ([]byte, error) {
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	c.count(func(s *Stats) { s.BytesReceived += int64(len(data)) })
+	if c.session != nil {
+		c.session.add(frame{Direction: "received", Data: data})
+	}
+	return data, nil
+}
+
+// writeFrame writes v to the connection, adding it to the session if one is being recorded.
+// The access token is never recorded.
+func (c *Client) writeFrame(conn *websocket.Conn, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if c.session != nil {
+		recorded := data
+		if auth, ok := v.(map[string]string); ok && auth["type"] == "auth" {
+			recorded = json.RawMessage(`{"type":"auth","access_token":"REDACTED"}`)
+		}
+		c.session.add(frame{Direction: "sent", Data: recorded})
+	}
+	return conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// saveSession writes the recorded session to the Record path.
+func (c *Client) saveSession() error {
+	c.session.mu.Lock()
+	data, err := json.MarshalIndent(c.session, "", "  ")
+	c.session.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("encoding session: %w", err)
+	}
+	if err := os.WriteFile(c.Config.Record, data, 0o600); err != nil {
+		return fmt.Errorf("writing session: %w", err)
+	}
+	c.logger().Info().Msgf("recorded %d frames to %s", len(c.session.Frames), c.Config.Record)
+	return nil
+}
+
+// replayServer is a local websocket server that plays back a recorded session. Whenever the
+// session shows powertracker sending a frame, the server waits for the client to send one, and
+// whenever it shows a frame being received, the server sends it. The frames sent by the client
+// aren't checked, so the same command should be replayed as was recorded.
+type replayServer struct {
+	session *session
+	server  *http.Server
+	url     string
+
+	mu   sync.Mutex
+	next int // next is the index of the next frame, carried across reconnections.
+}
+
+// startReplay reads the session at path and starts serving it on a local port.
+func startReplay(path string) (*replayServer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("decoding session: %w", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	r := &replayServer{session: &s, url: "http://" + listener.Addr().String()}
+	r.server = &http.Server{Handler: http.HandlerFunc(r.serve), ReadHeaderTimeout: 10 * time.Second}
+	go r.server.Serve(listener)
+	return r, nil
+}
+
+func (r *replayServer) serve(w http.ResponseWriter, req *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for ; r.next < len(r.session.Frames); r.next++ {
+		f := r.session.Frames[r.next]
+		if f.Direction == "sent" {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+			continue
+		}
+		if err := conn.WriteMessage(websocket.TextMessage, f.Data); err != nil {
+			return
+		}
+	}
+}
+
+// sent reports whether the session shows a command of the given type being sent.
+func (r *replayServer) sent(command string) bool {
+	for _, f := range r.session.Frames {
+		var msg struct {
+			Type string `json:"type"`
+		}
+		if f.Direction == "sent" && json.Unmarshal(f.Data, &msg) == nil && msg.Type == command {
+			return true
+		}
+	}
+	return false
+}
+
+// Close stops the server.
+func (r *replayServer) Close() error {
+	return r.server.Close()
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestClient_RecordReplay(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, _ := upgrader.Upgrade(w, r, nil)
+		defer conn.Close()
+
+		var msg map[string]interface{}
+		assert.NilError(t, conn.WriteJSON(map[string]string{"type": "auth_required"}), "write initial message failed")
+		assert.NilError(t, conn.ReadJSON(&msg), "read auth message failed")
+		assert.NilError(t, conn.WriteJSON(map[string]string{"type": "auth_ok"}), "write auth response failed")
+
+		// Each hour of the requested range used 0.5 kWh.
+		for conn.ReadJSON(&msg) == nil {
+			if msg["type"] == "get_config" {
+				assert.NilError(t, conn.WriteJSON(map[string]interface{}{
+					"id":      msg["id"],
+					"type":    "result",
+					"success": true,
+					"result":  map[string]interface{}{"time_zone": "Europe/London"},
+				}), "write config response failed")
+				continue
+			}
+			start, err := time.Parse(time.RFC3339, msg["start_time"].(string))
+			assert.NilError(t, err)
+			end, err := time.Parse(time.RFC3339, msg["end_time"].(string))
+			assert.NilError(t, err)
+			var stats []map[string]interface{}
+			for hour := start; hour.Before(end); hour = hour.Add(time.Hour) {
+				stats = append(stats, map[string]interface{}{"start": hour.UnixMilli(), "change": 0.5})
+			}
+			assert.NilError(t, conn.WriteJSON(map[string]interface{}{
+				"id":      msg["id"],
+				"type":    "result",
+				"success": true,
+				"result":  map[string]interface{}{"sensor.energy": stats},
+			}), "write statistics response failed")
+		}
+	}))
+	defer s.Close()
+
+	viper.Set("url", s.URL)
+	viper.Set("api_key", "test_token")
+	viper.Set("sensor_id", "sensor.energy")
+	defer viper.Set("url", "")
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	c := New(Config{Days: 2, Record: path})
+	assert.NilError(t, c.Connect())
+	recorded, err := getResults(c)
+	assert.NilError(t, err)
+	assert.NilError(t, c.Close())
+
+	data, err := os.ReadFile(path)
+	assert.NilError(t, err)
+	assert.Assert(t, !strings.Contains(string(data), "test_token"), "the access token was recorded")
+
+	// The replay gives the same results without Home Assistant.
+	s.Close()
+	c = New(Config{Days: 2, Replay: path})
+	assert.NilError(t, c.Connect())
+	replayed, err := getResults(c)
+	assert.NilError(t, err)
+	assert.NilError(t, c.Close())
+	assert.DeepEqual(t, replayed, recorded)
+	assert.Equal(t, replayed[0].Values[0], 0.5)
+	// Days run from midnight in Home Assistant's time zone, as recorded.
+	assert.Equal(t, replayed[0].Date.Location().String(), "Europe/London")
+}
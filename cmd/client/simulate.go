@@ -0,0 +1,160 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/powertracker/cmd/i18n"
+	"github.com/spf13/viper"
+)
+
+// Shift moves some of each day's consumption from one hour of the day to another, such as running
+// the dishwasher overnight instead of after dinner.
+type Shift struct {
+	KWh float64
+	// From and To are the hours of the day to move consumption from and to, 0 to 23.
+	From int
+	To   int
+}
+
+// apply returns a copy of the results with the shift made on each day, and how much was moved on
+// each. No more is moved than was used in the hour it is moved from, and it is moved within the same
+// day, so the total for each day stays the same.
+func (s Shift) apply(results []Day) ([]Day, []float64) {
+	shifted := make([]Day, len(results))
+	moved := make([]float64, len(results))
+	for i, day := range results {
+		values := make([]float64, len(day.Values))
+		copy(values, day.Values)
+		moved[i] = math.Min(s.KWh, values[s.From])
+		values[s.From] -= moved[i]
+		values[s.To] += moved[i]
+		shifted[i] = Day{Date: day.Date, Values: values}
+	}
+	return shifted, moved
+}
+
+// SimulateShift reports how much the shift would have changed the cost of the period on the
+// selected tariff and, when co2_intensity_sensor_id is set, its emissions.
+func (c *Client) SimulateShift(s Shift) error {
+	if s.KWh <= 0 {
+		return fmt.Errorf("the energy to move must be more than 0")
+	}
+	if s.From < 0 || s.From >= hoursInADay || s.To < 0 || s.To >= hoursInADay {
+		return fmt.Errorf("hours must be between 0 and %d", hoursInADay-1)
+	}
+	if s.From == s.To {
+		return fmt.Errorf("the hours to move from and to must differ")
+	}
+	if !c.hourly() {
+		return fmt.Errorf("simulations are not supported %s", c.mode())
+	}
+	t, err := c.tariff()
+	if err != nil {
+		return err
+	}
+
+	results, err := getResults(c)
+	if err != nil {
+		return fmt.Errorf("getting results: %w", err)
+	}
+	c.fixSpikes(results)
+	shifted, moved := s.apply(results)
+
+	// Standing charges and exports don't change, so only the energy and its tax matter.
+	before, err := t.bills(results, nil, time.Hour)
+	if err != nil {
+		return err
+	}
+	after, err := t.bills(shifted, nil, time.Hour)
+	if err != nil {
+		return err
+	}
+	var costBefore, costAfter float64
+	for i := range before {
+		costBefore += before[i].total()
+		costAfter += after[i].total()
+	}
+
+	var intensities map[int64]float64
+	if id := viper.GetString("co2_intensity_sensor_id"); id != "" {
+		if intensities, err = c.hourlyIntensities(id, results); err != nil {
+			return fmt.Errorf("getting carbon intensity: %w", err)
+		}
+	}
+
+	cur := currencyConfig()
+	table := tablewriter.NewWriter(os.Stdout)
+	header := []string{"", i18n.T("Cost")}
+	if intensities != nil {
+		header = append(header, i18n.T("kg CO₂"))
+	}
+	table.SetHeader(header)
+	co2Before, co2After := emissions(results, intensities), emissions(shifted, intensities)
+	for _, row := range []struct {
+		name      string
+		cost, co2 float64
+	}{
+		{i18n.T("Actual"), costBefore, co2Before},
+		{i18n.T("Shifted"), costAfter, co2After},
+		{i18n.T("Difference"), costAfter - costBefore, co2After - co2Before},
+	} {
+		line := []string{row.name, cur.format(row.cost)}
+		if intensities != nil {
+			line = append(line, fmt.Sprintf("%.1f", row.co2))
+		}
+		table.Append(line)
+	}
+	table.Render()
+
+	saving := costBefore - costAfter
+	summary := "Moving %.1f kWh a day from %02d:00 to %02d:00 would have saved %s over %d days."
+	if saving < 0 {
+		summary = "Moving %.1f kWh a day from %02d:00 to %02d:00 would have cost %s more over %d days."
+	}
+	fmt.Println(i18n.T(summary, sum(moved)/float64(len(results)), s.From, s.To, cur.format(math.Abs(saving)), len(results)))
+	if intensities != nil {
+		if co2After <= co2Before {
+			fmt.Println(i18n.T("It would have avoided %.1f kg of CO₂.", co2Before-co2After))
+		} else {
+			fmt.Println(i18n.T("It would have added %.1f kg of CO₂.", co2After-co2Before))
+		}
+	}
+	return nil
+}
+
+// hourlyIntensities returns the mean grid carbon intensity, in gCO₂eq/kWh, of each hour in the
+// results, keyed by its start in milliseconds.
+func (c *Client) hourlyIntensities(id string, results []Day) (map[int64]float64, error) {
+	start, end := span(results)
+	stats, err := c.statistics(id, start, end, "hour", "mean")
+	if err != nil {
+		return nil, err
+	}
+	intensities := make(map[int64]float64, len(stats))
+	for _, stat := range stats {
+		intensities[stat.Start] = stat.Mean
+	}
+	return intensities, nil
+}
+
+// emissions returns the kg of CO₂ emitted by the consumption in the results, given the carbon
+// intensity of each hour. Hours without an intensity are left out.
+func emissions(results []Day, intensities map[int64]float64) float64 {
+	var total float64
+	for _, day := range results {
+		for h, v := range day.Values {
+			start, ok := slotStart(day.Date, h, time.Hour)
+			if !ok {
+				continue
+			}
+			if intensity, ok := intensities[start.UnixMilli()]; ok {
+				total += v * intensity / 1000
+			}
+		}
+	}
+	return total
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestShift_Apply(t *testing.T) {
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	values := make([]float64, hoursInADay)
+	values[2] = 0.25
+	values[18] = 2
+	results := []Day{{Date: day, Values: values}}
+
+	shifted, moved := Shift{KWh: 3, From: 18, To: 2}.apply(results)
+	// Only what was used at 18:00 can be moved.
+	assert.DeepEqual(t, moved, []float64{2})
+	assert.Equal(t, shifted[0].Values[18], 0.0)
+	assert.Equal(t, shifted[0].Values[2], 2.25)
+	// The results themselves are left alone.
+	assert.Equal(t, results[0].Values[18], 2.0)
+}
+
+func TestEmissions(t *testing.T) {
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	results := []Day{{Date: day, Values: []float64{2, 4}}}
+	intensities := map[int64]float64{
+		day.UnixMilli(): 250,
+		// The second hour has no intensity, so it's left out.
+	}
+	assert.Equal(t, emissions(results, intensities), 0.5)
+	assert.Equal(t, emissions(results, nil), 0.0)
+}
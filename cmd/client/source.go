@@ -0,0 +1,84 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// Reading is a single consumption value, in kWh, for the period beginning at Start.
+type Reading struct {
+	Start time.Time
+	Value float64
+}
+
+// Source provides consumption readings for a statistic over a time range.
+// The period is one of the recorder's statistics periods, e.g. "hour".
+type Source interface {
+	Readings(id string, start, end time.Time, period string) ([]Reading, error)
+}
+
+// offline is the source used with --offline, when everything has to come from the local cache.
+type offline struct{}
+
+func (offline) Readings(id string, start, end time.Time, period string) ([]Reading, error) {
+	return nil, fmt.Errorf("%s on %s is not in the local cache - run without --offline to fetch it", id, start.Format("2006-01-02"))
+}
+
+// covers reports whether any of the readings fall in the last slot of the given width before end,
+// which shows the source had finished recording the range when they were fetched.
+func covers(readings []Reading, end time.Time, width time.Duration) bool {
+	for _, r := range readings {
+		if !r.Start.Before(end.Add(-width)) && r.Start.Before(end) {
+			return true
+		}
+	}
+	return false
+}
+
+// bucket sums readings into n slots of the given width, the first of which begins at start.
+// Readings falling outside of the slots are ignored, and slots without readings are left at zero.
+// Slots go by the clock in the time zone of start, so on the day the clocks go forward the hour
+// that is skipped is left at zero, and on the day they go back the hour that is repeated has both.
+func bucket(readings []Reading, start time.Time, width time.Duration, n int) []float64 {
+	values := make([]float64, n)
+	for _, r := range readings {
+		if i, ok := slot(r.Start, start, width, n); ok {
+			values[i] += r.Value
+		}
+	}
+	return values
+}
+
+// slot returns which of n slots of the given width from start, by the clock, t falls in.
+func slot(t, start time.Time, width time.Duration, n int) (int, bool) {
+	if t.Before(start) {
+		return 0, false
+	}
+	_, from := start.Zone()
+	_, at := t.In(start.Location()).Zone()
+	i := int((t.Sub(start) + time.Duration(at-from)*time.Second) / width)
+	return i, i < n
+}
+
+// slotStart returns when slot i of the given width from start begins, by the clock in the time
+// zone of start, the reverse of slot. It reports false for a slot that doesn't happen because the
+// clocks go forward in it, which bucket always leaves at zero.
+func slotStart(start time.Time, i int, width time.Duration) (time.Time, bool) {
+	t := start.Add(time.Duration(i) * width)
+	_, from := start.Zone()
+	_, at := t.Zone()
+	t = t.Add(-time.Duration(at-from) * time.Second)
+	j, _ := slot(t, start, width, i+1)
+	return t, j == i
+}
+
+// slotEnd returns when slot i of the given width from start ends, which is when the next slot
+// that happens begins. On the day the clocks go back, the slot with the repeated hour is an hour
+// longer than the rest.
+func slotEnd(start time.Time, i int, width time.Duration) time.Time {
+	for j := i + 1; ; j++ {
+		if t, ok := slotStart(start, j, width); ok {
+			return t
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/poolski/powertracker/cmd/cache"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+// fakeSource serves readings from memory, keyed by statistic ID.
+type fakeSource map[string][]Reading
+
+func (f fakeSource) Readings(id string, start, end time.Time, period string) ([]Reading, error) {
+	var readings []Reading
+	for _, r := range f[id] {
+		if !r.Start.Before(start) && r.Start.Before(end) {
+			readings = append(readings, r)
+		}
+	}
+	return readings, nil
+}
+
+func TestBucket(t *testing.T) {
+	start := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	readings := []Reading{
+		{Start: start.Add(-30 * time.Minute), Value: 9},
+		{Start: start, Value: 0.25},
+		{Start: start.Add(30 * time.Minute), Value: 0.5},
+		{Start: start.Add(2 * time.Hour), Value: 1},
+		{Start: start.Add(3 * time.Hour), Value: 9},
+	}
+
+	assert.DeepEqual(t, bucket(readings, start, time.Hour, 3), []float64{0.75, 0, 1})
+}
+
+func TestGetResults_HalfHourly(t *testing.T) {
+	day := time.Now().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	var readings []Reading
+	for i := 0; i < 24*12; i++ {
+		readings = append(readings, Reading{Start: day.Add(time.Duration(i) * fiveMinutes), Value: 0.125})
+	}
+	viper.Set("sensor_id", "sensor.energy")
+
+	c := New(Config{Days: 1, HalfHourly: true})
+	c.source = fakeSource{"sensor.energy": readings}
+	results, err := getResults(c)
+	assert.NilError(t, err)
+	assert.Equal(t, len(results[0].Values), halfHoursInADay)
+	assert.Equal(t, results[0].Values[0], 0.75)
+	assert.Equal(t, results[0].Values[47], 0.75)
+}
+
+func TestGetResults_HalfHourlyBeyondRetention(t *testing.T) {
+	day := time.Now().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	var readings []Reading
+	for i := 0; i < 24*12; i++ {
+		readings = append(readings, Reading{Start: day.Add(time.Duration(i) * fiveMinutes), Value: 0.125})
+	}
+	viper.Set("sensor_id", "sensor.energy")
+
+	// Only yesterday has 5-minute data, so the two days before are left out rather than
+	// averaged in as zeros.
+	c := New(Config{Days: 3, HalfHourly: true, Explain: true})
+	c.source = fakeSource{"sensor.energy": readings}
+	results, err := getResults(c)
+	assert.NilError(t, err)
+	assert.Equal(t, len(results), 1)
+	assert.Assert(t, results[0].Date.Equal(day))
+	assert.Equal(t, len(c.provenance.Excluded), 2)
+	for _, reason := range c.provenance.Excluded {
+		assert.Equal(t, reason, "no 5-minute data")
+	}
+}
+
+func TestDifferences(t *testing.T) {
+	start := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	stats := []Statistic{
+		{Start: start.UnixMilli(), State: 100},
+		{Start: start.Add(time.Hour).UnixMilli(), State: 101.5},
+		{Start: start.Add(2 * time.Hour).UnixMilli(), State: 0.5},
+		{Start: start.Add(3 * time.Hour).UnixMilli(), State: 1.25},
+	}
+
+	readings := differences(stats, func(s Statistic) float64 { return s.State })
+	assert.DeepEqual(t, bucket(readings, start, time.Hour, 4), []float64{0, 1.5, 0.5, 0.75})
+}
+
+func TestGetResults_Offline(t *testing.T) {
+	day := time.Now().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	var readings []Reading
+	for i := 0; i < hoursInADay; i++ {
+		readings = append(readings, Reading{Start: day.Add(time.Duration(i) * time.Hour), Value: 0.5})
+	}
+	viper.Set("sensor_id", "sensor.energy")
+	cfg := Config{Days: 1, CacheFile: filepath.Join(t.TempDir(), "cache.db")}
+
+	// Fetching a complete day stores it in the cache...
+	c := New(cfg)
+	c.source = fakeSource{"sensor.energy": readings}
+	_, err := getResults(c)
+	assert.NilError(t, err)
+
+	// ...so it can be used offline.
+	cfg.Offline = true
+	c = New(cfg)
+	assert.NilError(t, c.Connect())
+	results, err := getResults(c)
+	assert.NilError(t, err)
+	assert.Equal(t, results[0].Values[23], 0.5)
+
+	cfg.Days = 2
+	c = New(cfg)
+	assert.NilError(t, c.Connect())
+	_, err = getResults(c)
+	assert.ErrorContains(t, err, "is not in the local cache - run without --offline to fetch it")
+}
+
+func TestSettled(t *testing.T) {
+	end := time.Date(2023, 9, 2, 0, 0, 0, 0, time.UTC)
+
+	viper.Set("cache_ttl", "")
+	assert.Assert(t, !settled(cache.Entry{Fetched: end.Add(47 * time.Hour)}, end), "expected a day fetched within 48h to be re-fetched")
+	assert.Assert(t, settled(cache.Entry{Fetched: end.Add(48 * time.Hour)}, end), "expected a day fetched after 48h to be trusted")
+
+	assert.Assert(t, settled(cache.Entry{Fetched: end, Source: "import:octopus"}, end), "expected imported days to be trusted")
+
+	viper.Set("cache_ttl", "1h")
+	assert.Assert(t, settled(cache.Entry{Fetched: end.Add(2 * time.Hour)}, end), "expected cache_ttl to be used")
+	viper.Set("cache_ttl", "")
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"sort"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// defaultMaxHourlyKWh is the most a household could plausibly use in an hour. Anything above it
+// is almost certainly a meter reset or a glitch rather than real consumption.
+const defaultMaxHourlyKWh = 50
+
+// adjustment records a value that was corrected because it couldn't be real consumption.
+type adjustment struct {
+	Start    time.Time
+	Original float64
+	Value    float64
+}
+
+// correctSpikes replaces negative values, which are left behind when a meter resets, and values
+// above the limit with the median of the same period on the days that weren't affected, or zero if
+// there are none. It returns what was adjusted.
+func correctSpikes(results []Day, width time.Duration, limit float64) []adjustment {
+	bad := func(v float64) bool { return v < 0 || v > limit }
+
+	var adjustments []adjustment
+	for i, day := range results {
+		for j, v := range day.Values {
+			if !bad(v) {
+				continue
+			}
+			var others []float64
+			for k, other := range results {
+				if k != i && j < len(other.Values) && !bad(other.Values[j]) {
+					others = append(others, other.Values[j])
+				}
+			}
+			corrected := median(others)
+			start, _ := slotStart(day.Date, j, width)
+			adjustments = append(adjustments, adjustment{
+				Start:    start,
+				Original: v,
+				Value:    corrected,
+			})
+			day.Values[j] = corrected
+		}
+	}
+	return adjustments
+}
+
+// median returns the middle value, or zero if there are none.
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 0 {
+		return (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	return sorted[n/2]
+}
+
+// maxHourlyKWh returns the most that can be used in an hour, from max_hourly_kwh.
+func maxHourlyKWh() float64 {
+	if limit := viper.GetFloat64("max_hourly_kwh"); limit != 0 {
+		return limit
+	}
+	return defaultMaxHourlyKWh
+}
+
+// fixSpikes corrects impossible values in the results, using the max_hourly_kwh limit from the
+// config, and logs a warning for each one so they can be checked.
+func (c *Client) fixSpikes(results []Day) {
+	limit := maxHourlyKWh()
+	width, _ := c.slots()
+	c.reportSpikes(correctSpikes(results, width, limit*width.Hours()))
+}
+
+// reportSpikes records the corrections for --explain, and logs a warning for each.
+func (c *Client) reportSpikes(adjustments []adjustment) {
+	c.note(func(p *provenance) { p.Corrected = append(p.Corrected, adjustments...) })
+	for _, a := range adjustments {
+		c.logger().Warn().Msgf("corrected %f kWh at %s to %f kWh - this looks like a meter reset or spike", a.Original, a.Start.Format("2006-01-02 15:04"), a.Value)
+	}
+	if len(adjustments) > 0 {
+		c.logger().Warn().Msgf("corrected %d impossible values; set max_hourly_kwh if your usage is genuinely higher than %.0f kWh an hour", len(adjustments), maxHourlyKWh())
+	}
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gotest.tools/v3/assert"
+)
+
+func TestCorrectSpikes(t *testing.T) {
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	results := []Day{
+		{Date: day, Values: []float64{1, 2}},
+		{Date: day.Add(24 * time.Hour), Values: []float64{-40000, 4}},
+		{Date: day.Add(48 * time.Hour), Values: []float64{3, 60000}},
+	}
+
+	adjustments := correctSpikes(results, time.Hour, 50)
+	assert.DeepEqual(t, adjustments, []adjustment{
+		{Start: day.Add(24 * time.Hour), Original: -40000, Value: 2},
+		{Start: day.Add(49 * time.Hour), Original: 60000, Value: 3},
+	})
+	assert.DeepEqual(t, results[1].Values, []float64{2, 4})
+	assert.DeepEqual(t, results[2].Values, []float64{3, 3})
+}
+
+func TestClient_FixSpikes_Logger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+	client := New(Config{Logger: &logger})
+
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	client.fixSpikes([]Day{{Date: day, Values: []float64{1, 60000, 2}}})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, len(lines), 2, "expected a warning for the spike and a summary")
+	assert.Assert(t, strings.Contains(lines[0], "corrected 60000.000000 kWh at 2023-09-01 01:00"), lines[0])
+}
+
+func TestMedian(t *testing.T) {
+	assert.Equal(t, median(nil), 0.0)
+	assert.Equal(t, median([]float64{3, 1, 2}), 2.0)
+	assert.Equal(t, median([]float64{4, 1, 2, 3}), 2.5)
+}
@@ -0,0 +1,300 @@
+package client
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/powertracker/cmd/i18n"
+	"github.com/spf13/viper"
+)
+
+// split divides the hours of the results into groups, such as hours spent at home or away,
+// so a profile can be reported for each.
+type split struct {
+	Groups []string
+	// Classify returns the group the hour starting at the given time belongs to, or "" to
+	// leave it out.
+	Classify func(start time.Time) string
+}
+
+// newSplit returns the split selected with the --split flag.
+func (c *Client) newSplit(results []Day) (*split, error) {
+	switch c.Config.Split {
+	case "occupancy":
+		return c.occupancySplit(results)
+	case "season":
+		return c.seasonSplit(results)
+	case "weekday", "weekend":
+		return weekdaySplit(), nil
+	case "dayofweek":
+		return dayOfWeekSplit(), nil
+	default:
+		return nil, fmt.Errorf("unknown split %q", c.Config.Split)
+	}
+}
+
+// splitProfiles returns the average consumption in each hour of the day for each group in the
+// split. Hours without any data for a group are NaN.
+func splitProfiles(results []Day, s *split) [][]float64 {
+	sums := make([][]float64, len(s.Groups))
+	counts := make([][]int, len(s.Groups))
+	index := make(map[string]int, len(s.Groups))
+	for i, g := range s.Groups {
+		sums[i] = make([]float64, hoursInADay)
+		counts[i] = make([]int, hoursInADay)
+		index[g] = i
+	}
+
+	for _, day := range results {
+		for h, v := range day.Values {
+			start, ok := slotStart(day.Date, h, time.Hour)
+			if !ok {
+				continue
+			}
+			g, ok := index[s.Classify(start)]
+			if !ok {
+				continue
+			}
+			sums[g][h] += v
+			counts[g][h]++
+		}
+	}
+
+	profiles := make([][]float64, len(s.Groups))
+	for g := range s.Groups {
+		profiles[g] = make([]float64, hoursInADay)
+		for h := range profiles[g] {
+			profiles[g][h] = math.NaN()
+			if counts[g][h] > 0 {
+				profiles[g][h] = sums[g][h] / float64(counts[g][h])
+			}
+		}
+	}
+	return profiles
+}
+
+// splitTotals returns the total consumption of each group in the split.
+func splitTotals(results []Day, s *split) []float64 {
+	totals := make([]float64, len(s.Groups))
+	index := make(map[string]int, len(s.Groups))
+	for i, g := range s.Groups {
+		index[g] = i
+	}
+	for _, day := range results {
+		for h, v := range day.Values {
+			start, ok := slotStart(day.Date, h, time.Hour)
+			if !ok {
+				continue
+			}
+			if g, ok := index[s.Classify(start)]; ok {
+				totals[g] += v
+			}
+		}
+	}
+	return totals
+}
+
+// dailyTotal adds up a profile, skipping hours without any data.
+func dailyTotal(profile []float64) float64 {
+	total := 0.0
+	for _, v := range profile {
+		if !math.IsNaN(v) {
+			total += v
+		}
+	}
+	return total
+}
+
+// writeSplit outputs a profile for each group in the split, as a table, CSV file or plain text.
+// Tables and CSV files also have the average daily total of each profile, and the group's total
+// consumption over the whole period.
+func (c *Client) writeSplit(results []Day, headers []string) error {
+	s, err := c.newSplit(results)
+	if err != nil {
+		return err
+	}
+	profiles := splitProfiles(results, s)
+	totals := splitTotals(results, s)
+
+	format := func(v float64) string {
+		if math.IsNaN(v) {
+			return ""
+		}
+		return fmt.Sprintf("%f", v)
+	}
+
+	switch c.Config.Output {
+	case "text":
+		for g, name := range s.Groups {
+			fmt.Printf("# %s\n", name)
+			for _, v := range profiles[g] {
+				fmt.Printf("%s,\n", format(v))
+			}
+		}
+	case "csv":
+		f, err := os.Create(c.Config.FilePath)
+		if err != nil {
+			return fmt.Errorf("creating file: %w", err)
+		}
+		defer f.Close()
+
+		writer := csv.NewWriter(f)
+		if err := writer.Write(append(append([]string{"profile"}, headers...), "daily", "total")); err != nil {
+			return fmt.Errorf("writing headers: %w", err)
+		}
+		for g, name := range s.Groups {
+			row := []string{name}
+			for _, v := range profiles[g] {
+				row = append(row, format(v))
+			}
+			row = append(row, format(dailyTotal(profiles[g])), format(totals[g]))
+			if err := writer.Write(row); err != nil {
+				return fmt.Errorf("writing row: %w", err)
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader(append(append([]string{i18n.T("Profile")}, headers...), i18n.T("Daily"), i18n.T("Total")))
+		for g, name := range s.Groups {
+			row := []string{name}
+			for _, v := range profiles[g] {
+				row = append(row, format(v))
+			}
+			row = append(row, format(dailyTotal(profiles[g])), format(totals[g]))
+			table.Append(row)
+		}
+		table.Render()
+	}
+	return nil
+}
+
+// occupancySplit divides hours into those spent at home and away, using the configured
+// occupancy entity. An hour counts as at home if the entity was home for at least half of it.
+// Person, device tracker and group entities are home when their state is "home", binary
+// sensors when "on", and zones when at least one person is in them.
+func (c *Client) occupancySplit(results []Day) (*split, error) {
+	entityID := viper.GetString("occupancy.entity_id")
+	if entityID == "" {
+		return nil, fmt.Errorf("occupancy.entity_id is required")
+	}
+	start, end := span(results)
+	changes, err := c.history(entityID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("getting occupancy: %w", err)
+	}
+
+	return &split{
+		Groups: []string{"home", "away"},
+		Classify: func(start time.Time) string {
+			if timeIn(changes, start, start.Add(time.Hour), isHome) >= 30*time.Minute {
+				return "home"
+			}
+			return "away"
+		},
+	}, nil
+}
+
+func isHome(state string) bool {
+	switch state {
+	case "home", "on":
+		return true
+	}
+	n, err := strconv.Atoi(state)
+	return err == nil && n > 0
+}
+
+// defaultHeatingMonths are the months of the heating season, October to April, when neither
+// season.heating_months nor season.threshold is set.
+var defaultHeatingMonths = []int{10, 11, 12, 1, 2, 3, 4}
+
+// seasonSplit divides days into the heating and non-heating seasons. If season.threshold is set,
+// days with an average outdoor temperature below it are heating days, which needs
+// temperature_sensor_id. Otherwise, days in season.heating_months are.
+func (c *Client) seasonSplit(results []Day) (*split, error) {
+	heating := make(map[time.Time]bool, len(results))
+
+	if viper.IsSet("season.threshold") {
+		id := viper.GetString("temperature_sensor_id")
+		if id == "" {
+			return nil, fmt.Errorf("temperature_sensor_id is required for season.threshold")
+		}
+		temps, err := c.dailyTemperatures(id, results)
+		if err != nil {
+			return nil, fmt.Errorf("getting temperatures: %w", err)
+		}
+		threshold := viper.GetFloat64("season.threshold")
+		for i, day := range results {
+			if !math.IsNaN(temps[i]) {
+				heating[day.Date] = temps[i] < threshold
+			}
+		}
+	} else {
+		months := viper.GetIntSlice("season.heating_months")
+		if len(months) == 0 {
+			months = defaultHeatingMonths
+		}
+		for _, day := range results {
+			heating[day.Date] = false
+			for _, m := range months {
+				if day.Date.Month() == time.Month(m) {
+					heating[day.Date] = true
+				}
+			}
+		}
+	}
+
+	return &split{
+		Groups: []string{"heating", "non-heating"},
+		Classify: func(start time.Time) string {
+			h, ok := heating[midnight(start)]
+			switch {
+			case !ok:
+				return ""
+			case h:
+				return "heating"
+			default:
+				return "non-heating"
+			}
+		},
+	}, nil
+}
+
+// weekdaySplit divides days into weekdays, Monday to Friday, and weekends, as most homes use
+// energy quite differently at weekends.
+func weekdaySplit() *split {
+	return &split{
+		Groups: []string{"weekday", "weekend"},
+		Classify: func(start time.Time) string {
+			// Slots are in the time zone days run from midnight in, so that's the calendar the
+			// hour's day is looked up in.
+			switch start.Weekday() {
+			case time.Saturday, time.Sunday:
+				return "weekend"
+			default:
+				return "weekday"
+			}
+		},
+	}
+}
+
+// dayOfWeekSplit divides days by the day of the week, Monday first, for tools that take a
+// profile for each.
+func dayOfWeekSplit() *split {
+	s := &split{
+		Classify: func(start time.Time) string {
+			return strings.ToLower(start.Weekday().String())
+		},
+	}
+	for d := 1; d <= 7; d++ {
+		s.Groups = append(s.Groups, strings.ToLower(time.Weekday(d%7).String()))
+	}
+	return s
+}
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestSplitProfiles(t *testing.T) {
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	values := func(v float64) []float64 {
+		row := make([]float64, hoursInADay)
+		for i := range row {
+			row[i] = v
+		}
+		return row
+	}
+	results := []Day{
+		{Date: day, Values: values(1)},
+		{Date: day.Add(24 * time.Hour), Values: values(3)},
+	}
+
+	// Mornings of the first day are "a", the rest "b", and the last hour is left out.
+	s := &split{
+		Groups: []string{"a", "b"},
+		Classify: func(start time.Time) string {
+			switch {
+			case start.Hour() == 23:
+				return ""
+			case start.Day() == 1 && start.Hour() < 12:
+				return "a"
+			default:
+				return "b"
+			}
+		},
+	}
+	profiles := splitProfiles(results, s)
+
+	assert.Equal(t, profiles[0][0], 1.0)
+	assert.Assert(t, math.IsNaN(profiles[0][12]), "expected no data for a in the afternoon")
+	assert.Equal(t, profiles[1][0], 3.0)
+	assert.Equal(t, profiles[1][12], 2.0)
+	assert.Assert(t, math.IsNaN(profiles[1][23]), "expected the last hour to be left out")
+}
+
+func TestTimeIn(t *testing.T) {
+	start := time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)
+	changes := []stateChange{
+		{State: "not_home", Time: start.Add(-time.Hour)},
+		{State: "home", Time: start.Add(20 * time.Minute)},
+		{State: "2", Time: start.Add(40 * time.Minute)},
+		{State: "0", Time: start.Add(50 * time.Minute)},
+	}
+
+	assert.Equal(t, timeIn(changes, start, start.Add(time.Hour), isHome), 30*time.Minute)
+}
+
+func TestClient_History(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, _ := upgrader.Upgrade(w, r, nil)
+		defer conn.Close()
+
+		var msg map[string]interface{}
+		assert.NilError(t, conn.ReadJSON(&msg), "read history request failed")
+		assert.Equal(t, msg["type"], "history/history_during_period", "unexpected message type")
+		assert.DeepEqual(t, msg["entity_ids"], []interface{}{"person.me"})
+
+		assert.NilError(t, conn.WriteJSON(map[string]interface{}{
+			"id":      msg["id"],
+			"type":    "result",
+			"success": true,
+			"result": map[string]interface{}{
+				"person.me": []map[string]interface{}{
+					{"s": "home", "lu": 1693526400.5},
+					{"s": "not_home", "lu": 1693530000},
+				},
+			},
+		}), "write history response failed")
+	}))
+	defer s.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+	assert.NilError(t, err)
+	client := &Client{Conn: conn}
+
+	start := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	changes, err := client.history("person.me", start, start.Add(24*time.Hour))
+	assert.NilError(t, err)
+	assert.Equal(t, len(changes), 2)
+	assert.Equal(t, changes[0].State, "home")
+	assert.Assert(t, changes[0].Time.Equal(start.Add(500*time.Millisecond)))
+	assert.Equal(t, changes[1].State, "not_home")
+}
+
+func TestSeasonSplit(t *testing.T) {
+	defer viper.Set("season.heating_months", nil)
+	viper.Set("season.heating_months", []int{1})
+
+	jan := time.Date(2023, 1, 31, 0, 0, 0, 0, time.UTC)
+	results := []Day{
+		{Date: jan, Values: []float64{2, 4}},
+		{Date: jan.Add(24 * time.Hour), Values: []float64{1, 1}},
+	}
+
+	s, err := (&Client{}).seasonSplit(results)
+	assert.NilError(t, err)
+	assert.Equal(t, s.Classify(jan.Add(time.Hour)), "heating")
+	assert.Equal(t, s.Classify(jan.Add(25*time.Hour)), "non-heating")
+	assert.Equal(t, s.Classify(jan.Add(-time.Hour)), "")
+
+	assert.DeepEqual(t, splitTotals(results, s), []float64{6, 2})
+	assert.Equal(t, dailyTotal([]float64{1, math.NaN(), 2}), 3.0)
+}
+
+func TestWeekdaySplit(t *testing.T) {
+	friday := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	results := []Day{
+		{Date: friday, Values: []float64{1, 3}},
+		{Date: friday.Add(24 * time.Hour), Values: []float64{2, 2}},
+		{Date: friday.Add(48 * time.Hour), Values: []float64{4, 6}},
+	}
+
+	s, err := (&Client{Config: Config{Split: "weekday"}}).newSplit(results)
+	assert.NilError(t, err)
+	assert.Equal(t, s.Classify(friday.Add(23*time.Hour)), "weekday")
+	assert.Equal(t, s.Classify(friday.Add(24*time.Hour)), "weekend")
+	profiles := splitProfiles(results, s)
+	assert.DeepEqual(t, profiles[0][:2], []float64{1, 3})
+	assert.DeepEqual(t, profiles[1][:2], []float64{3, 4})
+	assert.DeepEqual(t, splitTotals(results, s), []float64{4, 14})
+}
+
+func TestDayOfWeekSplit(t *testing.T) {
+	s := dayOfWeekSplit()
+	assert.DeepEqual(t, s.Groups, []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"})
+	sunday := time.Date(2023, 9, 3, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, s.Classify(sunday.Add(23*time.Hour)), "sunday")
+	assert.Equal(t, s.Classify(sunday.Add(24*time.Hour)), "monday")
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Stats counts the work done by a Client, to help tune chunk_days and the cache for the
+// source and hardware in use.
+type Stats struct {
+	Requests      int           // Requests is the number of requests for readings made to the source, including retries.
+	Retries       int           // Retries is how many of those requests were retries of a failed one.
+	RequestTime   time.Duration // RequestTime is the total time spent waiting for requests to complete.
+	BytesReceived int64         // BytesReceived is the size of the responses read from Home Assistant.
+	CacheHits     int           // CacheHits is the number of days answered from the cache.
+	CacheMisses   int           // CacheMisses is the number of days that had to be fetched.
+	LastFetch     time.Time     // LastFetch is when readings were last fetched from the source without an error.
+}
+
+// CacheHitRate returns the fraction of days answered from the cache, or 0 if no days were looked up.
+func (s Stats) CacheHitRate() float64 {
+	if s.CacheHits+s.CacheMisses == 0 {
+		return 0
+	}
+	return float64(s.CacheHits) / float64(s.CacheHits+s.CacheMisses)
+}
+
+// AverageLatency returns the mean time taken by each request.
+func (s Stats) AverageLatency() time.Duration {
+	if s.Requests == 0 {
+		return 0
+	}
+	return s.RequestTime / time.Duration(s.Requests)
+}
+
+// Print writes a short summary of the stats.
+func (s Stats) Print(w io.Writer) {
+	fmt.Fprintf(w, "requests:       %d (%d retries)\n", s.Requests, s.Retries)
+	fmt.Fprintf(w, "request time:   %s (%s average)\n", s.RequestTime.Round(time.Millisecond), s.AverageLatency().Round(time.Millisecond))
+	fmt.Fprintf(w, "bytes received: %d\n", s.BytesReceived)
+	fmt.Fprintf(w, "cache hit rate: %.0f%% (%d of %d days)\n", s.CacheHitRate()*100, s.CacheHits, s.CacheHits+s.CacheMisses)
+}
+
+// Stats returns a snapshot of the client's stats so far.
+func (c *Client) Stats() Stats {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	return c.stats
+}
+
+// count updates the client's stats.
+func (c *Client) count(update func(s *Stats)) {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	update(&c.stats)
+}
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestStats_Print(t *testing.T) {
+	stats := Stats{
+		Requests:      4,
+		Retries:       1,
+		RequestTime:   2 * time.Second,
+		BytesReceived: 2048,
+		CacheHits:     3,
+		CacheMisses:   1,
+	}
+	var buf bytes.Buffer
+	stats.Print(&buf)
+	assert.Equal(t, buf.String(), `requests:       4 (1 retries)
+request time:   2s (500ms average)
+bytes received: 2048
+cache hit rate: 75% (3 of 4 days)
+`)
+
+	assert.Equal(t, Stats{}.CacheHitRate(), 0.0)
+	assert.Equal(t, Stats{}.AverageLatency(), time.Duration(0))
+}
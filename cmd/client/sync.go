@@ -0,0 +1,168 @@
+package client
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	// The pure Go SQLite driver, so builds need no C compiler.
+	_ "modernc.org/sqlite"
+)
+
+// Sync mirrors the hourly consumption of the configured sensors, along with generation_sensor_id
+// and export_sensor_id if they are set, into the SQLite database at SyncDatabase, for other tools
+// to query. The days fetched go into the local cache too, if there is one, so later runs, and
+// --offline, can answer from it. The first sync covers the days given with --days or --start.
+// After that, only the days since the last sync are fetched, along with the days before it that
+// were fetched before they had settled. It returns the number of days it covered, which are only
+// the days in full so far, along with ErrInterrupted or ErrIncomplete, if it stops part way
+// through; the next sync picks up from the last one that finished.
+func (c *Client) Sync() (int, error) {
+	if c.Config.SyncDatabase == "" {
+		return 0, fmt.Errorf("sync needs a database to mirror into")
+	}
+	if c.Config.Offline {
+		return 0, fmt.Errorf("sync can't be combined with --offline")
+	}
+	if err := c.checkEnd(); err != nil {
+		return 0, err
+	}
+	id := SensorID()
+	if id == "" {
+		if id = strings.Join(viper.GetStringSlice("phase_sensor_ids"), ","); id == "" {
+			return 0, fmt.Errorf("sensor_id is required")
+		}
+	}
+	db, err := openMirror(c.Config.SyncDatabase)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	until := c.until()
+
+	days := c.days()
+	if c.Config.Start.IsZero() {
+		through, found, err := c.syncedThrough(db, id)
+		if err != nil {
+			return 0, err
+		}
+		if found {
+			settling := int(math.Ceil(float64(cacheTTL()) / float64(24*time.Hour)))
+			days = datesBetween(through.AddDate(0, 0, -settling), until)
+		}
+	}
+
+	results, err := c.results(days)
+	if mirrorErr := c.mirror(db, id, results); mirrorErr != nil {
+		return 0, mirrorErr
+	}
+	if err != nil {
+		return len(results), err
+	}
+	for _, other := range []string{generationSensorID(), viper.GetString("export_sensor_id")} {
+		if other == "" {
+			continue
+		}
+		otherDays, err := c.sensorDays(other, until, days)
+		if mirrorErr := c.mirror(db, other, otherDays); mirrorErr != nil {
+			return len(results), mirrorErr
+		}
+		if err != nil {
+			return len(results), fmt.Errorf("%s: %w", other, err)
+		}
+	}
+
+	_, err = db.Exec(`INSERT INTO synced (sensor_id, through) VALUES (?, ?)
+		ON CONFLICT (sensor_id) DO UPDATE SET through = excluded.through`, id, until.Format("2006-01-02"))
+	if err != nil {
+		return len(results), fmt.Errorf("recording the sync: %w", err)
+	}
+	return len(results), nil
+}
+
+// mirrorSchema is the layout of the database Sync mirrors into. Each reading is the consumption,
+// in kWh, of the slot beginning at start, an RFC 3339 time in UTC, on the day, in the time zone days
+// run from midnight in. synced has the end of the last day mirrored for each sensor.
+const mirrorSchema = `
+CREATE TABLE IF NOT EXISTS readings (
+	sensor_id TEXT NOT NULL,
+	start     TEXT NOT NULL,
+	day       TEXT NOT NULL,
+	kwh       REAL NOT NULL,
+	PRIMARY KEY (sensor_id, start)
+);
+CREATE INDEX IF NOT EXISTS readings_day ON readings (sensor_id, day);
+CREATE TABLE IF NOT EXISTS synced (
+	sensor_id TEXT PRIMARY KEY,
+	through   TEXT NOT NULL
+);`
+
+// openMirror opens the SQLite database at path, creating it and its tables if need be.
+func openMirror(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	if _, err := db.Exec(mirrorSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating the tables in %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// mirror writes the readings of the days into the database under the sensor ID, replacing any
+// there from an earlier sync. Days that weren't fetched are left out.
+func (c *Client) mirror(db *sql.DB, id string, days []Day) error {
+	width, _ := c.slots()
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("mirroring %s: %w", id, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	stmt, err := tx.Prepare(`INSERT INTO readings (sensor_id, start, day, kwh) VALUES (?, ?, ?, ?)
+		ON CONFLICT (sensor_id, start) DO UPDATE SET day = excluded.day, kwh = excluded.kwh`)
+	if err != nil {
+		return fmt.Errorf("mirroring %s: %w", id, err)
+	}
+	defer stmt.Close()
+	for _, day := range days {
+		if day.Values == nil {
+			continue
+		}
+		for j, v := range day.Values {
+			start, ok := slotStart(day.Date, j, width)
+			if !ok {
+				continue
+			}
+			if _, err := stmt.Exec(id, start.UTC().Format(time.RFC3339), day.Date.Format("2006-01-02"), v); err != nil {
+				return fmt.Errorf("mirroring %s: %w", id, err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("mirroring %s: %w", id, err)
+	}
+	return nil
+}
+
+// syncedThrough returns the end of the last day mirrored by sync for the sensor ID, and whether
+// there has been a sync.
+func (c *Client) syncedThrough(db *sql.DB, id string) (time.Time, bool, error) {
+	var value string
+	err := db.QueryRow(`SELECT through FROM synced WHERE sensor_id = ?`, id).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("reading the last sync of %s: %w", id, err)
+	}
+	through, err := time.ParseInLocation("2006-01-02", value, c.timeZone())
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("reading the last sync of %s: %w", id, err)
+	}
+	return through, true, nil
+}
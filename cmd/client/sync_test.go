@@ -0,0 +1,82 @@
+package client
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestClient_Sync(t *testing.T) {
+	viper.Set("sensor_id", "sensor.energy")
+	viper.Set("generation_sensor_id", "sensor.solar")
+	defer viper.Set("generation_sensor_id", "")
+	viper.Set("cache_ttl", "24h")
+	defer viper.Set("cache_ttl", nil)
+
+	yesterday := time.Now().UTC().Add(-24 * time.Hour).Truncate(24 * time.Hour)
+	var readings, generation []Reading
+	for i := 0; i < 5*hoursInADay; i++ {
+		start := yesterday.Add(time.Duration(i-4*hoursInADay) * time.Hour)
+		readings = append(readings, Reading{Start: start, Value: 1})
+		generation = append(generation, Reading{Start: start, Value: 0.5})
+	}
+	source := fakeSource{"sensor.energy": readings, "sensor.solar": generation}
+	dir := t.TempDir()
+	cfg := Config{Days: 3, TimeZone: "UTC", CacheFile: filepath.Join(dir, "cache.db"), SyncDatabase: filepath.Join(dir, "powertracker.sqlite")}
+
+	// The first sync covers --days...
+	c := New(cfg)
+	c.source = source
+	n, err := c.Sync()
+	assert.NilError(t, err)
+	assert.Equal(t, n, 3)
+	// Generation is mirrored along with consumption.
+	assert.Equal(t, c.Stats().CacheMisses, 6)
+
+	// ...and the next only the days since, with those that hadn't settled, however many days are
+	// asked for.
+	cfg.Days = 5
+	c = New(cfg)
+	c.source = source
+	n, err = c.Sync()
+	assert.NilError(t, err)
+	assert.Equal(t, n, 1)
+	assert.Equal(t, c.Stats().CacheMisses, 2)
+
+	// The days synced are in the database, for other tools to query...
+	db, err := sql.Open("sqlite", cfg.SyncDatabase)
+	assert.NilError(t, err)
+	defer db.Close()
+	var days int
+	var total float64
+	assert.NilError(t, db.QueryRow(`SELECT COUNT(DISTINCT day), SUM(kwh) FROM readings WHERE sensor_id = 'sensor.energy'`).Scan(&days, &total))
+	assert.Equal(t, days, 3)
+	assert.Equal(t, total, float64(3*hoursInADay))
+	var start string
+	assert.NilError(t, db.QueryRow(`SELECT start FROM readings WHERE sensor_id = 'sensor.solar' AND day = ? ORDER BY start DESC LIMIT 1`,
+		yesterday.Format("2006-01-02")).Scan(&start))
+	assert.Equal(t, start, yesterday.Add(23*time.Hour).Format(time.RFC3339))
+	var through string
+	assert.NilError(t, db.QueryRow(`SELECT through FROM synced WHERE sensor_id = 'sensor.energy'`).Scan(&through))
+	assert.Equal(t, through, yesterday.AddDate(0, 0, 1).Format("2006-01-02"))
+
+	// ...and in the cache, offline.
+	cfg.Days, cfg.Offline = 3, true
+	c = New(cfg)
+	c.source = offline{}
+	results, err := getResults(c)
+	assert.NilError(t, err)
+	assert.Equal(t, len(results), 3)
+	assert.Equal(t, sum(results[2].Values), float64(hoursInADay))
+	solar, err := c.statistic("sensor.solar", results)
+	assert.NilError(t, err)
+	assert.Equal(t, len(solar), 3*hoursInADay)
+	assert.Equal(t, sum(bucket(solar, results[0].Date, 24*time.Hour, 1)), float64(hoursInADay)/2)
+
+	_, err = New(Config{Days: 1}).Sync()
+	assert.ErrorContains(t, err, "sync needs a database to mirror into")
+}
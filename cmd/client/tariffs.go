@@ -0,0 +1,411 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/powertracker/cmd/i18n"
+	"github.com/spf13/viper"
+)
+
+// Tariff is a named tariff structure from the tariffs section of the config.
+type Tariff struct {
+	Name string `mapstructure:"-"`
+	// Type is "flat" for a single unit rate, "tou" for time-of-use bands, "tiered" for rates that
+	// go up with usage, or "dynamic" for rates from a price provider.
+	Type     string  `mapstructure:"type"`
+	Rate     float64 `mapstructure:"rate"`
+	Bands    []Band  `mapstructure:"bands"`
+	Tiers    []Tier  `mapstructure:"tiers"`
+	Provider string  `mapstructure:"provider"`
+	// Period is how often the tiers of a tiered tariff start again, "day" or "month", which
+	// follows billing_day. It defaults to "month".
+	Period string `mapstructure:"period"`
+	// StandingCharge is charged for every day, regardless of consumption.
+	StandingCharge float64 `mapstructure:"standing_charge"`
+	// ExportRate is paid for each kWh exported, as measured by export_sensor_id.
+	ExportRate float64 `mapstructure:"export_rate"`
+	// Tax is the tax charged on the tariff. Without it, the tax setting applies.
+	Tax *Tax `mapstructure:"tax"`
+	// Zone is the time zone the clock times of the bands are in, which is the one days run from
+	// midnight in. If nil, it is the zone of the start of the range the rates are asked for.
+	Zone *time.Location `mapstructure:"-"`
+}
+
+// Tax is a tax such as VAT, charged on unit rates and standing charges. Export credits are not
+// taxed.
+type Tax struct {
+	// Rate is the fraction charged, e.g. 0.05 for 5%.
+	Rate float64 `mapstructure:"rate"`
+	// StandingRate is the rate charged on standing charges, when it differs from Rate.
+	StandingRate *float64 `mapstructure:"standing_rate"`
+	// Inclusive is whether the rates and standing charge already include the tax, rather than
+	// having it added on top.
+	Inclusive bool `mapstructure:"inclusive"`
+}
+
+// Band is a unit rate that applies between two local clock times every day, e.g. "00:30" to
+// "07:30". A band whose end isn't after its start runs past midnight.
+type Band struct {
+	From string  `mapstructure:"from"`
+	To   string  `mapstructure:"to"`
+	Rate float64 `mapstructure:"rate"`
+}
+
+// Tier is a unit rate that applies to the usage in each period up to UpTo kWh, once the usage
+// covered by the tiers before it is used up. The last tier has no UpTo, and covers the rest.
+type Tier struct {
+	UpTo float64 `mapstructure:"up_to"`
+	Rate float64 `mapstructure:"rate"`
+}
+
+// bill is what a day cost on a tariff. Energy and Standing exclude tax, which is shown on its own
+// as it is on a bill.
+type bill struct {
+	Energy   float64
+	Standing float64
+	Export   float64 // Export is the credit for exported energy.
+	Tax      float64
+}
+
+func (b bill) total() float64 {
+	return b.Energy + b.Standing + b.Tax - b.Export
+}
+
+// split returns the part of an amount before tax at the given rate, and the tax on it.
+func (tax Tax) split(amount, rate float64) (float64, float64) {
+	if tax.Inclusive {
+		net := amount / (1 + rate)
+		return net, amount - net
+	}
+	return amount, amount * rate
+}
+
+// tax returns the tax charged on the tariff, from its own tax setting or the global one.
+func (t Tariff) tax() (Tax, error) {
+	if t.Tax != nil {
+		return *t.Tax, nil
+	}
+	var tax Tax
+	if err := viper.UnmarshalKey("tax", &tax); err != nil {
+		return Tax{}, fmt.Errorf("parsing tax: %w", err)
+	}
+	return tax, nil
+}
+
+// loadTariffs returns the tariffs defined in the config, keyed by name.
+func loadTariffs() (map[string]Tariff, error) {
+	tariffs := make(map[string]Tariff)
+	if err := viper.UnmarshalKey("tariffs", &tariffs); err != nil {
+		return nil, fmt.Errorf("parsing tariffs: %w", err)
+	}
+	for name, t := range tariffs {
+		t.Name = name
+		tariffs[name] = t
+	}
+	return tariffs, nil
+}
+
+// tariff returns the selected tariff, with its bands in the time zone days run from midnight in.
+func (c *Client) tariff() (Tariff, error) {
+	t, err := selectedTariff()
+	if err != nil {
+		return Tariff{}, err
+	}
+	t.Zone = c.timeZone()
+	return t, nil
+}
+
+// selectedTariff returns the tariff named by the tariff setting, or the only one defined. Configs
+// without a tariffs section use the rates from prices.provider, with no standing charge.
+func selectedTariff() (Tariff, error) {
+	tariffs, err := loadTariffs()
+	if err != nil {
+		return Tariff{}, err
+	}
+	if name := viper.GetString("tariff"); name != "" {
+		t, ok := tariffs[name]
+		if !ok {
+			return Tariff{}, fmt.Errorf("unknown tariff %q", name)
+		}
+		return t, nil
+	}
+
+	switch len(tariffs) {
+	case 0:
+		provider := viper.GetString("prices.provider")
+		if provider == "" {
+			return Tariff{}, fmt.Errorf("tariffs or prices.provider is required")
+		}
+		return Tariff{Name: provider, Type: "dynamic", Provider: provider}, nil
+	case 1:
+		for _, t := range tariffs {
+			return t, nil
+		}
+	}
+	return Tariff{}, fmt.Errorf("tariff is required to choose between %s", strings.Join(tariffNames(tariffs), ", "))
+}
+
+// pricesConfigured reports whether the config has any rates to work out costs with.
+func pricesConfigured() bool {
+	tariffs, err := loadTariffs()
+	return viper.GetString("prices.provider") != "" || (err == nil && len(tariffs) > 0)
+}
+
+func tariffNames(tariffs map[string]Tariff) []string {
+	names := make([]string, 0, len(tariffs))
+	for name := range tariffs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// source returns the unit rates of the tariff.
+func (t Tariff) source() (PriceSource, error) {
+	switch t.Type {
+	case "flat", "tou":
+		return t, nil
+	case "dynamic":
+		return providerSource(t.Provider)
+	case "tiered":
+		return nil, fmt.Errorf("tariff %s: tiered rates depend on usage rather than the time of day", t.Name)
+	default:
+		return nil, fmt.Errorf("tariff %s: unknown type %q", t.Name, t.Type)
+	}
+}
+
+// Prices returns the unit rates of a flat or time-of-use tariff.
+func (t Tariff) Prices(start, end time.Time) ([]Price, error) {
+	if t.Type == "flat" {
+		return []Price{{Start: start, End: end, Rate: t.Rate}}, nil
+	}
+
+	loc := t.Zone
+	if loc == nil {
+		loc = start.Location()
+	}
+	// Start from the day before, as its last band may run past midnight.
+	first := start.In(loc)
+	first = time.Date(first.Year(), first.Month(), first.Day()-1, 0, 0, 0, 0, loc)
+	var prices []Price
+	for day := first; day.Before(end); day = day.AddDate(0, 0, 1) {
+		for _, b := range t.Bands {
+			from, err := clockTime(day, b.From)
+			if err != nil {
+				return nil, fmt.Errorf("tariff %s: %w", t.Name, err)
+			}
+			to, err := clockTime(day, b.To)
+			if err != nil {
+				return nil, fmt.Errorf("tariff %s: %w", t.Name, err)
+			}
+			if !to.After(from) {
+				to = to.AddDate(0, 0, 1)
+			}
+			prices = append(prices, Price{Start: from, End: to, Rate: b.Rate})
+		}
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i].Start.Before(prices[j].Start) })
+	return prices, nil
+}
+
+// clockTime returns the time on the day given as "15:04".
+func clockTime(day time.Time, clock string) (time.Time, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing band time %q: %w", clock, err)
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, day.Location()), nil
+}
+
+// bills works out what each day would have cost on the tariff, from its slots of the given width.
+// Exports are the kWh exported each day, or nil if they aren't known.
+func (t Tariff) bills(results []Day, exports []float64, width time.Duration) ([]bill, error) {
+	costs, err := t.energyCosts(results, width)
+	if err != nil {
+		return nil, err
+	}
+
+	tax, err := t.tax()
+	if err != nil {
+		return nil, err
+	}
+	standingRate := tax.Rate
+	if tax.StandingRate != nil {
+		standingRate = *tax.StandingRate
+	}
+
+	bills := make([]bill, len(results))
+	for i := range results {
+		energy, energyTax := tax.split(costs[i], tax.Rate)
+		standing, standingTax := tax.split(t.StandingCharge, standingRate)
+		bills[i] = bill{Energy: energy, Standing: standing, Tax: energyTax + standingTax}
+		if exports != nil {
+			bills[i].Export = exports[i] * t.ExportRate
+		}
+	}
+	return bills, nil
+}
+
+// energyCosts returns the cost of each day's consumption on the tariff, from its slots of the given
+// width.
+func (t Tariff) energyCosts(results []Day, width time.Duration) ([]float64, error) {
+	if t.Type == "tiered" {
+		return t.tieredCosts(results)
+	}
+	source, err := t.source()
+	if err != nil {
+		return nil, err
+	}
+	start, end := span(results)
+	prices, err := source.Prices(start, end)
+	if err != nil {
+		return nil, fmt.Errorf("getting prices for %s: %w", t.Name, err)
+	}
+	costs, err := dailyCosts(results, prices, width)
+	if err != nil {
+		return nil, fmt.Errorf("tariff %s: %w", t.Name, err)
+	}
+	return costs, nil
+}
+
+// tieredCosts returns the cost of each day's consumption on a tiered tariff. Usage counts towards
+// the tiers from the start of each period, so a month the results start part way through is billed
+// as if nothing was used before its first day.
+func (t Tariff) tieredCosts(results []Day) ([]float64, error) {
+	if len(t.Tiers) == 0 {
+		return nil, fmt.Errorf("tariff %s: tiers is required", t.Name)
+	}
+	for i, tier := range t.Tiers {
+		last := i == len(t.Tiers)-1
+		if last && tier.UpTo != 0 {
+			return nil, fmt.Errorf("tariff %s: the last tier must not have up_to, as it covers the rest", t.Name)
+		}
+		if !last && (tier.UpTo <= 0 || (i > 0 && tier.UpTo <= t.Tiers[i-1].UpTo)) {
+			return nil, fmt.Errorf("tariff %s: up_to must go up with each tier", t.Name)
+		}
+	}
+	var period func(day time.Time) time.Time
+	switch t.Period {
+	case "day":
+		period = func(day time.Time) time.Time { return day }
+	case "", "month":
+		// Months follow the billing cycle.
+		billing, err := billingDay()
+		if err != nil {
+			return nil, err
+		}
+		period = func(day time.Time) time.Time { return billingMonth(day, billing) }
+	default:
+		return nil, fmt.Errorf("tariff %s: unknown period %q - use day or month", t.Name, t.Period)
+	}
+
+	order := make([]int, len(results))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return results[order[i]].Date.Before(results[order[j]].Date) })
+
+	costs := make([]float64, len(results))
+	used := make(map[time.Time]float64)
+	for _, i := range order {
+		p := period(results[i].Date)
+		from := used[p]
+		to := from + sum(results[i].Values)
+		used[p] = to
+		// Each tier charges for the part of the day's usage that falls within it.
+		lower := 0.0
+		for _, tier := range t.Tiers {
+			upper := tier.UpTo
+			if upper == 0 {
+				upper = to
+			}
+			if lo, hi := math.Max(from, lower), math.Min(to, upper); hi > lo {
+				costs[i] += (hi - lo) * tier.Rate
+			}
+			lower = upper
+		}
+	}
+	return costs, nil
+}
+
+// dailyExports returns the energy exported on each day, from export_sensor_id, or nil if it isn't set.
+func (c *Client) dailyExports(results []Day) ([]float64, error) {
+	id := viper.GetString("export_sensor_id")
+	if id == "" {
+		return nil, nil
+	}
+	readings, err := c.statistic(id, results)
+	if err != nil {
+		return nil, fmt.Errorf("getting exports: %w", err)
+	}
+	exports := make([]float64, len(results))
+	for i, day := range results {
+		exports[i] = sum(bucket(readings, day.Date, 24*time.Hour, 1))
+	}
+	return exports, nil
+}
+
+// printComparison prints what the period would have cost on each of the tariffs in the config,
+// cheapest first.
+func (c *Client) printComparison(results []Day) error {
+	tariffs, err := loadTariffs()
+	if err != nil {
+		return err
+	}
+	if len(tariffs) == 0 {
+		return fmt.Errorf("tariffs is required")
+	}
+	exports, err := c.dailyExports(results)
+	if err != nil {
+		return err
+	}
+
+	type row struct {
+		name  string
+		total bill
+	}
+	var rows []row
+	var taxed bool
+	for _, name := range tariffNames(tariffs) {
+		t := tariffs[name]
+		t.Zone = c.timeZone()
+		bills, err := t.bills(results, exports, time.Hour)
+		if err != nil {
+			return err
+		}
+		var total bill
+		for _, b := range bills {
+			total.Energy += b.Energy
+			total.Standing += b.Standing
+			total.Export += b.Export
+			total.Tax += b.Tax
+		}
+		taxed = taxed || total.Tax != 0
+		rows = append(rows, row{name: name, total: total})
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].total.total() < rows[j].total.total() })
+
+	cur := currencyConfig()
+	table := tablewriter.NewWriter(os.Stdout)
+	header := []string{i18n.T("Tariff"), i18n.T("Energy"), i18n.T("Standing charges"), i18n.T("Export")}
+	// Tax is only shown when it's configured, as most rates are quoted with it included.
+	if taxed {
+		header = append(header, i18n.T("Tax"))
+	}
+	table.SetHeader(append(header, i18n.T("Total"), i18n.T("Per day")))
+	for _, r := range rows {
+		line := []string{r.name, cur.format(r.total.Energy), cur.format(r.total.Standing), cur.format(-r.total.Export)}
+		if taxed {
+			line = append(line, cur.format(r.total.Tax))
+		}
+		table.Append(append(line, cur.format(r.total.total()), cur.format(r.total.total()/float64(len(results)))))
+	}
+	table.Render()
+	return nil
+}
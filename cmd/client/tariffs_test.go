@@ -0,0 +1,129 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestTariff_Prices(t *testing.T) {
+	// The bands are in the tariff's zone, whatever the zone of the range asked for.
+	london, err := time.LoadLocation("Europe/London")
+	assert.NilError(t, err)
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, london)
+	economy7 := Tariff{Name: "economy7", Type: "tou", Zone: london, Bands: []Band{
+		{From: "00:30", To: "07:30", Rate: 0.125},
+		{From: "07:30", To: "00:30", Rate: 0.25},
+	}}
+
+	prices, err := economy7.Prices(day.UTC(), day.Add(24*time.Hour).UTC())
+	assert.NilError(t, err)
+	rate, err := averageRate(prices, day, day.Add(time.Hour))
+	assert.NilError(t, err)
+	// The first half hour is the day band carried over from the evening before.
+	assert.Equal(t, rate, (0.25+0.125)/2)
+	rate, err = averageRate(prices, day.Add(23*time.Hour), day.Add(24*time.Hour))
+	assert.NilError(t, err)
+	assert.Equal(t, rate, 0.25)
+
+	_, err = Tariff{Name: "bad", Type: "tou", Bands: []Band{{From: "7am", To: "00:00"}}}.Prices(day, day.Add(time.Hour))
+	assert.ErrorContains(t, err, "tariff bad: parsing band time \"7am\"")
+}
+
+func TestTariff_Bills(t *testing.T) {
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	flat := Tariff{Name: "flat", Type: "flat", Rate: 0.25, StandingCharge: 0.5, ExportRate: 0.125}
+
+	bills, err := flat.bills([]Day{{Date: day, Values: []float64{2, 2}}}, []float64{4}, time.Hour)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, bills, []bill{{Energy: 1, Standing: 0.5, Export: 0.5}})
+	assert.Equal(t, bills[0].total(), 1.0)
+
+	// Tax is added on top of the rates, at a different rate on the standing charge.
+	standingRate := 0.5
+	flat.Tax = &Tax{Rate: 0.25, StandingRate: &standingRate}
+	bills, err = flat.bills([]Day{{Date: day, Values: []float64{2, 2}}}, []float64{4}, time.Hour)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, bills, []bill{{Energy: 1, Standing: 0.5, Export: 0.5, Tax: 0.5}})
+
+	// Inclusive rates have their tax taken out, leaving the total as it was.
+	defer viper.Set("tax", nil)
+	viper.Set("tax", map[string]any{"rate": 0.25, "inclusive": true})
+	flat = Tariff{Name: "flat", Type: "flat", Rate: 0.3125, StandingCharge: 0.625}
+	bills, err = flat.bills([]Day{{Date: day, Values: []float64{2, 2}}}, nil, time.Hour)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, bills, []bill{{Energy: 1, Standing: 0.5, Tax: 0.375}})
+	assert.Equal(t, bills[0].total(), 1.875)
+}
+
+func TestTariff_Tiered(t *testing.T) {
+	sep := time.Date(2023, 9, 30, 0, 0, 0, 0, time.UTC)
+	results := []Day{
+		{Date: sep.AddDate(0, 0, 1), Values: []float64{4}},
+		{Date: sep, Values: []float64{6, 2}},
+		{Date: sep.AddDate(0, 0, 2), Values: []float64{5}},
+	}
+	tiers := []Tier{{UpTo: 3, Rate: 0.125}, {UpTo: 8, Rate: 0.25}, {Rate: 0.5}}
+
+	// Each month starts again from the first tier, with days counted in date order.
+	monthly := Tariff{Name: "monthly", Type: "tiered", Tiers: tiers}
+	costs, err := monthly.energyCosts(results, time.Hour)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, costs, []float64{3*0.125 + 1*0.25, 3*0.125 + 5*0.25, 4*0.25 + 1*0.5})
+
+	daily := Tariff{Name: "daily", Type: "tiered", Period: "day", Tiers: tiers}
+	costs, err = daily.energyCosts(results, time.Hour)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, costs, []float64{3*0.125 + 1*0.25, 3*0.125 + 5*0.25, 3*0.125 + 2*0.25})
+
+	_, err = Tariff{Name: "bad", Type: "tiered", Tiers: []Tier{{UpTo: 3, Rate: 0.1}}}.energyCosts(results, time.Hour)
+	assert.ErrorContains(t, err, "tariff bad: the last tier must not have up_to")
+	_, err = Tariff{Name: "bad", Type: "tiered", Tiers: []Tier{{UpTo: 3}, {UpTo: 2}, {}}}.energyCosts(results, time.Hour)
+	assert.ErrorContains(t, err, "tariff bad: up_to must go up with each tier")
+	_, err = monthly.source()
+	assert.ErrorContains(t, err, "tiered rates depend on usage")
+}
+
+func TestSelectedTariff(t *testing.T) {
+	defer func() {
+		viper.Set("tariffs", nil)
+		viper.Set("tariff", "")
+		viper.Set("prices.provider", "")
+	}()
+
+	viper.Set("prices.provider", "entsoe")
+	tariff, err := selectedTariff()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, tariff, Tariff{Name: "entsoe", Type: "dynamic", Provider: "entsoe"})
+
+	viper.Set("tariffs", map[string]any{
+		"flat":     map[string]any{"type": "flat", "rate": 0.245, "standing_charge": 0.53},
+		"economy7": map[string]any{"type": "tou", "bands": []map[string]any{{"from": "00:30", "to": "07:30", "rate": 0.13}}},
+	})
+	_, err = selectedTariff()
+	assert.ErrorContains(t, err, "tariff is required to choose between economy7, flat")
+
+	viper.Set("tariff", "flat")
+	tariff, err = selectedTariff()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, tariff, Tariff{Name: "flat", Type: "flat", Rate: 0.245, StandingCharge: 0.53})
+
+	viper.Set("tariff", "agile")
+	_, err = selectedTariff()
+	assert.ErrorContains(t, err, "unknown tariff \"agile\"")
+}
+
+func TestClient_DailyBills_Tiered(t *testing.T) {
+	defer viper.Set("tariffs", nil)
+	viper.Set("tariffs", map[string]any{
+		"blocks": map[string]any{"type": "tiered", "period": "day", "standing_charge": 0.5, "tiers": []map[string]any{{"up_to": 2, "rate": 0.1}, {"rate": 0.2}}},
+	})
+
+	// Tiered rates have no time of day, but still cost what -o cost and -o mqtt need.
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	costs, err := New(Config{}).dailyBills([]Day{{Date: day, Values: []float64{1, 2}}})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, costs, []float64{2*0.1 + 1*0.2 + 0.5})
+}
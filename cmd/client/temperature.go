@@ -0,0 +1,184 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/poolski/powertracker/cmd/i18n"
+	"github.com/spf13/viper"
+)
+
+// fit is a straight line fitted to a set of points by least squares.
+type fit struct {
+	Slope     float64
+	Intercept float64
+	R2        float64 // R2 is the coefficient of determination, from 0 (no fit) to 1 (perfect fit).
+}
+
+// linearFit fits a line y = slope*x + intercept through the points.
+func linearFit(x, y []float64) (fit, error) {
+	if len(x) != len(y) {
+		return fit{}, fmt.Errorf("mismatched lengths: %d and %d", len(x), len(y))
+	}
+	if len(x) < 2 {
+		return fit{}, fmt.Errorf("at least 2 points are needed, got %d", len(x))
+	}
+
+	n := float64(len(x))
+	var meanX, meanY float64
+	for i := range x {
+		meanX += x[i] / n
+		meanY += y[i] / n
+	}
+	var sxx, sxy, syy float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	if sxx == 0 {
+		return fit{}, fmt.Errorf("all points have the same x value")
+	}
+
+	f := fit{Slope: sxy / sxx}
+	f.Intercept = meanY - f.Slope*meanX
+	f.R2 = 1
+	if syy > 0 {
+		f.R2 = sxy * sxy / (sxx * syy)
+	}
+	return f, nil
+}
+
+// dailyTemperatures returns the average of the hourly means of a temperature statistic for each
+// day in the results. Days without any readings are NaN.
+func (c *Client) dailyTemperatures(id string, results []Day) ([]float64, error) {
+	start, end := span(results)
+	stats, err := c.statistics(id, start, end, "hour", "mean")
+	if err != nil {
+		return nil, err
+	}
+
+	temps := make([]float64, len(results))
+	for i, day := range results {
+		var total float64
+		var count int
+		dayEnd := day.Date.AddDate(0, 0, 1)
+		for _, stat := range stats {
+			t := time.UnixMilli(stat.Start)
+			if !t.Before(day.Date) && t.Before(dayEnd) {
+				total += stat.Mean
+				count++
+			}
+		}
+		temps[i] = math.NaN()
+		if count > 0 {
+			temps[i] = total / float64(count)
+		}
+	}
+	return temps, nil
+}
+
+// printTemperature prints the average outdoor temperature and consumption of each day, along with
+// a line fitted through them. The slope is the extra consumption for each degree colder (when
+// negative), which separates heating-driven usage from everything else.
+func (c *Client) printTemperature(results []Day) error {
+	id := viper.GetString("temperature_sensor_id")
+	if id == "" {
+		return fmt.Errorf("temperature_sensor_id is required")
+	}
+	temps, err := c.dailyTemperatures(id, results)
+	if err != nil {
+		return fmt.Errorf("getting temperatures: %w", err)
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{i18n.T("Date"), i18n.T("Avg temp (°C)"), "kWh"})
+	var x, y []float64
+	for i, day := range results {
+		usage := sum(day.Values)
+		if math.IsNaN(temps[i]) {
+			table.Append([]string{day.Date.Format("2006-01-02"), "", fmt.Sprintf("%f", usage)})
+			continue
+		}
+		x = append(x, temps[i])
+		y = append(y, usage)
+		table.Append([]string{day.Date.Format("2006-01-02"), fmt.Sprintf("%.1f", temps[i]), fmt.Sprintf("%f", usage)})
+	}
+	table.Render()
+
+	f, err := linearFit(x, y)
+	if err != nil {
+		return fmt.Errorf("fitting line: %w", err)
+	}
+	fmt.Printf("kWh = %.3f × temp + %.3f (r² = %.2f)\n", f.Slope, f.Intercept, f.R2)
+
+	if c.Config.Chart {
+		fmt.Print(scatterChart(x, y, f, 60, 20))
+	}
+	return nil
+}
+
+// scatterChart draws the points as a text chart of the given size, with the fitted line behind them.
+// Temperature runs along the x axis and consumption up the y axis.
+func scatterChart(x, y []float64, f fit, width, height int) string {
+	minX, maxX := x[0], x[0]
+	minY, maxY := y[0], y[0]
+	for i := range x {
+		minX, maxX = math.Min(minX, x[i]), math.Max(maxX, x[i])
+		minY, maxY = math.Min(minY, y[i]), math.Max(maxY, y[i])
+	}
+	if maxX == minX {
+		maxX = minX + 1
+	}
+	if maxY == minY {
+		maxY = minY + 1
+	}
+
+	grid := make([][]rune, height)
+	for row := range grid {
+		grid[row] = []rune(strings.Repeat(" ", width))
+	}
+	col := func(v float64) int {
+		return int(math.Round((v - minX) / (maxX - minX) * float64(width-1)))
+	}
+	row := func(v float64) (int, bool) {
+		r := height - 1 - int(math.Round((v-minY)/(maxY-minY)*float64(height-1)))
+		return r, r >= 0 && r < height
+	}
+	for cx := 0; cx < width; cx++ {
+		v := minX + float64(cx)/float64(width-1)*(maxX-minX)
+		if r, ok := row(f.Slope*v + f.Intercept); ok {
+			grid[r][cx] = '.'
+		}
+	}
+	for i := range x {
+		r, _ := row(y[i])
+		grid[r][col(x[i])] = '*'
+	}
+
+	var b strings.Builder
+	for r, line := range grid {
+		label := ""
+		switch r {
+		case 0:
+			label = fmt.Sprintf("%.1f", maxY)
+		case height - 1:
+			label = fmt.Sprintf("%.1f", minY)
+		}
+		fmt.Fprintf(&b, "%8s |%s\n", label, strings.TrimRight(string(line), " "))
+	}
+	fmt.Fprintf(&b, "%8s +%s\n", "kWh", strings.Repeat("-", width))
+	minLabel := fmt.Sprintf("%.1f", minX)
+	maxLabel := fmt.Sprintf("%.1f°C", maxX)
+	gap := width - len(minLabel) - len([]rune(maxLabel))
+	if gap < 1 {
+		gap = 1
+	}
+	fmt.Fprintf(&b, "%8s  %s%s%s\n", "", minLabel, strings.Repeat(" ", gap), maxLabel)
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"gotest.tools/v3/assert"
+)
+
+func TestLinearFit(t *testing.T) {
+	f, err := linearFit([]float64{0, 5, 10}, []float64{20, 15, 10})
+	assert.NilError(t, err)
+	assert.Equal(t, f.Slope, -1.0)
+	assert.Equal(t, f.Intercept, 20.0)
+	assert.Equal(t, f.R2, 1.0)
+
+	_, err = linearFit([]float64{1}, []float64{1})
+	assert.ErrorContains(t, err, "at least 2 points are needed")
+	_, err = linearFit([]float64{3, 3}, []float64{1, 2})
+	assert.ErrorContains(t, err, "all points have the same x value")
+}
+
+func TestClient_DailyTemperatures(t *testing.T) {
+	day := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, _ := upgrader.Upgrade(w, r, nil)
+		defer conn.Close()
+
+		var msg map[string]interface{}
+		assert.NilError(t, conn.ReadJSON(&msg), "read statistics request failed")
+		assert.DeepEqual(t, msg["types"], []interface{}{"mean"})
+
+		assert.NilError(t, conn.WriteJSON(map[string]interface{}{
+			"id":      msg["id"],
+			"type":    "result",
+			"success": true,
+			"result": map[string]interface{}{
+				"sensor.outdoor": []map[string]interface{}{
+					{"start": day.UnixMilli(), "mean": 10.0},
+					{"start": day.Add(time.Hour).UnixMilli(), "mean": 14.0},
+				},
+			},
+		}), "write statistics response failed")
+	}))
+	defer s.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http"), nil)
+	assert.NilError(t, err)
+	client := &Client{Conn: conn}
+
+	results := []Day{
+		{Date: day, Values: make([]float64, hoursInADay)},
+		{Date: day.Add(24 * time.Hour), Values: make([]float64, hoursInADay)},
+	}
+	temps, err := client.dailyTemperatures("sensor.outdoor", results)
+	assert.NilError(t, err)
+	assert.Equal(t, temps[0], 12.0)
+	assert.Assert(t, math.IsNaN(temps[1]), "expected no temperature for the second day")
+}
+
+func TestScatterChart(t *testing.T) {
+	x := []float64{0, 10}
+	y := []float64{20, 10}
+	f, err := linearFit(x, y)
+	assert.NilError(t, err)
+
+	lines := strings.Split(scatterChart(x, y, f, 11, 3), "\n")
+	assert.Equal(t, lines[0], "    20.0 |*..")
+	assert.Equal(t, lines[1], "         |   .....")
+	assert.Equal(t, lines[2], "    10.0 |        ..*")
+	assert.Equal(t, lines[3], "     kWh +-----------")
+}
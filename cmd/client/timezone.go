@@ -0,0 +1,114 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// timeZoneKey is where the cache remembers Home Assistant's time zone, for answering from the
+// cache alone with the same days.
+const timeZoneKey = "time_zone"
+
+// timeZone returns the time zone days run from midnight in: the one in Config.TimeZone, or else
+// Home Assistant's. For other sources, and offline, it is the one Home Assistant had when days
+// were last fetched into the cache, or else UTC. It is only looked up once.
+func (c *Client) timeZone() *time.Location {
+	c.zoneMu.Lock()
+	defer c.zoneMu.Unlock()
+	if c.zone == nil {
+		c.zone = c.lookUpTimeZone()
+	}
+	return c.zone
+}
+
+func (c *Client) lookUpTimeZone() *time.Location {
+	name := c.Config.TimeZone
+	if name == "" {
+		var err error
+		if name, err = c.homeAssistantTimeZone(); err != nil {
+			c.logger().Warn().Msgf("getting Home Assistant's time zone: %v - days run from midnight UTC", err)
+			return time.UTC
+		}
+	}
+	loc, err := loadLocation(name)
+	if err != nil {
+		c.logger().Warn().Msgf("%v - days run from midnight UTC", err)
+		return time.UTC
+	}
+	return loc
+}
+
+// loadLocation returns the time zone with the IANA name, such as Europe/London. UTC's other names
+// are all UTC, so they share its cached days.
+func loadLocation(name string) (*time.Location, error) {
+	switch name {
+	case "", "UTC", "Etc/UTC", "GMT", "Etc/GMT", "Universal", "Etc/Universal", "Zulu", "Etc/Zulu":
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// homeAssistantTimeZone returns the name of Home Assistant's time zone, from its config, and
+// remembers it in the cache. Without a connection, it is the one remembered, or "" for UTC.
+func (c *Client) homeAssistantTimeZone() (string, error) {
+	store, err := c.openCache()
+	if err != nil {
+		return "", err
+	}
+	if store != nil {
+		defer store.Close()
+	}
+
+	// Sessions recorded before days followed the time zone have to be replayed with UTC days.
+	if c.replay != nil && !c.replay.sent("get_config") {
+		return "", nil
+	}
+	if !c.connected() {
+		if store == nil {
+			return "", nil
+		}
+		name, _, err := store.Meta(timeZoneKey)
+		return name, err
+	}
+
+	var data struct {
+		Success bool `json:"success"`
+		Result  struct {
+			TimeZone string `json:"time_zone"`
+		} `json:"result"`
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := c.request(map[string]interface{}{"type": "get_config"}, &data); err != nil {
+		return "", err
+	}
+	if !data.Success {
+		return "", fmt.Errorf("api response error: %v", data.Error)
+	}
+	if store != nil {
+		if err := store.PutMeta(timeZoneKey, data.Result.TimeZone); err != nil {
+			return "", err
+		}
+	}
+	return data.Result.TimeZone, nil
+}
+
+// cacheID returns the ID days of the statistic are cached under. Days that run from midnight in
+// another time zone than UTC cover different hours, so they are cached apart, by time zone.
+func (c *Client) cacheID(id string) string {
+	if loc := c.timeZone(); loc != time.UTC {
+		return id + "@" + loc.String()
+	}
+	return id
+}
+
+// midnight returns the start of the day t falls on, in the time zone of t.
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/poolski/powertracker/cmd/hatest"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestLoadLocation(t *testing.T) {
+	for _, name := range []string{"", "UTC", "Etc/UTC", "GMT"} {
+		loc, err := loadLocation(name)
+		assert.NilError(t, err, name)
+		assert.Equal(t, loc, time.UTC, name)
+	}
+	loc, err := loadLocation("Europe/London")
+	assert.NilError(t, err)
+	assert.Equal(t, loc.String(), "Europe/London")
+	_, err = loadLocation("Europe/Nowhere")
+	assert.ErrorContains(t, err, `unknown time zone "Europe/Nowhere"`)
+}
+
+func TestSlot_ClocksChange(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	assert.NilError(t, err)
+
+	// The clocks went forward at 01:00 on 26 March 2023, so the day is 23 hours long and 01:00
+	// never happened: 02:00 BST is the third hour of the day by the clock, and 01:00 is left empty.
+	start := time.Date(2023, 3, 26, 0, 0, 0, 0, london)
+	i, ok := slot(start.Add(time.Hour), start, time.Hour, hoursInADay)
+	assert.Assert(t, ok)
+	assert.Equal(t, i, 2)
+	i, ok = slot(start.Add(22*time.Hour), start, time.Hour, hoursInADay)
+	assert.Assert(t, ok)
+	assert.Equal(t, i, 23)
+
+	// They went back at 02:00 on 29 October, so the day is 25 hours long and both 01:00s share a
+	// slot.
+	start = time.Date(2023, 10, 29, 0, 0, 0, 0, london)
+	i, _ = slot(start.Add(time.Hour), start, time.Hour, hoursInADay)
+	assert.Equal(t, i, 1)
+	i, _ = slot(start.Add(2*time.Hour), start, time.Hour, hoursInADay)
+	assert.Equal(t, i, 1)
+	i, ok = slot(start.Add(24*time.Hour), start, time.Hour, hoursInADay)
+	assert.Assert(t, ok)
+	assert.Equal(t, i, 23)
+}
+
+func TestClient_Until_TimeZone(t *testing.T) {
+	c := New(Config{
+		TimeZone: "Australia/Sydney",
+		Start:    time.Date(2023, 9, 30, 0, 0, 0, 0, time.UTC),
+		End:      time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC),
+	})
+	sydney, err := time.LoadLocation("Australia/Sydney")
+	assert.NilError(t, err)
+	// The clocks went forward on 1 October, but it is still three days.
+	assert.Assert(t, c.until().Equal(time.Date(2023, 10, 3, 0, 0, 0, 0, sydney)))
+	assert.Equal(t, c.days(), 3)
+	assert.Equal(t, c.cacheID("sensor.energy"), "sensor.energy@Australia/Sydney")
+
+	c = New(Config{TimeZone: "Etc/UTC"})
+	assert.Equal(t, c.cacheID("sensor.energy"), "sensor.energy")
+}
+
+func TestClient_TimeZone_HomeAssistant(t *testing.T) {
+	s := hatest.NewServer("test_token")
+	defer s.Close()
+	s.SetTimeZone("Europe/Berlin")
+	viper.Set("url", s.URL)
+	viper.Set("api_key", "test_token")
+	defer viper.Set("url", "")
+	cacheFile := filepath.Join(t.TempDir(), "cache.db")
+
+	c := New(Config{CacheFile: cacheFile})
+	assert.NilError(t, c.Connect())
+	assert.Equal(t, c.timeZone().String(), "Europe/Berlin")
+	// It is only asked for once.
+	assert.Equal(t, c.timeZone().String(), "Europe/Berlin")
+	assert.Equal(t, len(s.Received()), 1)
+	assert.NilError(t, c.Close())
+
+	// Offline, it is the one remembered in the cache...
+	c = New(Config{CacheFile: cacheFile, Offline: true})
+	assert.NilError(t, c.Connect())
+	defer c.Close()
+	assert.Equal(t, c.timeZone().String(), "Europe/Berlin")
+
+	// ...unless one is given.
+	c = New(Config{CacheFile: cacheFile, Offline: true, TimeZone: "America/New_York"})
+	assert.Equal(t, c.timeZone().String(), "America/New_York")
+}
+
+func TestClient_CheckEnd(t *testing.T) {
+	// Today is a different date in UTC for much of the day in zones far from it.
+	for _, name := range []string{"Pacific/Kiritimati", "Pacific/Pago_Pago", "UTC"} {
+		loc, err := time.LoadLocation(name)
+		assert.NilError(t, err)
+		y, m, d := time.Now().In(loc).Date()
+		today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+
+		c := New(Config{TimeZone: name, End: today.AddDate(0, 0, -1)})
+		assert.NilError(t, c.checkEnd(), name)
+		c = New(Config{TimeZone: name, End: today})
+		assert.ErrorContains(t, c.checkEnd(), "--end must be before today", name)
+	}
+	assert.NilError(t, New(Config{TimeZone: "UTC"}).checkEnd())
+}
+
+func TestSlotStart_ClocksChange(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	assert.NilError(t, err)
+
+	// When the clocks go forward, 01:00 never happens, and 00:00 runs until 02:00 BST.
+	start := time.Date(2023, 3, 26, 0, 0, 0, 0, london)
+	_, ok := slotStart(start, 1, time.Hour)
+	assert.Assert(t, !ok)
+	at, ok := slotStart(start, 2, time.Hour)
+	assert.Assert(t, ok)
+	assert.Assert(t, at.Equal(time.Date(2023, 3, 26, 2, 0, 0, 0, london)))
+	assert.Assert(t, slotEnd(start, 0, time.Hour).Equal(at))
+	assert.Assert(t, slotEnd(start, hoursInADay-1, time.Hour).Equal(time.Date(2023, 3, 27, 0, 0, 0, 0, london)))
+
+	// When they go back, 01:00 lasts two hours.
+	start = time.Date(2023, 10, 29, 0, 0, 0, 0, london)
+	at, ok = slotStart(start, 1, time.Hour)
+	assert.Assert(t, ok)
+	assert.Equal(t, slotEnd(start, 1, time.Hour).Sub(at), 2*time.Hour)
+	at, _ = slotStart(start, 2, time.Hour)
+	assert.Equal(t, at.Format("15:04 MST"), "02:00 GMT")
+
+	// Every slot is the reverse of slot.
+	for i := 0; i < 2*hoursInADay; i++ {
+		at, ok := slotStart(start, i, 30*time.Minute)
+		assert.Assert(t, ok)
+		j, _ := slot(at, start, 30*time.Minute, 2*hoursInADay)
+		assert.Equal(t, j, i)
+	}
+}
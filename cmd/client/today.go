@@ -0,0 +1,49 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/poolski/powertracker/cmd/i18n"
+	"github.com/spf13/viper"
+)
+
+// today returns the slots of today so far, for --include-today, as a partial day. Slots still to
+// come are left at zero, and so is the current hour until the source has added it up. Today is
+// never cached, as its slots are still being filled in. Without sensor_id, the phases in
+// phase_sensor_ids are added together, as results does.
+func (c *Client) today() ([]Day, error) {
+	ids := []string{SensorID()}
+	if ids[0] == "" {
+		if ids = viper.GetStringSlice("phase_sensor_ids"); len(ids) == 0 {
+			return nil, fmt.Errorf("sensor_id is required")
+		}
+	}
+
+	now := c.now().In(c.timeZone())
+	start := midnight(now)
+	width, slots := c.slots()
+	day := Day{Date: start, Values: make([]float64, slots), Partial: true}
+	for _, id := range ids {
+		readings, err := c.fetchChunk(id, start, now, c.readingPeriod())
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range bucket(readings, start, width, slots) {
+			day.Values[i] += v
+		}
+	}
+	return []Day{day}, nil
+}
+
+// dateLabel returns the date of a row of the table, CSV and Markdown outputs, marking today's as
+// only being so far.
+func dateLabel(day Day) string {
+	date := day.Date.Format("2006-01-02")
+	if day.Label != "" {
+		date = day.Label
+	}
+	if day.Partial {
+		return i18n.T("%s (so far)", date)
+	}
+	return date
+}
@@ -0,0 +1,55 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/poolski/powertracker/cmd/i18n"
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestClient_Today(t *testing.T) {
+	viper.Set("sensor_id", "sensor.energy")
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	readings := []Reading{
+		{Start: today.Add(-time.Hour), Value: 9},
+		{Start: today, Value: 0.5},
+		{Start: today.Add(30 * time.Minute), Value: 0.25},
+		// Readings from after now, as a made-up source has, are left out.
+		{Start: time.Now().Add(time.Hour), Value: 9},
+	}
+
+	c := New(Config{TimeZone: "UTC"})
+	c.source = fakeSource{"sensor.energy": readings}
+	days, err := c.today()
+	assert.NilError(t, err)
+	assert.Equal(t, len(days), 1)
+	assert.Assert(t, days[0].Partial)
+	assert.Assert(t, days[0].Date.Equal(today))
+	assert.Equal(t, len(days[0].Values), hoursInADay)
+	assert.Equal(t, days[0].Values[0], 0.75)
+	assert.Equal(t, sum(days[0].Values), 0.75)
+}
+
+func TestDateLabel(t *testing.T) {
+	defer func() { _ = i18n.SetLanguage("en") }()
+	day := Day{Date: time.Date(2023, 9, 4, 0, 0, 0, 0, time.UTC)}
+	assert.Equal(t, dateLabel(day), "2023-09-04")
+	day.Partial = true
+	assert.Equal(t, dateLabel(day), "2023-09-04 (so far)")
+	assert.NilError(t, i18n.SetLanguage("de"))
+	assert.Equal(t, dateLabel(day), "2023-09-04 (bisher)")
+}
+
+func TestTotalColumn_Partial(t *testing.T) {
+	results := []Day{
+		{Values: []float64{0.5}, Partial: true},
+		{Values: []float64{2, 2}},
+		{Values: []float64{1, 1}},
+	}
+	col := totalColumn(results, []stat{{q: 0.5, label: "Median"}})
+	assert.DeepEqual(t, col.Values, []string{"0.500000", "4.000000", "2.000000"})
+	// Today so far doesn't drag down the statistics of the days.
+	assert.DeepEqual(t, col.Footers, []string{"3.000000"})
+}
@@ -0,0 +1,185 @@
+package client
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/poolski/powertracker/cmd/i18n"
+)
+
+// Cell styles, indexes into the cellXfs of xlsxStyles.
+const (
+	xlsxStyleHeader = 1
+	xlsxStyleDate   = 2
+	xlsxStyleNumber = 3
+)
+
+// xlsxStyles has a bold style for headers, and formats for dates and for kWh to three decimal
+// places, rather than the six of the CSV.
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="2"><numFmt numFmtId="164" formatCode="yyyy-mm-dd"/><numFmt numFmtId="165" formatCode="0.000"/></numFmts>
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="4"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/><xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs>
+</styleSheet>`
+
+// xlsxSheet is a worksheet being built up a row at a time.
+type xlsxSheet struct {
+	Name string
+	rows bytes.Buffer
+	n    int
+}
+
+// xlsxCell is a cell of a row: a string, a time.Time for a date or a float64 for kWh. A NaN is
+// left empty.
+type xlsxCell any
+
+func (s *xlsxSheet) row(style int, cells ...xlsxCell) {
+	s.n++
+	fmt.Fprintf(&s.rows, `<row r="%d">`, s.n)
+	for i, cell := range cells {
+		ref := fmt.Sprintf("%s%d", xlsxColumn(i), s.n)
+		switch v := cell.(type) {
+		case string:
+			fmt.Fprintf(&s.rows, `<c r="%s" s="%d" t="inlineStr"><is><t>%s</t></is></c>`, ref, style, xmlText(v))
+		case time.Time:
+			fmt.Fprintf(&s.rows, `<c r="%s" s="%d"><v>%d</v></c>`, ref, xlsxStyleDate, xlsxDate(v))
+		case float64:
+			if !math.IsNaN(v) {
+				fmt.Fprintf(&s.rows, `<c r="%s" s="%d"><v>%s</v></c>`, ref, xlsxStyleNumber, fmt.Sprint(v))
+			}
+		}
+	}
+	s.rows.WriteString(`</row>`)
+}
+
+// xml returns the worksheet, with its header row and first column frozen so they stay in view.
+func (s *xlsxSheet) xml() string {
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<sheetViews><sheetView workbookViewId="0"><pane xSplit="1" ySplit="1" topLeftCell="B2" activePane="bottomRight" state="frozen"/></sheetView></sheetViews>` +
+		`<cols><col min="1" max="1" width="12" customWidth="1"/></cols>` +
+		`<sheetData>` + s.rows.String() + `</sheetData></worksheet>`
+}
+
+// xlsxColumn returns the letters naming the i'th column, from 0: A to Z, then AA and so on.
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// xlsxDate returns the day as a spreadsheet serial date, the days since the end of 1899.
+func xlsxDate(t time.Time) int64 {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	y, m, d := t.Date()
+	return int64(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Sub(epoch) / (24 * time.Hour))
+}
+
+func xmlText(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// writeXLSX writes an Excel workbook with a sheet of the days, with a row for each day and its
+// total, and one of the summaries, with a row for each slot and the daily total.
+func (c *Client) writeXLSX(results []Day, summaries []summary, headers []string, stats []stat) error {
+	days := &xlsxSheet{Name: i18n.T("Days")}
+	header := []xlsxCell{i18n.T("Date")}
+	for _, h := range headers {
+		header = append(header, h)
+	}
+	days.row(xlsxStyleHeader, append(header, i18n.T("Total"))...)
+	totals := make([]float64, len(results))
+	for i, day := range results {
+		row := []xlsxCell{day.Date}
+		for _, v := range day.Values {
+			row = append(row, v)
+		}
+		totals[i] = sum(day.Values)
+		days.row(0, append(row, totals[i])...)
+	}
+
+	averages := &xlsxSheet{Name: i18n.T("Averages")}
+	header = []xlsxCell{i18n.T("Hour")}
+	for _, s := range summaries {
+		header = append(header, s.Label)
+	}
+	averages.row(xlsxStyleHeader, header...)
+	for i, h := range headers {
+		row := []xlsxCell{h}
+		for _, s := range summaries {
+			row = append(row, s.Values[i])
+		}
+		averages.row(0, row...)
+	}
+	row := []xlsxCell{i18n.T("Total")}
+	for _, s := range stats {
+		row = append(row, statOrZero(s, totals))
+	}
+	averages.row(xlsxStyleHeader, row...)
+
+	f, err := os.Create(c.outputFile(".xlsx"))
+	if err != nil {
+		return fmt.Errorf("creating file: %w", err)
+	}
+	defer f.Close()
+	if err := writeWorkbook(f, days, averages); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// writeWorkbook writes the sheets as an Office Open XML workbook, which is a zip of XML parts.
+func writeWorkbook(w io.Writer, sheets ...*xlsxSheet) error {
+	var types, sheetList, rels strings.Builder
+	for i, s := range sheets {
+		fmt.Fprintf(&types, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+		fmt.Fprintf(&sheetList, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlText(s.Name), i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(sheets)+1)
+
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` + types.String() + `</Types>`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` + sheetList.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + rels.String() + `</Relationships>`},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for i, s := range sheets {
+		parts = append(parts, struct{ name, body string }{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), s.xml()})
+	}
+
+	z := zip.NewWriter(w)
+	for _, p := range parts {
+		part, err := z.Create(p.name)
+		if err != nil {
+			return fmt.Errorf("adding %s: %w", p.name, err)
+		}
+		if _, err := part.Write([]byte(p.body)); err != nil {
+			return fmt.Errorf("writing %s: %w", p.name, err)
+		}
+	}
+	if err := z.Close(); err != nil {
+		return fmt.Errorf("writing workbook: %w", err)
+	}
+	return nil
+}
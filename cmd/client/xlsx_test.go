@@ -0,0 +1,80 @@
+package client
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestXLSXColumn(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		assert.Equal(t, xlsxColumn(i), want)
+	}
+}
+
+func TestXLSXDate(t *testing.T) {
+	assert.Equal(t, xlsxDate(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)), int64(45352))
+	assert.Equal(t, xlsxDate(time.Date(1900, 3, 1, 12, 0, 0, 0, time.UTC)), int64(61))
+}
+
+func TestClient_WriteXLSX(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.csv")
+	c := New(Config{FilePath: path})
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	results := []Day{
+		{Date: day, Values: []float64{0.5, 1}},
+		{Date: day.AddDate(0, 0, 1), Values: []float64{0.25, 2}},
+	}
+	stats, err := parseStats([]string{"mean", "p100"})
+	assert.NilError(t, err)
+	err = c.writeXLSX(results, summaries(results, 2, stats), []string{"0", "1 & 2"}, stats)
+	assert.NilError(t, err)
+
+	z, err := zip.OpenReader(strings.TrimSuffix(path, ".csv") + ".xlsx")
+	assert.NilError(t, err)
+	defer z.Close()
+	parts := map[string]string{}
+	for _, f := range z.File {
+		r, err := f.Open()
+		assert.NilError(t, err)
+		b, err := io.ReadAll(r)
+		assert.NilError(t, err)
+		r.Close()
+		// Every part must be well-formed, or Excel refuses to open the workbook.
+		d := xml.NewDecoder(strings.NewReader(string(b)))
+		for err == nil {
+			_, err = d.Token()
+		}
+		assert.Equal(t, err, io.EOF, f.Name)
+		parts[f.Name] = string(b)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+		assert.Assert(t, parts[name] != "", "missing %s", name)
+	}
+	assert.Assert(t, strings.Contains(parts["xl/workbook.xml"], `<sheet name="Days" sheetId="1" r:id="rId1"/><sheet name="Averages" sheetId="2" r:id="rId2"/>`))
+
+	days := parts["xl/worksheets/sheet1.xml"]
+	for _, expected := range []string{
+		`<c r="C1" s="1" t="inlineStr"><is><t>1 &amp; 2</t></is></c><c r="D1" s="1" t="inlineStr"><is><t>Total</t></is></c>`,
+		`<row r="2"><c r="A2" s="2"><v>45352</v></c><c r="B2" s="3"><v>0.5</v></c><c r="C2" s="3"><v>1</v></c><c r="D2" s="3"><v>1.5</v></c></row>`,
+		`<c r="A3" s="2"><v>45353</v></c>`,
+	} {
+		assert.Assert(t, strings.Contains(days, expected), "missing %s", expected)
+	}
+
+	averages := parts["xl/worksheets/sheet2.xml"]
+	for _, expected := range []string{
+		`<c r="B1" s="1" t="inlineStr"><is><t>Average</t></is></c><c r="C1" s="1" t="inlineStr"><is><t>P100</t></is></c>`,
+		`<row r="2"><c r="A2" s="0" t="inlineStr"><is><t>0</t></is></c><c r="B2" s="3"><v>0.375</v></c><c r="C2" s="3"><v>0.5</v></c></row>`,
+		`<row r="4"><c r="A4" s="1" t="inlineStr"><is><t>Total</t></is></c><c r="B4" s="3"><v>1.875</v></c><c r="C4" s="3"><v>2.25</v></c></row>`,
+	} {
+		assert.Assert(t, strings.Contains(averages, expected), "missing %s", expected)
+	}
+}
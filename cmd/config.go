@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/poolski/powertracker/cmd/secret"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// secretKeys are the names of the settings shown masked by config show, wherever they are.
+var secretKeys = map[string]bool{
+	"api_key":  true,
+	"token":    true,
+	"password": true,
+}
+
+// masked is shown in place of a secret.
+const masked = "********"
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show or change settings in the config file",
+	Long: `
+	Works on the config file as it is written, leaving its comments and layout alone, rather than with the settings as overridden by flags and environment variables.
+	Keys are given as they appear in the config, with dots between sections, e.g. prices.provider.
+	With --profile, get reads the key from the profile if it is set there, and set writes it there.`,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the config file, with tokens and passwords masked",
+	Args:  cobra.NoArgs,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		doc := loadConfigDoc()
+		maskSecrets(doc)
+		out, err := encodeConfigDoc(doc)
+		if err != nil {
+			log.Fatal().Msgf("printing config: %s", err.Error())
+		}
+		fmt.Print(string(out))
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the value of a setting in the config file",
+	Args:  cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		value, err := configValue(loadConfigDoc(), args[0], profileName())
+		if err != nil {
+			log.Fatal().Msg(err.Error())
+		}
+		fmt.Println(value)
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting in the config file",
+	Long: `
+	Sets the key to the value, which is read as YAML, so a list can be given as e.g. "[sensor.house, sensor.garage]".
+	The config is checked before it is written, and left alone if the change would make it invalid.`,
+	Args: cobra.ExactArgs(2),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		doc := loadConfigDoc()
+		key := args[0]
+		if name := profileName(); name != "" {
+			key = "profiles." + name + "." + key
+		}
+		if err := setConfigValue(doc, key, args[1]); err != nil {
+			log.Fatal().Msg(err.Error())
+		}
+		out, err := encodeConfigDoc(doc)
+		if err != nil {
+			log.Fatal().Msgf("writing config: %s", err.Error())
+		}
+		warnings, err := validateConfig(cfgFile, out)
+		if err != nil {
+			log.Fatal().Msgf("not setting %s:\n%s", key, err.Error())
+		}
+		// An unknown key would only be ignored, so is most likely a typo.
+		if len(warnings) > 0 {
+			log.Fatal().Msgf("not setting %s: %s", key, strings.Join(warnings, "\n"))
+		}
+		if err := os.WriteFile(cfgFile, out, 0600); err != nil {
+			log.Fatal().Msgf("writing config file: %s", err.Error())
+		}
+		log.Info().Msgf("set %s in %s", key, cfgFile)
+	},
+}
+
+// runningConfigCmd reports whether one of the config commands is being run, which need none of
+// the settings in the config applied.
+func runningConfigCmd() bool {
+	cmd, _, err := rootCmd.Find(os.Args[1:])
+	return err == nil && cmd.HasParent() && cmd.Parent() == configCmd
+}
+
+// loadConfigDoc reads and parses the config file, exiting if there isn't one it can edit.
+func loadConfigDoc() *yaml.Node {
+	if !localConfig() {
+		log.Fatal().Msg("there is no config file to work on")
+	}
+	if !isYAML(cfgFile) {
+		log.Fatal().Msg("only YAML config files can be worked on")
+	}
+	data, err := os.ReadFile(cfgFile)
+	if err != nil {
+		log.Fatal().Msgf("reading config file: %s", err.Error())
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		log.Fatal().Msgf("reading config file: %s", err.Error())
+	}
+	return &doc
+}
+
+// encodeConfigDoc writes the config out as YAML, indented as the first-time setup writes it.
+func encodeConfigDoc(doc *yaml.Node) ([]byte, error) {
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// configValue returns the value of the key in the config, from the named profile if it is set
+// there, as a plain string for a single value and as YAML for a section or list.
+func configValue(doc *yaml.Node, key, profile string) (string, error) {
+	var node *yaml.Node
+	if profile != "" {
+		node = lookup(doc, "profiles."+profile+"."+key)
+	}
+	if node == nil {
+		node = lookup(doc, key)
+	}
+	if node == nil {
+		return "", fmt.Errorf("%s isn't set in the config", key)
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode {
+		return node.Value, nil
+	}
+	out, err := yaml.Marshal(node)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// lookup returns the value of the key in the config, or nil if it isn't set. Keys are matched
+// regardless of case, as viper does.
+func lookup(doc *yaml.Node, key string) *yaml.Node {
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	node := doc.Content[0]
+	for _, name := range strings.Split(key, ".") {
+		if node.Kind == yaml.AliasNode {
+			node = node.Alias
+		}
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		i := keyIndex(node, name)
+		if i < 0 {
+			return nil
+		}
+		node = node.Content[i+1]
+	}
+	return node
+}
+
+// setConfigValue sets the key in the config to value, read as YAML, adding any sections on the
+// way to it that aren't there yet.
+func setConfigValue(doc *yaml.Node, key, value string) error {
+	var parsed yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &parsed); err != nil {
+		return fmt.Errorf("reading the value of %s: %w", key, err)
+	}
+	v := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str"}
+	if len(parsed.Content) > 0 {
+		v = parsed.Content[0]
+	}
+
+	if len(doc.Content) == 0 {
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	node := doc.Content[0]
+	names := strings.Split(key, ".")
+	for n, name := range names {
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s isn't a section", strings.Join(names[:n], "."))
+		}
+		i := keyIndex(node, name)
+		if n == len(names)-1 {
+			if i < 0 {
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, v)
+			} else {
+				// Comments on the old value stay with the key.
+				v.LineComment = node.Content[i+1].LineComment
+				node.Content[i+1] = v
+			}
+			return nil
+		}
+		if i < 0 {
+			sub := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, sub)
+			node = sub
+			continue
+		}
+		node = node.Content[i+1]
+	}
+	return nil
+}
+
+// writeConfigString sets the key in the YAML config file at path to the string value, leaving the
+// rest of the file, comments included, as it was, rather than writing out every setting as viper
+// has them, with those from flags, the environment and the profile in use.
+func writeConfigString(path, key, value string) error {
+	if !isYAML(path) {
+		return fmt.Errorf("only YAML config files can be rewritten")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	// The value is quoted as YAML needs, so it is read back as the same string.
+	quoted, err := yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := setConfigValue(&doc, key, string(quoted)); err != nil {
+		return err
+	}
+	out, err := encodeConfigDoc(&doc)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, out, 0600)
+}
+
+// keyIndex returns the index of the key in the content of a mapping, or -1 if it isn't there.
+func keyIndex(mapping *yaml.Node, name string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if strings.EqualFold(mapping.Content[i].Value, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// maskSecrets replaces the values of the secretKeys in the config with masked.
+func maskSecrets(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			// A token kept in the OS keyring is only referred to, so is left showing.
+			if secretKeys[strings.ToLower(key.Value)] && value.Kind == yaml.ScalarNode && value.Value != "" && value.Value != secret.Keyring {
+				value.Value, value.Tag, value.Style = masked, "!!str", 0
+				continue
+			}
+			maskSecrets(value)
+		}
+		return
+	}
+	for _, child := range node.Content {
+		maskSecrets(child)
+	}
+}
+
+func init() {
+	configCmd.AddCommand(configShowCmd, configGetCmd, configSetCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+	"gotest.tools/v3/assert"
+)
+
+const testConfig = `# My house
+url: http://homeassistant.local:8123
+api_key: my_token
+sensor_id: sensor.energy # the main meter
+glow:
+  username: me@example.com
+  password: hunter2
+profiles:
+  parents:
+    url: http://parents.example.com:8123
+`
+
+func parseConfig(t *testing.T, config string) *yaml.Node {
+	t.Helper()
+	var doc yaml.Node
+	assert.NilError(t, yaml.Unmarshal([]byte(config), &doc))
+	return &doc
+}
+
+func TestConfigValue(t *testing.T) {
+	doc := parseConfig(t, testConfig)
+	tests := []struct {
+		key, profile string
+		want, err    string
+	}{
+		{key: "url", want: "http://homeassistant.local:8123"},
+		{key: "URL", want: "http://homeassistant.local:8123"},
+		{key: "glow.username", want: "me@example.com"},
+		{key: "glow", want: "username: me@example.com\npassword: hunter2"},
+		{key: "url", profile: "parents", want: "http://parents.example.com:8123"},
+		// What a profile leaves out comes from the top level.
+		{key: "sensor_id", profile: "parents", want: "sensor.energy"},
+		{key: "prices.provider", err: "prices.provider isn't set in the config"},
+		{key: "url.scheme", err: "url.scheme isn't set in the config"},
+	}
+	for _, tt := range tests {
+		got, err := configValue(doc, tt.key, tt.profile)
+		if tt.err != "" {
+			assert.ErrorContains(t, err, tt.err, tt.key)
+			continue
+		}
+		assert.NilError(t, err, tt.key)
+		assert.Equal(t, got, tt.want, tt.key)
+	}
+}
+
+func TestSetConfigValue(t *testing.T) {
+	doc := parseConfig(t, testConfig)
+	assert.NilError(t, setConfigValue(doc, "sensor_id", "[sensor.house, sensor.garage]"))
+	assert.NilError(t, setConfigValue(doc, "glow.username", "you@example.com"))
+	assert.NilError(t, setConfigValue(doc, "prices.amber.site_id", "01ABC"))
+	assert.NilError(t, setConfigValue(doc, "profiles.parents.sensor_id", "sensor.house_energy"))
+	assert.ErrorContains(t, setConfigValue(doc, "url.scheme", "https"), "url isn't a section")
+
+	out, err := encodeConfigDoc(doc)
+	assert.NilError(t, err)
+	// Comments are kept.
+	assert.Equal(t, string(out), `# My house
+url: http://homeassistant.local:8123
+api_key: my_token
+sensor_id: [sensor.house, sensor.garage] # the main meter
+glow:
+  username: you@example.com
+  password: hunter2
+profiles:
+  parents:
+    url: http://parents.example.com:8123
+    sensor_id: sensor.house_energy
+prices:
+  amber:
+    site_id: 01ABC
+`)
+	warnings, err := validateConfig("config.yaml", out)
+	assert.NilError(t, err)
+	assert.Equal(t, len(warnings), 0)
+
+	// An empty config is started afresh.
+	doc = &yaml.Node{}
+	assert.NilError(t, setConfigValue(doc, "url", "http://homeassistant.local:8123"))
+	out, err = encodeConfigDoc(doc)
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "url: http://homeassistant.local:8123\n")
+}
+
+func TestMaskSecrets(t *testing.T) {
+	doc := parseConfig(t, testConfig+"influxdb:\n  token: \"\"\n")
+	maskSecrets(doc)
+	out, err := encodeConfigDoc(doc)
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), `# My house
+url: http://homeassistant.local:8123
+api_key: '********'
+sensor_id: sensor.energy # the main meter
+glow:
+  username: me@example.com
+  password: '********'
+profiles:
+  parents:
+    url: http://parents.example.com:8123
+influxdb:
+  token: ""
+`)
+
+	// A token kept in the OS keyring isn't in the file to mask.
+	doc = parseConfig(t, "api_key: keyring\n")
+	maskSecrets(doc)
+	out, err = encodeConfigDoc(doc)
+	assert.NilError(t, err)
+	assert.Equal(t, string(out), "api_key: keyring\n")
+}
+
+func TestWriteConfigString(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NilError(t, os.WriteFile(path, []byte(testConfig), 0600))
+
+	// The token is the only thing changed: the other keys and the comments are as they were, and
+	// nothing from the environment or flags is written.
+	t.Setenv("POWERTRACKER_SENSOR_ID", "sensor.from_env")
+	assert.NilError(t, writeConfigString(path, "api_key", "age:YWdlLWVuY3J5cHRpb24ub3JnL3Yx+/=="))
+	assert.Equal(t, readFile(t, path), strings.Replace(testConfig, "api_key: my_token", "api_key: age:YWdlLWVuY3J5cHRpb24ub3JnL3Yx+/==", 1))
+
+	// A profile's token is written to the profile.
+	assert.NilError(t, writeConfigString(path, "profiles.parents.api_key", "keyring"))
+	value, err := configValue(parseConfig(t, readFile(t, path)), "api_key", "parents")
+	assert.NilError(t, err)
+	assert.Equal(t, value, "keyring")
+	value, err = configValue(parseConfig(t, readFile(t, path)), "api_key", "")
+	assert.NilError(t, err)
+	assert.Equal(t, value, "age:YWdlLWVuY3J5cHRpb24ub3JnL3Yx+/==")
+
+	// Values YAML would read as something else are quoted.
+	assert.NilError(t, writeConfigString(path, "api_key", "true"))
+	assert.Assert(t, strings.Contains(readFile(t, path), `api_key: "true"`))
+
+	assert.ErrorContains(t, writeConfigString(filepath.Join(t.TempDir(), "config.toml"), "api_key", "x"), "only YAML config files")
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	assert.NilError(t, err)
+	return string(data)
+}
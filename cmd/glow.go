@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/poolski/powertracker/cmd/client"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var glowCmd = &cobra.Command{
+	Use:   "glow",
+	Short: "Record the half-hourly consumption a Glow CAD publishes over MQTT, for source: glow",
+	Long: `
+	Subscribes to the electricity meter messages a Glow CAD or display publishes to glow.broker, and records the consumption of each half hour next to the config, for the glow source to read.
+	The CAD only publishes readings as they happen, so this needs to be left running, e.g. as a service. Press Ctrl+C to stop.`,
+	Example: "  powertracker glow",
+	Args:    cobra.NoArgs,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		c := client.New(clientConfig())
+		stopOnSignal(c.Stop)
+		if err := c.RecordGlow(); err != nil {
+			log.Fatal().Msgf("recording glow readings: %s", err.Error())
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(glowCmd)
+}
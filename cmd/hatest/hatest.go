@@ -0,0 +1,400 @@
+// Package hatest provides a fake Home Assistant websocket server for tests. It runs in-process,
+// goes through the same authentication flow as Home Assistant, answers long-term statistics
+// requests from the statistics it is given, fires events at subscribers, and can be told to fail
+// requests, so code that talks to Home Assistant can be tested without a real instance.
+//
+//	s := hatest.NewServer("token")
+//	defer s.Close()
+//	s.SetStatistics("sensor.energy", hatest.Hourly(start, 0.5, 0.4, 0.3))
+//	viper.Set("url", s.URL)
+//	viper.Set("api_key", "token")
+package hatest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Version is the Home Assistant version the server reports.
+const Version = "2023.9.0"
+
+// Statistic is a row of long-term statistics, as stored by the recorder.
+type Statistic struct {
+	Start  time.Time
+	End    time.Time
+	Change float64
+	Mean   float64
+	Sum    float64
+	State  float64
+}
+
+// Hourly returns a statistic for each value, one hour apart from start, with the value as the
+// change over the hour. Sum and State run on from zero, as they would for an energy meter.
+func Hourly(start time.Time, values ...float64) []Statistic {
+	stats := make([]Statistic, len(values))
+	var total float64
+	for i, v := range values {
+		total += v
+		from := start.Add(time.Duration(i) * time.Hour)
+		stats[i] = Statistic{Start: from, End: from.Add(time.Hour), Change: v, Mean: v, Sum: total, State: total}
+	}
+	return stats
+}
+
+// Error is the error returned in an unsuccessful result, e.g. {"code": "unknown_command"}.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// HandlerFunc answers a command sent to the server with a result, or an error. Commands are
+// answered concurrently, so handlers may be called from several goroutines at once.
+type HandlerFunc func(msg map[string]interface{}) (interface{}, *Error)
+
+// Server is a fake Home Assistant. Its methods can be called while clients are connected.
+type Server struct {
+	// URL is the base URL of the server, in the form http://127.0.0.1:1234, as set in the url
+	// config value.
+	URL string
+
+	server *httptest.Server
+	token  string
+
+	mu         sync.Mutex
+	statistics map[string][]Statistic
+	timeZone   string
+	handlers   map[string]HandlerFunc
+	failures   []Error
+	drops      int
+	hangs      int
+	received   []map[string]interface{}
+	// subscribers are the subscriptions made with subscribe_events on connections still open.
+	subscribers []subscriber
+}
+
+// connection is a client's connection to the server. Responses and events are written to it from
+// several goroutines, one at a time.
+type connection struct {
+	conn    *websocket.Conn
+	writing sync.Mutex
+}
+
+func (c *connection) write(v interface{}) {
+	c.writing.Lock()
+	defer c.writing.Unlock()
+	_ = c.conn.WriteJSON(v)
+}
+
+// subscriber is a subscription to events of a type, or to every event if the type is empty.
+type subscriber struct {
+	conn      *connection
+	id        interface{}
+	eventType string
+}
+
+// NewServer starts a server that accepts the given access token.
+func NewServer(token string) *Server {
+	return start(token, httptest.NewServer)
+}
+
+// NewTLSServer starts a server, as NewServer does, that is served over HTTPS with a self-signed
+// certificate, which Certificate returns.
+func NewTLSServer(token string) *Server {
+	return start(token, httptest.NewTLSServer)
+}
+
+// NewMutualTLSServer starts a server, as NewTLSServer does, that also requires a client
+// certificate signed by one of the CAs, as a reverse proxy in front of Home Assistant can.
+func NewMutualTLSServer(token string, clientCAs *x509.CertPool) *Server {
+	return start(token, func(h http.Handler) *httptest.Server {
+		s := httptest.NewUnstartedServer(h)
+		s.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+		s.StartTLS()
+		return s
+	})
+}
+
+func start(token string, listen func(http.Handler) *httptest.Server) *Server {
+	s := &Server{
+		token:      token,
+		statistics: map[string][]Statistic{},
+		timeZone:   "UTC",
+		handlers:   map[string]HandlerFunc{},
+	}
+	s.handlers["get_config"] = s.getConfig
+	s.handlers["ping"] = nil
+	s.handlers["recorder/statistics_during_period"] = s.statisticsDuringPeriod
+	s.server = listen(http.HandlerFunc(s.serve))
+	s.URL = s.server.URL
+	return s
+}
+
+// Certificate returns the certificate of a server started with NewTLSServer, encoded as PEM.
+func (s *Server) Certificate() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.server.Certificate().Raw})
+}
+
+// Close shuts the server down, closing any connections.
+func (s *Server) Close() {
+	s.server.CloseClientConnections()
+	s.server.Close()
+}
+
+// SetStatistics replaces the statistics recorded for the statistic ID. They are returned for
+// whatever period is asked for, so give them at the period the code under test uses.
+func (s *Server) SetStatistics(id string, stats []Statistic) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statistics[id] = stats
+}
+
+// SetTimeZone sets the time zone the server reports in its config, UTC unless set.
+func (s *Server) SetTimeZone(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timeZone = name
+}
+
+// Handle answers commands of the given type with fn, replacing the server's own handling.
+func (s *Server) Handle(command string, fn HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[command] = fn
+}
+
+// FailNext answers the next n commands with the error, instead of their result.
+func (s *Server) FailNext(n int, code, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < n; i++ {
+		s.failures = append(s.failures, Error{Code: code, Message: message})
+	}
+}
+
+// DropNext closes the connection when the next n commands arrive, without answering them.
+func (s *Server) DropNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drops += n
+}
+
+// HangNext leaves the next n commands unanswered, as a Home Assistant that has hung does, while
+// keeping the connection open.
+func (s *Server) HangNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hangs += n
+}
+
+// FireEvent sends an event to every connection subscribed to its type, as Home Assistant does
+// when, for example, a state changes. It returns the number of subscriptions it was sent to, so
+// tests can wait for the code under test to subscribe.
+func (s *Server) FireEvent(eventType string, data interface{}) int {
+	s.mu.Lock()
+	subscribers := append([]subscriber(nil), s.subscribers...)
+	s.mu.Unlock()
+
+	sent := 0
+	for _, sub := range subscribers {
+		if sub.eventType != "" && sub.eventType != eventType {
+			continue
+		}
+		sub.conn.write(map[string]interface{}{
+			"id":   sub.id,
+			"type": "event",
+			"event": map[string]interface{}{
+				"event_type": eventType,
+				"data":       data,
+				"origin":     "LOCAL",
+				"time_fired": time.Now().UTC().Format(time.RFC3339Nano),
+			},
+		})
+		sent++
+	}
+	return sent
+}
+
+// Received returns the commands received since the server started, in order, as decoded from
+// JSON. Authentication messages aren't included.
+func (s *Server) Received() []map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]map[string]interface{}(nil), s.received...)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/websocket" {
+		http.NotFound(w, r)
+		return
+	}
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	if err := conn.WriteJSON(map[string]string{"type": "auth_required", "ha_version": Version}); err != nil {
+		return
+	}
+	var auth struct {
+		Type        string `json:"type"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := conn.ReadJSON(&auth); err != nil {
+		return
+	}
+	if auth.Type != "auth" || auth.AccessToken != s.token {
+		_ = conn.WriteJSON(map[string]string{"type": "auth_invalid", "message": "Invalid access token or password"})
+		return
+	}
+	if err := conn.WriteJSON(map[string]string{"type": "auth_ok", "ha_version": Version}); err != nil {
+		return
+	}
+
+	// Like Home Assistant, commands are answered as they finish rather than in the order they
+	// arrived, so a handler that takes a while doesn't hold up the others.
+	c := &connection{conn: conn}
+	defer s.unsubscribeAll(c)
+	var answering sync.WaitGroup
+	defer answering.Wait()
+	for {
+		var msg map[string]interface{}
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		respond, ok := s.answer(c, msg)
+		if !ok {
+			return
+		}
+		answering.Add(1)
+		go func() {
+			defer answering.Done()
+			if resp := respond(); resp != nil {
+				c.write(resp)
+			}
+		}()
+	}
+}
+
+// unsubscribeAll removes the subscriptions made on a connection that has closed.
+func (s *Server) unsubscribeAll(c *connection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.subscribers[:0]
+	for _, sub := range s.subscribers {
+		if sub.conn != c {
+			kept = append(kept, sub)
+		}
+	}
+	s.subscribers = kept
+}
+
+// answer returns a function that works out the response to a command, or nil if it isn't to be
+// answered, or false if the connection should be dropped instead. Commands are recorded, and failures used up, in the order they
+// arrive.
+func (s *Server) answer(c *connection, msg map[string]interface{}) (func() map[string]interface{}, bool) {
+	s.mu.Lock()
+	s.received = append(s.received, msg)
+	if s.drops > 0 {
+		s.drops--
+		s.mu.Unlock()
+		return nil, false
+	}
+	if s.hangs > 0 {
+		s.hangs--
+		s.mu.Unlock()
+		return func() map[string]interface{} { return nil }, true
+	}
+	var failure *Error
+	if len(s.failures) > 0 {
+		failure = &s.failures[0]
+		s.failures = s.failures[1:]
+	}
+	command, _ := msg["type"].(string)
+	handler, known := s.handlers[command]
+	id := msg["id"]
+	if command == "subscribe_events" && failure == nil {
+		eventType, _ := msg["event_type"].(string)
+		s.subscribers = append(s.subscribers, subscriber{conn: c, id: id, eventType: eventType})
+		known, handler = true, func(map[string]interface{}) (interface{}, *Error) { return nil, nil }
+	}
+	s.mu.Unlock()
+
+	return func() map[string]interface{} {
+		switch {
+		case failure != nil:
+			return errorResult(id, failure)
+		case !known:
+			return errorResult(id, &Error{Code: "unknown_command", Message: "Unknown command."})
+		case handler == nil:
+			// Pings are answered with a pong rather than a result.
+			return map[string]interface{}{"id": id, "type": "pong"}
+		}
+		result, err := handler(msg)
+		if err != nil {
+			return errorResult(id, err)
+		}
+		return map[string]interface{}{"id": id, "type": "result", "success": true, "result": result}
+	}, true
+}
+
+func errorResult(id interface{}, err *Error) map[string]interface{} {
+	return map[string]interface{}{"id": id, "type": "result", "success": false, "error": err}
+}
+
+// getConfig answers get_config with the parts of Home Assistant's config that are set.
+func (s *Server) getConfig(map[string]interface{}) (interface{}, *Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return map[string]interface{}{"time_zone": s.timeZone, "version": Version}, nil
+}
+
+// statisticsDuringPeriod answers recorder/statistics_during_period with the statistics that start
+// within the window, keyed by statistic ID. IDs without any are left out, as in Home Assistant.
+func (s *Server) statisticsDuringPeriod(msg map[string]interface{}) (interface{}, *Error) {
+	start, err := parseTime(msg["start_time"])
+	if err != nil {
+		return nil, &Error{Code: "invalid_start_time", Message: "Invalid start_time"}
+	}
+	end := time.Now()
+	if msg["end_time"] != nil {
+		if end, err = parseTime(msg["end_time"]); err != nil {
+			return nil, &Error{Code: "invalid_end_time", Message: "Invalid end_time"}
+		}
+	}
+	ids, _ := msg["statistic_ids"].([]interface{})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := map[string][]map[string]interface{}{}
+	for _, v := range ids {
+		id, _ := v.(string)
+		for _, st := range s.statistics[id] {
+			if st.Start.Before(start) || !st.Start.Before(end) {
+				continue
+			}
+			result[id] = append(result[id], map[string]interface{}{
+				"start":  st.Start.UnixMilli(),
+				"end":    st.End.UnixMilli(),
+				"change": st.Change,
+				"mean":   st.Mean,
+				"sum":    st.Sum,
+				"state":  st.State,
+			})
+		}
+	}
+	return result, nil
+}
+
+func parseTime(v interface{}) (time.Time, error) {
+	s, _ := v.(string)
+	return time.Parse(time.RFC3339, s)
+}
@@ -0,0 +1,151 @@
+package hatest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"gotest.tools/v3/assert"
+)
+
+// dial connects to the server and authenticates with the token.
+func dial(t *testing.T, s *Server, token string) (*websocket.Conn, map[string]interface{}) {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+"/api/websocket", nil)
+	assert.NilError(t, err)
+	var msg map[string]interface{}
+	assert.NilError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, msg["type"], "auth_required")
+	assert.NilError(t, conn.WriteJSON(map[string]string{"type": "auth", "access_token": token}))
+	assert.NilError(t, conn.ReadJSON(&msg))
+	return conn, msg
+}
+
+func TestServer_Auth(t *testing.T) {
+	s := NewServer("token")
+	defer s.Close()
+
+	conn, msg := dial(t, s, "token")
+	defer conn.Close()
+	assert.Equal(t, msg["type"], "auth_ok")
+
+	bad, msg := dial(t, s, "wrong")
+	defer bad.Close()
+	assert.Equal(t, msg["type"], "auth_invalid")
+}
+
+func TestServer_Statistics(t *testing.T) {
+	s := NewServer("token")
+	defer s.Close()
+	start := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	s.SetStatistics("sensor.energy", Hourly(start, 1, 2, 3))
+
+	conn, _ := dial(t, s, "token")
+	defer conn.Close()
+	assert.NilError(t, conn.WriteJSON(map[string]interface{}{
+		"id":            2,
+		"type":          "recorder/statistics_during_period",
+		"start_time":    "2023-09-01T01:00:00.000Z",
+		"end_time":      "2023-09-01T03:00:00.000Z",
+		"statistic_ids": []string{"sensor.energy", "sensor.other"},
+		"period":        "hour",
+	}))
+	var resp struct {
+		ID      int                             `json:"id"`
+		Success bool                            `json:"success"`
+		Result  map[string][]map[string]float64 `json:"result"`
+	}
+	assert.NilError(t, conn.ReadJSON(&resp))
+	assert.Equal(t, resp.ID, 2)
+	assert.Assert(t, resp.Success)
+	assert.Equal(t, len(resp.Result), 1)
+	assert.Equal(t, len(resp.Result["sensor.energy"]), 2)
+	assert.Equal(t, resp.Result["sensor.energy"][0]["change"], 2.0)
+	assert.Equal(t, resp.Result["sensor.energy"][1]["sum"], 6.0)
+	assert.Equal(t, int64(resp.Result["sensor.energy"][0]["start"]), start.Add(time.Hour).UnixMilli())
+}
+
+func TestServer_Config(t *testing.T) {
+	s := NewServer("token")
+	defer s.Close()
+	s.SetTimeZone("Europe/London")
+
+	conn, _ := dial(t, s, "token")
+	defer conn.Close()
+	assert.NilError(t, conn.WriteJSON(map[string]interface{}{"id": 2, "type": "get_config"}))
+	var resp struct {
+		Success bool              `json:"success"`
+		Result  map[string]string `json:"result"`
+	}
+	assert.NilError(t, conn.ReadJSON(&resp))
+	assert.Assert(t, resp.Success)
+	assert.Equal(t, resp.Result["time_zone"], "Europe/London")
+	assert.Equal(t, resp.Result["version"], Version)
+}
+
+func TestServer_Events(t *testing.T) {
+	s := NewServer("token")
+	defer s.Close()
+
+	conn, _ := dial(t, s, "token")
+	defer conn.Close()
+	assert.NilError(t, conn.WriteJSON(map[string]interface{}{"id": 2, "type": "subscribe_events", "event_type": "state_changed"}))
+	var resp map[string]interface{}
+	assert.NilError(t, conn.ReadJSON(&resp))
+	assert.Equal(t, resp["success"], true)
+
+	assert.Equal(t, s.FireEvent("call_service", nil), 0)
+	assert.Equal(t, s.FireEvent("state_changed", map[string]string{"entity_id": "sensor.power"}), 1)
+	assert.NilError(t, conn.ReadJSON(&resp))
+	assert.Equal(t, resp["id"], float64(2))
+	assert.Equal(t, resp["type"], "event")
+	event := resp["event"].(map[string]interface{})
+	assert.Equal(t, event["event_type"], "state_changed")
+	assert.Equal(t, event["data"].(map[string]interface{})["entity_id"], "sensor.power")
+}
+
+func TestServer_Errors(t *testing.T) {
+	s := NewServer("token")
+	defer s.Close()
+	s.FailNext(1, "home_assistant_error", "Database is locked")
+	s.Handle("energy/get_prefs", func(msg map[string]interface{}) (interface{}, *Error) {
+		return map[string]interface{}{"energy_sources": []interface{}{}}, nil
+	})
+
+	conn, _ := dial(t, s, "token")
+	defer conn.Close()
+	var resp map[string]interface{}
+
+	// The injected failure answers the first command, whatever it is...
+	assert.NilError(t, conn.WriteJSON(map[string]interface{}{"id": 2, "type": "ping"}))
+	assert.NilError(t, conn.ReadJSON(&resp))
+	assert.Equal(t, resp["success"], false)
+	assert.DeepEqual(t, resp["error"], map[string]interface{}{"code": "home_assistant_error", "message": "Database is locked"})
+
+	// ...then commands are answered normally.
+	assert.NilError(t, conn.WriteJSON(map[string]interface{}{"id": 3, "type": "ping"}))
+	assert.NilError(t, conn.ReadJSON(&resp))
+	assert.Equal(t, resp["type"], "pong")
+
+	assert.NilError(t, conn.WriteJSON(map[string]interface{}{"id": 4, "type": "energy/get_prefs"}))
+	assert.NilError(t, conn.ReadJSON(&resp))
+	assert.Equal(t, resp["success"], true)
+
+	assert.NilError(t, conn.WriteJSON(map[string]interface{}{"id": 5, "type": "config/unknown"}))
+	assert.NilError(t, conn.ReadJSON(&resp))
+	assert.Equal(t, resp["error"].(map[string]interface{})["code"], "unknown_command")
+
+	// A hung command is never answered, but the next one is.
+	s.HangNext(1)
+	assert.NilError(t, conn.WriteJSON(map[string]interface{}{"id": 6, "type": "ping"}))
+	assert.NilError(t, conn.WriteJSON(map[string]interface{}{"id": 7, "type": "ping"}))
+	assert.NilError(t, conn.ReadJSON(&resp))
+	assert.Equal(t, resp["id"], float64(7))
+
+	// A dropped command closes the connection.
+	s.DropNext(1)
+	assert.NilError(t, conn.WriteJSON(map[string]interface{}{"id": 8, "type": "ping"}))
+	assert.Assert(t, conn.ReadJSON(&resp) != nil)
+	assert.Equal(t, len(s.Received()), 7)
+}
@@ -0,0 +1,465 @@
+package i18n
+
+// catalogs holds the translations for each language other than English, keyed by the English
+// message. Date layouts are translated too, as Go only formats English month and day names.
+var catalogs = map[string]map[string]string{
+	"de": {
+		// Table headers.
+		"Actual":                  "Tatsächlich",
+		"Actual kWh":              "Ist kWh",
+		"At":                      "Zeitpunkt",
+		"Average":                 "Durchschnitt",
+		"Averages":                "Durchschnitte",
+		"Median":                  "Median",
+		"Average paid":            "Bezahlt (Ø)",
+		"Avg temp (°C)":           "Ø Temp. (°C)",
+		"Billing month":           "Abrechnungsmonat",
+		"Cheapest hours":          "Günstigste Stunden",
+		"Consumption":             "Verbrauch",
+		"Cost":                    "Kosten",
+		"Daily":                   "Pro Tag",
+		"Date":                    "Datum",
+		"Day":                     "Tag",
+		"Days":                    "Tage",
+		"Daylight %":              "Tageslicht %",
+		"Daylight kWh":            "Tageslicht kWh",
+		"Difference":              "Unterschied",
+		"Electricity kWh":         "Strom kWh",
+		"End":                     "Ende",
+		"Energy":                  "Energie",
+		"Error %":                 "Fehler %",
+		"Export":                  "Einspeisung",
+		"Forecast kWh":            "Prognose kWh",
+		"Fossil %":                "Fossil %",
+		"Fossil kWh":              "Fossil kWh",
+		"Generation":              "Erzeugung",
+		"Group":                   "Gruppe",
+		"Heat kWh":                "Wärme kWh",
+		"Hour":                    "Stunde",
+		"Hours":                   "Stunden",
+		"Imbalance %":             "Schieflast %",
+		"Import":                  "Bezug",
+		"kg CO₂":                  "kg CO₂",
+		"Last reset":              "Zuletzt zurückgesetzt",
+		"Likely cause":            "Wahrscheinliche Ursache",
+		"Max demand (%d min, kW)": "Höchstlast (%d Min., kW)",
+		"Month":                   "Monat",
+		"Name":                    "Name",
+		"Night kWh":               "Nacht kWh",
+		"Occurrences":             "Vorkommen",
+		"Per day":                 "Pro Tag",
+		"Profile":                 "Profil",
+		"Rate":                    "Preis",
+		"Saving":                  "Ersparnis",
+		"Self-use":                "Eigenverbrauch",
+		"Share":                   "Anteil",
+		"Shifted":                 "Verschoben",
+		"Standing charges":        "Grundgebühren",
+		"Start":                   "Beginn",
+		"Statistic ID":            "Statistik-ID",
+		"Sunrise":                 "Sonnenaufgang",
+		"Sunset":                  "Sonnenuntergang",
+		"Tariff":                  "Tarif",
+		"Tax":                     "Steuern",
+		"Total":                   "Gesamt",
+		"Typical %s (kWh)":        "Typisch, %s (kWh)",
+		"Typical draw":            "Typische Leistung",
+		"Typical duration":        "Typische Dauer",
+		"Unit":                    "Einheit",
+		"You (kWh)":               "Sie (kWh)",
+
+		// Values.
+		"%s (so far)":             "%s (bisher)",
+		"Home Assistant downtime": "Home Assistant nicht erreichbar",
+		"high":                    "hoch",
+		"low":                     "niedrig",
+		"medium":                  "mittel",
+		"power outage":            "Stromausfall",
+		"unknown":                 "unbekannt",
+
+		// Summaries.
+		"%d gaps in the data look like Home Assistant was down - see -o gaps.": "%d Lücken in den Daten sehen aus, als wäre Home Assistant nicht erreichbar gewesen - siehe -o gaps.",
+		"15:04 on Monday 2 January":             "2.1. um 15:04",
+		"COP over the period: %s":               "Arbeitszahl über den Zeitraum: %s",
+		"Insights:":                             "Erkenntnisse:",
+		"It would have added %.1f kg of CO₂.":   "Dadurch wären %.1f kg CO₂ mehr ausgestoßen worden.",
+		"It would have avoided %.1f kg of CO₂.": "Dadurch wären %.1f kg CO₂ vermieden worden.",
+		"Monday 2 January":                      "2.1.",
+		"Moving %.1f kWh a day from %02d:00 to %02d:00 would have cost %s more over %d days.":  "%.1f kWh pro Tag von %02d:00 auf %02d:00 Uhr zu verschieben, hätte in %[5]d Tagen %[4]s mehr gekostet.",
+		"Moving %.1f kWh a day from %02d:00 to %02d:00 would have saved %s over %d days.":      "%.1f kWh pro Tag von %02d:00 auf %02d:00 Uhr zu verschieben, hätte %s in %d Tagen gespart.",
+		"Moving %.1f kWh a day into the cheapest %d hours would have saved %s over %d days.":   "%.1f kWh pro Tag in die günstigsten %d Stunden zu verschieben, hätte %s in %d Tagen gespart.",
+		"No forecasts have been kept for these days yet. Run -o solar daily to build them up.": "Für diese Tage wurden noch keine Prognosen gespeichert. Führen Sie -o solar täglich aus, um sie zu sammeln.",
+		"No gaps in usage found.":                                                                     "Keine Lücken im Verbrauch gefunden.",
+		"Nothing was used %s, which looks like a power cut.":                                          "Es wurde %s nichts verbraucht, was nach einem Stromausfall aussieht.",
+		"Nothing was used in %d separate windows, which look like power cuts - see -o gaps.":          "In %d Zeiträumen wurde nichts verbraucht, was nach Stromausfällen aussieht - siehe -o gaps.",
+		"Now %s   min %s   avg %s   max %s   (last %s)":                                               "Jetzt %s   min. %s   Ø %s   max. %s   (letzte %s)",
+		"Over %d days, the forecast was out by %.0f%% a day on average, and %.0f%% too high overall.": "Über %d Tage lag die Prognose im Schnitt um %.0f%% pro Tag daneben, insgesamt %.0f%% zu hoch.",
+		"Over %d days, the forecast was out by %.0f%% a day on average, and %.0f%% too low overall.":  "Über %d Tage lag die Prognose im Schnitt um %.0f%% pro Tag daneben, insgesamt %.0f%% zu niedrig.",
+		"The cheapest hours were most often %02d:00-%02d:00 (%d of %d days).":                         "Am häufigsten waren %02d:00-%02d:00 Uhr am günstigsten (an %d von %d Tagen).",
+		"You use %.0f%% less on weekends than on weekdays.":                                           "Am Wochenende verbrauchen Sie %.0f%% weniger als an Werktagen.",
+		"You use %.0f%% more on weekends than on weekdays.":                                           "Am Wochenende verbrauchen Sie %.0f%% mehr als an Werktagen.",
+		"You use around %.0f kWh a year, against %.0f kWh for a typical %s-consumption household.":    "Sie verbrauchen etwa %.0f kWh im Jahr, gegenüber %.0f kWh in einem typischen Haushalt (Verbrauch: %s).",
+		"Your %02d:00-%02d:00 usage is %.0f%% above your hourly average.":                             "Ihr Verbrauch von %02d:00 bis %02d:00 Uhr liegt %.0f%% über Ihrem Stundendurchschnitt.",
+		"Your baseload fell %.0f%% over the last %d days compared with the %d days before.":           "Ihre Grundlast ist in den letzten %[2]d Tagen um %.0[1]f%% gesunken, verglichen mit den %[3]d Tagen davor.",
+		"Your baseload rose %.0f%% over the last %d days compared with the %d days before.":           "Ihre Grundlast ist in den letzten %[2]d Tagen um %.0[1]f%% gestiegen, verglichen mit den %[3]d Tagen davor.",
+		"Your highest day was %s at %.1f kWh, %.1fx your daily average.":                              "Ihr höchster Tag war der %s mit %.1f kWh, das %.1f-Fache Ihres Tagesdurchschnitts.",
+		"between %s and %s on %s":                                                                     "zwischen %s und %s Uhr am %s",
+		"from %s until %s":                                                                            "von %s bis %s",
+
+		// Explanations.
+		"Nothing to explain - no consumption was read.": "Nichts zu erklären - es wurde kein Verbrauch gelesen.",
+		"How these figures were worked out:":            "So wurden diese Zahlen ermittelt:",
+		"days:":                                         "Tage:",
+		"time zone:":                                    "Zeitzone:",
+		"source:":                                       "Quelle:",
+		"cache:":                                        "Cache:",
+		"padded:":                                       "aufgefüllt:",
+		"corrected:":                                    "korrigiert:",
+		"excluded:":                                     "ausgelassen:",
+		"averages:":                                     "Mittelwerte:",
+		"queries:":                                      "Anfragen:",
+		"hours":                                         "Stunden",
+		"hourly":                                        "stündlich",
+		"half hours":                                    "halben Stunden",
+		"half-hourly":                                   "halbstündlich",
+		"5-minute periods":                              "5-Minuten-Abschnitte",
+		"5-minute":                                      "5-minütig",
+		"none":                                          "keine",
+		"%d, from %s to %s":                             "%d, vom %s bis %s",
+		"days run from midnight in %s; local times are shown in %s (%s)":                  "Tage beginnen um Mitternacht in %s; Ortszeiten werden in %s (%s) angezeigt",
+		"%s, %s, the %q statistic, %s":                                                    "%s, %s, die Statistik %q, %s",
+		"%d days from the cache, %d fetched":                                              "%d Tage aus dem Cache, %d abgerufen",
+		"none - every one of the %s had readings":                                         "keine - alle %s hatten Messwerte",
+		"%d %s without readings count as zero: %s":                                        "%d %s ohne Messwerte zählen als null: %s",
+		"%d impossible values replaced with the median of the same period on other days:": "%d unmögliche Werte durch den Median desselben Zeitraums an anderen Tagen ersetzt:",
+		"the mean of each of the %s over the days included":                               "der Mittelwert jeder der %s über die berücksichtigten Tage",
+		"the median of each of the %s over the days included":                             "der Median jeder der %s über die berücksichtigten Tage",
+		"percentile %s of each of the %s over the days included":                          "das %s. Perzentil jeder der %s über die berücksichtigten Tage",
+		"none - --period %s only shows the total of each":                                 "keine - --period %s zeigt nur die jeweilige Summe",
+		"not fetched before the run was stopped":                                          "nicht abgerufen, bevor der Lauf angehalten wurde",
+		"not fetched before the fetch failed":                                             "nicht abgerufen, bevor der Abruf fehlschlug",
+		"no 5-minute data":                                                                "keine 5-Minuten-Daten",
+		"none sent to Home Assistant":                                                     "keine an Home Assistant gesendet",
+		"queries sent to Home Assistant:":                                                 "an Home Assistant gesendete Anfragen:",
+
+		// Prompts.
+		"Keep the access token in the OS keyring?":        "Den Zugriffstoken im Schlüsselbund des Betriebssystems speichern?",
+		"Encrypt the access token with a passphrase?":     "Den Zugriffstoken mit einer Passphrase verschlüsseln?",
+		"Energy statistics in Home Assistant:":            "Energiestatistiken in Home Assistant:",
+		"Home Assistant Long-Lived Access Token":          "Langlebiger Zugriffstoken für Home Assistant",
+		"Home Assistant URL - e.g. http://localhost:8123": "Home-Assistant-URL - z. B. http://localhost:8123",
+		"New passphrase": "Neue Passphrase",
+		"No config file found. Let's set one up.": "Keine Konfigurationsdatei gefunden. Legen wir eine an.",
+		"Passphrase":                                         "Passphrase",
+		"Passphrase for the access token":                    "Passphrase für den Zugriffstoken",
+		"Power sensor entity ID - e.g. sensor.power":         "Entitäts-ID des Energiesensors - z. B. sensor.power",
+		"Sensor - a number from the list, or a statistic ID": "Sensor - eine Nummer aus der Liste oder eine Statistik-ID",
+		"Repeat passphrase":                                  "Passphrase wiederholen",
+	},
+	"es": {
+		// Table headers.
+		"Actual":                  "Real",
+		"Actual kWh":              "kWh reales",
+		"At":                      "Momento",
+		"Average":                 "Media",
+		"Averages":                "Medias",
+		"Median":                  "Mediana",
+		"Average paid":            "Pagado (media)",
+		"Avg temp (°C)":           "Temp. media (°C)",
+		"Billing month":           "Mes de facturación",
+		"Cheapest hours":          "Horas más baratas",
+		"Consumption":             "Consumo",
+		"Cost":                    "Coste",
+		"Daily":                   "Diario",
+		"Date":                    "Fecha",
+		"Day":                     "Día",
+		"Days":                    "Días",
+		"Daylight %":              "Luz diurna %",
+		"Daylight kWh":            "Luz diurna kWh",
+		"Difference":              "Diferencia",
+		"Electricity kWh":         "Electricidad kWh",
+		"End":                     "Fin",
+		"Energy":                  "Energía",
+		"Error %":                 "Error %",
+		"Export":                  "Exportación",
+		"Forecast kWh":            "kWh previstos",
+		"Fossil %":                "% fósil",
+		"Fossil kWh":              "kWh fósiles",
+		"Generation":              "Generación",
+		"Group":                   "Grupo",
+		"Heat kWh":                "Calor kWh",
+		"Hour":                    "Hora",
+		"Hours":                   "Horas",
+		"Imbalance %":             "Desequilibrio %",
+		"Import":                  "Importación",
+		"kg CO₂":                  "kg de CO₂",
+		"Last reset":              "Último reinicio",
+		"Likely cause":            "Causa probable",
+		"Max demand (%d min, kW)": "Demanda máxima (%d min, kW)",
+		"Month":                   "Mes",
+		"Name":                    "Nombre",
+		"Night kWh":               "Noche kWh",
+		"Occurrences":             "Apariciones",
+		"Per day":                 "Por día",
+		"Profile":                 "Perfil",
+		"Rate":                    "Precio",
+		"Saving":                  "Ahorro",
+		"Self-use":                "Autoconsumo",
+		"Share":                   "Proporción",
+		"Shifted":                 "Desplazado",
+		"Standing charges":        "Término fijo",
+		"Start":                   "Inicio",
+		"Statistic ID":            "ID de estadística",
+		"Sunrise":                 "Amanecer",
+		"Sunset":                  "Atardecer",
+		"Tariff":                  "Tarifa",
+		"Tax":                     "Impuestos",
+		"Total":                   "Total",
+		"Typical %s (kWh)":        "Típico %s (kWh)",
+		"Typical draw":            "Potencia típica",
+		"Typical duration":        "Duración típica",
+		"Unit":                    "Unidad",
+		"You (kWh)":               "Usted (kWh)",
+
+		// Values.
+		"%s (so far)":             "%s (hasta ahora)",
+		"Home Assistant downtime": "Home Assistant caído",
+		"high":                    "alto",
+		"low":                     "bajo",
+		"medium":                  "medio",
+		"power outage":            "corte de luz",
+		"unknown":                 "desconocida",
+
+		// Summaries.
+		"%d gaps in the data look like Home Assistant was down - see -o gaps.": "%d huecos en los datos parecen deberse a que Home Assistant estaba caído - consulte -o gaps.",
+		"15:04 on Monday 2 January":             "2/1 a las 15:04",
+		"COP over the period: %s":               "COP en el periodo: %s",
+		"Insights:":                             "Observaciones:",
+		"It would have added %.1f kg of CO₂.":   "Habría añadido %.1f kg de CO₂.",
+		"It would have avoided %.1f kg of CO₂.": "Habría evitado %.1f kg de CO₂.",
+		"Monday 2 January":                      "2/1",
+		"Moving %.1f kWh a day from %02d:00 to %02d:00 would have cost %s more over %d days.":  "Trasladar %.1f kWh al día de las %02d:00 a las %02d:00 habría costado %s más en %d días.",
+		"Moving %.1f kWh a day from %02d:00 to %02d:00 would have saved %s over %d days.":      "Trasladar %.1f kWh al día de las %02d:00 a las %02d:00 habría ahorrado %s en %d días.",
+		"Moving %.1f kWh a day into the cheapest %d hours would have saved %s over %d days.":   "Trasladar %.1f kWh al día a las %d horas más baratas habría ahorrado %s en %d días.",
+		"No forecasts have been kept for these days yet. Run -o solar daily to build them up.": "Todavía no se han guardado previsiones para estos días. Ejecute -o solar a diario para ir acumulándolas.",
+		"No gaps in usage found.":                                                                     "No se han encontrado huecos en el consumo.",
+		"Nothing was used %s, which looks like a power cut.":                                          "No se consumió nada %s, lo que parece un corte de luz.",
+		"Nothing was used in %d separate windows, which look like power cuts - see -o gaps.":          "No se consumió nada en %d intervalos distintos, que parecen cortes de luz - consulte -o gaps.",
+		"Now %s   min %s   avg %s   max %s   (last %s)":                                               "Ahora %s   mín. %s   media %s   máx. %s   (últimos %s)",
+		"Over %d days, the forecast was out by %.0f%% a day on average, and %.0f%% too high overall.": "En %d días, la previsión se desvió de media un %.0f%% al día, y un %.0f%% por encima en total.",
+		"Over %d days, the forecast was out by %.0f%% a day on average, and %.0f%% too low overall.":  "En %d días, la previsión se desvió de media un %.0f%% al día, y un %.0f%% por debajo en total.",
+		"The cheapest hours were most often %02d:00-%02d:00 (%d of %d days).":                         "Las horas más baratas fueron casi siempre de %02d:00 a %02d:00 (%d de %d días).",
+		"You use %.0f%% less on weekends than on weekdays.":                                           "Consume un %.0f%% menos los fines de semana que entre semana.",
+		"You use %.0f%% more on weekends than on weekdays.":                                           "Consume un %.0f%% más los fines de semana que entre semana.",
+		"You use around %.0f kWh a year, against %.0f kWh for a typical %s-consumption household.":    "Consume unos %.0f kWh al año, frente a %.0f kWh de un hogar típico de consumo %s.",
+		"Your %02d:00-%02d:00 usage is %.0f%% above your hourly average.":                             "Su consumo de %02d:00 a %02d:00 está un %.0f%% por encima de su media por hora.",
+		"Your baseload fell %.0f%% over the last %d days compared with the %d days before.":           "Su consumo base bajó un %.0f%% en los últimos %d días respecto a los %d días anteriores.",
+		"Your baseload rose %.0f%% over the last %d days compared with the %d days before.":           "Su consumo base subió un %.0f%% en los últimos %d días respecto a los %d días anteriores.",
+		"Your highest day was %s at %.1f kWh, %.1fx your daily average.":                              "Su día más alto fue el %s con %.1f kWh, %.1f veces su media diaria.",
+		"between %s and %s on %s":                                                                     "entre las %s y las %s del %s",
+		"from %s until %s":                                                                            "desde el %s hasta el %s",
+
+		// Explanations.
+		"Nothing to explain - no consumption was read.": "Nada que explicar - no se leyó ningún consumo.",
+		"How these figures were worked out:":            "Cómo se calcularon estas cifras:",
+		"days:":                                         "días:",
+		"time zone:":                                    "zona horaria:",
+		"source:":                                       "origen:",
+		"cache:":                                        "caché:",
+		"padded:":                                       "rellenado:",
+		"corrected:":                                    "corregido:",
+		"excluded:":                                     "excluido:",
+		"averages:":                                     "medias:",
+		"queries:":                                      "consultas:",
+		"hours":                                         "horas",
+		"hourly":                                        "por horas",
+		"half hours":                                    "medias horas",
+		"half-hourly":                                   "por medias horas",
+		"5-minute periods":                              "periodos de 5 minutos",
+		"5-minute":                                      "por 5 minutos",
+		"none":                                          "ninguno",
+		"%d, from %s to %s":                             "%d, del %s al %s",
+		"days run from midnight in %s; local times are shown in %s (%s)":                  "los días empiezan a medianoche en %s; las horas locales se muestran en %s (%s)",
+		"%s, %s, the %q statistic, %s":                                                    "%s, %s, la estadística %q, %s",
+		"%d days from the cache, %d fetched":                                              "%d días de la caché, %d descargados",
+		"none - every one of the %s had readings":                                         "ninguno - todas las %s tenían lecturas",
+		"%d %s without readings count as zero: %s":                                        "%d %s sin lecturas cuentan como cero: %s",
+		"%d impossible values replaced with the median of the same period on other days:": "%d valores imposibles sustituidos por la mediana del mismo periodo en otros días:",
+		"the mean of each of the %s over the days included":                               "la media de cada una de las %s en los días incluidos",
+		"the median of each of the %s over the days included":                             "la mediana de cada una de las %s en los días incluidos",
+		"percentile %s of each of the %s over the days included":                          "el percentil %s de cada una de las %s en los días incluidos",
+		"none - --period %s only shows the total of each":                                 "ninguna - --period %s solo muestra el total de cada uno",
+		"not fetched before the run was stopped":                                          "no obtenido antes de que se detuviera la ejecución",
+		"not fetched before the fetch failed":                                             "no obtenido antes de que fallara la descarga",
+		"no 5-minute data":                                                                "sin datos de 5 minutos",
+		"none sent to Home Assistant":                                                     "ninguna enviada a Home Assistant",
+		"queries sent to Home Assistant:":                                                 "consultas enviadas a Home Assistant:",
+
+		// Prompts.
+		"Keep the access token in the OS keyring?":        "¿Guardar el token de acceso en el llavero del sistema operativo?",
+		"Encrypt the access token with a passphrase?":     "¿Cifrar el token de acceso con una frase de contraseña?",
+		"Energy statistics in Home Assistant:":            "Estadísticas de energía en Home Assistant:",
+		"Home Assistant Long-Lived Access Token":          "Token de acceso de larga duración de Home Assistant",
+		"Home Assistant URL - e.g. http://localhost:8123": "URL de Home Assistant - p. ej. http://localhost:8123",
+		"New passphrase": "Nueva frase de contraseña",
+		"No config file found. Let's set one up.": "No se ha encontrado ningún archivo de configuración. Vamos a crear uno.",
+		"Passphrase":                                         "Frase de contraseña",
+		"Passphrase for the access token":                    "Frase de contraseña del token de acceso",
+		"Power sensor entity ID - e.g. sensor.power":         "ID de entidad del sensor de energía - p. ej. sensor.power",
+		"Sensor - a number from the list, or a statistic ID": "Sensor - un número de la lista o un ID de estadística",
+		"Repeat passphrase":                                  "Repita la frase de contraseña",
+	},
+	"fr": {
+		// Table headers.
+		"Actual":                  "Réel",
+		"Actual kWh":              "kWh réels",
+		"At":                      "Moment",
+		"Average":                 "Moyenne",
+		"Averages":                "Moyennes",
+		"Median":                  "Médiane",
+		"Average paid":            "Payé (moyenne)",
+		"Avg temp (°C)":           "Temp. moy. (°C)",
+		"Billing month":           "Mois de facturation",
+		"Cheapest hours":          "Heures les moins chères",
+		"Consumption":             "Consommation",
+		"Cost":                    "Coût",
+		"Daily":                   "Par jour",
+		"Date":                    "Date",
+		"Day":                     "Jour",
+		"Days":                    "Jours",
+		"Daylight %":              "Jour %",
+		"Daylight kWh":            "Jour kWh",
+		"Difference":              "Écart",
+		"Electricity kWh":         "Électricité kWh",
+		"End":                     "Fin",
+		"Energy":                  "Énergie",
+		"Error %":                 "Erreur %",
+		"Export":                  "Injection",
+		"Forecast kWh":            "kWh prévus",
+		"Fossil %":                "% fossile",
+		"Fossil kWh":              "kWh fossiles",
+		"Generation":              "Production",
+		"Group":                   "Groupe",
+		"Heat kWh":                "Chaleur kWh",
+		"Hour":                    "Heure",
+		"Hours":                   "Heures",
+		"Imbalance %":             "Déséquilibre %",
+		"Import":                  "Soutirage",
+		"kg CO₂":                  "kg de CO₂",
+		"Last reset":              "Dernière remise à zéro",
+		"Likely cause":            "Cause probable",
+		"Max demand (%d min, kW)": "Puissance max. (%d min, kW)",
+		"Month":                   "Mois",
+		"Name":                    "Nom",
+		"Night kWh":               "Nuit kWh",
+		"Occurrences":             "Occurrences",
+		"Per day":                 "Par jour",
+		"Profile":                 "Profil",
+		"Rate":                    "Prix",
+		"Saving":                  "Économie",
+		"Self-use":                "Autoconsommation",
+		"Share":                   "Part",
+		"Shifted":                 "Décalé",
+		"Standing charges":        "Abonnement",
+		"Start":                   "Début",
+		"Statistic ID":            "ID de statistique",
+		"Sunrise":                 "Lever du soleil",
+		"Sunset":                  "Coucher du soleil",
+		"Tariff":                  "Tarif",
+		"Tax":                     "Taxes",
+		"Total":                   "Total",
+		"Typical %s (kWh)":        "Typique %s (kWh)",
+		"Typical draw":            "Puissance typique",
+		"Typical duration":        "Durée typique",
+		"Unit":                    "Unité",
+		"You (kWh)":               "Vous (kWh)",
+
+		// Values.
+		"%s (so far)":             "%s (jusqu'ici)",
+		"Home Assistant downtime": "Home Assistant indisponible",
+		"high":                    "élevée",
+		"low":                     "faible",
+		"medium":                  "moyenne",
+		"power outage":            "coupure de courant",
+		"unknown":                 "inconnue",
+
+		// Summaries.
+		"%d gaps in the data look like Home Assistant was down - see -o gaps.": "%d trous dans les données semblent dus à une indisponibilité de Home Assistant - voir -o gaps.",
+		"15:04 on Monday 2 January":             "2/1 à 15:04",
+		"COP over the period: %s":               "COP sur la période : %s",
+		"Insights:":                             "Observations :",
+		"It would have added %.1f kg of CO₂.":   "Cela aurait ajouté %.1f kg de CO₂.",
+		"It would have avoided %.1f kg of CO₂.": "Cela aurait évité %.1f kg de CO₂.",
+		"Monday 2 January":                      "2/1",
+		"Moving %.1f kWh a day from %02d:00 to %02d:00 would have cost %s more over %d days.":  "Déplacer %.1f kWh par jour de %02d:00 à %02d:00 aurait coûté %s de plus sur %d jours.",
+		"Moving %.1f kWh a day from %02d:00 to %02d:00 would have saved %s over %d days.":      "Déplacer %.1f kWh par jour de %02d:00 à %02d:00 aurait économisé %s sur %d jours.",
+		"Moving %.1f kWh a day into the cheapest %d hours would have saved %s over %d days.":   "Déplacer %.1f kWh par jour vers les %d heures les moins chères aurait économisé %s sur %d jours.",
+		"No forecasts have been kept for these days yet. Run -o solar daily to build them up.": "Aucune prévision n'a encore été conservée pour ces jours. Lancez -o solar chaque jour pour les accumuler.",
+		"No gaps in usage found.":                                                                     "Aucun trou dans la consommation.",
+		"Nothing was used %s, which looks like a power cut.":                                          "Rien n'a été consommé %s, ce qui ressemble à une coupure de courant.",
+		"Nothing was used in %d separate windows, which look like power cuts - see -o gaps.":          "Rien n'a été consommé pendant %d périodes distinctes, qui ressemblent à des coupures de courant - voir -o gaps.",
+		"Now %s   min %s   avg %s   max %s   (last %s)":                                               "Maintenant %s   min %s   moy. %s   max %s   (dernières %s)",
+		"Over %d days, the forecast was out by %.0f%% a day on average, and %.0f%% too high overall.": "Sur %d jours, la prévision s'est écartée de %.0f%% par jour en moyenne, et a été trop élevée de %.0f%% au total.",
+		"Over %d days, the forecast was out by %.0f%% a day on average, and %.0f%% too low overall.":  "Sur %d jours, la prévision s'est écartée de %.0f%% par jour en moyenne, et a été trop basse de %.0f%% au total.",
+		"The cheapest hours were most often %02d:00-%02d:00 (%d of %d days).":                         "Les heures les moins chères étaient le plus souvent %02d:00-%02d:00 (%d jours sur %d).",
+		"You use %.0f%% less on weekends than on weekdays.":                                           "Vous consommez %.0f%% de moins le week-end qu'en semaine.",
+		"You use %.0f%% more on weekends than on weekdays.":                                           "Vous consommez %.0f%% de plus le week-end qu'en semaine.",
+		"You use around %.0f kWh a year, against %.0f kWh for a typical %s-consumption household.":    "Vous consommez environ %.0f kWh par an, contre %.0f kWh pour un foyer type à consommation %s.",
+		"Your %02d:00-%02d:00 usage is %.0f%% above your hourly average.":                             "Votre consommation de %02d:00 à %02d:00 est %.0f%% au-dessus de votre moyenne horaire.",
+		"Your baseload fell %.0f%% over the last %d days compared with the %d days before.":           "Votre consommation de base a baissé de %.0f%% sur les %d derniers jours par rapport aux %d jours précédents.",
+		"Your baseload rose %.0f%% over the last %d days compared with the %d days before.":           "Votre consommation de base a augmenté de %.0f%% sur les %d derniers jours par rapport aux %d jours précédents.",
+		"Your highest day was %s at %.1f kWh, %.1fx your daily average.":                              "Votre jour le plus élevé a été le %s avec %.1f kWh, soit %.1f fois votre moyenne quotidienne.",
+		"between %s and %s on %s":                                                                     "entre %s et %s le %s",
+		"from %s until %s":                                                                            "du %s au %s",
+
+		// Explanations.
+		"Nothing to explain - no consumption was read.": "Rien à expliquer - aucune consommation n'a été lue.",
+		"How these figures were worked out:":            "Comment ces chiffres ont été obtenus :",
+		"days:":                                         "jours :",
+		"time zone:":                                    "fuseau horaire :",
+		"source:":                                       "source :",
+		"cache:":                                        "cache :",
+		"padded:":                                       "complété :",
+		"corrected:":                                    "corrigé :",
+		"excluded:":                                     "exclu :",
+		"averages:":                                     "moyennes :",
+		"queries:":                                      "requêtes :",
+		"hours":                                         "heures",
+		"hourly":                                        "horaire",
+		"half hours":                                    "demi-heures",
+		"half-hourly":                                   "par demi-heure",
+		"5-minute periods":                              "périodes de 5 minutes",
+		"5-minute":                                      "par 5 minutes",
+		"none":                                          "aucun",
+		"%d, from %s to %s":                             "%d, du %s au %s",
+		"days run from midnight in %s; local times are shown in %s (%s)":                  "les jours commencent à minuit en %s ; les heures locales sont affichées en %s (%s)",
+		"%s, %s, the %q statistic, %s":                                                    "%s, %s, la statistique %q, %s",
+		"%d days from the cache, %d fetched":                                              "%d jours depuis le cache, %d récupérés",
+		"none - every one of the %s had readings":                                         "aucun - toutes les %s avaient des relevés",
+		"%d %s without readings count as zero: %s":                                        "%d %s sans relevés comptent comme zéro : %s",
+		"%d impossible values replaced with the median of the same period on other days:": "%d valeurs impossibles remplacées par la médiane de la même période les autres jours :",
+		"the mean of each of the %s over the days included":                               "la moyenne de chacune des %s sur les jours inclus",
+		"the median of each of the %s over the days included":                             "la médiane de chacune des %s sur les jours inclus",
+		"percentile %s of each of the %s over the days included":                          "le %se centile de chacune des %s sur les jours inclus",
+		"none - --period %s only shows the total of each":                                 "aucune - --period %s n'affiche que le total de chacun",
+		"not fetched before the run was stopped":                                          "non récupéré avant l'arrêt de l'exécution",
+		"not fetched before the fetch failed":                                             "non récupéré avant l'échec de la récupération",
+		"no 5-minute data":                                                                "aucune donnée sur 5 minutes",
+		"none sent to Home Assistant":                                                     "aucune envoyée à Home Assistant",
+		"queries sent to Home Assistant:":                                                 "requêtes envoyées à Home Assistant :",
+
+		// Prompts.
+		"Keep the access token in the OS keyring?":        "Enregistrer le jeton d'accès dans le trousseau du système d'exploitation ?",
+		"Encrypt the access token with a passphrase?":     "Chiffrer le jeton d'accès avec une phrase secrète ?",
+		"Energy statistics in Home Assistant:":            "Statistiques d'énergie dans Home Assistant :",
+		"Home Assistant Long-Lived Access Token":          "Jeton d'accès longue durée de Home Assistant",
+		"Home Assistant URL - e.g. http://localhost:8123": "URL de Home Assistant - par ex. http://localhost:8123",
+		"New passphrase": "Nouvelle phrase secrète",
+		"No config file found. Let's set one up.": "Aucun fichier de configuration trouvé. Créons-en un.",
+		"Passphrase":                                         "Phrase secrète",
+		"Passphrase for the access token":                    "Phrase secrète du jeton d'accès",
+		"Power sensor entity ID - e.g. sensor.power":         "ID d'entité du capteur d'énergie - par ex. sensor.power",
+		"Sensor - a number from the list, or a statistic ID": "Capteur - un numéro de la liste ou un ID de statistique",
+		"Repeat passphrase":                                  "Répétez la phrase secrète",
+	},
+}
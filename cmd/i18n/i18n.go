@@ -0,0 +1,82 @@
+// Package i18n translates the text people read, such as table headers, summaries and prompts.
+// Messages are looked up by their English text, so anything without a translation is shown in
+// English. Log messages and errors are always in English, so they can be searched for.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// lang is the language in use. It is set once at startup, before anything is printed.
+var lang = "en"
+
+// SetLanguage selects the language to use, given as a code such as "de" or a locale such as
+// "de_DE.UTF-8". English is always available.
+func SetLanguage(code string) error {
+	l := normalize(code)
+	if _, ok := catalogs[l]; !ok && l != "en" {
+		return fmt.Errorf("unsupported language %q - use one of %s", code, strings.Join(Languages(), ", "))
+	}
+	lang = l
+	return nil
+}
+
+// Language returns the language in use.
+func Language() string {
+	return lang
+}
+
+// Languages returns the codes of the supported languages.
+func Languages() []string {
+	langs := []string{"en"}
+	for l := range catalogs {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Detect returns the language of the user's locale, from the LC_ALL, LC_MESSAGES and LANG
+// environment variables, or "en" if none of them is set to a supported language.
+func Detect() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		v := os.Getenv(name)
+		if v == "" {
+			continue
+		}
+		// The first variable that is set wins, even if it isn't supported.
+		if l := normalize(v); l == "en" || catalogs[l] != nil {
+			return l
+		}
+		return "en"
+	}
+	return "en"
+}
+
+// normalize turns a locale such as "de_DE.UTF-8" into a language code such as "de".
+func normalize(locale string) string {
+	l := strings.ToLower(locale)
+	if i := strings.IndexAny(l, "_-.@"); i >= 0 {
+		l = l[:i]
+	}
+	if l == "" || l == "c" || l == "posix" {
+		return "en"
+	}
+	return l
+}
+
+// T returns the message in the language in use. With args, the message is a format string, and
+// the arguments are formatted into the translation. Translations that need the arguments in a
+// different order use explicit indexes, such as %[2]d.
+func T(msg string, args ...interface{}) string {
+	if translated, ok := catalogs[lang][msg]; ok {
+		msg = translated
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
@@ -0,0 +1,72 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestT(t *testing.T) {
+	defer func() { lang = "en" }()
+
+	assert.Equal(t, T("Date"), "Date")
+	assert.Equal(t, T("You use %.0f%% more on weekends than on weekdays.", 20.0), "You use 20% more on weekends than on weekdays.")
+
+	assert.NilError(t, SetLanguage("de_DE.UTF-8"))
+	assert.Equal(t, Language(), "de")
+	assert.Equal(t, T("Date"), "Datum")
+	assert.Equal(t, T("Your baseload rose %.0f%% over the last %d days compared with the %d days before.", 15.0, 7, 7),
+		"Ihre Grundlast ist in den letzten 7 Tagen um 15% gestiegen, verglichen mit den 7 Tagen davor.")
+	// Anything without a translation is shown in English.
+	assert.Equal(t, T("Not translated %d", 1), "Not translated 1")
+
+	assert.ErrorContains(t, SetLanguage("xx"), `unsupported language "xx" - use one of de, en, es, fr`)
+	assert.Equal(t, Language(), "de")
+}
+
+func TestDetect(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "fr_FR.UTF-8")
+	assert.Equal(t, Detect(), "fr")
+
+	t.Setenv("LC_MESSAGES", "C")
+	assert.Equal(t, Detect(), "en")
+
+	t.Setenv("LC_ALL", "es_ES")
+	assert.Equal(t, Detect(), "es")
+
+	t.Setenv("LC_ALL", "ja_JP.UTF-8")
+	assert.Equal(t, Detect(), "en")
+}
+
+// Every translation must take the same arguments as the English message.
+func TestCatalogs(t *testing.T) {
+	defer func() { lang = "en" }()
+	for l, catalog := range catalogs {
+		lang = l
+		for msg := range catalog {
+			assert.Equal(t, countVerbs(T(msg)), countVerbs(msg), "%s: %q", l, msg)
+		}
+	}
+}
+
+// countVerbs counts the formatting verbs in a message, ignoring escaped percent signs and headers
+// such as "% fossile", which are never formatted.
+func countVerbs(msg string) int {
+	var n int
+	for i := 0; i < len(msg); i++ {
+		if msg[i] != '%' {
+			continue
+		}
+		if i+1 < len(msg) && msg[i+1] == '%' {
+			i++
+			continue
+		}
+		if i+1 < len(msg) && strings.ContainsRune("[.0123456789+-#dfsv", rune(msg[i+1])) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/poolski/powertracker/cmd/client"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var liveWindow time.Duration
+
+var liveCmd = &cobra.Command{
+	Use:   "live [sensor]",
+	Short: "Show the power being drawn right now, as it changes",
+	Long: `
+	Shows the power reported by a power sensor, in W or kW, as it changes, along with the least, average and most drawn over the last few minutes.
+	The sensor is the entity ID given, or power_sensor_id from the config. Press Ctrl+C to stop.`,
+	Example: "  powertracker live sensor.house_power --window 15m",
+	Args:    cobra.MaximumNArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		entityID := viper.GetString("power_sensor_id")
+		if len(args) > 0 {
+			entityID = args[0]
+		}
+		if entityID == "" {
+			log.Fatal().Msg("give the entity ID of a power sensor, or set power_sensor_id")
+		}
+
+		c := client.New(clientConfig())
+		stopOnSignal(c.Stop)
+		if err := c.Connect(); err != nil {
+			log.Fatal().Msgf("connecting to websocket: %s", err.Error())
+		}
+		defer c.Close()
+		if err := c.Live(os.Stdout, entityID, liveWindow, isTerminal(os.Stdout)); err != nil {
+			log.Error().Msgf("showing live power: %s", err.Error())
+		}
+	},
+}
+
+// isTerminal reports whether f is a terminal rather than a file or a pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func init() {
+	liveCmd.Flags().DurationVar(&liveWindow, "window", 5*time.Minute, "how far back the least, average and most drawn go")
+	rootCmd.AddCommand(liveCmd)
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// legacyKeys are top-level config keys that have been renamed, or are common misspellings of one,
+// by the key that replaces them. They are matched in lower case.
+var legacyKeys = map[string]string{
+	"sensor":    "sensor_id",
+	"sensorid":  "sensor_id",
+	"sensor-id": "sensor_id",
+}
+
+// rename is a legacy key renamed by migrateConfig.
+type rename struct {
+	Line     int
+	From, To string
+}
+
+// migrateConfig renames legacy keys at the top of a YAML (or JSON) config to the keys that replace
+// them. Only the keys are changed, so the rest of the config, comments included, is left as it
+// was. A legacy key is left alone if its replacement is also set, for the schema check to report.
+// It returns the migrated config and the keys that were renamed.
+func migrateConfig(data []byte) ([]byte, []rename, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return data, nil, nil
+	}
+	root := doc.Content[0]
+	set := make(map[string]bool)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		set[strings.ToLower(root.Content[i].Value)] = true
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+	var changes []rename
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key := root.Content[i]
+		replacement, ok := legacyKeys[strings.ToLower(key.Value)]
+		if !ok || set[replacement] {
+			continue
+		}
+		if !renameKey(lines, key, replacement) {
+			continue
+		}
+		set[replacement] = true
+		changes = append(changes, rename{Line: key.Line, From: key.Value, To: replacement})
+	}
+	if len(changes) == 0 {
+		return data, nil, nil
+	}
+	return bytes.Join(lines, []byte("\n")), changes, nil
+}
+
+// renameKey replaces the key where it appears in the lines, inside its quotes if it has them. It
+// reports false if the key isn't where the parser said it was, as with keys spread over lines.
+func renameKey(lines [][]byte, key *yaml.Node, name string) bool {
+	if key.Line < 1 || key.Line > len(lines) {
+		return false
+	}
+	line := lines[key.Line-1]
+	col := key.Column - 1
+	if col < 0 || col >= len(line) {
+		return false
+	}
+	if line[col] == '"' || line[col] == '\'' {
+		col++
+	}
+	if !bytes.HasPrefix(line[col:], []byte(key.Value)) {
+		return false
+	}
+	renamed := append([]byte(nil), line[:col]...)
+	renamed = append(renamed, name...)
+	lines[key.Line-1] = append(renamed, line[col+len(key.Value):]...)
+	return true
+}
+
+// migrateConfigFile migrates the config file at path, rewriting it if anything was renamed, and
+// returns the migrated config. If the file can't be rewritten, the migrated config is still used
+// for this run.
+func migrateConfigFile(path string, data []byte) []byte {
+	migrated, changes, err := migrateConfig(data)
+	if err != nil || len(changes) == 0 {
+		// Configs that don't parse are reported by the schema check.
+		return data
+	}
+	mode := os.FileMode(0o600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.WriteFile(path, migrated, mode); err != nil {
+		log.Warn().Msgf("updating config file: %s", err.Error())
+		warnRenames(path, changes)
+		return migrated
+	}
+	for _, r := range changes {
+		log.Info().Msgf("%s:%d: renamed '%s' to '%s'", path, r.Line, r.From, r.To)
+	}
+	return migrated
+}
+
+// migrateConfigData migrates a config that can't be rewritten, such as one read from stdin or a
+// URL, warning about each legacy key so it can be renamed where the config comes from.
+func migrateConfigData(name string, data []byte) []byte {
+	migrated, changes, err := migrateConfig(data)
+	if err != nil || len(changes) == 0 {
+		return data
+	}
+	warnRenames(name, changes)
+	return migrated
+}
+
+func warnRenames(name string, changes []rename) {
+	for _, r := range changes {
+		log.Warn().Msgf("%s:%d: '%s' is read as '%s' - rename it in the config", name, r.Line, r.From, r.To)
+	}
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestMigrateConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    string
+		renamed []rename
+	}{
+		{
+			name:    "legacy key",
+			config:  "url: http://localhost:8123\n# The meter.\nsensor: sensor.energy # kWh\n",
+			want:    "url: http://localhost:8123\n# The meter.\nsensor_id: sensor.energy # kWh\n",
+			renamed: []rename{{Line: 3, From: "sensor", To: "sensor_id"}},
+		},
+		{
+			name:    "misspelling in another case",
+			config:  "SensorID:\n  - sensor.house\n  - sensor.garage\n",
+			want:    "sensor_id:\n  - sensor.house\n  - sensor.garage\n",
+			renamed: []rename{{Line: 1, From: "SensorID", To: "sensor_id"}},
+		},
+		{
+			name:    "JSON",
+			config:  `{"url": "http://localhost:8123", "sensor-id": "sensor.energy"}`,
+			want:    `{"url": "http://localhost:8123", "sensor_id": "sensor.energy"}`,
+			renamed: []rename{{Line: 1, From: "sensor-id", To: "sensor_id"}},
+		},
+		{
+			name:   "already migrated",
+			config: "sensor: sensor.old\nsensor_id: sensor.energy\n",
+			want:   "sensor: sensor.old\nsensor_id: sensor.energy\n",
+		},
+		{
+			name:   "nested keys are left alone",
+			config: "heat_pump:\n  sensor: sensor.heat\n",
+			want:   "heat_pump:\n  sensor: sensor.heat\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, renamed, err := migrateConfig([]byte(tt.config))
+			assert.NilError(t, err)
+			assert.Equal(t, string(got), tt.want)
+			assert.DeepEqual(t, renamed, tt.renamed)
+		})
+	}
+}
+
+func TestMigrateConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NilError(t, os.WriteFile(path, []byte("sensor: sensor.energy\n"), 0o640))
+
+	got := migrateConfigFile(path, []byte("sensor: sensor.energy\n"))
+	assert.Equal(t, string(got), "sensor_id: sensor.energy\n")
+	data, err := os.ReadFile(path)
+	assert.NilError(t, err)
+	assert.Equal(t, string(data), "sensor_id: sensor.energy\n")
+	info, err := os.Stat(path)
+	assert.NilError(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0o640))
+}
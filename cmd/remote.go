@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// configHeader is an extra header, such as "Authorization: Bearer <token>", sent when fetching
+// a remote config.
+var configHeader string
+
+// remoteConfigTimeout is how long fetching a remote config may take.
+var remoteConfigTimeout = 30 * time.Second
+
+// remoteConfig reports whether the config is fetched from a URL.
+func remoteConfig() bool {
+	return strings.HasPrefix(cfgFile, "http://") || strings.HasPrefix(cfgFile, "https://")
+}
+
+// readRemoteConfig fetches the config from the URL given with --config, so a fleet of machines
+// can share one centrally-managed config.
+func readRemoteConfig() error {
+	u, err := url.Parse(cfgFile)
+	if err != nil {
+		return fmt.Errorf("parsing URL: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	header := configHeader
+	if header == "" {
+		header = os.Getenv("POWERTRACKER_CONFIG_HEADER")
+	}
+	if header != "" {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok {
+			return fmt.Errorf("config header must be in the form \"Name: value\"")
+		}
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	client := http.Client{Timeout: remoteConfigTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetching config: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response (%d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	configType := remoteConfigType(u, resp.Header.Get("Content-Type"))
+	if configType != "toml" {
+		body = migrateConfigData(cfgFile, body)
+		checkConfig(cfgFile, body)
+	}
+	viper.SetConfigType(configType)
+	return viper.ReadConfig(bytes.NewReader(body))
+}
+
+// remoteConfigType returns the format of a config fetched from u: json, toml or yaml, going by the
+// extension, or by the content type if there isn't one it knows. It defaults to YAML.
+func remoteConfigType(u *url.URL, contentType string) string {
+	switch ext := strings.ToLower(strings.TrimPrefix(path.Ext(u.Path), ".")); ext {
+	case "json", "toml":
+		return ext
+	case "yaml", "yml":
+		return "yaml"
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"):
+		return "json"
+	case mediaType == "application/toml":
+		return "toml"
+	default:
+		return "yaml"
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"gotest.tools/v3/assert"
+)
+
+func TestReadRemoteConfig(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fleet_token" {
+			http.Error(w, "who are you?", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/config.json":
+			// The extension says JSON, whatever the content type.
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte(`{"url": "http://json.example.com:8123", "days": 7}`))
+		case "/config":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			_, _ = w.Write([]byte(`{"url": "http://typed.example.com:8123", "days": 14}`))
+		case "/powertracker":
+			_, _ = w.Write([]byte("url: http://yaml.example.com:8123\ndays: 21\n"))
+		case "/slow.yaml":
+			time.Sleep(200 * time.Millisecond)
+			_, _ = w.Write([]byte("days: 1\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+
+	defer func(file, header string, timeout time.Duration) {
+		cfgFile, configHeader, remoteConfigTimeout = file, header, timeout
+		viper.Reset()
+	}(cfgFile, configHeader, remoteConfigTimeout)
+	t.Setenv("POWERTRACKER_CONFIG_HEADER", "Authorization: Bearer fleet_token")
+	configHeader = ""
+
+	for _, tt := range []struct {
+		path string
+		url  string
+		days int
+	}{
+		{path: "/config.json", url: "http://json.example.com:8123", days: 7},
+		{path: "/config", url: "http://typed.example.com:8123", days: 14},
+		{path: "/powertracker", url: "http://yaml.example.com:8123", days: 21},
+	} {
+		viper.Reset()
+		cfgFile = s.URL + tt.path
+		assert.NilError(t, readRemoteConfig(), tt.path)
+		assert.Equal(t, viper.GetString("url"), tt.url, tt.path)
+		assert.Equal(t, viper.GetInt("days"), tt.days, tt.path)
+	}
+
+	cfgFile = s.URL + "/missing.yaml"
+	assert.ErrorContains(t, readRemoteConfig(), "unexpected response (404): 404 page not found")
+
+	// --config-header takes the place of the environment.
+	configHeader = "Authorization: Bearer someone_else"
+	cfgFile = s.URL + "/config.json"
+	assert.ErrorContains(t, readRemoteConfig(), "unexpected response (401): who are you?")
+	configHeader = "Bearer fleet_token"
+	assert.ErrorContains(t, readRemoteConfig(), "config header must be in the form")
+	configHeader = ""
+
+	remoteConfigTimeout = 50 * time.Millisecond
+	cfgFile = s.URL + "/slow.yaml"
+	assert.ErrorContains(t, readRemoteConfig(), "Client.Timeout exceeded")
+}
@@ -1,25 +1,62 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Songmu/prompter"
 	"github.com/poolski/powertracker/cmd/client"
+	"github.com/poolski/powertracker/cmd/i18n"
+	"github.com/poolski/powertracker/cmd/secret"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	cfgFile string
+	cfgFile          string
+	defaultConfigDir string
 
-	days     int
-	output   string
-	csvFile  string
-	insecure bool
+	days       int
+	start      string
+	end        string
+	output     string
+	csvFile    string
+	insecure   bool
+	caCert     string
+	split      string
+	chart      bool
+	halfHourly bool
+	period     string
+	timeZone   string
+	offline    bool
+	refresh    bool
+	noCache    bool
+	resume     bool
+	noConfig   bool
+	stats      bool
+	record     string
+	replay     string
+	demo       bool
+	lang       string
+	block      time.Duration
+	statistics []string
+	clipboard  bool
+	explain    bool
+	sensors    []string
+	cost       bool
+	addon      bool
+	today      bool
+	profile    string
 )
 
 var rootCmd = &cobra.Command{
@@ -32,19 +69,145 @@ var rootCmd = &cobra.Command{
 	It also saves the data to a CSV file in the current directory.`,
 
 	Run: func(cmd *cobra.Command, args []string) {
-		c := client.New(client.Config{
-			Days:     days,
-			Output:   output,
-			FilePath: csvFile,
-			Insecure: insecure,
-		})
+		c := client.New(clientConfig())
+		received := stopOnSignal(c.Stop)
 		if err := c.Connect(); err != nil {
+			if errors.Is(err, client.ErrInterrupted) {
+				os.Exit(exitCode(<-received))
+			}
 			log.Fatal().Msgf("connecting to websocket: %s", err.Error())
 		}
 		c.ComputePowerStats()
+		if err := c.Close(); err != nil {
+			log.Error().Msgf("closing connection: %s", err.Error())
+		}
+		if stats {
+			c.Stats().Print(os.Stderr)
+		}
+		if explain {
+			c.Explain(os.Stderr)
+		}
+		if c.Interrupted() {
+			os.Exit(exitCode(<-received))
+		}
 	},
 }
 
+// clientConfig builds the client configuration from the command line flags.
+func clientConfig() client.Config {
+	from, until, err := dateRange(start, end)
+	if err != nil {
+		log.Fatal().Msg(err.Error())
+	}
+	return client.Config{
+		Days:         days,
+		Start:        from,
+		End:          until,
+		TimeZone:     timeZoneName(),
+		Output:       output,
+		FilePath:     csvFile,
+		Insecure:     insecure,
+		CAFile:       caFile(),
+		ClientCert:   viper.GetString("client_cert"),
+		ClientKey:    viper.GetString("client_key"),
+		Split:        split,
+		Block:        block,
+		IncludeToday: today,
+		Statistics:   statistics,
+		Clipboard:    clipboard,
+		Cost:         cost,
+		Explain:      explain,
+		Chart:        chart,
+		HalfHourly:   halfHourly,
+		Period:       period,
+		Offline:      offline,
+		Refresh:      refresh,
+		Resume:       resume,
+		CacheFile:    cacheFile(),
+		GlowDir:      stateDir(),
+		Record:       record,
+		Replay:       replay,
+		Demo:         demo,
+	}
+}
+
+// timeZoneName returns the time zone days run from midnight in, from --timezone or else timezone.
+func timeZoneName() string {
+	if timeZone != "" {
+		return timeZone
+	}
+	return viper.GetString("timezone")
+}
+
+// caFile returns the CA certificate to trust for Home Assistant, from --ca-cert or else ca_file.
+func caFile() string {
+	if caCert != "" {
+		return caCert
+	}
+	return viper.GetString("ca_file")
+}
+
+// dateRange parses the --start and --end flags, which are dates in the form 2006-01-02 and
+// include the days they name. Either can be left empty. Whether --end is before today depends on
+// the time zone days run from midnight in, so the client checks that.
+func dateRange(start, end string) (from, until time.Time, err error) {
+	if start != "" {
+		if from, err = time.Parse("2006-01-02", start); err != nil {
+			return from, until, fmt.Errorf("--start must be a date like 2023-12-01: %w", err)
+		}
+	}
+	if end != "" {
+		if until, err = time.Parse("2006-01-02", end); err != nil {
+			return from, until, fmt.Errorf("--end must be a date like 2023-12-31: %w", err)
+		}
+	}
+	if !from.IsZero() && !until.IsZero() && until.Before(from) {
+		return from, until, fmt.Errorf("--end must not be before --start")
+	}
+	return from, until, nil
+}
+
+// cacheFile returns the path of the local cache, next to the config file, or "" if it is disabled.
+// Each profile has a cache of its own, as the same sensor can be on more than one Home Assistant.
+func cacheFile() string {
+	if noCache || stateDir() == "" {
+		return ""
+	}
+	if name := profileName(); name != "" {
+		return filepath.Join(stateDir(), "cache-"+strings.ToLower(name)+".db")
+	}
+	return filepath.Join(stateDir(), "cache.db")
+}
+
+// profileName returns the profile to use, from --profile or else POWERTRACKER_PROFILE, or "" for
+// the top-level settings alone.
+func profileName() string {
+	if profile != "" {
+		return profile
+	}
+	return os.Getenv("POWERTRACKER_PROFILE")
+}
+
+// localConfig reports whether the config comes from a file on disk, rather than from stdin, a URL
+// or the environment.
+func localConfig() bool {
+	return !noConfig && cfgFile != "-" && !remoteConfig()
+}
+
+// stateDir returns the directory the cache and other state are kept in: next to the config file,
+// or in the default config directory when the config is fetched from a URL. It is "" when nothing
+// should be written to disk.
+func stateDir() string {
+	switch {
+	case localConfig():
+		return filepath.Dir(cfgFile)
+	case remoteConfig() && !noConfig:
+		return defaultConfigDir
+	default:
+		return ""
+	}
+}
+
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
@@ -64,28 +227,236 @@ func init() {
 
 		sep := string(filepath.Separator)
 		confDir := home + sep + ".config"
-		rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", confDir+"/powertracker/config.yaml", "config file")
+		defaultConfigDir = confDir + "/powertracker"
+		rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", confDir+"/powertracker/config.yaml", "config file, a URL to fetch it from, or - to read it from stdin")
+		rootCmd.PersistentFlags().StringVar(&configHeader, "config-header", "", "header to send when fetching the config from a URL, e.g. \"Authorization: Bearer <token>\"")
+
+		rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "named profile from profiles in the config to use, e.g. for a second Home Assistant")
+		rootCmd.PersistentFlags().BoolVar(&noConfig, "no-config", false, "don't read or create a config file, and take all settings from the environment")
 
 		rootCmd.PersistentFlags().IntVarP(&days, "days", "d", 30, "number of days to compute power stats for")
-		rootCmd.PersistentFlags().StringVarP(&output, "output", "o", "", "output format (text, table, csv)")
-		rootCmd.PersistentFlags().StringVarP(&csvFile, "csv-file", "f", "results.csv", "the path of the CSV file to write to")
+		rootCmd.PersistentFlags().StringArrayVar(&sensors, "sensor", nil, "statistic ID of a consumption sensor, instead of sensor_id; repeat it to add several sensors together")
+		rootCmd.PersistentFlags().StringVar(&start, "start", "", "first day to compute power stats for, e.g. 2023-12-01, instead of --days")
+		rootCmd.PersistentFlags().StringVar(&timeZone, "timezone", "", "time zone days run from midnight in, e.g. Europe/London, instead of timezone (default Home Assistant's)")
+		rootCmd.PersistentFlags().StringVar(&end, "end", "", "last day to compute power stats for, e.g. 2023-12-31 (default yesterday)")
+		rootCmd.PersistentFlags().BoolVar(&today, "include-today", false, "add a row for today's hours so far to the table, CSV and Markdown outputs, left out of the averages")
+		rootCmd.PersistentFlags().StringVarP(&output, "output", "o", "", "output format (text, table, csv, markdown, xlsx, emoncms, pvoutput, greenbutton, parquet, arrow, graphite, influxdb, bigquery, mqtt, cost, compare, recommendations, benchmark, gaps, balance, daylight, solar, fossil, temperature, cop, appliances, demand, phases)")
+		rootCmd.PersistentFlags().StringVarP(&csvFile, "csv-file", "f", "results.csv", "the path of the file to write to (a .csv extension is swapped to match the output format)")
 		rootCmd.PersistentFlags().BoolVarP(&insecure, "insecure", "i", false, "skip TLS verification")
+		rootCmd.PersistentFlags().StringVar(&caCert, "ca-cert", "", "PEM file of a CA certificate to trust for Home Assistant, instead of ca_file")
+		rootCmd.PersistentFlags().StringVar(&split, "split", "", "report a separate profile for each group of hours (occupancy, season, weekday, dayofweek)")
+		rootCmd.PersistentFlags().DurationVar(&block, "block", 0, "combine the hours of the table, CSV, Markdown and Excel outputs into blocks, e.g. 3h")
+		rootCmd.PersistentFlags().StringSliceVar(&statistics, "stat", nil, "what to report of each hour over the days: mean, median or a percentile such as p95; list several for a row of each (default mean)")
+		rootCmd.PersistentFlags().BoolVar(&halfHourly, "half-hourly", false, "report 48 half-hour settlement periods per day instead of hours")
+		rootCmd.PersistentFlags().StringVar(&period, "period", "hour", "length of each value: 5minute for a column per 5 minutes of each day, hour, or day, week or month for a row per period with its total")
+		rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "answer entirely from the local cache without connecting")
+		rootCmd.PersistentFlags().BoolVar(&refresh, "refresh", false, "fetch every day again, replacing what is in the local cache")
+		rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "don't read from or write to the local cache")
+		rootCmd.PersistentFlags().BoolVar(&resume, "resume", false, "continue an interrupted fetch, using the days it had already cached")
+		rootCmd.PersistentFlags().StringVar(&lang, "lang", "", "language for tables, summaries and prompts (en, de, es, fr; default from the locale)")
+		rootCmd.PersistentFlags().BoolVar(&demo, "demo", false, "use made-up consumption instead of connecting to Home Assistant, to try out the outputs")
+		rootCmd.PersistentFlags().StringVar(&record, "record", "", "record the frames exchanged with Home Assistant to a session file")
+		rootCmd.PersistentFlags().StringVar(&replay, "replay", "", "play back a recorded session file instead of connecting to Home Assistant")
+		rootCmd.PersistentFlags().BoolVar(&stats, "stats", false, "print request, retry and cache statistics to stderr at the end of the run")
+		rootCmd.PersistentFlags().BoolVar(&clipboard, "clipboard", false, "copy the averages to the clipboard in the plain text format, for pasting into solar modelling sites")
+		rootCmd.PersistentFlags().BoolVar(&cost, "cost", false, "add each day's cost on the selected tariff, and the average daily cost, to the table, CSV and Markdown outputs")
+		rootCmd.PersistentFlags().BoolVar(&explain, "explain", false, "print how the figures were worked out to stderr: the days used, padding, corrections, time zone and queries")
+		rootCmd.PersistentFlags().BoolVar(&addon, "addon", false, "run as a Home Assistant add-on, connecting through the Supervisor with SUPERVISOR_TOKEN and reading the add-on options")
+		rootCmd.PersistentFlags().BoolVar(&chart, "chart", false, "add a chart to outputs that support one (temperature, balance)")
 	}
 }
 
 // Setup configuration
 func initConfig() {
+	bindEnv()
+
+	// An add-on's options are kept in /data, which is also where it can keep the cache.
+	if addon && !rootCmd.PersistentFlags().Changed("config") {
+		cfgFile = addonOptions
+	}
+
+	// The language is needed before the config is read, for the first-time setup, and can then be
+	// changed by the config.
+	setLanguage(lang)
+
+	// Without a config file, everything comes from the environment and nothing is written to disk.
+	if !noConfig {
+		readConfigFile()
+	}
+	if err := viper.MergeConfigMap(envSettings()); err != nil {
+		log.Fatal().Msgf("reading settings from the environment: %s", err.Error())
+	}
+	// The config commands work on the file as written, and can add profiles as well as read them,
+	// so none of it needs applying, and the token needn't be decrypted.
+	if runningConfigCmd() {
+		return
+	}
+	if err := applyProfile(profileName()); err != nil {
+		log.Fatal().Msg(err.Error())
+	}
+	if lang == "" && viper.GetString("lang") != "" {
+		setLanguage(viper.GetString("lang"))
+	}
+	if len(sensors) > 0 {
+		viper.Set("sensor_id", sensors)
+	}
+	if demo {
+		viper.SetDefault("sensor_id", "sensor.demo_energy")
+		viper.SetDefault("generation_sensor_id", "sensor.demo_solar")
+	}
+
+	if addon {
+		if err := addonConfig(); err != nil {
+			log.Fatal().Msg(err.Error())
+		}
+	}
+	if err := decryptAPIKey(); err != nil {
+		log.Fatal().Msgf("decrypting api_key: %s", err.Error())
+	}
+	if err := keyringAPIKey(); err != nil {
+		log.Fatal().Msgf("reading api_key: %s", err.Error())
+	}
+}
+
+// bindEnv lets config values be set with environment variables, e.g. POWERTRACKER_PRICES_PROVIDER
+// for prices.provider. The original unprefixed names of the basic settings still work too.
+func bindEnv() {
+	viper.SetEnvPrefix("powertracker")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+	for _, key := range []string{"url", "api_key", "sensor_id"} {
+		_ = viper.BindEnv(key, "POWERTRACKER_"+strings.ToUpper(key), strings.ToUpper(key))
+	}
+}
+
+// envSettings returns the settings given in the environment, laid out as in a config. AutomaticEnv
+// only answers for keys asked for by name, so these are merged into the config for sections read
+// whole, such as currency, to have them too.
+func envSettings() map[string]any {
+	settings := map[string]any{}
+	for _, key := range envKeys(configSchema, "") {
+		value, ok := os.LookupEnv("POWERTRACKER_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_")))
+		if !ok {
+			continue
+		}
+		names := strings.Split(key, ".")
+		section := settings
+		for _, name := range names[:len(names)-1] {
+			sub, ok := section[name].(map[string]any)
+			if !ok {
+				sub = map[string]any{}
+				section[name] = sub
+			}
+			section = sub
+		}
+		section[names[len(names)-1]] = value
+	}
+	return settings
+}
+
+// addonOptions is where the Supervisor writes an add-on's options.
+const addonOptions = "/data/options.json"
+
+// addonConfig points the client at Home Assistant through the Supervisor, which proxies the
+// websocket API for add-ons and gives them a token of their own, so none needs creating.
+func addonConfig() error {
+	token := os.Getenv("SUPERVISOR_TOKEN")
+	if token == "" {
+		return fmt.Errorf("--addon needs SUPERVISOR_TOKEN, which the Supervisor sets for add-ons with homeassistant_api: true")
+	}
+	viper.Set("url", "http://supervisor/core")
+	viper.Set("api_key", token)
+	return nil
+}
+
+// applyProfile puts the settings of the named profile, kept under profiles in the config, in place
+// of the top-level ones. Settings the profile leaves out keep their top-level values, so those
+// shared by every profile, such as tariffs, only need to be given once.
+func applyProfile(name string) error {
+	if name == "" {
+		return nil
+	}
+	key := "profiles." + strings.ToLower(name)
+	if !viper.IsSet(key) {
+		names := make([]string, 0)
+		for n := range viper.GetStringMap("profiles") {
+			names = append(names, n)
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("no profile %q: the config has no profiles", name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("no profile %q in the config, only %s", name, strings.Join(names, ", "))
+	}
+	settings := viper.Sub(key)
+	if settings == nil {
+		return fmt.Errorf("profile %q must be a section of settings", name)
+	}
+	for _, k := range settings.AllKeys() {
+		viper.Set(k, settings.Get(k))
+	}
+	return nil
+}
+
+// setLanguage selects the language for output and prompts, detecting it from the locale if code
+// is empty. An unsupported language falls back to English.
+func setLanguage(code string) {
+	if code == "" {
+		code = i18n.Detect()
+	}
+	if err := i18n.SetLanguage(code); err != nil {
+		log.Warn().Msg(err.Error())
+	}
+}
+
+// readConfigFile reads the config file, running the first-time setup to create it if it doesn't exist.
+// With --config -, the config is read from stdin instead.
+func readConfigFile() {
+	if cfgFile == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatal().Msgf("reading config from stdin: %s", err.Error())
+		}
+		data = migrateConfigData("stdin", data)
+		checkConfig("stdin", data)
+		viper.SetConfigType("yaml")
+		if err := viper.ReadConfig(bytes.NewReader(data)); err != nil {
+			log.Fatal().Msgf("reading config from stdin: %s", err.Error())
+		}
+		return
+	}
+	if remoteConfig() {
+		if err := readRemoteConfig(); err != nil {
+			log.Fatal().Msgf("reading config from %s: %s", cfgFile, err.Error())
+		}
+		return
+	}
+
 	viper.SetConfigFile(cfgFile)
-	viper.AutomaticEnv() // read in environment variables that match
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err != nil {
+	// The demo works without a config, and an add-on is set up through its options, so there's
+	// no need for the first-time setup.
+	if _, err := os.Stat(cfgFile); os.IsNotExist(err) && (demo || addon) {
+		return
+	}
+
+	// If a config file is found, migrate any legacy keys, check it and read it in. Misspelt keys
+	// would otherwise be silently ignored. The migrated config is read even if the file couldn't
+	// be rewritten.
+	if data, err := os.ReadFile(cfgFile); err == nil && isYAML(cfgFile) {
+		data = migrateConfigFile(cfgFile, data)
+		checkConfig(cfgFile, data)
+		viper.SetConfigType(strings.TrimPrefix(strings.ToLower(filepath.Ext(cfgFile)), "."))
+		if err := viper.ReadConfig(bytes.NewReader(data)); err != nil {
+			log.Err(err).Msg("reading config file")
+		}
+	} else if err := viper.ReadInConfig(); err != nil {
 		log.Err(err).Msg("reading config file")
 	}
 
 	// If a config file doesn't exist, prompt the user for a first-time setup
 	if _, err := os.Stat(cfgFile); os.IsNotExist(err) {
-		fmt.Println("No config file found. Let's set one up.")
+		fmt.Println(i18n.T("No config file found. Let's set one up."))
 
 		err := promtUserConfig()
 		if err != nil {
@@ -110,9 +481,8 @@ func initConfig() {
 }
 
 func promtUserConfig() error {
-	urlPrompt := prompter.Prompt("Home Assistant URL - e.g. http://localhost:8123", "")
-	token := prompter.Password("Home Assistant Long-Lived Access Token")
-	sensorID := prompter.Prompt("Power sensor entity ID - e.g. sensor.power", "")
+	urlPrompt := prompter.Prompt(i18n.T("Home Assistant URL - e.g. http://localhost:8123"), "")
+	token := prompter.Password(i18n.T("Home Assistant Long-Lived Access Token"))
 
 	haURL, err := url.Parse(urlPrompt)
 	if haURL.Scheme == "" {
@@ -122,8 +492,130 @@ func promtUserConfig() error {
 		return fmt.Errorf("parsing URL: %w", err)
 	}
 
-	viper.Set("api_key", token)
+	// Connecting straight away lets the sensor be picked from a list, and shows up a wrong URL or
+	// token now rather than on the first real query.
 	viper.Set("url", haURL.String())
+	viper.Set("api_key", token)
+	sensors, err := listSensors()
+	if err != nil {
+		log.Warn().Msgf("listing sensors: %s", err.Error())
+	}
+	sensorID := promptSensor(sensors)
+
+	if prompter.YN(i18n.T("Keep the access token in the OS keyring?"), false) {
+		if err := secret.Store(haURL.String(), token); err != nil {
+			return err
+		}
+		token = secret.Keyring
+	} else if prompter.YN(i18n.T("Encrypt the access token with a passphrase?"), false) {
+		passphrase = prompter.Password(i18n.T("Passphrase"))
+		if token, err = secret.Encrypt(token, passphrase); err != nil {
+			return fmt.Errorf("encrypting token: %w", err)
+		}
+	}
+
+	viper.Set("api_key", token)
 	viper.Set("sensor_id", sensorID)
 	return nil
 }
+
+// listSensors connects to Home Assistant with the configured URL and token and returns its
+// energy statistics.
+func listSensors() ([]client.Sensor, error) {
+	c := client.New(client.Config{
+		Insecure:   insecure,
+		CAFile:     caFile(),
+		ClientCert: viper.GetString("client_cert"),
+		ClientKey:  viper.GetString("client_key"),
+	})
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.Sensors()
+}
+
+// promptSensor asks for the sensor to use, from a numbered list of the sensors if there are any.
+// A statistic ID can still be typed in, for one that isn't listed.
+func promptSensor(sensors []client.Sensor) string {
+	if len(sensors) == 0 {
+		return prompter.Prompt(i18n.T("Power sensor entity ID - e.g. sensor.power"), "")
+	}
+	fmt.Println(i18n.T("Energy statistics in Home Assistant:"))
+	for i, s := range sensors {
+		fmt.Printf("%3d) %s\n", i+1, describeSensor(s))
+	}
+	for {
+		answer := prompter.Prompt(i18n.T("Sensor - a number from the list, or a statistic ID"), "1")
+		if id, ok := pickSensor(sensors, answer); ok {
+			return id
+		}
+	}
+}
+
+// describeSensor describes a sensor in the list, e.g. "sensor.energy - Energy consumed (kWh)".
+func describeSensor(s client.Sensor) string {
+	d := s.ID
+	if s.Name != "" {
+		d += " - " + s.Name
+	}
+	if s.Unit != "" {
+		d += " (" + s.Unit + ")"
+	}
+	return d
+}
+
+// pickSensor returns the statistic ID chosen by the answer, which is either a number from the
+// list or a statistic ID. It reports false if the answer is empty or not in the list.
+func pickSensor(sensors []client.Sensor, answer string) (string, bool) {
+	answer = strings.TrimSpace(answer)
+	if n, err := strconv.Atoi(answer); err == nil {
+		if n < 1 || n > len(sensors) {
+			return "", false
+		}
+		return sensors[n-1].ID, true
+	}
+	return answer, answer != ""
+}
+
+// passphrase is the passphrase the access token is encrypted with, once it is known.
+var passphrase string
+
+// decryptAPIKey replaces an encrypted api_key with its plain value. The passphrase is read from
+// POWERTRACKER_PASSPHRASE, or asked for if that isn't set.
+func decryptAPIKey() error {
+	token := viper.GetString("api_key")
+	if !secret.IsEncrypted(token) {
+		return nil
+	}
+	if passphrase == "" {
+		passphrase = os.Getenv("POWERTRACKER_PASSPHRASE")
+	}
+	if passphrase == "" {
+		passphrase = prompter.Password(i18n.T("Passphrase for the access token"))
+	}
+	token, err := secret.Decrypt(token, passphrase)
+	if err != nil {
+		return err
+	}
+	viper.Set("api_key", token)
+	return nil
+}
+
+// tokenInKeyring reports whether the access token was read from the OS keyring.
+var tokenInKeyring bool
+
+// keyringAPIKey replaces an api_key of "keyring" with the token kept in the OS keyring for the
+// configured url.
+func keyringAPIKey() error {
+	if viper.GetString("api_key") != secret.Keyring {
+		return nil
+	}
+	token, err := secret.Load(viper.GetString("url"))
+	if err != nil {
+		return err
+	}
+	viper.Set("api_key", token)
+	tokenInKeyring = true
+	return nil
+}
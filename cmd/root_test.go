@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/poolski/powertracker/cmd/client"
+	"github.com/poolski/powertracker/cmd/secret"
+	"github.com/spf13/viper"
+	"github.com/zalando/go-keyring"
+	"gotest.tools/v3/assert"
+)
+
+func TestDateRange(t *testing.T) {
+	from, until, err := dateRange("2023-12-01", "2023-12-31")
+	assert.NilError(t, err)
+	assert.Equal(t, from, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, until, time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC))
+
+	from, until, err = dateRange("", "")
+	assert.NilError(t, err)
+	assert.Assert(t, from.IsZero() && until.IsZero())
+
+	_, _, err = dateRange("1 December", "")
+	assert.ErrorContains(t, err, "--start must be a date like 2023-12-01")
+	_, _, err = dateRange("2023-12-31", "2023-12-01")
+	assert.Error(t, err, "--end must not be before --start")
+}
+
+func TestAddonConfig(t *testing.T) {
+	defer viper.Reset()
+	t.Setenv("SUPERVISOR_TOKEN", "")
+	assert.ErrorContains(t, addonConfig(), "--addon needs SUPERVISOR_TOKEN")
+
+	t.Setenv("SUPERVISOR_TOKEN", "supervisor_token")
+	assert.NilError(t, addonConfig())
+	assert.Equal(t, viper.GetString("url"), "http://supervisor/core")
+	assert.Equal(t, viper.GetString("api_key"), "supervisor_token")
+}
+
+func TestEnvSettings(t *testing.T) {
+	defer viper.Reset()
+	t.Setenv("POWERTRACKER_URL", "http://homeassistant:8123")
+	t.Setenv("SENSOR_ID", "sensor.energy")
+	t.Setenv("POWERTRACKER_PRICES_PROVIDER", "nordpool")
+	t.Setenv("POWERTRACKER_CURRENCY_SYMBOL", "€")
+	t.Setenv("POWERTRACKER_INFLUXDB_URL", "http://influxdb:8086")
+	t.Setenv("POWERTRACKER_LOCATION_LATITUDE", "51.5")
+	bindEnv()
+	viper.SetConfigType("yaml")
+	assert.NilError(t, viper.ReadConfig(strings.NewReader(`
+url: http://homeassistant.local:8123
+currency:
+  symbol: £
+  decimals: 3
+`)))
+	assert.NilError(t, viper.MergeConfigMap(envSettings()))
+
+	assert.Equal(t, viper.GetString("url"), "http://homeassistant:8123")
+	assert.Equal(t, viper.GetString("sensor_id"), "sensor.energy")
+	assert.Equal(t, viper.GetString("prices.provider"), "nordpool")
+	assert.Assert(t, viper.IsSet("location.latitude"))
+	assert.Assert(t, !viper.IsSet("location.longitude"))
+
+	// Sections read whole have the environment too, along with the rest of their keys.
+	var cur struct {
+		Symbol   string
+		Decimals int
+	}
+	assert.NilError(t, viper.UnmarshalKey("currency", &cur))
+	assert.Equal(t, cur.Symbol, "€")
+	assert.Equal(t, cur.Decimals, 3)
+	assert.Equal(t, viper.Sub("influxdb").GetString("url"), "http://influxdb:8086")
+}
+
+func TestInitConfig_NoConfig(t *testing.T) {
+	defer viper.Reset()
+	defer func(file string) { cfgFile, noConfig = file, false }(cfgFile)
+	t.Setenv("POWERTRACKER_URL", "http://homeassistant:8123")
+	t.Setenv("POWERTRACKER_API_KEY", "env_token")
+
+	// A config file that is there isn't read...
+	dir := t.TempDir()
+	cfgFile = filepath.Join(dir, "config.yaml")
+	assert.NilError(t, os.WriteFile(cfgFile, []byte("url: http://from.file:8123\ndays: 7\n"), 0600))
+	noConfig = true
+	initConfig()
+	assert.Equal(t, viper.GetString("url"), "http://homeassistant:8123")
+	assert.Equal(t, viper.GetString("api_key"), "env_token")
+	assert.Assert(t, !viper.IsSet("days"))
+	assert.Equal(t, viper.ConfigFileUsed(), "")
+	assert.Equal(t, cacheFile(), "")
+
+	// ...and one that isn't there isn't created, nor is anything else.
+	viper.Reset()
+	dir = t.TempDir()
+	cfgFile = filepath.Join(dir, "powertracker", "config.yaml")
+	initConfig()
+	entries, err := os.ReadDir(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 0)
+}
+
+func TestReadConfigFile_Stdin(t *testing.T) {
+	defer viper.Reset()
+	defer func(file string, in *os.File) { cfgFile, os.Stdin = file, in }(cfgFile, os.Stdin)
+
+	in := filepath.Join(t.TempDir(), "stdin")
+	assert.NilError(t, os.WriteFile(in, []byte(`
+url: http://homeassistant.local:8123
+api_key: my_token
+sensor_id:
+  - sensor.house
+  - sensor.garage
+prices:
+  provider: nordpool
+  nordpool:
+    area: SE3
+`), 0600))
+	f, err := os.Open(in)
+	assert.NilError(t, err)
+	defer f.Close()
+	os.Stdin = f
+	cfgFile = "-"
+
+	readConfigFile()
+	assert.Equal(t, viper.GetString("url"), "http://homeassistant.local:8123")
+	assert.Equal(t, viper.GetString("api_key"), "my_token")
+	assert.DeepEqual(t, viper.GetStringSlice("sensor_id"), []string{"sensor.house", "sensor.garage"})
+	assert.Equal(t, viper.GetString("prices.nordpool.area"), "SE3")
+	// Nothing is written to disk, as there is no file the cache could go next to.
+	assert.Equal(t, cacheFile(), "")
+}
+
+func TestApplyProfile(t *testing.T) {
+	defer viper.Reset()
+	viper.SetConfigType("yaml")
+	assert.NilError(t, viper.ReadConfig(strings.NewReader(`
+url: http://homeassistant.local:8123
+api_key: my_token
+sensor_id: sensor.energy
+cache_ttl: 24h
+profiles:
+  Parents:
+    url: http://parents.example.com:8123
+    api_key: their_token
+`)))
+
+	assert.NilError(t, applyProfile(""))
+	assert.Equal(t, viper.GetString("url"), "http://homeassistant.local:8123")
+
+	assert.NilError(t, applyProfile("parents"))
+	assert.Equal(t, viper.GetString("url"), "http://parents.example.com:8123")
+	assert.Equal(t, viper.GetString("api_key"), "their_token")
+	// What the profile leaves out is shared.
+	assert.Equal(t, viper.GetString("sensor_id"), "sensor.energy")
+	assert.Equal(t, viper.GetDuration("cache_ttl"), 24*time.Hour)
+
+	assert.ErrorContains(t, applyProfile("holiday"), `no profile "holiday" in the config, only parents`)
+	viper.Reset()
+	assert.ErrorContains(t, applyProfile("holiday"), "the config has no profiles")
+}
+
+func TestKeyringAPIKey(t *testing.T) {
+	defer viper.Reset()
+	defer func() { tokenInKeyring = false }()
+	keyring.MockInit()
+	assert.NilError(t, secret.Store("http://homeassistant.local:8123", "my_token"))
+
+	viper.Set("url", "http://homeassistant.local:8123")
+	viper.Set("api_key", "plain_token")
+	assert.NilError(t, keyringAPIKey())
+	assert.Equal(t, viper.GetString("api_key"), "plain_token")
+	assert.Assert(t, !tokenInKeyring)
+
+	viper.Set("api_key", "keyring")
+	assert.NilError(t, keyringAPIKey())
+	assert.Equal(t, viper.GetString("api_key"), "my_token")
+	assert.Assert(t, tokenInKeyring)
+
+	viper.Set("url", "http://parents.example.com:8123")
+	viper.Set("api_key", "keyring")
+	assert.ErrorContains(t, keyringAPIKey(), "there is no token for http://parents.example.com:8123 in the OS keyring")
+}
+
+func TestPickSensor(t *testing.T) {
+	sensors := []client.Sensor{{ID: "sensor.energy"}, {ID: "sensor.solar"}}
+	tests := []struct {
+		answer string
+		want   string
+		ok     bool
+	}{
+		{answer: "1", want: "sensor.energy", ok: true},
+		{answer: " 2 ", want: "sensor.solar", ok: true},
+		{answer: "3"},
+		{answer: "0"},
+		{answer: "sensor.grid", want: "sensor.grid", ok: true},
+		{answer: ""},
+	}
+	for _, tt := range tests {
+		got, ok := pickSensor(sensors, tt.answer)
+		assert.Equal(t, got, tt.want, tt.answer)
+		assert.Equal(t, ok, tt.ok, tt.answer)
+	}
+}
+
+func TestDescribeSensor(t *testing.T) {
+	assert.Equal(t, describeSensor(client.Sensor{ID: "sensor.energy", Name: "Energy", Unit: "kWh"}), "sensor.energy - Energy (kWh)")
+	assert.Equal(t, describeSensor(client.Sensor{ID: "sensor.energy"}), "sensor.energy")
+}
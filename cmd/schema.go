@@ -0,0 +1,425 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// kind is the type of value a config key takes.
+type kind int
+
+const (
+	kindString kind = iota
+	kindNumber
+	kindInt
+	kindBool
+	kindDuration
+	kindList
+	kindMap
+	kindStrings
+)
+
+func (k kind) String() string {
+	return [...]string{"a string", "a number", "a whole number", "true or false", "a duration such as 48h", "a list", "a section", "a string or a list of strings"}[k]
+}
+
+// field describes a config key. Sections list their keys in Keys, or describe every entry with
+// Entry when the keys are names chosen by the user, as in tariffs. Lists, and keys that take
+// either one string or a list of them, describe their items with Entry.
+type field struct {
+	Kind  kind
+	Keys  map[string]field
+	Entry *field
+}
+
+func str() field                          { return field{Kind: kindString} }
+func number() field                       { return field{Kind: kindNumber} }
+func integer() field                      { return field{Kind: kindInt} }
+func boolean() field                      { return field{Kind: kindBool} }
+func duration() field                     { return field{Kind: kindDuration} }
+func listOf(item field) field             { return field{Kind: kindList, Entry: &item} }
+func strOrList() field                    { return field{Kind: kindStrings, Entry: &field{Kind: kindString}} }
+func section(keys map[string]field) field { return field{Kind: kindMap, Keys: keys} }
+func namedSections(entry field) field     { return field{Kind: kindMap, Entry: &entry} }
+
+var taxSchema = section(map[string]field{
+	"rate":          number(),
+	"standing_rate": number(),
+	"inclusive":     boolean(),
+})
+
+// configSchema is every key read from the config file. Keys missing from it are reported as
+// unknown, so it has to be kept up to date as settings are added.
+var configSchema = section(map[string]field{
+	"url":                     str(),
+	"api_key":                 str(),
+	"ca_file":                 str(),
+	"client_cert":             str(),
+	"client_key":              str(),
+	"proxy":                   str(),
+	"sensor_id":               strOrList(),
+	"source":                  str(),
+	"statistic_type":          str(),
+	"lang":                    str(),
+	"timezone":                str(),
+	"chunk_days":              integer(),
+	"concurrent_requests":     integer(),
+	"request_timeout":         duration(),
+	"ping_interval":           duration(),
+	"retry_attempts":          integer(),
+	"retry_delay":             duration(),
+	"cache_ttl":               duration(),
+	"max_hourly_kwh":          number(),
+	"export_sensor_id":        str(),
+	"generation_sensor_id":    str(),
+	"temperature_sensor_id":   str(),
+	"power_sensor_id":         str(),
+	"fossil_sensor_id":        str(),
+	"co2_intensity_sensor_id": str(),
+	"phase_sensor_ids":        listOf(str()),
+	"tariff":                  str(),
+	"billing_day":             integer(),
+	"tax":                     taxSchema,
+	"tariffs": namedSections(section(map[string]field{
+		"type":     str(),
+		"rate":     number(),
+		"provider": str(),
+		"period":   str(),
+		"bands": listOf(section(map[string]field{
+			"from": str(),
+			"to":   str(),
+			"rate": number(),
+		})),
+		"tiers": listOf(section(map[string]field{
+			"up_to": number(),
+			"rate":  number(),
+		})),
+		"standing_charge": number(),
+		"export_rate":     number(),
+		"tax":             taxSchema,
+	})),
+	"currency": section(map[string]field{
+		"symbol":   str(),
+		"decimals": integer(),
+		"position": str(),
+	}),
+	"prices": section(map[string]field{
+		"provider": str(),
+		"amber":    section(map[string]field{"site_id": str(), "token": str()}),
+		"awattar":  section(map[string]field{"country": str()}),
+		"entsoe":   section(map[string]field{"token": str(), "zone": str()}),
+		"nordpool": section(map[string]field{"area": str(), "currency": str()}),
+	}),
+	"glow": section(map[string]field{"broker": str(), "topic": str(), "username": str(), "password": str()}),
+	"benchmark": section(map[string]field{
+		"annual_kwh": number(),
+		"occupants":  integer(),
+		"size":       str(),
+	}),
+	"bigquery": section(map[string]field{
+		"credentials": str(),
+		"project":     str(),
+		"dataset":     str(),
+		"table":       str(),
+	}),
+	"demand": section(map[string]field{"window": integer()}),
+	"emoncms": section(map[string]field{
+		"url":     str(),
+		"api_key": str(),
+		"node":    str(),
+		"input":   str(),
+	}),
+	"graphite": section(map[string]field{"address": str(), "prefix": str()}),
+	"heat_pump": section(map[string]field{
+		"electricity_sensor_id": str(),
+		"heat_sensor_id":        str(),
+	}),
+	"influxdb": section(map[string]field{
+		"url":         str(),
+		"token":       str(),
+		"org":         str(),
+		"bucket":      str(),
+		"measurement": str(),
+	}),
+	"location": section(map[string]field{
+		"latitude":  number(),
+		"longitude": number(),
+	}),
+	"mqtt": section(map[string]field{
+		"broker":           str(),
+		"username":         str(),
+		"password":         str(),
+		"topic_prefix":     str(),
+		"discovery":        boolean(),
+		"discovery_prefix": str(),
+		"profile":          boolean(),
+	}),
+	"occupancy": section(map[string]field{"entity_id": str()}),
+	"pvoutput": section(map[string]field{
+		"api_key":              str(),
+		"system_id":            str(),
+		"generation_sensor_id": str(),
+	}),
+	"recommendations": section(map[string]field{
+		"hours":        integer(),
+		"flexible_kwh": number(),
+	}),
+	"season": section(map[string]field{
+		"heating_months": listOf(integer()),
+		"threshold":      number(),
+	}),
+	"serve": section(map[string]field{
+		"allow_origin":     str(),
+		"ready_max_age":    duration(),
+		"metrics_interval": duration(),
+	}),
+})
+
+func init() {
+	// A profile can hold any setting but profiles, so it is described by a copy of the schema
+	// taken before profiles is added to it.
+	profile := section(map[string]field{})
+	for key, f := range configSchema.Keys {
+		profile.Keys[key] = f
+	}
+	configSchema.Keys["profiles"] = namedSections(profile)
+}
+
+// validateConfig checks a YAML (or JSON) config against the schema, each problem found with the
+// line it's on. Unknown keys are returned as warnings, as they are only ignored, but values of the
+// wrong type are errors.
+func validateConfig(name string, data []byte) (warnings []string, err error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	var problems []string
+	check(configSchema, doc.Content[0], "", func(line int, msg string, warning bool) {
+		msg = fmt.Sprintf("%s:%d: %s", name, line, msg)
+		if warning {
+			warnings = append(warnings, msg)
+			return
+		}
+		problems = append(problems, msg)
+	})
+	if len(problems) == 0 {
+		return warnings, nil
+	}
+	return warnings, errors.New(strings.Join(problems, "\n"))
+}
+
+// checkConfig validates the config, logging a warning for each unknown key, and each error before
+// exiting if there are any.
+func checkConfig(name string, data []byte) {
+	warnings, err := validateConfig(name, data)
+	for _, warning := range warnings {
+		log.Warn().Msg(warning)
+	}
+	if err == nil {
+		return
+	}
+	for _, problem := range strings.Split(err.Error(), "\n") {
+		log.Error().Msg(problem)
+	}
+	log.Fatal().Msg("invalid config - see the problems above")
+}
+
+// envKeys returns the keys at or under path that can be set from the environment: every one but
+// those in sections whose keys are names chosen by the user, such as tariffs, which can't be known.
+func envKeys(f field, path string) []string {
+	if f.Kind != kindMap {
+		return []string{path}
+	}
+	if f.Entry != nil {
+		return nil
+	}
+	var keys []string
+	for name, sub := range f.Keys {
+		if path != "" {
+			name = path + "." + name
+		}
+		keys = append(keys, envKeys(sub, name)...)
+	}
+	return keys
+}
+
+// check reports any problems with the value of the key at path, and the keys within it.
+func check(f field, node *yaml.Node, path string, report func(line int, msg string, warning bool)) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if !matches(f.Kind, node) {
+		report(node.Line, fmt.Sprintf("%s should be %s", describe(path), f.Kind), false)
+		return
+	}
+
+	switch f.Kind {
+	case kindList, kindStrings:
+		for _, item := range node.Content {
+			check(*f.Entry, item, path+"[]", report)
+		}
+	case kindMap:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "<<" {
+				// Merged keys are checked where they're defined.
+				continue
+			}
+			sub := key.Value
+			if path != "" {
+				sub = path + "." + key.Value
+			}
+			if f.Entry != nil {
+				check(*f.Entry, value, sub, report)
+				continue
+			}
+			// Keys are case-insensitive, as viper lowercases them.
+			known, ok := f.Keys[strings.ToLower(key.Value)]
+			if !ok {
+				valid := "valid keys are"
+				if path != "" {
+					valid = fmt.Sprintf("valid keys in %s are", path)
+				}
+				valid += ": " + strings.Join(keyNames(f.Keys), ", ")
+				msg := fmt.Sprintf("unknown key '%s' - %s", sub, valid)
+				if suggestion := suggest(key.Value, f.Keys); suggestion != "" {
+					if path != "" {
+						suggestion = path + "." + suggestion
+					}
+					msg = fmt.Sprintf("unknown key '%s' - did you mean '%s'? The %s", sub, suggestion, valid)
+				}
+				report(key.Line, msg, true)
+				continue
+			}
+			check(known, value, sub, report)
+		}
+	}
+}
+
+// describe names the key at path in a message.
+func describe(path string) string {
+	if path == "" {
+		return "the config"
+	}
+	return path
+}
+
+// keyNames returns the names of the keys, sorted.
+func keyNames(keys map[string]field) []string {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// matches reports whether the node holds a value of the kind. Empty values are allowed for
+// everything, as they leave the default in place.
+func matches(k kind, node *yaml.Node) bool {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return true
+	}
+	switch k {
+	case kindList:
+		return node.Kind == yaml.SequenceNode
+	case kindStrings:
+		return node.Kind == yaml.SequenceNode || node.Kind == yaml.ScalarNode
+	case kindMap:
+		return node.Kind == yaml.MappingNode
+	}
+	if node.Kind != yaml.ScalarNode {
+		return false
+	}
+	switch k {
+	case kindNumber:
+		return node.Tag == "!!int" || node.Tag == "!!float"
+	case kindInt:
+		return node.Tag == "!!int"
+	case kindBool:
+		return node.Tag == "!!bool"
+	case kindDuration:
+		if node.Tag == "!!int" {
+			return true
+		}
+		_, err := time.ParseDuration(node.Value)
+		return err == nil
+	}
+	// Numbers and the like are read as strings where one is expected, e.g. a system ID of 12345.
+	return true
+}
+
+// suggest returns the known key closest to an unknown one, or "" if none is close enough to be
+// what was meant.
+func suggest(key string, keys map[string]field) string {
+	names := keyNames(keys)
+	best, bestDistance := "", 0
+	for _, name := range names {
+		lower := strings.ToLower(key)
+		d := distance(lower, name)
+		// A key missing its suffix or prefix, like "sensor" for "sensor_id", is worth suggesting
+		// even though it's several edits away.
+		if len(lower) > 3 && (strings.HasPrefix(name, lower) || strings.HasSuffix(name, lower)) {
+			d = 1
+		}
+		if d > len(name)/3+1 {
+			continue
+		}
+		if best == "" || d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+	return best
+}
+
+// distance is the Levenshtein distance between two strings: the number of characters that have
+// to be inserted, deleted or changed to turn one into the other.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// isYAML reports whether the config file at path is checked against the schema, which covers YAML
+// and JSON, as JSON is also YAML.
+func isYAML(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		warning string // warning is the start of the only warning expected.
+		err     string
+	}{
+		{
+			name:   "valid",
+			config: "url: http://localhost:8123\nsensor_id: sensor.energy\nchunk_days: 7\ncache_ttl: 24h\nmqtt:\n  discovery: true\n",
+		},
+		{
+			name:    "unknown key",
+			config:  "url: http://localhost:8123\nsensor: sensor.energy\n",
+			warning: "config.yaml:2: unknown key 'sensor' - did you mean 'sensor_id'? The valid keys are: api_key, benchmark, ",
+		},
+		{
+			name:    "misspelt nested key",
+			config:  "prices:\n  provider: nordpool\n  nordpool:\n    aera: SE3\n",
+			warning: "config.yaml:4: unknown key 'prices.nordpool.aera' - did you mean 'prices.nordpool.area'? The valid keys in prices.nordpool are: area, currency",
+		},
+		{
+			name:    "unknown key without a suggestion",
+			config:  "colour: blue\n",
+			warning: "config.yaml:1: unknown key 'colour' - valid keys are: api_key, ",
+		},
+		{
+			name:    "tariff names are free",
+			config:  "tariffs:\n  agile:\n    type: dynamic\n    provider: octopus\n  eco7:\n    type: tou\n    bands:\n      - from: \"00:30\"\n        to: \"07:30\"\n        rate: 0.09\n        cost: 1\n",
+			warning: "config.yaml:11: unknown key 'tariffs.eco7.bands[].cost' - valid keys in tariffs.eco7.bands[] are: from, rate, to",
+		},
+		{
+			name:    "profiles take any setting",
+			config:  "profiles:\n  parents:\n    url: http://parents.example.com:8123\n    sensor_id: [sensor.house, sensor.garage]\n    colour: blue\n",
+			warning: "config.yaml:5: unknown key 'profiles.parents.colour' - valid keys in profiles.parents are: api_key, ",
+		},
+		{
+			name:   "wrong types",
+			config: "chunk_days: lots\ncache_ttl: 2 days\nseason:\n  heating_months: [10, 11, december]\nmqtt: true\n",
+			err: "config.yaml:1: chunk_days should be a whole number\n" +
+				"config.yaml:2: cache_ttl should be a duration such as 48h\n" +
+				"config.yaml:4: season.heating_months[] should be a whole number\n" +
+				"config.yaml:5: mqtt should be a section",
+		},
+		{
+			name:   "several sensors",
+			config: "sensor_id:\n  - sensor.house_energy\n  - sensor.garage_energy\n",
+		},
+		{
+			name:   "sensors have to be strings",
+			config: "sensor_id:\n  - sensor.house_energy\n  - id: sensor.garage_energy\n",
+			err:    "config.yaml:3: sensor_id[] should be a string",
+		},
+		{
+			name:   "keys are case-insensitive",
+			config: "Sensor_ID: sensor.energy\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, err := validateConfig("config.yaml", []byte(tt.config))
+			if tt.warning == "" {
+				assert.Equal(t, len(warnings), 0, warnings)
+			} else {
+				assert.Equal(t, len(warnings), 1, warnings)
+				assert.Assert(t, strings.HasPrefix(warnings[0], tt.warning), warnings[0])
+			}
+			if tt.err == "" {
+				assert.NilError(t, err)
+				return
+			}
+			assert.Error(t, err, tt.err)
+		})
+	}
+}
+
+// The examples in the README have to pass, or the schema has fallen behind.
+func TestValidateConfig_README(t *testing.T) {
+	readme, err := os.ReadFile("../README.md")
+	assert.NilError(t, err)
+	blocks := regexp.MustCompile("(?s)```yaml\n(.*?)```").FindAllSubmatch(readme, -1)
+	assert.Assert(t, len(blocks) > 0)
+	for _, block := range blocks {
+		// Dashboard cards are YAML too, but aren't config.
+		if regexp.MustCompile(`^type: custom:`).Match(block[1]) {
+			continue
+		}
+		warnings, err := validateConfig("README.md", block[1])
+		assert.NilError(t, err, string(block[1]))
+		assert.Equal(t, len(warnings), 0, warnings)
+	}
+}
@@ -0,0 +1,36 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zalando/go-keyring"
+)
+
+// Keyring is the value of api_key that says the token is kept in the OS keyring instead: the
+// macOS Keychain, Windows Credential Manager or the Secret Service on Linux.
+const Keyring = "keyring"
+
+// keyringService is the name the tokens are kept under in the OS keyring.
+const keyringService = "powertracker"
+
+// Store keeps the token in the OS keyring, under the URL of the Home Assistant it is for, so each
+// instance has a token of its own.
+func Store(url, token string) error {
+	if err := keyring.Set(keyringService, url, token); err != nil {
+		return fmt.Errorf("storing the token in the OS keyring: %w", err)
+	}
+	return nil
+}
+
+// Load returns the token kept in the OS keyring for the Home Assistant at url.
+func Load(url string) (string, error) {
+	token, err := keyring.Get(keyringService, url)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", fmt.Errorf("there is no token for %s in the OS keyring", url)
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading the token from the OS keyring: %w", err)
+	}
+	return token, nil
+}
@@ -0,0 +1,21 @@
+package secret
+
+import (
+	"testing"
+
+	"github.com/zalando/go-keyring"
+	"gotest.tools/v3/assert"
+)
+
+func TestStoreLoad(t *testing.T) {
+	keyring.MockInit()
+
+	assert.NilError(t, Store("http://homeassistant.local:8123", "long-lived-token"))
+	assert.NilError(t, Store("http://parents.example.com:8123", "their-token"))
+	token, err := Load("http://homeassistant.local:8123")
+	assert.NilError(t, err)
+	assert.Equal(t, token, "long-lived-token")
+
+	_, err = Load("http://elsewhere:8123")
+	assert.ErrorContains(t, err, "there is no token for http://elsewhere:8123 in the OS keyring")
+}
@@ -0,0 +1,72 @@
+// Package secret encrypts config values, such as the Home Assistant access token, with a
+// passphrase, so the config file can be kept somewhere public like a dotfiles repo.
+package secret
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// prefix marks a config value as encrypted.
+const prefix = "age:"
+
+// workFactor is the scrypt work factor used to derive the key from the passphrase.
+var workFactor = 18
+
+// IsEncrypted reports whether the value was produced by Encrypt.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Encrypt encrypts the value with the passphrase, using age, and returns it as a single line
+// that can be stored in the config file.
+func Encrypt(value, passphrase string) (string, error) {
+	recipient, err := age.NewScryptRecipient(passphrase)
+	if err != nil {
+		return "", fmt.Errorf("creating recipient: %w", err)
+	}
+	recipient.SetWorkFactor(workFactor)
+
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipient)
+	if err != nil {
+		return "", fmt.Errorf("encrypting: %w", err)
+	}
+	if _, err := io.WriteString(w, value); err != nil {
+		return "", fmt.Errorf("encrypting: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("encrypting: %w", err)
+	}
+	return prefix + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// Decrypt decrypts a value produced by Encrypt with the passphrase.
+func Decrypt(value, passphrase string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", fmt.Errorf("value is not encrypted")
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, prefix))
+	if err != nil {
+		return "", fmt.Errorf("decoding: %w", err)
+	}
+
+	identity, err := age.NewScryptIdentity(passphrase)
+	if err != nil {
+		return "", fmt.Errorf("creating identity: %w", err)
+	}
+	r, err := age.Decrypt(bytes.NewReader(ciphertext), identity)
+	if err != nil {
+		return "", fmt.Errorf("decrypting: %w", err)
+	}
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("decrypting: %w", err)
+	}
+	return string(plain), nil
+}
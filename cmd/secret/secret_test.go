@@ -0,0 +1,26 @@
+package secret
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	workFactor = 10
+
+	encrypted, err := Encrypt("long-lived-token", "correct horse")
+	assert.NilError(t, err)
+	assert.Assert(t, IsEncrypted(encrypted), "expected the value to be marked as encrypted")
+	assert.Assert(t, encrypted != "age:long-lived-token")
+
+	plain, err := Decrypt(encrypted, "correct horse")
+	assert.NilError(t, err)
+	assert.Equal(t, plain, "long-lived-token")
+
+	_, err = Decrypt(encrypted, "wrong passphrase")
+	assert.ErrorContains(t, err, "decrypting")
+
+	_, err = Decrypt("long-lived-token", "correct horse")
+	assert.ErrorContains(t, err, "value is not encrypted")
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/poolski/powertracker/cmd/client"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var sensorsCmd = &cobra.Command{
+	Use:   "sensors",
+	Short: "List the energy statistics in Home Assistant, to choose sensor_id from",
+	Long: `
+	Lists the energy statistics kept by the Home Assistant recorder, with their units and when they were last reset.
+	Any of the statistic IDs can be used as sensor_id, or with --sensor.`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		c := client.New(clientConfig())
+		if err := c.Connect(); err != nil {
+			log.Fatal().Msgf("connecting to websocket: %s", err.Error())
+		}
+		defer c.Close()
+		if err := c.PrintSensors(os.Stdout); err != nil {
+			log.Error().Msgf("listing sensors: %s", err.Error())
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sensorsCmd)
+}
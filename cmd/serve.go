@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/poolski/powertracker/cmd/client"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var listen string
+
+// shutdownTimeout is how long requests in progress are given to finish when the server is stopped.
+const shutdownTimeout = 30 * time.Second
+
+// defaultMetricsInterval is how often the averages served on /metrics are worked out again.
+const defaultMetricsInterval = 15 * time.Minute
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve consumption as chart series over HTTP, for Lovelace cards",
+	Long: `
+	Connects once and serves consumption over HTTP until stopped, in the series shape used by ApexCharts-card and other Lovelace chart cards.
+	GET /api/series?group=day&days=30 returns the totals for each day of the last 30 days; group can also be hour, week, month or hour_of_day.
+	GET /metrics serves the average of each hour of the day for Prometheus, recomputed every serve.metrics_interval (15m by default).`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		c := client.New(clientConfig())
+		if err := c.Connect(); err != nil {
+			log.Fatal().Msgf("connecting to websocket: %s", err.Error())
+		}
+
+		viper.SetDefault("serve.metrics_interval", defaultMetricsInterval)
+		server := &http.Server{
+			Addr:              listen,
+			Handler:           c.Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		served := make(chan error, 1)
+		go func() {
+			log.Info().Msgf("listening on %s", listen)
+			served <- server.ListenAndServe()
+		}()
+
+		// On SIGINT or SIGTERM, fetches in progress stop after their current request, keeping
+		// the days they have cached, and the server waits for requests to finish.
+		received := stopOnSignal(c.Stop)
+		var sig os.Signal
+		select {
+		case err := <-served:
+			log.Fatal().Msgf("serving: %s", err.Error())
+		case sig = <-received:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		if err := server.Shutdown(ctx); err != nil {
+			log.Error().Msgf("shutting down: %s", err.Error())
+		}
+		cancel()
+		<-served
+		if err := c.Close(); err != nil {
+			log.Error().Msgf("closing connection: %s", err.Error())
+		}
+		os.Exit(exitCode(sig))
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&listen, "listen", "localhost:8099", "address to listen on")
+	rootCmd.AddCommand(serveCmd)
+}
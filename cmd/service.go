@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/poolski/powertracker/cmd/client"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// serviceName is the name the service is registered under.
+const serviceName = "powertracker"
+
+var (
+	windowsService bool
+	interval       time.Duration
+)
+
+var installCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install powertracker as a service that runs on a schedule",
+	Long: `
+	Installs powertracker as a Windows service, which runs straight away and then every --interval with the flags given to this command.
+	Output is logged to powertracker.log, next to the config file.`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if !windowsService {
+			log.Fatal().Msg("only --windows-service is supported")
+		}
+		if interval <= 0 {
+			log.Fatal().Msg("--interval must be more than zero")
+		}
+		if err := installWindowsService(serviceArgs()); err != nil {
+			log.Fatal().Msgf("installing service: %s", err.Error())
+		}
+		log.Info().Msgf("installed the %s service", serviceName)
+	},
+}
+
+var uninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the powertracker service",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if !windowsService {
+			log.Fatal().Msg("only --windows-service is supported")
+		}
+		if err := uninstallWindowsService(); err != nil {
+			log.Fatal().Msgf("removing service: %s", err.Error())
+		}
+		log.Info().Msgf("removed the %s service", serviceName)
+	},
+}
+
+// serviceCmd is run by the Windows service manager.
+var serviceCmd = &cobra.Command{
+	Use:    "service",
+	Hidden: true,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if dir := stateDir(); dir != "" {
+			f, err := os.OpenFile(filepath.Join(dir, "powertracker.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				log.Fatal().Msgf("opening log file: %s", err.Error())
+			}
+			defer f.Close()
+			log.Logger = zerolog.New(f).With().Timestamp().Logger()
+		}
+
+		if err := runWindowsService(); err != nil {
+			log.Fatal().Msgf("running service: %s", err.Error())
+		}
+	},
+}
+
+// serviceArgs returns the arguments the service is started with: the interval, the absolute
+// path of the config file, and any other flags that were set when installing it.
+func serviceArgs() []string {
+	config := cfgFile
+	if localConfig() {
+		if abs, err := filepath.Abs(cfgFile); err == nil {
+			config = abs
+		}
+	}
+	args := []string{"service", "--interval=" + interval.String(), "--config=" + config}
+	rootCmd.PersistentFlags().Visit(func(f *pflag.Flag) {
+		if f.Name != "config" {
+			args = append(args, "--"+f.Name+"="+f.Value.String())
+		}
+	})
+	return args
+}
+
+// runScheduled runs the configured analysis straight away and then every interval, as nextRun
+// decides, until stop is closed. Failures are logged, and the next run goes ahead as normal.
+func runScheduled(stop <-chan struct{}) {
+	for {
+		started := time.Now()
+		c := client.New(clientConfig())
+		// Stopping the service cuts a run short, keeping what it has fetched.
+		finished := make(chan struct{})
+		go func() {
+			select {
+			case <-stop:
+				c.Stop()
+			case <-finished:
+			}
+		}()
+		if err := c.Connect(); err != nil {
+			log.Error().Msgf("connecting to websocket: %s", err.Error())
+		} else {
+			c.ComputePowerStats()
+			c.Close()
+		}
+		close(finished)
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(nextRun(started, time.Now(), interval)):
+		}
+	}
+}
+
+// nextRun returns how long to wait before the next run, given when the last one started. Runs keep
+// to the schedule set by the first, rather than drifting by however long each took, and any due
+// while a run was still going are skipped instead of being made up back to back.
+func nextRun(started, now time.Time, interval time.Duration) time.Duration {
+	elapsed := now.Sub(started)
+	if elapsed <= 0 {
+		return interval
+	}
+	if over := elapsed % interval; over != 0 {
+		return interval - over
+	}
+	return 0
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{installCmd, uninstallCmd} {
+		cmd.Flags().BoolVar(&windowsService, "windows-service", false, "install as a Windows service")
+	}
+	installCmd.Flags().DurationVar(&interval, "interval", 24*time.Hour, "how often the service runs")
+	serviceCmd.Flags().DurationVar(&interval, "interval", 24*time.Hour, "how often the service runs")
+
+	rootCmd.AddCommand(installCmd, uninstallCmd, serviceCmd)
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package cmd
+
+import "fmt"
+
+var errNotWindows = fmt.Errorf("Windows services are only supported on Windows")
+
+func installWindowsService(args []string) error {
+	return errNotWindows
+}
+
+func uninstallWindowsService() error {
+	return errNotWindows
+}
+
+func runWindowsService() error {
+	return errNotWindows
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestNextRun(t *testing.T) {
+	started := time.Date(2023, 12, 1, 6, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		took time.Duration
+		want time.Duration
+	}{
+		{name: "quick run", took: 5 * time.Minute, want: 55 * time.Minute},
+		{name: "run that took the whole interval", took: time.Hour, want: 0},
+		// The 07:00 run is skipped, and the schedule picks up again at 08:00.
+		{name: "run that overran", took: 90 * time.Minute, want: 30 * time.Minute},
+		// The clock going back mid-run mustn't make it run back to back.
+		{name: "clock went back", took: -time.Minute, want: time.Hour},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, nextRun(started, started.Add(tt.took), time.Hour), tt.want, tt.name)
+	}
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rs/zerolog/log"
+)
+
+// stopOnSignal calls stop when the process receives SIGINT or SIGTERM, so a run can finish the
+// requests in progress and report what it has, and exits straight away on a second one. The
+// returned channel receives the first signal.
+func stopOnSignal(stop func()) <-chan os.Signal {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	received := make(chan os.Signal, 1)
+	go func() {
+		sig := <-signals
+		received <- sig
+		log.Warn().Msgf("received %s - stopping after the current request, send it again to quit now", sig)
+		stop()
+		os.Exit(exitCode(<-signals))
+	}()
+	return received
+}
+
+// exitCode returns the conventional exit code for being stopped by a signal, 128 plus its number,
+// so scripts can tell an interrupted run from a failed one.
+func exitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"strconv"
+
+	"github.com/poolski/powertracker/cmd/client"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	shiftKWh  float64
+	shiftFrom string
+	shiftTo   string
+)
+
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Work out what changing when you use energy would have done to your bill",
+}
+
+var simulateShiftCmd = &cobra.Command{
+	Use:   "shift",
+	Short: "Move some energy from one hour of the day to another and compare the cost",
+	Long: `
+	Replays the period with some of each day's consumption moved from one hour of the day to another, such as running the dishwasher at 02:00 instead of 18:00, and reports the difference in cost on the selected tariff.
+	When co2_intensity_sensor_id is set, the difference in emissions is reported too.`,
+	Example: "  powertracker simulate shift --kwh 3 --from 18 --to 02",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		from, err := strconv.Atoi(shiftFrom)
+		if err != nil {
+			log.Fatal().Msgf("parsing --from: %s", err.Error())
+		}
+		to, err := strconv.Atoi(shiftTo)
+		if err != nil {
+			log.Fatal().Msgf("parsing --to: %s", err.Error())
+		}
+
+		c := client.New(clientConfig())
+		if err := c.Connect(); err != nil {
+			log.Fatal().Msgf("connecting to websocket: %s", err.Error())
+		}
+		defer c.Close()
+		if err := c.SimulateShift(client.Shift{KWh: shiftKWh, From: from, To: to}); err != nil {
+			log.Error().Msgf("simulating shift: %s", err.Error())
+		}
+	},
+}
+
+func init() {
+	simulateShiftCmd.Flags().Float64Var(&shiftKWh, "kwh", 1, "energy to move each day, in kWh")
+	simulateShiftCmd.Flags().StringVar(&shiftFrom, "from", "", "hour of the day to move energy from, e.g. 18")
+	simulateShiftCmd.Flags().StringVar(&shiftTo, "to", "", "hour of the day to move energy to, e.g. 02")
+	_ = simulateShiftCmd.MarkFlagRequired("from")
+	_ = simulateShiftCmd.MarkFlagRequired("to")
+	simulateCmd.AddCommand(simulateShiftCmd)
+	rootCmd.AddCommand(simulateCmd)
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/poolski/powertracker/cmd/client"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var syncDatabase string
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Mirror the hourly consumption of the configured sensors into a SQLite database",
+	Long: `
+	Fetches the hourly consumption of sensor_id, or of the phases in phase_sensor_ids, along with generation_sensor_id and export_sensor_id if they are set, into a SQLite database other tools can query, powertracker.sqlite next to the config unless --database says otherwise.
+	The days fetched go into the local cache too, so reports and --offline can answer from it without Home Assistant.
+	The first sync covers the days given with --days or --start. After that, each sync only fetches the days since the last one, so it can be run every day from cron or a scheduled task.`,
+	Example: "  powertracker sync --days 365",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		config := clientConfig()
+		config.SyncDatabase = mirrorFile()
+		if config.SyncDatabase == "" {
+			log.Fatal().Msg("there is nowhere to keep the database - give its path with --database")
+		}
+		c := client.New(config)
+		received := stopOnSignal(c.Stop)
+		if err := c.Connect(); err != nil {
+			if errors.Is(err, client.ErrInterrupted) {
+				os.Exit(exitCode(<-received))
+			}
+			log.Fatal().Msgf("connecting to websocket: %s", err.Error())
+		}
+		n, err := c.Sync()
+		if closeErr := c.Close(); closeErr != nil {
+			log.Error().Msgf("closing connection: %s", closeErr.Error())
+		}
+		if stats {
+			c.Stats().Print(os.Stderr)
+		}
+		switch {
+		case errors.Is(err, client.ErrInterrupted):
+			log.Warn().Msgf("interrupted - synced %d days in full", n)
+			os.Exit(exitCode(<-received))
+		case err != nil:
+			log.Fatal().Msgf("syncing: %s", err.Error())
+		}
+		log.Info().Msgf("synced %d days, %d fetched from the source", n, c.Stats().CacheMisses)
+	},
+}
+
+// mirrorFile returns the path of the database sync mirrors into: --database, or else one next to
+// the config, for each profile its own, or "" if nothing should be written to disk.
+func mirrorFile() string {
+	if syncDatabase != "" {
+		return syncDatabase
+	}
+	if stateDir() == "" {
+		return ""
+	}
+	if name := profileName(); name != "" {
+		return filepath.Join(stateDir(), "powertracker-"+strings.ToLower(name)+".sqlite")
+	}
+	return filepath.Join(stateDir(), "powertracker.sqlite")
+}
+
+func init() {
+	syncCmd.Flags().StringVar(&syncDatabase, "database", "", "the SQLite database to mirror into (default powertracker.sqlite next to the config)")
+	rootCmd.AddCommand(syncCmd)
+}
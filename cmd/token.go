@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"github.com/Songmu/prompter"
+	"github.com/poolski/powertracker/cmd/i18n"
+	"github.com/poolski/powertracker/cmd/secret"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var encryptTokenCmd = &cobra.Command{
+	Use:   "encrypt-token",
+	Short: "Encrypt the access token in the config file with a passphrase",
+	Long: `
+	Encrypts the api_key in the config file with a passphrase, so the file can be kept somewhere public like a dotfiles repo. With --profile, the profile's api_key is encrypted.
+	The passphrase is asked for whenever the token is needed, or read from POWERTRACKER_PASSPHRASE.`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if !localConfig() {
+			log.Fatal().Msg("there is no config file to encrypt the token in")
+		}
+		if passphrase != "" {
+			log.Fatal().Msg("the access token is already encrypted")
+		}
+		if tokenInKeyring {
+			log.Fatal().Msg("the access token is kept in the OS keyring")
+		}
+
+		passphrase = prompter.Password(i18n.T("New passphrase"))
+		if prompter.Password(i18n.T("Repeat passphrase")) != passphrase {
+			log.Fatal().Msg("passphrases don't match")
+		}
+		token, err := secret.Encrypt(viper.GetString("api_key"), passphrase)
+		if err != nil {
+			log.Fatal().Msgf("encrypting token: %s", err.Error())
+		}
+
+		if err := writeConfigString(cfgFile, tokenKey(), token); err != nil {
+			log.Fatal().Msgf("writing config file: %s", err.Error())
+		}
+		log.Info().Msgf("encrypted the access token in %s", cfgFile)
+	},
+}
+
+var storeTokenCmd = &cobra.Command{
+	Use:   "store-token",
+	Short: "Move the access token from the config file into the OS keyring",
+	Long: `
+	Moves the api_key in the config file into the OS keyring, the macOS Keychain, Windows Credential Manager or the Secret Service on Linux, leaving "keyring" in its place.
+	The token is kept under the url it is for, so with --profile, the profile's token is moved.`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if !localConfig() {
+			log.Fatal().Msg("there is no config file to move the token out of")
+		}
+		if tokenInKeyring {
+			log.Fatal().Msg("the access token is already kept in the OS keyring")
+		}
+		if err := secret.Store(viper.GetString("url"), viper.GetString("api_key")); err != nil {
+			log.Fatal().Msg(err.Error())
+		}
+		if err := writeConfigString(cfgFile, tokenKey(), secret.Keyring); err != nil {
+			log.Fatal().Msgf("writing config file: %s", err.Error())
+		}
+		log.Info().Msgf("moved the access token for %s into the OS keyring", viper.GetString("url"))
+	},
+}
+
+// tokenKey returns the key of the access token in the config: the profile's with --profile.
+func tokenKey() string {
+	if name := profileName(); name != "" {
+		return "profiles." + name + ".api_key"
+	}
+	return "api_key"
+}
+
+func init() {
+	rootCmd.AddCommand(encryptTokenCmd, storeTokenCmd)
+}
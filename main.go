@@ -1,6 +1,11 @@
 package main
 
-import "github.com/poolski/powertracker/cmd"
+import (
+	// Windows has no time zone database of its own.
+	_ "time/tzdata"
+
+	"github.com/poolski/powertracker/cmd"
+)
 
 func main() {
 	cmd.Execute()